@@ -0,0 +1,111 @@
+package toki
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+)
+
+// ErrAdvisoryLocksUnsupported is returned by WithAdvisoryLock and
+// TryAdvisoryLock for dialects without advisory locks
+var ErrAdvisoryLocksUnsupported = errors.New("advisory locks are not supported by the dialect")
+
+// advisoryLockSQL holds the statements taking and releasing an advisory
+// lock, sent as written and so in the dialect's placeholder style. lock and
+// try return whether the lock was taken. Locks without
+// unlock are released when the transaction ends.
+type advisoryLockSQL struct {
+	lock   string
+	try    string
+	unlock string
+	// named locks take a string name rather than the int64 key
+	named bool
+}
+
+// AdvisoryKey derives a lock key from a name with 64-bit FNV-1a, so
+// processes agree on the key for the same name
+func AdvisoryKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+// SupportsAdvisoryLocks reports whether WithAdvisoryLock can take a lock
+// with the dialect
+func (d *Dialect) SupportsAdvisoryLocks() bool {
+	return d.advisoryLocks != nil
+}
+
+// WithAdvisoryLock runs fn inside RunInTx holding the advisory lock key,
+// waiting for it when another session holds it. On PostgreSQL the lock is
+// pg_advisory_xact_lock, released when the transaction ends; MySQL takes
+// GET_LOCK on the transaction's connection and releases it after fn. Other
+// dialects return ErrAdvisoryLocksUnsupported. Locks use the package-wide
+// dialect.
+func WithAdvisoryLock(ctx context.Context, db *sql.DB, key int64, fn func(tx *Transaction) error) error {
+	_, err := runLocked(ctx, db, defaultDialect, key, false, fn)
+	return err
+}
+
+// WithAdvisoryLockFor is WithAdvisoryLock with the locks of dialect d
+// rather than the package-wide dialect
+func WithAdvisoryLockFor(ctx context.Context, db *sql.DB, d *Dialect, key int64, fn func(tx *Transaction) error) error {
+	_, err := runLocked(ctx, db, d, key, false, fn)
+	return err
+}
+
+// TryAdvisoryLock is WithAdvisoryLock without waiting: when another session
+// holds the lock it returns false without running fn
+func TryAdvisoryLock(ctx context.Context, db *sql.DB, key int64, fn func(tx *Transaction) error) (bool, error) {
+	return runLocked(ctx, db, defaultDialect, key, true, fn)
+}
+
+func runLocked(ctx context.Context, db *sql.DB, d *Dialect, key int64, try bool, fn func(tx *Transaction) error) (bool, error) {
+	locks := d.advisoryLocks
+	if locks == nil {
+		return false, fmt.Errorf("%w: %s", ErrAdvisoryLocksUnsupported, d)
+	}
+
+	var arg interface{} = key
+	if locks.named {
+		arg = "toki:" + strconv.FormatInt(key, 10)
+	}
+	query := locks.lock
+	if try {
+		query = locks.try
+	}
+
+	acquired := false
+	err := RunInTx(ctx, db, nil, func(tx *Transaction) (err error) {
+		q := QueryInfo{SQL: query, Args: []interface{}{arg}}
+		if try || locks.named {
+			var ok sql.NullBool
+			if err := (hooks{}).queryRow(ctx, tx.tx, q).Scan(&ok); err != nil {
+				return fmt.Errorf("failed to take advisory lock %d: %w", key, err)
+			}
+			if !ok.Bool && try {
+				return nil
+			}
+			if !ok.Bool {
+				return fmt.Errorf("failed to take advisory lock %d", key)
+			}
+		} else if _, err := (hooks{}).exec(ctx, tx.tx, q); err != nil {
+			// pg_advisory_xact_lock returns void, so it is run as a statement
+			return fmt.Errorf("failed to take advisory lock %d: %w", key, err)
+		}
+		acquired = true
+
+		if locks.unlock != "" {
+			defer func() {
+				if _, unlockErr := (hooks{}).exec(ctx, tx.tx, QueryInfo{SQL: locks.unlock, Args: []interface{}{arg}}); unlockErr != nil && err == nil {
+					err = fmt.Errorf("failed to release advisory lock %d: %w", key, unlockErr)
+				}
+			}()
+		}
+		return fn(tx)
+	})
+	return acquired, err
+}
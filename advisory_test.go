@@ -0,0 +1,89 @@
+package toki
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdvisoryKey(t *testing.T) {
+	assert.Equal(t, int64(4417635575514249131), AdvisoryKey("nightly-report"))
+	assert.NotEqual(t, AdvisoryKey("nightly-report"), AdvisoryKey("nightly-reports"))
+}
+
+func TestWithAdvisoryLock(t *testing.T) {
+	db, mock := newMockDB(t)
+	key := AdvisoryKey("nightly-report")
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT pg_advisory_xact_lock($1)").WithArgs(key).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE jobs SET last_run = NOW()").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := WithAdvisoryLock(context.Background(), db, key, func(tx *Transaction) error {
+		_, err := tx.Raw("UPDATE jobs SET last_run = NOW()").Exec()
+		return err
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTryAdvisoryLock(t *testing.T) {
+	db, mock := newMockDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT pg_try_advisory_xact_lock($1)").WithArgs(int64(42)).
+		WillReturnRows(sqlmock.NewRows([]string{"ok"}).AddRow(false))
+	mock.ExpectCommit()
+
+	ran := false
+	acquired, err := TryAdvisoryLock(context.Background(), db, 42, func(tx *Transaction) error {
+		ran = true
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.False(t, acquired)
+	assert.False(t, ran)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT pg_try_advisory_xact_lock($1)").WithArgs(int64(42)).
+		WillReturnRows(sqlmock.NewRows([]string{"ok"}).AddRow(true))
+	mock.ExpectRollback()
+
+	failed := errors.New("job failed")
+	acquired, err = TryAdvisoryLock(context.Background(), db, 42, func(tx *Transaction) error {
+		return failed
+	})
+	assert.ErrorIs(t, err, failed)
+	assert.True(t, acquired)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAdvisoryLockMySQL(t *testing.T) {
+	SetDialect(MySQL)
+	defer SetDialect(nil)
+
+	db, mock := newMockDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT GET_LOCK(?, -1) = 1").WithArgs("toki:7").
+		WillReturnRows(sqlmock.NewRows([]string{"ok"}).AddRow(1))
+	mock.ExpectExec("SELECT RELEASE_LOCK(?)").WithArgs("toki:7").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	err := WithAdvisoryLock(context.Background(), db, 7, func(tx *Transaction) error { return nil })
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAdvisoryLockUnsupported(t *testing.T) {
+	SetDialect(SQLite)
+	defer SetDialect(nil)
+
+	_, err := TryAdvisoryLock(context.Background(), nil, 1, func(tx *Transaction) error { return nil })
+	assert.ErrorIs(t, err, ErrAdvisoryLocksUnsupported)
+	assert.EqualError(t, err, "advisory locks are not supported by the dialect: sqlite")
+}
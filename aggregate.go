@@ -0,0 +1,111 @@
+package toki
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AggregateExpr is an aggregate function call for SelectExpr, optionally
+// restricted by a FILTER (WHERE ...) condition with bound args:
+//
+//	toki.New().SelectExpr(
+//		toki.Count("*").Filter("status = ?", "failed").As("failed"),
+//		toki.Sum("amount").Filter("status = ?", "paid").As("revenue"),
+//	).From("orders")
+type AggregateExpr struct {
+	fn         string
+	arg        string
+	filter     string
+	filterArgs []interface{}
+	alias      string
+}
+
+// Aggregate calls the aggregate function fn on arg, for functions without
+// their own constructor such as Aggregate("ARRAY_AGG", "name")
+func Aggregate(fn, arg string) *AggregateExpr {
+	return &AggregateExpr{fn: strings.ToUpper(fn), arg: arg}
+}
+
+// Count is COUNT(arg), with arg "*" to count rows
+func Count(arg string) *AggregateExpr { return Aggregate("COUNT", arg) }
+
+// Sum is SUM(arg)
+func Sum(arg string) *AggregateExpr { return Aggregate("SUM", arg) }
+
+// Avg is AVG(arg)
+func Avg(arg string) *AggregateExpr { return Aggregate("AVG", arg) }
+
+// Min is MIN(arg)
+func Min(arg string) *AggregateExpr { return Aggregate("MIN", arg) }
+
+// Max is MAX(arg)
+func Max(arg string) *AggregateExpr { return Aggregate("MAX", arg) }
+
+// Filter restricts the rows aggregated to those matching condition. Its ?
+// placeholders are bound to args where the expression is used. Dialects
+// without FILTER get arg wrapped in CASE WHEN condition THEN arg END, which
+// only works for the aggregates ignoring NULLs: COUNT, SUM, AVG, MIN and
+// MAX.
+func (a *AggregateExpr) Filter(condition string, args ...interface{}) *AggregateExpr {
+	a.filter = condition
+	a.filterArgs = args
+	return a
+}
+
+// As names the result column
+func (a *AggregateExpr) As(alias string) *AggregateExpr {
+	a.alias = alias
+	return a
+}
+
+// SQL renders the aggregate with ? placeholders for the filter args
+func (a *AggregateExpr) SQL() string {
+	query, _, _ := a.bind(Postgres)
+	return query
+}
+
+func (a *AggregateExpr) bind(d *Dialect) (string, []interface{}, error) {
+	query := a.fn + "(" + a.arg + ")"
+
+	var err error
+	switch {
+	case a.filter == "":
+	case d.aggregateFilter:
+		query += " FILTER (WHERE " + a.filter + ")"
+	default:
+		query, err = a.caseFilter(d)
+	}
+
+	if a.alias != "" {
+		query += " AS " + a.alias
+	}
+	return query, a.filterArgs, err
+}
+
+// caseFilter rewrites the filter as a CASE inside the aggregate. Rows not
+// matching become NULL, which COUNT, SUM, AVG, MIN and MAX skip.
+func (a *AggregateExpr) caseFilter(d *Dialect) (string, error) {
+	switch a.fn {
+	case "COUNT", "SUM", "AVG", "MIN", "MAX":
+	default:
+		return "", fmt.Errorf("the %s dialect has no FILTER and %s cannot be rewritten with CASE", d, a.fn)
+	}
+
+	arg, distinct := a.arg, ""
+	if rest, ok := cutPrefixFold(arg, "DISTINCT "); ok {
+		arg, distinct = rest, "DISTINCT "
+	}
+	if arg == "*" {
+		// COUNT(*) counts rows, so any non-NULL value stands in for them
+		arg = "1"
+	}
+	return a.fn + "(" + distinct + "CASE WHEN " + a.filter + " THEN " + arg + " END)", nil
+}
+
+// cutPrefixFold is strings.CutPrefix ignoring the case of prefix
+func cutPrefixFold(s, prefix string) (string, bool) {
+	if len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix) {
+		return s[len(prefix):], true
+	}
+	return s, false
+}
@@ -0,0 +1,45 @@
+package toki
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAggregateFilter(t *testing.T) {
+	b := New().SelectExpr(
+		Raw("region"),
+		Count("*").Filter("status = ?", "failed").As("failed"),
+		Sum("amount").Filter("status = ? AND amount > ?", "paid", 100).As("big_revenue"),
+	).From("orders").Where("created_at > ?", TestTime)
+	assert.NoError(t, b.Err())
+	assert.Equal(t, "SELECT region, COUNT(*) FILTER (WHERE status = $1) AS failed, SUM(amount) FILTER (WHERE status = $2 AND amount > $3) AS big_revenue FROM orders WHERE created_at > $4", b.String())
+	assert.Equal(t, []interface{}{"failed", "paid", 100, TestTime}, b.args)
+
+	b = New().WithDialect(MySQL).SelectExpr(
+		Count("*").Filter("status = ?", "failed").As("failed"),
+		Avg("amount").Filter("status = ?", "paid"),
+		Count("DISTINCT customer_id").Filter("amount > ?", 0),
+	).From("orders")
+	assert.NoError(t, b.Err())
+	assert.Equal(t, "SELECT COUNT(CASE WHEN status = ? THEN 1 END) AS failed, AVG(CASE WHEN status = ? THEN amount END), COUNT(DISTINCT CASE WHEN amount > ? THEN customer_id END) FROM orders", b.String())
+	assert.Equal(t, []interface{}{"failed", "paid", 0}, b.args)
+
+	assert.Equal(t, "MAX(score) FILTER (WHERE team = ?)", Max("score").Filter("team = ?", "red").SQL())
+	assert.Equal(t, "MIN(score) AS low", Min("score").As("low").SQL())
+}
+
+func TestAggregateFilterUnsupported(t *testing.T) {
+	db, _, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	b := New().WithDialect(MySQL).SelectExpr(
+		Aggregate("json_arrayagg", "name").Filter("active = ?", true),
+	).From("users")
+	assert.EqualError(t, b.Err(), "the mysql dialect has no FILTER and JSON_ARRAYAGG cannot be rewritten with CASE")
+
+	_, err = b.Prepare(db)
+	assert.Equal(t, b.Err(), err)
+}
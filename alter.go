@@ -0,0 +1,230 @@
+package toki
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type alterKind int
+
+const (
+	alterAddColumn alterKind = iota
+	alterDropColumn
+	alterRenameColumn
+	alterColumnType
+	alterAddConstraint
+	alterDropConstraint
+)
+
+type alterAction struct {
+	kind   alterKind
+	column columnDef
+	// name is the column or constraint acted on, to the new column name
+	// for renames and def the type or constraint definition
+	name, to, def, using string
+}
+
+// AlterTableBuilder builds the ALTER TABLE statements changing one table.
+// Actions are combined into one statement where the dialect allows it and
+// rendered as separate statements otherwise.
+type AlterTableBuilder struct {
+	b       *Builder
+	name    string
+	actions []alterAction
+}
+
+// AlterTable starts altering a table. It runs on the builder's transaction
+// when it has one and renders for the builder's dialect.
+func (b *Builder) AlterTable(name string) *AlterTableBuilder {
+	return &AlterTableBuilder{b: b, name: name}
+}
+
+// AddColumn adds a column, with the same types and options as
+// CreateTableBuilder.Column
+func (t *AlterTableBuilder) AddColumn(name, typ string, opts ...ColumnOption) *AlterTableBuilder {
+	col := columnDef{name: name, typ: typ}
+	for _, opt := range opts {
+		opt(&col)
+	}
+	t.actions = append(t.actions, alterAction{kind: alterAddColumn, column: col})
+	return t
+}
+
+// DropColumn drops a column
+func (t *AlterTableBuilder) DropColumn(name string) *AlterTableBuilder {
+	t.actions = append(t.actions, alterAction{kind: alterDropColumn, name: name})
+	return t
+}
+
+// RenameColumn renames column from to to. PostgreSQL cannot combine a
+// rename with other actions, so it always gets its own statement.
+func (t *AlterTableBuilder) RenameColumn(from, to string) *AlterTableBuilder {
+	t.actions = append(t.actions, alterAction{kind: alterRenameColumn, name: from, to: to})
+	return t
+}
+
+// AlterColumnType changes the type of a column, converting existing values
+// with the using expression when it is not empty. SQLite does not support
+// it.
+func (t *AlterTableBuilder) AlterColumnType(name, typ, using string) *AlterTableBuilder {
+	t.actions = append(t.actions, alterAction{kind: alterColumnType, name: name, def: typ, using: using})
+	return t
+}
+
+// AddConstraint adds a named table constraint such as "UNIQUE (email)" or
+// "CHECK (price > 0)". The definition is SQL and is not validated. SQLite
+// does not support it.
+func (t *AlterTableBuilder) AddConstraint(name, definition string) *AlterTableBuilder {
+	t.actions = append(t.actions, alterAction{kind: alterAddConstraint, name: name, def: definition})
+	return t
+}
+
+// DropConstraint drops a named table constraint. SQLite does not support
+// it.
+func (t *AlterTableBuilder) DropConstraint(name string) *AlterTableBuilder {
+	t.actions = append(t.actions, alterAction{kind: alterDropConstraint, name: name})
+	return t
+}
+
+// Statements builds the statements without validating them
+func (t *AlterTableBuilder) Statements() []string {
+	dialect := t.b.Dialect()
+
+	var (
+		statements []string
+		group      []string
+	)
+	flush := func() {
+		if len(group) > 0 {
+			statements = append(statements, "ALTER TABLE "+t.name+" "+strings.Join(group, ", "))
+			group = group[:0]
+		}
+	}
+
+	for _, action := range t.actions {
+		if !dialect.multiAlter || action.kind == alterRenameColumn {
+			flush()
+			statements = append(statements, "ALTER TABLE "+t.name+" "+action.render(dialect))
+			continue
+		}
+		group = append(group, action.render(dialect))
+	}
+	flush()
+
+	return statements
+}
+
+// String builds the statements without validating them, separated by
+// semicolons
+func (t *AlterTableBuilder) String() string {
+	return strings.Join(t.Statements(), "; ")
+}
+
+// Validate checks names and types and rejects actions the dialect does not
+// support
+func (t *AlterTableBuilder) Validate() error {
+	if err := checkIdent("table", t.name); err != nil {
+		return err
+	}
+	if len(t.actions) == 0 {
+		return fmt.Errorf("ALTER TABLE %s has no actions", t.name)
+	}
+
+	dialect := t.b.Dialect()
+	for _, action := range t.actions {
+		if err := action.validate(dialect); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Exec validates the statements and executes them in order, stopping at
+// the first failure. They are not wrapped in a transaction; attach one to
+// the builder to apply them atomically.
+func (t *AlterTableBuilder) Exec(ctx context.Context, db *sql.DB) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	if t.b.tx != nil && t.b.tx.readOnly {
+		return fmt.Errorf("cannot run %s statement: %w", KindDDL, ErrReadOnlyTransaction)
+	}
+
+	var tx *sql.Tx
+	if t.b.tx != nil {
+		tx = t.b.tx.tx
+	}
+	c := connFor(db, tx)
+	if c == nil {
+		return errors.New("DDL statement needs a database or transaction")
+	}
+
+	for _, query := range t.Statements() {
+		if _, err := t.b.hooks.exec(ctx, c, QueryInfo{SQL: query, Kind: KindDDL, Table: t.name}); err != nil {
+			return fmt.Errorf("failed to alter table %s: %w", t.name, err)
+		}
+	}
+	return nil
+}
+
+func (a *alterAction) render(dialect *Dialect) string {
+	switch a.kind {
+	case alterAddColumn:
+		var sb strings.Builder
+		sb.WriteString("ADD COLUMN ")
+		a.column.render(&sb, dialect)
+		return sb.String()
+	case alterDropColumn:
+		return "DROP COLUMN " + a.name
+	case alterRenameColumn:
+		return "RENAME COLUMN " + a.name + " TO " + a.to
+	case alterColumnType:
+		query := "ALTER COLUMN " + a.name + " TYPE " + dialect.columnType(a.def)
+		if a.using != "" {
+			query += " USING " + a.using
+		}
+		return query
+	case alterAddConstraint:
+		return "ADD CONSTRAINT " + a.name + " " + a.def
+	default:
+		return "DROP CONSTRAINT " + a.name
+	}
+}
+
+func (a *alterAction) validate(dialect *Dialect) error {
+	switch a.kind {
+	case alterAddColumn:
+		return a.column.validate()
+	case alterDropColumn:
+		return checkIdent("column", a.name)
+	case alterRenameColumn:
+		if err := checkIdent("column", a.name); err != nil {
+			return err
+		}
+		return checkIdent("column", a.to)
+	case alterColumnType:
+		if !dialect.alterColumns {
+			return fmt.Errorf("ALTER COLUMN ... TYPE is not supported by the %s dialect", dialect)
+		}
+		if err := checkIdent("column", a.name); err != nil {
+			return err
+		}
+		return checkColumnType(a.name, a.def)
+	case alterAddConstraint, alterDropConstraint:
+		if !dialect.alterColumns {
+			keyword := "ADD CONSTRAINT"
+			if a.kind == alterDropConstraint {
+				keyword = "DROP CONSTRAINT"
+			}
+			return fmt.Errorf("%s is not supported by the %s dialect", keyword, dialect)
+		}
+		if a.kind == alterAddConstraint && strings.TrimSpace(a.def) == "" {
+			return fmt.Errorf("constraint %s has no definition", a.name)
+		}
+		return checkIdent("constraint", a.name)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package toki
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// BatchError reports the statement of a batch that failed
+type BatchError struct {
+	// Index is the 0-based position of the statement in the batch. The
+	// error message counts from 1.
+	Index     int
+	Statement string
+	Err       error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("batch statement %d (%s) failed: %v", e.Index+1, snippet(e.Statement, 60), e.Err)
+}
+
+func (e *BatchError) Unwrap() error { return e.Err }
+
+// Batchable is a statement that can be added to a Batch: a *Builder or a
+// *RawQuery
+type Batchable interface {
+	batchQuery() (QueryInfo, hooks, error)
+}
+
+func (b *Builder) batchQuery() (QueryInfo, hooks, error) {
+	if err := b.validate(); err != nil {
+		return QueryInfo{}, b.hooks, err
+	}
+	return QueryInfo{SQL: b.String(), Args: b.Args(), Kind: b.kind, Table: b.table}, b.hooks, nil
+}
+
+func (r *RawQuery) batchQuery() (QueryInfo, hooks, error) {
+	return QueryInfo{SQL: r.sql, Args: r.args, Kind: KindRaw}, r.hooks, nil
+}
+
+// Batch runs several write statements in order over one connection,
+// inside one transaction unless NoTransaction is set. Statements keep their
+// own hooks but not their transactions or contexts: the batch runs with
+// the context given to Exec.
+type Batch struct {
+	items []Batchable
+	noTx  bool
+}
+
+// NewBatch returns an empty batch
+func NewBatch() *Batch {
+	return &Batch{}
+}
+
+// Add appends statements to the batch
+func (b *Batch) Add(statements ...Batchable) *Batch {
+	b.items = append(b.items, statements...)
+	return b
+}
+
+// NoTransaction runs the statements without a transaction, so those before
+// a failing one stay applied
+func (b *Batch) NoTransaction() *Batch {
+	b.noTx = true
+	return b
+}
+
+// Len returns the number of statements in the batch
+func (b *Batch) Len() int {
+	return len(b.items)
+}
+
+// Queries renders the statements, for adapters running batches natively.
+// A statement that cannot be rendered is reported as a *BatchError.
+func (b *Batch) Queries() ([]QueryInfo, error) {
+	queries := make([]QueryInfo, len(b.items))
+	for i, item := range b.items {
+		q, _, err := item.batchQuery()
+		if err != nil {
+			return nil, &BatchError{Index: i, Statement: q.SQL, Err: err}
+		}
+		queries[i] = q
+	}
+	return queries, nil
+}
+
+// Exec runs the statements in order on one connection from db and returns
+// their results. It stops at the first failing statement, reported as a
+// *BatchError; inside the transaction all statements are rolled back.
+func (b *Batch) Exec(ctx context.Context, db *sql.DB) ([]sql.Result, error) {
+	if db == nil {
+		return nil, errors.New("batch needs a database")
+	}
+	if len(b.items) == 0 {
+		return nil, nil
+	}
+	// render everything before touching the database
+	if _, err := b.Queries(); err != nil {
+		return nil, err
+	}
+
+	if !b.noTx {
+		var results []sql.Result
+		err := RunInTx(ctx, db, nil, func(tx *Transaction) error {
+			var err error
+			results, err = b.run(ctx, tx.tx)
+			return err
+		})
+		return results, err
+	}
+
+	c, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get a connection for the batch: %w", err)
+	}
+	defer c.Close()
+	return b.run(ctx, c)
+}
+
+func (b *Batch) run(ctx context.Context, c conn) ([]sql.Result, error) {
+	results := make([]sql.Result, 0, len(b.items))
+	for i, item := range b.items {
+		q, h, _ := item.batchQuery()
+		res, err := h.exec(ctx, c, q)
+		if err != nil {
+			return results, &BatchError{Index: i, Statement: q.SQL, Err: err}
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
@@ -0,0 +1,81 @@
+package toki
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchExec(t *testing.T) {
+	db, mock := newMockDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO orders (id, total) VALUES ($1, $2)").WithArgs(1, 50).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE stock SET qty = qty - 1 WHERE sku = $1").WithArgs("a").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM carts WHERE user_id = $1").WithArgs(9).WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	batch := NewBatch().
+		Add(New().Insert("orders", "id", "total").Values(1, 50)).
+		Add(New().Raw("UPDATE stock SET qty = qty - 1 WHERE sku = $1", "a"), New().Delete("carts").Where("user_id = ?", 9))
+	assert.Equal(t, 3, batch.Len())
+
+	results, err := batch.Exec(context.Background(), db)
+	assert.NoError(t, err)
+	assert.Len(t, results, 3)
+	n, _ := results[2].RowsAffected()
+	assert.Equal(t, int64(3), n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBatchErrorAttribution(t *testing.T) {
+	db, mock := newMockDB(t)
+	failed := errors.New("constraint violated")
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO a (x) VALUES ($1)").WithArgs(1).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO b (y) VALUES ($1)").WithArgs(2).WillReturnError(failed)
+	mock.ExpectRollback()
+
+	results, err := NewBatch().
+		Add(New().Insert("a", "x").Values(1)).
+		Add(New().Insert("b", "y").Values(2)).
+		Add(New().Insert("c", "z").Values(3)).
+		Exec(context.Background(), db)
+
+	var batchErr *BatchError
+	assert.ErrorAs(t, err, &batchErr)
+	assert.Equal(t, 1, batchErr.Index)
+	assert.ErrorIs(t, err, failed)
+	assert.EqualError(t, err, "batch statement 2 (INSERT INTO b (y) VALUES ($1)) failed: constraint violated")
+	assert.Len(t, results, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBatchNoTransaction(t *testing.T) {
+	db, mock := newMockDB(t)
+
+	mock.ExpectExec("DELETE FROM a").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM b").WillReturnError(assert.AnError)
+
+	_, err := NewBatch().NoTransaction().
+		Add(New().Raw("DELETE FROM a"), New().Raw("DELETE FROM b")).
+		Exec(context.Background(), db)
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBatchRendersFirst(t *testing.T) {
+	db, mock := newMockDB(t)
+
+	broken := New().WithDialect(MySQL).SelectExpr(Aggregate("json_arrayagg", "x").Filter("y")).From("t")
+	_, err := NewBatch().Add(New().Raw("DELETE FROM a"), broken).Exec(context.Background(), db)
+
+	var batchErr *BatchError
+	assert.ErrorAs(t, err, &batchErr)
+	assert.Equal(t, 1, batchErr.Index)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
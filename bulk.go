@@ -0,0 +1,173 @@
+package toki
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// maxBindParams is the largest number of bind parameters ExecChunked puts in
+// one statement, PostgreSQL's limit
+const maxBindParams = 65535
+
+// ChunkError reports the chunk of a bulk insert that failed
+type ChunkError struct {
+	// Chunk is the 0-based index of the failed chunk and Start and End the
+	// range of rows it held
+	Chunk      int
+	Start, End int
+	Err        error
+}
+
+func (e *ChunkError) Error() string {
+	return fmt.Sprintf("bulk insert chunk %d (rows %d-%d) failed: %v", e.Chunk, e.Start, e.End-1, e.Err)
+}
+
+func (e *ChunkError) Unwrap() error { return e.Err }
+
+// preparer is implemented by *sql.DB and *sql.Tx
+type preparer interface {
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// ExecChunked executes a multi-row INSERT built with repeated Values calls in
+// chunks of at most chunkSize rows and returns the total rows affected. The
+// chunk size is lowered when needed to stay under the bind parameter limit;
+// chunkSize <= 0 uses the largest size the limit allows. Each chunk shape is
+// prepared once and reused. It runs on the builder's transaction when it has
+// one, otherwise on db, inside a single transaction with InTransaction. A
+// failing chunk is reported as a *ChunkError; with a transaction everything
+// is rolled back.
+func (b *Builder) ExecChunked(ctx context.Context, db *sql.DB, chunkSize int, opts ...ExecOption) (int64, error) {
+	if b.kind != KindInsert || len(b.rows) == 0 {
+		return 0, errors.New("ExecChunked needs an INSERT with at least one Values row")
+	}
+	if b.tx != nil && b.tx.readOnly {
+		return 0, fmt.Errorf("cannot run %s statement: %w", b.kind, ErrReadOnlyTransaction)
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+
+	bound := 0
+	width := 1
+	for _, row := range b.rows {
+		n := b.boundValues(row)
+		bound += n
+		if n > width {
+			width = n
+		}
+	}
+	if bound != len(b.args) {
+		return 0, errors.New("ExecChunked cannot split a statement with args outside its VALUES rows")
+	}
+
+	if limit := maxBindParams / width; chunkSize <= 0 || chunkSize > limit {
+		chunkSize = limit
+	}
+
+	cfg := &execConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	switch {
+	case b.tx != nil:
+		return b.execChunks(ctx, b.tx.tx, chunkSize)
+	case db == nil:
+		return 0, errors.New("ExecChunked needs a database or transaction")
+	case !cfg.inTx:
+		return b.execChunks(ctx, db, chunkSize)
+	}
+
+	tx, err := b.hooks.begin(ctx, db, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin bulk insert transaction: %w", err)
+	}
+
+	total, err := b.execChunks(ctx, tx, chunkSize)
+	if err != nil {
+		if rbErr := b.hooks.rollback(ctx, tx); rbErr != nil {
+			return 0, errors.Join(err, rbErr)
+		}
+		return 0, err
+	}
+
+	if err := b.hooks.commit(ctx, tx); err != nil {
+		return 0, fmt.Errorf("failed to commit bulk insert: %w", err)
+	}
+	return total, nil
+}
+
+// execChunks renders and runs each chunk, preparing every distinct chunk
+// statement once
+func (b *Builder) execChunks(ctx context.Context, p preparer, chunkSize int) (int64, error) {
+	stmts := make(map[string]*sql.Stmt)
+	defer func() {
+		for _, stmt := range stmts {
+			stmt.Close()
+		}
+	}()
+
+	var total int64
+	for chunk, start := 0, 0; start < len(b.rows); chunk, start = chunk+1, start+chunkSize {
+		end := start + chunkSize
+		if end > len(b.rows) {
+			end = len(b.rows)
+		}
+
+		query, args := b.renderChunk(b.rows[start:end])
+		if comment := b.hooks.comment(ctx); comment != "" {
+			query = appendComment(query, comment)
+		}
+
+		stmt, ok := stmts[query]
+		if !ok {
+			var err error
+			if stmt, err = p.PrepareContext(ctx, query); err != nil {
+				return total, &ChunkError{Chunk: chunk, Start: start, End: end, Err: err}
+			}
+			stmts[query] = stmt
+		}
+
+		res, err := b.hooks.exec(ctx, stmtConn{stmt}, QueryInfo{SQL: query, Args: args, Kind: KindInsert, Table: b.table})
+		if err != nil {
+			return total, &ChunkError{Chunk: chunk, Start: start, End: end, Err: err}
+		}
+
+		if n, err := res.RowsAffected(); err == nil {
+			total += n
+		}
+	}
+
+	return total, nil
+}
+
+// renderChunk renders the INSERT with only the given rows
+func (b *Builder) renderChunk(rows [][]interface{}) (string, []interface{}) {
+	chunk := &Builder{clauses: b.clauses, dialect: b.dialect, identCase: b.identCase, hooks: b.hooks}
+	chunk.clauses.values = make([]string, len(rows))
+	for i, row := range rows {
+		chunk.clauses.values[i] = chunk.renderRow(row)
+	}
+
+	return chunk.String(), chunk.Args()
+}
+
+// boundValues counts the args row binds: its plain values and the args of
+// its bound expressions
+func (b *Builder) boundValues(row []interface{}) int {
+	n := 0
+	for _, val := range row {
+		switch v := val.(type) {
+		case boundExpression:
+			_, args, _ := v.bind(b.Dialect())
+			n += len(args)
+		case SQLExpression:
+		default:
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,119 @@
+package toki
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func bulkInsert(rows int) *Builder {
+	b := New().Insert("events", "user_id", "kind", "created_at")
+	for i := 1; i <= rows; i++ {
+		b.Values(i, "click", Raw("NOW()"))
+	}
+	return b
+}
+
+func TestMultiRowValues(t *testing.T) {
+	b := bulkInsert(3).Returning("id")
+
+	assert.Equal(t, "INSERT INTO events (user_id, kind, created_at) VALUES ($1, $2, NOW()), ($3, $4, NOW()), ($5, $6, NOW()) RETURNING id", b.String())
+	assert.Equal(t, []interface{}{1, "click", 2, "click", 3, "click"}, b.args)
+}
+
+func TestExecChunked(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	full := mock.ExpectPrepare("INSERT INTO events (user_id, kind, created_at) VALUES ($1, $2, NOW()), ($3, $4, NOW())")
+	full.ExpectExec().WithArgs(1, "click", 2, "click").WillReturnResult(sqlmock.NewResult(0, 2))
+	full.ExpectExec().WithArgs(3, "click", 4, "click").WillReturnResult(sqlmock.NewResult(0, 2))
+	last := mock.ExpectPrepare("INSERT INTO events (user_id, kind, created_at) VALUES ($1, $2, NOW())")
+	last.ExpectExec().WithArgs(5, "click").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	total, err := bulkInsert(5).ExecChunked(context.Background(), db, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), total)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExecChunkedRollback(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	failure := errors.New("duplicate key")
+	mock.ExpectBegin()
+	prep := mock.ExpectPrepare("INSERT INTO events")
+	prep.ExpectExec().WithArgs(1, "click", 2, "click").WillReturnResult(sqlmock.NewResult(0, 2))
+	prep.ExpectExec().WithArgs(3, "click", 4, "click").WillReturnError(failure)
+	mock.ExpectRollback()
+
+	total, err := bulkInsert(5).ExecChunked(context.Background(), db, 2, InTransaction())
+	assert.Equal(t, int64(0), total)
+	assert.ErrorIs(t, err, failure)
+
+	var chunkErr *ChunkError
+	assert.True(t, errors.As(err, &chunkErr))
+	assert.Equal(t, 1, chunkErr.Chunk)
+	assert.EqualError(t, err, "bulk insert chunk 1 (rows 2-3) failed: duplicate key")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExecChunkedDialect(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	b := New().WithDialect(MySQL).Insert("t", "a", "b", "c")
+	for i := 1; i <= 3; i++ {
+		b.Values(Cast(Expr("NOW()"), "TEXT"), i, Cast(i, "TEXT"))
+	}
+	assert.Equal(t, "INSERT INTO t (a, b, c) VALUES (CAST(NOW() AS TEXT), ?, CAST(? AS TEXT)), "+
+		"(CAST(NOW() AS TEXT), ?, CAST(? AS TEXT)), (CAST(NOW() AS TEXT), ?, CAST(? AS TEXT))", b.String())
+
+	full := mock.ExpectPrepare("INSERT INTO t (a, b, c) VALUES (CAST(NOW() AS TEXT), ?, CAST(? AS TEXT)), (CAST(NOW() AS TEXT), ?, CAST(? AS TEXT))")
+	full.ExpectExec().WithArgs(1, 1, 2, 2).WillReturnResult(sqlmock.NewResult(0, 2))
+	last := mock.ExpectPrepare("INSERT INTO t (a, b, c) VALUES (CAST(NOW() AS TEXT), ?, CAST(? AS TEXT))")
+	last.ExpectExec().WithArgs(3, 3).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	total, err := b.ExecChunked(context.Background(), db, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExecChunkedLimits(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	// 3 bound values per row: one statement holds at most 21845 rows
+	b := New().Insert("t", "a", "b", "c")
+	for i := 0; i < 21846; i++ {
+		b.Values(i, i, i)
+	}
+
+	mock.ExpectPrepare("INSERT INTO t").ExpectExec().WillReturnResult(sqlmock.NewResult(0, 21845))
+	mock.ExpectPrepare("INSERT INTO t").ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+
+	total, err := b.ExecChunked(context.Background(), db, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(21846), total)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	_, err = New().Select("*").From("t").ExecChunked(context.Background(), db, 10)
+	assert.EqualError(t, err, "ExecChunked needs an INSERT with at least one Values row")
+}
@@ -0,0 +1,58 @@
+package toki
+
+import "fmt"
+
+// CastExpr binds a value cast to an SQL type, made by Cast
+type CastExpr struct {
+	value   interface{}
+	sqlType string
+}
+
+// Cast binds value and casts it to sqlType, $1::uuid on PostgreSQL and
+// CAST($1 AS uuid) elsewhere, with the type mapped for the dialect as in
+// CreateTable. An SQLExpression value is cast inline, so Cast(Expr("price"),
+// "TEXT") casts a column. It can be used in Values, Set, SelectExpr and as
+// a condition arg: Where("id = ?", toki.Cast(id, "uuid")).
+//
+// sqlType is a type name with optional size and [] suffixes, such as
+// int[] or varchar(64); anything else fails the builder, reported by Err
+// and Prepare.
+func Cast(value interface{}, sqlType string) *CastExpr {
+	return &CastExpr{value: value, sqlType: sqlType}
+}
+
+// SQL renders the cast for PostgreSQL, with a ? placeholder for the value
+func (c *CastExpr) SQL() string {
+	query, _, _ := c.bind(Postgres)
+	return query
+}
+
+func (c *CastExpr) bind(d *Dialect) (string, []interface{}, error) {
+	if !columnTypePattern.MatchString(c.sqlType) {
+		return "?", []interface{}{c.value}, fmt.Errorf("invalid cast type %q", c.sqlType)
+	}
+
+	operand := "?"
+	var args []interface{}
+	switch v := c.value.(type) {
+	case boundExpression:
+		var err error
+		if operand, args, err = v.bind(d); err != nil {
+			return operand, args, err
+		}
+	case SQLExpression:
+		operand = v.SQL()
+	default:
+		args = []interface{}{normalizeArg(v)}
+	}
+
+	typ := d.columnType(c.sqlType)
+	if d.castOperator {
+		if _, ok := c.value.(SQLExpression); ok {
+			// :: binds tighter than any operator in the expression
+			operand = "(" + operand + ")"
+		}
+		return operand + "::" + typ, args, nil
+	}
+	return "CAST(" + operand + " AS " + typ + ")", args, nil
+}
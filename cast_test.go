@@ -0,0 +1,54 @@
+package toki
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCast(t *testing.T) {
+	id := "8c4f5e9a-0000-4000-8000-000000000001"
+
+	b := New().Select("*").From("users").
+		Where("id = ?", Cast(id, "uuid")).
+		AndWhere("team_id = ANY(?) AND age > ?", Cast("{1,2}", "int[]"), 18)
+	assert.NoError(t, b.Err())
+	assert.Equal(t, "SELECT * FROM users WHERE id = $1::uuid AND team_id = ANY($2::int[]) AND age > $3", b.String())
+	assert.Equal(t, []interface{}{id, "{1,2}", 18}, b.args)
+
+	b = New().Insert("events", "id", "tags", "at").Values(Cast(id, "uuid"), Cast("{a,b}", "text[]"), Raw("NOW()"))
+	assert.Equal(t, "INSERT INTO events (id, tags, at) VALUES ($1::uuid, $2::text[], NOW())", b.String())
+	assert.Equal(t, []interface{}{id, "{a,b}"}, b.args)
+
+	b = New().Update("products").Set(map[string]interface{}{
+		"price": Cast("9.50", "NUMERIC(10, 2)"),
+		"label": Cast(Raw("sku || '-' || id"), "TEXT"),
+		"stock": 3,
+	}).Where("id = ?", 1)
+	assert.Equal(t, "UPDATE products SET label = (sku || '-' || id)::TEXT, price = $1::NUMERIC(10, 2), stock = $2 WHERE id = $3", b.String())
+	assert.Equal(t, []interface{}{"9.50", 3, 1}, b.args)
+
+	b = New().SelectExpr(Cast(7, "bigint"), Raw("name")).From("users").Where("age > ?", 18)
+	assert.Equal(t, "SELECT $1::bigint, name FROM users WHERE age > $2", b.String())
+	assert.Equal(t, []interface{}{7, 18}, b.args)
+
+	assert.Equal(t, "?::uuid", Cast(id, "uuid").SQL())
+}
+
+func TestCastFallback(t *testing.T) {
+	b := New().WithDialect(MySQL).Select("*").From("events").
+		Where("created_at > ?", Cast("2024-01-01", "DATETIME")).
+		WhereNotDistinctFrom("parent_id", Cast(nil, "SIGNED"))
+	assert.Equal(t, "SELECT * FROM events WHERE created_at > CAST(? AS DATETIME) AND parent_id <=> CAST(? AS SIGNED)", b.String())
+	assert.Equal(t, []interface{}{"2024-01-01", nil}, b.args)
+
+	// SQLite maps PostgreSQL types as CreateTable does
+	b = New().WithDialect(SQLite).Insert("t", "id", "n").Values(Cast("x", "UUID"), Cast(Raw("a + b"), "INTEGER"))
+	assert.Equal(t, "INSERT INTO t (id, n) VALUES (CAST($1 AS TEXT), CAST(a + b AS INTEGER))", b.String())
+}
+
+func TestCastInvalidType(t *testing.T) {
+	b := New().Select("*").From("users").Where("id = ?", Cast(1, "int); DROP TABLE users; --"))
+	assert.EqualError(t, b.Err(), `invalid cast type "int); DROP TABLE users; --"`)
+	assert.Equal(t, "SELECT * FROM users WHERE id = $1", b.String())
+}
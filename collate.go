@@ -0,0 +1,67 @@
+package toki
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// collationPattern matches collation names such as C, de_DE.utf8,
+// und-x-icu and utf8mb4_0900_ai_ci. Collations cannot be bound as args, so
+// anything else is rejected.
+var collationPattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.@-]*$`)
+
+// CollateExpr is an expression compared or sorted with a collation, made by
+// Collate
+type CollateExpr struct {
+	expr      string
+	collation string
+	desc      bool
+}
+
+// Collate renders expr COLLATE "collation", quoting the collation for the
+// dialect it is used with. Use it with OrderByExpr and SelectExpr, or
+// WhereCollate for conditions. An invalid collation name fails the builder,
+// reported by Err and Prepare.
+func Collate(expr, collation string) *CollateExpr {
+	return &CollateExpr{expr: expr, collation: collation}
+}
+
+// Desc sorts in descending order when used with OrderByExpr
+func (c *CollateExpr) Desc() *CollateExpr {
+	c.desc = true
+	return c
+}
+
+// SQL renders the expression for PostgreSQL
+func (c *CollateExpr) SQL() string {
+	query, _, _ := c.bind(Postgres)
+	return query
+}
+
+func (c *CollateExpr) bind(d *Dialect) (string, []interface{}, error) {
+	if err := checkCollation(c.collation); err != nil {
+		return "", nil, err
+	}
+	query := c.expr + " COLLATE " + d.quoteIdent(c.collation)
+	if c.desc {
+		query += " DESC"
+	}
+	return query, nil, nil
+}
+
+// WhereCollate adds column = value COLLATE "collation", binding value. It
+// starts the WHERE clause or is ANDed to it.
+func (b *Builder) WhereCollate(column string, value interface{}, collation string) *Builder {
+	if err := checkCollation(collation); err != nil {
+		b.fail(err)
+		return b
+	}
+	return b.Where(column+" = ? COLLATE "+b.Dialect().quoteIdent(collation), value)
+}
+
+func checkCollation(name string) error {
+	if !collationPattern.MatchString(name) {
+		return fmt.Errorf("invalid collation name %q", name)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package toki
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollate(t *testing.T) {
+	b := New().Select("*").From("users").
+		WhereCollate("name", "müller", "und-x-icu").
+		OrderByExpr(Collate("name", "de_DE.utf8"), Collate("city", "C").Desc())
+	assert.NoError(t, b.Err())
+	assert.Equal(t, `SELECT * FROM users WHERE name = $1 COLLATE "und-x-icu" ORDER BY name COLLATE "de_DE.utf8", city COLLATE "C" DESC`, b.String())
+	assert.Equal(t, []interface{}{"müller"}, b.args)
+
+	b = New().WithDialect(MySQL).Select("*").From("users").
+		Where("active = ?", true).
+		WhereCollate("name", "ann", "utf8mb4_0900_ai_ci").
+		OrderByExpr(Collate("name", "utf8mb4_0900_ai_ci"))
+	assert.Equal(t, "SELECT * FROM users WHERE active = ? AND name = ? COLLATE `utf8mb4_0900_ai_ci` ORDER BY name COLLATE `utf8mb4_0900_ai_ci`", b.String())
+
+	assert.Equal(t, `name COLLATE "C"`, Collate("name", "C").SQL())
+}
+
+func TestCollateInvalid(t *testing.T) {
+	b := New().Select("*").From("users").WhereCollate("name", "x", `C" OR 1=1 --`)
+	assert.EqualError(t, b.Err(), `invalid collation name "C\" OR 1=1 --"`)
+	assert.Equal(t, "SELECT * FROM users", b.String())
+
+	b = New().Select("*").From("users").OrderByExpr(Collate("name", "de DE"))
+	assert.EqualError(t, b.Err(), `invalid collation name "de DE"`)
+}
@@ -0,0 +1,76 @@
+package toki
+
+// WithColumnMap renames the columns of the struct for this builder's Bind,
+// InsertStruct, UpdateStruct and DeleteStruct calls, for tables whose column
+// names differ from the struct's tags. Keys are the column names the struct
+// maps, values the names to use instead; an empty value leaves the field
+// out. Columns without an entry keep their name.
+func (b *Builder) WithColumnMap(m map[string]string) *Builder {
+	b.columnMap = m
+	return b
+}
+
+// WithColumnMap renames the columns of the destination struct for one scan.
+// Keys are the column names the struct maps, values the result column names
+// to read instead; an empty value leaves the field unscanned.
+func WithColumnMap(m map[string]string) ScanOption {
+	return func(c *scanConfig) {
+		c.columnMap = m
+	}
+}
+
+// mapColumn returns the name m gives col, or false when m leaves it out
+func mapColumn(m map[string]string, col string) (string, bool) {
+	if mapped, ok := m[col]; ok {
+		return mapped, mapped != ""
+	}
+	return col, true
+}
+
+// mapColumns renames columns by the builder's column map, dropping the
+// values of left out columns
+func (b *Builder) mapColumns(columns []string, values []interface{}) ([]string, []interface{}) {
+	if len(b.columnMap) == 0 {
+		return columns, values
+	}
+
+	mappedColumns := make([]string, 0, len(columns))
+	mappedValues := make([]interface{}, 0, len(values))
+	for i, col := range columns {
+		if mapped, ok := mapColumn(b.columnMap, col); ok {
+			mappedColumns = append(mappedColumns, mapped)
+			mappedValues = append(mappedValues, values[i])
+		}
+	}
+	return mappedColumns, mappedValues
+}
+
+// mapUpdates renames the keys of updates by the builder's column map
+func (b *Builder) mapUpdates(updates map[string]interface{}) map[string]interface{} {
+	if len(b.columnMap) == 0 {
+		return updates
+	}
+
+	mapped := make(map[string]interface{}, len(updates))
+	for col, v := range updates {
+		if name, ok := mapColumn(b.columnMap, col); ok {
+			mapped[name] = v
+		}
+	}
+	return mapped
+}
+
+// resultColumn returns the struct column the result column col scans into
+// under m, or false when m renames or leaves out the struct column of that
+// name
+func resultColumn(m map[string]string, col string) (string, bool) {
+	for from, to := range m {
+		if to == col && to != "" {
+			return from, true
+		}
+	}
+	if _, ok := m[col]; ok {
+		return "", false
+	}
+	return col, true
+}
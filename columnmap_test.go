@@ -0,0 +1,66 @@
+package toki
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+type legacyUser struct {
+	ID    int    `db:"id,pk"`
+	Name  string `db:"name"`
+	Email string `db:"email"`
+	Notes string `db:"notes"`
+}
+
+var legacyColumns = map[string]string{"id": "user_no", "name": "full_name", "notes": ""}
+
+func TestColumnMapStructHelpers(t *testing.T) {
+	u := legacyUser{ID: 7, Name: "zakirkun", Email: "z@example.com", Notes: "x"}
+
+	assert.Equal(t, map[string]interface{}{"user_no": 7, "full_name": "zakirkun", "email": "z@example.com"},
+		New().WithColumnMap(legacyColumns).Bind(u))
+
+	insert := New().WithColumnMap(legacyColumns).InsertStruct("old_users", u)
+	assert.Equal(t, "INSERT INTO old_users (user_no, full_name, email) VALUES ($1, $2, $3)", insert.String())
+	assert.Equal(t, []interface{}{7, "zakirkun", "z@example.com"}, insert.Args())
+
+	update := New().WithColumnMap(legacyColumns).UpdateStruct("old_users", u)
+	assert.Equal(t, "UPDATE old_users SET email = $1, full_name = $2 WHERE user_no = $3", update.String())
+
+	del := New().WithColumnMap(legacyColumns).DeleteStruct("old_users", u)
+	assert.Equal(t, "DELETE FROM old_users WHERE user_no = $1", del.String())
+
+	del = New().WithColumnMap(map[string]string{"id": ""}).DeleteStruct("old_users", u)
+	assert.EqualError(t, del.Err(), "pk column id cannot be excluded by the column map")
+
+	// the map is per builder
+	assert.Equal(t, "INSERT INTO users (id, name, email, notes) VALUES ($1, $2, $3, $4)", New().InsertStruct("users", u).String())
+}
+
+func TestColumnMapScan(t *testing.T) {
+	db, mock, _ := setupTest(t)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT").WillReturnRows(
+		sqlmock.NewRows([]string{"user_no", "full_name", "email", "notes", "name"}).AddRow(7, "zakirkun", "z@example.com", "x", "ignored"))
+
+	rows, err := db.Query("SELECT * FROM old_users")
+	assert.NoError(t, err)
+
+	var users []legacyUser
+	assert.NoError(t, ScanAll(rows, &users, WithColumnMap(legacyColumns)))
+	assert.Equal(t, []legacyUser{{ID: 7, Name: "zakirkun", Email: "z@example.com"}}, users)
+
+	mock.ExpectQuery("SELECT").WillReturnRows(
+		sqlmock.NewRows([]string{"user_no", "email"}).AddRow(7, "z@example.com"))
+
+	rows, err = db.Query("SELECT user_no, email FROM old_users")
+	assert.NoError(t, err)
+
+	err = ScanAll(rows, &users, WithColumnMap(legacyColumns), WithScanMode(ScanStrictFields))
+	assert.Error(t, err)
+	assert.EqualError(t, err, "fields Name (full_name) have no result column in toki.legacyUser, "+
+		"which maps ID (user_no), Name (full_name), Email (email)")
+}
@@ -0,0 +1,104 @@
+package toki
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// commentTag is a key/value pair rendered into the trailing SQL comment
+type commentTag struct {
+	key, value string
+}
+
+// CommentExtractor returns comment tags taken from a context, such as a
+// request ID or route
+type CommentExtractor func(ctx context.Context) map[string]string
+
+// defaultCommentExtractor adds context tags to every query; nil disables it
+var defaultCommentExtractor CommentExtractor
+
+// SetCommentExtractor sets a function whose tags are added to the comment
+// of every query, alongside tags from WithComment. Call it during
+// initialization, before queries run.
+func SetCommentExtractor(fn CommentExtractor) {
+	defaultCommentExtractor = fn
+}
+
+// WithComment tags statements run from this builder with key=value in a
+// trailing sqlcommenter-style comment, such as /*route='GET%20%2Fusers'*/.
+// Keys and values are URL-encoded, so they cannot close the comment. The
+// comment is added when the statement is sent to the database and does not
+// affect args or placeholder numbering. Setting a key again replaces it.
+func (b *Builder) WithComment(key, value string) *Builder {
+	b.hooks.comments = append(b.hooks.comments[:len(b.hooks.comments):len(b.hooks.comments)], commentTag{key, value})
+	return b
+}
+
+// WithComment tags this raw query with key=value, see Builder.WithComment
+func (r *RawQuery) WithComment(key, value string) *RawQuery {
+	r.hooks.comments = append(r.hooks.comments[:len(r.hooks.comments):len(r.hooks.comments)], commentTag{key, value})
+	return r
+}
+
+// comment renders the tags for a query run with ctx, or "" when there are
+// none. Context tags are overridden by explicit ones.
+func (h hooks) comment(ctx context.Context) string {
+	var fromCtx map[string]string
+	if defaultCommentExtractor != nil {
+		fromCtx = defaultCommentExtractor(ctx)
+	}
+	if len(h.comments) == 0 && len(fromCtx) == 0 {
+		return ""
+	}
+
+	tags := make(map[string]string, len(h.comments)+len(fromCtx))
+	for k, v := range fromCtx {
+		tags[k] = v
+	}
+	for _, tag := range h.comments {
+		tags[tag.key] = tag.value
+	}
+
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	sb.WriteString("/*")
+	for i, k := range keys {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(url.PathEscape(k))
+		sb.WriteString("='")
+		sb.WriteString(url.PathEscape(tags[k]))
+		sb.WriteByte('\'')
+	}
+	sb.WriteString("*/")
+	return sb.String()
+}
+
+// appendComment adds comment to the end of query, before a trailing
+// semicolon and on a new line when the query ends in a line comment
+func appendComment(query, comment string) string {
+	trimmed := strings.TrimRight(query, " \t\r\n")
+	semicolon := strings.HasSuffix(trimmed, ";")
+	trimmed = strings.TrimSuffix(trimmed, ";")
+
+	sep := " "
+	scanSQL(trimmed, func(from, to int, code bool) {
+		if to == len(trimmed) && !code && strings.HasPrefix(trimmed[from:], "--") {
+			sep = "\n"
+		}
+	})
+
+	out := trimmed + sep + comment
+	if semicolon {
+		out += ";"
+	}
+	return out
+}
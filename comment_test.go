@@ -0,0 +1,86 @@
+package toki
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+type routeKey struct{}
+
+func TestAppendComment(t *testing.T) {
+	comment := "/*a='1'*/"
+
+	assert.Equal(t, "SELECT 1 /*a='1'*/", appendComment("SELECT 1", comment))
+	assert.Equal(t, "SELECT 1 /*a='1'*/;", appendComment("SELECT 1;\n", comment))
+	assert.Equal(t, "SELECT 1 -- note\n/*a='1'*/", appendComment("SELECT 1 -- note", comment))
+	assert.Equal(t, "SELECT '--' /*a='1'*/", appendComment("SELECT '--'", comment))
+}
+
+func TestWithComment(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT * FROM users WHERE id = $1 /*route='GET%20%2Fusers',team='x%27%2A%2F%3B%20DROP%20TABLE%20users%3B%20--'*/").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	query := New().
+		WithComment("team", "billing").
+		WithComment("route", "GET /users").
+		WithComment("team", "x'*/; DROP TABLE users; --").
+		Select("*").
+		From("users").
+		Where("id = ?", 1)
+	assert.Equal(t, "SELECT * FROM users WHERE id = $1", query.String())
+
+	stmt, err := query.Prepare(db)
+	assert.NoError(t, err)
+	rows, err := stmt.Query()
+	assert.NoError(t, err)
+	rows.Close()
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCommentExtractor(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	SetCommentExtractor(func(ctx context.Context) map[string]string {
+		if route, ok := ctx.Value(routeKey{}).(string); ok {
+			return map[string]string{"route": route, "app": "api"}
+		}
+		return nil
+	})
+	defer SetCommentExtractor(nil)
+
+	ctx := context.WithValue(context.Background(), routeKey{}, "/reports")
+
+	mock.ExpectPrepare("UPDATE reports SET seen = true WHERE id = $1 /*app='api',route='%2Freports'*/").
+		ExpectExec().
+		WithArgs(7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	stmt, err := New().Raw("UPDATE reports SET seen = true WHERE id = $1").WithDB(db).Prepare(ctx)
+	assert.NoError(t, err)
+	_, err = stmt.Exec(7)
+	assert.NoError(t, err)
+
+	assert.NoError(t, stmt.Close())
+
+	// explicit tags replace context tags with the same key
+	mock.ExpectExec("DELETE FROM reports /*app='web',route='%2Freports'*/").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = New().Raw("DELETE FROM reports").WithComment("app", "web").WithDB(db).ExecScript(ctx)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
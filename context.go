@@ -0,0 +1,63 @@
+package toki
+
+import "context"
+
+// WithContext attaches ctx to the builder. Statements prepared from it and
+// raw queries created by it run with ctx unless a context is passed to the
+// call. Without one, builders bound to a transaction use the context the
+// transaction was begun with.
+func (b *Builder) WithContext(ctx context.Context) *Builder {
+	b.ctx = ctx
+	return b
+}
+
+// context returns the context statements of the builder run with
+func (b *Builder) context() context.Context {
+	if b.ctx != nil {
+		return b.ctx
+	}
+	if b.tx != nil {
+		return b.tx.context()
+	}
+	return context.Background()
+}
+
+// WithContext attaches ctx to the raw query, which then runs with it
+func (r *RawQuery) WithContext(ctx context.Context) *RawQuery {
+	r.ctx = ctx
+	return r
+}
+
+// context returns the context the raw query runs with
+func (r *RawQuery) context() context.Context {
+	if r.ctx != nil {
+		return r.ctx
+	}
+	return context.Background()
+}
+
+// context returns the context the prepared query runs with when the call
+// has none
+func (p *PreparedRaw) context() context.Context {
+	if p.ctx != nil {
+		return p.ctx
+	}
+	return context.Background()
+}
+
+// context returns the context the statement runs with when the call has
+// none
+func (s *Stmt) context() context.Context {
+	if s.ctx != nil {
+		return s.ctx
+	}
+	return context.Background()
+}
+
+// context returns the context the transaction was begun with
+func (t *Transaction) context() context.Context {
+	if t.ctx != nil {
+		return t.ctx
+	}
+	return context.Background()
+}
@@ -0,0 +1,138 @@
+package toki
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithContextCancelsQuery(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT * FROM reports").WillDelayFor(time.Second).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec("DELETE FROM reports").WillDelayFor(time.Second).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	stmt, err := New().WithContext(ctx).Select("*").From("reports").Prepare(db)
+	assert.NoError(t, err)
+	start := time.Now()
+	_, err = stmt.Query()
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+
+	_, err = New().WithContext(ctx).Raw("DELETE FROM reports").WithDB(db).Exec()
+	assert.Error(t, err)
+}
+
+func TestExplicitContextTakesPrecedence(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec("DELETE FROM reports").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	stmt, err := New().WithContext(canceled).Delete("reports").Prepare(db)
+	assert.NoError(t, err)
+	_, err = stmt.ExecContext(context.Background())
+	assert.NoError(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+type ctxKey struct{}
+
+func TestTransactionContextFlowsToStatements(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM reports").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM logs").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	var seen []interface{}
+	record := func(ctx context.Context, q QueryInfo, next Handler) (Result, error) {
+		seen = append(seen, ctx.Value(ctxKey{}))
+		return next(ctx, q)
+	}
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "request-1")
+	err = RunInTx(ctx, db, nil, func(tx *Transaction) error {
+		stmt, err := tx.Builder().Use(record).Delete("reports").Prepare(nil)
+		if err != nil {
+			return err
+		}
+		if _, err := stmt.Exec(); err != nil {
+			return err
+		}
+		_, err = tx.Raw("DELETE FROM logs").Use(record).Exec()
+		return err
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"request-1", "request-1"}, seen)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+type ctxLogger struct {
+	seen []string
+}
+
+func (l *ctxLogger) LogQuery(ctx context.Context, query string, args []interface{}, duration time.Duration, err error) {
+	l.seen = append(l.seen, fmt.Sprint(query, " ", ctx.Value(ctxKey{})))
+}
+
+func TestTransactionContextFlowsToCommitAndRollback(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	logger := &ctxLogger{}
+	SetLogger(logger)
+	defer SetLogger(nil)
+
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "request-1")
+	tx, err := BeginTx(ctx, db, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, tx.Commit())
+
+	ctx = context.WithValue(context.Background(), ctxKey{}, "request-2")
+	tx, err = BeginTx(ctx, db, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, tx.Rollback())
+
+	assert.Equal(t, []string{
+		"BEGIN request-1",
+		"COMMIT request-1",
+		"BEGIN request-2",
+		"ROLLBACK request-2",
+	}, logger.seen)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,25 @@
+package toki
+
+// With adds a common table expression named name, rendered as
+// WITH name AS (q) before the statement. Further calls add to the same
+// WITH list. q may be a SELECT or, on PostgreSQL, an INSERT, UPDATE or
+// DELETE whose RETURNING rows the outer statement reads:
+//
+//	moved := toki.New().Delete("queue").Where("done").Returning("*")
+//	toki.New().With("moved", moved).Insert("archive").Select("*").From("moved")
+//
+// The placeholders of q are renumbered to follow those bound so far. A
+// writing q makes the whole statement a write, so its Kind is that of q
+// unless the outer statement's kind is already known.
+func (b *Builder) With(name string, q *Builder) *Builder {
+	if q.kind != KindSelect {
+		b.setKind(q.kind)
+	}
+
+	cte := name + " AS (" + shiftPlaceholders(q.statement(), b.argIndex) + ")"
+	b.appendArgs(q.args)
+	b.argIndex += len(q.args)
+	b.changed()
+	b.clauses.with = append(b.clauses.with, cte)
+	return b
+}
@@ -0,0 +1,47 @@
+package toki
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWith(t *testing.T) {
+	recent := New().Select("user_id").From("orders").Where("created_at > ?", TestTime)
+	b := New().With("recent", recent).
+		Select("*").From("users").
+		Where("id IN (SELECT user_id FROM recent)").
+		AndWhere("status = ?", "active").
+		Limit(10)
+	assert.Equal(t, "WITH recent AS (SELECT user_id FROM orders WHERE created_at > $1) SELECT * FROM users WHERE id IN (SELECT user_id FROM recent) AND status = $2 LIMIT $3", b.String())
+	assert.Equal(t, []interface{}{TestTime, "active", 10}, b.args)
+	assert.Equal(t, KindSelect, b.Kind())
+
+	a := New().Select("id").From("a").Where("x = ?", 1)
+	c := New().Select("id").From("c").Where("y = ? OR z = ?", 2, 3)
+	b = New().With("a", a).With("c", c).Select("*").From("a").Join("c", "c.id = a.id")
+	assert.Equal(t, "WITH a AS (SELECT id FROM a WHERE x = $1), c AS (SELECT id FROM c WHERE y = $2 OR z = $3) SELECT * FROM a JOIN c ON c.id = a.id", b.String())
+	assert.Equal(t, []interface{}{1, 2, 3}, b.args)
+}
+
+func TestWithWritingStatements(t *testing.T) {
+	moved := New().Delete("queue").Where("status = ?", "done").AndWhere("finished_at < ?", TestTime).Returning("*")
+	b := New().With("moved", moved).Insert("archive").Select("*").From("moved")
+	assert.Equal(t, "WITH moved AS (DELETE FROM queue WHERE status = $1 AND finished_at < $2 RETURNING *) INSERT INTO archive SELECT * FROM moved", b.String())
+	assert.Equal(t, []interface{}{"done", TestTime}, b.args)
+	assert.Equal(t, KindDelete, b.Kind())
+	assert.True(t, b.Kind().IsWrite())
+
+	claimed := New().Update("jobs").Set(map[string]interface{}{"worker": "w1"}).Where("id = ?", 7).Returning("id")
+	b = New().With("claimed", claimed).
+		Update("job_stats").Set(map[string]interface{}{"claimed_by": "w1"}).
+		Where("job_id IN (SELECT id FROM claimed)").
+		AndWhere("day = ?", "2024-01-01").
+		Returning("job_id")
+	assert.Equal(t, "WITH claimed AS (UPDATE jobs SET worker = $1 WHERE id = $2 RETURNING id) UPDATE job_stats SET claimed_by = $3 WHERE job_id IN (SELECT id FROM claimed) AND day = $4 RETURNING job_id", b.String())
+	assert.Equal(t, []interface{}{"w1", 7, "w1", "2024-01-01"}, b.args)
+
+	// reads of a writing CTE are writes
+	b = New().With("gone", New().Delete("sessions").Where("user_id = ?", 3).Returning("id")).Select("COUNT(*)").From("gone")
+	assert.True(t, b.Kind().IsWrite())
+}
@@ -0,0 +1,31 @@
+package toki
+
+import "time"
+
+// WhereDateRange adds the half-open range "column >= from AND column < to",
+// so consecutive ranges never share an instant. A zero from or to leaves
+// that side open, and with both zero nothing is added. It starts the WHERE
+// clause or is ANDed to it.
+func (b *Builder) WhereDateRange(column string, from, to time.Time) *Builder {
+	switch {
+	case !from.IsZero() && !to.IsZero():
+		return b.Where(column+" >= ? AND "+column+" < ?", from, to)
+	case !from.IsZero():
+		return b.Where(column+" >= ?", from)
+	case !to.IsZero():
+		return b.Where(column+" < ?", to)
+	}
+	return b
+}
+
+// WhereOnDay matches the calendar day of day in loc, from its midnight up to
+// the next one. The day is 23 or 25 hours long across a daylight saving
+// change. A nil loc uses the location of day.
+func (b *Builder) WhereOnDay(column string, day time.Time, loc *time.Location) *Builder {
+	if loc == nil {
+		loc = day.Location()
+	}
+	day = day.In(loc)
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc)
+	return b.WhereDateRange(column, start, start.AddDate(0, 0, 1))
+}
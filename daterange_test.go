@@ -0,0 +1,50 @@
+package toki
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWhereDateRange(t *testing.T) {
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	b := New().Select("*").From("orders").Where("status = ?", "paid").WhereDateRange("created_at", from, to)
+	assert.Equal(t, "SELECT * FROM orders WHERE status = $1 AND created_at >= $2 AND created_at < $3", b.String())
+	assert.Equal(t, []interface{}{"paid", from, to}, b.args)
+
+	b = New().Select("*").From("orders").WhereDateRange("created_at", from, time.Time{})
+	assert.Equal(t, "SELECT * FROM orders WHERE created_at >= $1", b.String())
+
+	b = New().Select("*").From("orders").WhereDateRange("created_at", time.Time{}, to)
+	assert.Equal(t, "SELECT * FROM orders WHERE created_at < $1", b.String())
+	assert.Equal(t, []interface{}{to}, b.args)
+
+	b = New().Select("*").From("orders").WhereDateRange("created_at", time.Time{}, time.Time{})
+	assert.Equal(t, "SELECT * FROM orders", b.String())
+	assert.Empty(t, b.args)
+}
+
+func TestWhereOnDay(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+
+	// 23:30 UTC on March 30 is already March 31 in Berlin, the day clocks
+	// go forward, so the day is 23 hours long
+	day := time.Date(2024, 3, 30, 23, 30, 0, 0, time.UTC)
+	b := New().Select("*").From("events").WhereOnDay("at", day, berlin)
+
+	assert.Equal(t, "SELECT * FROM events WHERE at >= $1 AND at < $2", b.String())
+	start, end := b.args[0].(time.Time), b.args[1].(time.Time)
+	assert.True(t, start.Equal(time.Date(2024, 3, 30, 23, 0, 0, 0, time.UTC)))
+	assert.True(t, end.Equal(time.Date(2024, 3, 31, 22, 0, 0, 0, time.UTC)))
+	assert.Equal(t, 23*time.Hour, end.Sub(start))
+
+	b = New().Select("*").From("events").WhereOnDay("at", day, nil)
+	assert.True(t, b.args[0].(time.Time).Equal(time.Date(2024, 3, 30, 0, 0, 0, 0, time.UTC)))
+	assert.True(t, b.args[1].(time.Time).Equal(time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)))
+}
@@ -0,0 +1,127 @@
+package toki
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// truncFormats are the strftime formats truncating to each precision on
+// SQLite. MySQL's DATE_FORMAT takes them with %i and %s for minutes and
+// seconds.
+var truncFormats = map[string]string{
+	"second": "%Y-%m-%d %H:%M:%S",
+	"minute": "%Y-%m-%d %H:%M:00",
+	"hour":   "%Y-%m-%d %H:00:00",
+	"day":    "%Y-%m-%d 00:00:00",
+	"month":  "%Y-%m-01 00:00:00",
+	"year":   "%Y-01-01 00:00:00",
+}
+
+// datePrecisions are the precisions date_trunc accepts
+var datePrecisions = map[string]bool{
+	"microseconds": true,
+	"milliseconds": true,
+	"second":       true,
+	"minute":       true,
+	"hour":         true,
+	"day":          true,
+	"week":         true,
+	"month":        true,
+	"quarter":      true,
+	"year":         true,
+	"decade":       true,
+	"century":      true,
+	"millennium":   true,
+}
+
+// TimeExpr is a timestamp truncated to a precision or bucketed by an
+// interval, made by DateTrunc or TimeBucket. It renders identically in
+// SelectExpr, GroupByExpr and OrderByExpr, so the same value can be used
+// in each.
+type TimeExpr struct {
+	column    string
+	precision string
+	bucket    time.Duration
+}
+
+// DateTrunc truncates column to precision: date_trunc('hour', column) on
+// PostgreSQL. MySQL and SQLite format the timestamp instead and support
+// second, minute, hour, day, week, month and year; other precisions, and
+// ones date_trunc does not know, fail the builder, reported by Err and
+// Prepare.
+func DateTrunc(precision, column string) *TimeExpr {
+	return &TimeExpr{column: column, precision: strings.ToLower(precision)}
+}
+
+// TimeBucket groups column into buckets of interval counted from the Unix
+// epoch. A whole second, minute, hour or day is DateTrunc; other intervals
+// use date_bin on PostgreSQL 14+ and Unix time arithmetic elsewhere.
+// interval must be a positive whole number of seconds.
+func TimeBucket(interval time.Duration, column string) *TimeExpr {
+	switch interval {
+	case time.Second:
+		return DateTrunc("second", column)
+	case time.Minute:
+		return DateTrunc("minute", column)
+	case time.Hour:
+		return DateTrunc("hour", column)
+	case 24 * time.Hour:
+		return DateTrunc("day", column)
+	}
+	return &TimeExpr{column: column, bucket: interval}
+}
+
+// SQL renders the expression for PostgreSQL
+func (t *TimeExpr) SQL() string {
+	query, _, _ := t.bind(Postgres)
+	return query
+}
+
+func (t *TimeExpr) bind(d *Dialect) (string, []interface{}, error) {
+	if t.precision == "" {
+		return t.bin(d)
+	}
+
+	if !datePrecisions[t.precision] {
+		return t.column, nil, fmt.Errorf("unknown date_trunc precision %q", t.precision)
+	}
+
+	switch d.name {
+	case "mysql":
+		if t.precision == "week" {
+			return "DATE_SUB(DATE(" + t.column + "), INTERVAL WEEKDAY(" + t.column + ") DAY)", nil, nil
+		}
+		if format, ok := truncFormats[t.precision]; ok {
+			format = strings.NewReplacer("%M", "%i", "%S", "%s").Replace(format)
+			return "CAST(DATE_FORMAT(" + t.column + ", '" + format + "') AS DATETIME)", nil, nil
+		}
+	case "sqlite":
+		if t.precision == "week" {
+			return "datetime(" + t.column + ", 'weekday 0', '-6 days', 'start of day')", nil, nil
+		}
+		if format, ok := truncFormats[t.precision]; ok {
+			return "strftime('" + format + "', " + t.column + ")", nil, nil
+		}
+	default:
+		return "date_trunc('" + t.precision + "', " + t.column + ")", nil, nil
+	}
+	return t.column, nil, fmt.Errorf("the %s dialect cannot truncate to %s", d, t.precision)
+}
+
+// bin renders an interval bucket
+func (t *TimeExpr) bin(d *Dialect) (string, []interface{}, error) {
+	if t.bucket < time.Second || t.bucket%time.Second != 0 {
+		return t.column, nil, fmt.Errorf("time bucket interval must be a positive whole number of seconds, got %s", t.bucket)
+	}
+	secs := strconv.FormatInt(int64(t.bucket/time.Second), 10)
+
+	switch d.name {
+	case "mysql":
+		return "FROM_UNIXTIME(FLOOR(UNIX_TIMESTAMP(" + t.column + ") / " + secs + ") * " + secs + ")", nil, nil
+	case "sqlite":
+		return "datetime((CAST(strftime('%s', " + t.column + ") AS INTEGER) / " + secs + ") * " + secs + ", 'unixepoch')", nil, nil
+	}
+	return "date_bin('" + secs + " seconds', " + t.column + ", TIMESTAMP '1970-01-01')", nil, nil
+}
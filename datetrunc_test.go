@@ -0,0 +1,63 @@
+package toki
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDateTruncHourlyCounts(t *testing.T) {
+	hour := DateTrunc("hour", "created_at")
+	b := New().SelectExpr(hour, Count("*").As("n")).
+		From("events").
+		Where("created_at >= ?", TestTime).
+		GroupByExpr(hour).
+		OrderByExpr(hour)
+	assert.NoError(t, b.Err())
+	assert.Equal(t, "SELECT date_trunc('hour', created_at), COUNT(*) AS n FROM events WHERE created_at >= $1 GROUP BY date_trunc('hour', created_at) ORDER BY date_trunc('hour', created_at)", b.String())
+	assert.Equal(t, []interface{}{TestTime}, b.args)
+
+	b = New().WithDialect(MySQL).SelectExpr(hour).From("events").GroupByExpr(hour)
+	assert.Equal(t, "SELECT CAST(DATE_FORMAT(created_at, '%Y-%m-%d %H:00:00') AS DATETIME) FROM events GROUP BY CAST(DATE_FORMAT(created_at, '%Y-%m-%d %H:00:00') AS DATETIME)", b.String())
+
+	b = New().WithDialect(SQLite).SelectExpr(hour).From("events").GroupByExpr(hour)
+	assert.Equal(t, "SELECT strftime('%Y-%m-%d %H:00:00', created_at) FROM events GROUP BY strftime('%Y-%m-%d %H:00:00', created_at)", b.String())
+}
+
+func TestDateTrunc(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect *Dialect
+		expr    *TimeExpr
+		want    string
+	}{
+		{"postgres quarter", Postgres, DateTrunc("QUARTER", "at"), "date_trunc('quarter', at)"},
+		{"mysql minute", MySQL, DateTrunc("minute", "at"), "CAST(DATE_FORMAT(at, '%Y-%m-%d %H:%i:00') AS DATETIME)"},
+		{"mysql week", MySQL, DateTrunc("week", "at"), "DATE_SUB(DATE(at), INTERVAL WEEKDAY(at) DAY)"},
+		{"sqlite week", SQLite, DateTrunc("week", "at"), "datetime(at, 'weekday 0', '-6 days', 'start of day')"},
+		{"bucket of an hour", Postgres, TimeBucket(time.Hour, "at"), "date_trunc('hour', at)"},
+		{"postgres bucket", Postgres, TimeBucket(15*time.Minute, "at"), "date_bin('900 seconds', at, TIMESTAMP '1970-01-01')"},
+		{"mysql bucket", MySQL, TimeBucket(15*time.Minute, "at"), "FROM_UNIXTIME(FLOOR(UNIX_TIMESTAMP(at) / 900) * 900)"},
+		{"sqlite bucket", SQLite, TimeBucket(5*time.Minute, "at"), "datetime((CAST(strftime('%s', at) AS INTEGER) / 300) * 300, 'unixepoch')"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := New().WithDialect(tt.dialect).SelectExpr(tt.expr)
+			assert.NoError(t, b.Err())
+			assert.Equal(t, "SELECT "+tt.want, b.String())
+		})
+	}
+}
+
+func TestDateTruncInvalid(t *testing.T) {
+	b := New().SelectExpr(DateTrunc("fortnight", "at"))
+	assert.EqualError(t, b.Err(), `unknown date_trunc precision "fortnight"`)
+
+	b = New().WithDialect(SQLite).SelectExpr(DateTrunc("quarter", "at"))
+	assert.EqualError(t, b.Err(), "the sqlite dialect cannot truncate to quarter")
+
+	b = New().SelectExpr(TimeBucket(1500*time.Millisecond, "at"))
+	assert.EqualError(t, b.Err(), "time bucket interval must be a positive whole number of seconds, got 1.5s")
+}
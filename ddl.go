@@ -0,0 +1,372 @@
+package toki
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// identifier matches table, column and constraint names, optionally
+	// schema-qualified
+	identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+	// columnTypePattern matches type names such as TEXT, VARCHAR(255),
+	// NUMERIC(10, 2), DOUBLE PRECISION and INTEGER[]
+	columnTypePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_ ]*(\(\s*\d+\s*(,\s*\d+\s*)?\))?[A-Za-z ]*(\[\])*$`)
+)
+
+// referentialActions are the accepted ON DELETE and ON UPDATE actions
+var referentialActions = map[string]bool{
+	"CASCADE":     true,
+	"RESTRICT":    true,
+	"NO ACTION":   true,
+	"SET NULL":    true,
+	"SET DEFAULT": true,
+}
+
+// checkIdent reports an error when name is not a plain identifier
+func checkIdent(what, name string) error {
+	if !identifier.MatchString(name) {
+		return fmt.Errorf("invalid %s name %q", what, name)
+	}
+	return nil
+}
+
+// CreateTableBuilder builds a CREATE TABLE statement. Names and types are
+// validated when the statement is prepared or executed.
+type CreateTableBuilder struct {
+	b           *Builder
+	name        string
+	ifNotExists bool
+	columns     []columnDef
+	primaryKey  []string
+	foreignKeys []foreignKey
+}
+
+type columnDef struct {
+	name       string
+	typ        string
+	primaryKey bool
+	notNull    bool
+	unique     bool
+	hasDefault bool
+	def        interface{}
+	references *foreignKey
+}
+
+type foreignKey struct {
+	columns    []string
+	table      string
+	refColumns []string
+	onDelete   string
+	onUpdate   string
+}
+
+// ColumnOption sets a constraint on a CreateTable column
+type ColumnOption func(*columnDef)
+
+// ForeignKeyOption sets a referential action on a foreign key
+type ForeignKeyOption func(*foreignKey)
+
+// PrimaryKey makes the column the primary key. Use
+// CreateTableBuilder.PrimaryKey for a composite key.
+func PrimaryKey() ColumnOption {
+	return func(c *columnDef) { c.primaryKey = true }
+}
+
+// NotNull adds a NOT NULL constraint
+func NotNull() ColumnOption {
+	return func(c *columnDef) { c.notNull = true }
+}
+
+// Unique adds a UNIQUE constraint
+func Unique() ColumnOption {
+	return func(c *columnDef) { c.unique = true }
+}
+
+// Default sets the column default. SQLExpressions are rendered as they are,
+// other values as SQL literals since DDL cannot take bound args.
+func Default(value interface{}) ColumnOption {
+	return func(c *columnDef) {
+		c.hasDefault = true
+		c.def = value
+	}
+}
+
+// References makes the column a foreign key to column of table
+func References(table, column string, opts ...ForeignKeyOption) ColumnOption {
+	return func(c *columnDef) {
+		fk := &foreignKey{table: table, refColumns: []string{column}}
+		for _, opt := range opts {
+			opt(fk)
+		}
+		c.references = fk
+	}
+}
+
+// OnDelete sets the ON DELETE action, such as CASCADE or SET NULL
+func OnDelete(action string) ForeignKeyOption {
+	return func(fk *foreignKey) { fk.onDelete = strings.ToUpper(action) }
+}
+
+// OnUpdate sets the ON UPDATE action, such as CASCADE or SET NULL
+func OnUpdate(action string) ForeignKeyOption {
+	return func(fk *foreignKey) { fk.onUpdate = strings.ToUpper(action) }
+}
+
+// CreateTable starts a CREATE TABLE statement. It runs on the builder's
+// transaction when it has one and renders for the builder's dialect.
+func (b *Builder) CreateTable(name string) *CreateTableBuilder {
+	return &CreateTableBuilder{b: b, name: name}
+}
+
+// IfNotExists skips creating the table when it already exists
+func (t *CreateTableBuilder) IfNotExists() *CreateTableBuilder {
+	t.ifNotExists = true
+	return t
+}
+
+// Column adds a column of type typ. Types missing from the builder's
+// dialect are mapped to an equivalent, such as BIGSERIAL to INTEGER on
+// SQLite.
+func (t *CreateTableBuilder) Column(name, typ string, opts ...ColumnOption) *CreateTableBuilder {
+	col := columnDef{name: name, typ: typ}
+	for _, opt := range opts {
+		opt(&col)
+	}
+	t.columns = append(t.columns, col)
+	return t
+}
+
+// PrimaryKey adds a table primary key over columns
+func (t *CreateTableBuilder) PrimaryKey(columns ...string) *CreateTableBuilder {
+	t.primaryKey = columns
+	return t
+}
+
+// ForeignKey adds a table foreign key from columns to refColumns of table
+func (t *CreateTableBuilder) ForeignKey(columns []string, table string, refColumns []string, opts ...ForeignKeyOption) *CreateTableBuilder {
+	fk := foreignKey{columns: columns, table: table, refColumns: refColumns}
+	for _, opt := range opts {
+		opt(&fk)
+	}
+	t.foreignKeys = append(t.foreignKeys, fk)
+	return t
+}
+
+// String builds the statement without validating it
+func (t *CreateTableBuilder) String() string {
+	dialect := t.b.Dialect()
+
+	var sb strings.Builder
+	sb.WriteString("CREATE TABLE ")
+	if t.ifNotExists {
+		sb.WriteString("IF NOT EXISTS ")
+	}
+	sb.WriteString(t.name)
+	sb.WriteString(" (")
+
+	for i, col := range t.columns {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		col.render(&sb, dialect)
+	}
+
+	if len(t.primaryKey) > 0 {
+		sb.WriteString(", PRIMARY KEY (")
+		sb.WriteString(strings.Join(t.primaryKey, ", "))
+		sb.WriteByte(')')
+	}
+	for _, fk := range t.foreignKeys {
+		sb.WriteString(", FOREIGN KEY (")
+		sb.WriteString(strings.Join(fk.columns, ", "))
+		sb.WriteString(") ")
+		fk.render(&sb)
+	}
+
+	sb.WriteByte(')')
+	return sb.String()
+}
+
+// Validate checks names, types and constraints
+func (t *CreateTableBuilder) Validate() error {
+	if err := checkIdent("table", t.name); err != nil {
+		return err
+	}
+	if len(t.columns) == 0 {
+		return fmt.Errorf("table %s has no columns", t.name)
+	}
+
+	keys := 0
+	for _, col := range t.columns {
+		if err := col.validate(); err != nil {
+			return err
+		}
+		if col.primaryKey {
+			keys++
+		}
+	}
+
+	if len(t.primaryKey) > 0 {
+		keys++
+		for _, name := range t.primaryKey {
+			if err := checkIdent("column", name); err != nil {
+				return err
+			}
+		}
+	}
+	if keys > 1 {
+		return fmt.Errorf("table %s has more than one primary key; use PrimaryKey(columns...) for a composite key", t.name)
+	}
+
+	for _, fk := range t.foreignKeys {
+		if len(fk.columns) == 0 || len(fk.columns) != len(fk.refColumns) {
+			return fmt.Errorf("foreign key to %s needs as many columns as it references", fk.table)
+		}
+		for _, name := range fk.columns {
+			if err := checkIdent("column", name); err != nil {
+				return err
+			}
+		}
+		if err := fk.validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Prepare validates the statement and creates a prepared statement for it
+func (t *CreateTableBuilder) Prepare(db *sql.DB) (*Stmt, error) {
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+	return t.b.prepareDDL(db, t.String(), t.name)
+}
+
+// Exec validates and executes the statement
+func (t *CreateTableBuilder) Exec(ctx context.Context, db *sql.DB) (sql.Result, error) {
+	stmt, err := t.Prepare(db)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.execDDL(ctx)
+}
+
+func (c *columnDef) render(sb *strings.Builder, dialect *Dialect) {
+	sb.WriteString(c.name)
+	sb.WriteByte(' ')
+	sb.WriteString(dialect.columnType(c.typ))
+	if c.primaryKey {
+		sb.WriteString(" PRIMARY KEY")
+	}
+	if c.notNull {
+		sb.WriteString(" NOT NULL")
+	}
+	if c.unique {
+		sb.WriteString(" UNIQUE")
+	}
+	if c.hasDefault {
+		sb.WriteString(" DEFAULT ")
+		sb.WriteString(dialect.defaultValue(c.def))
+	}
+	if c.references != nil {
+		sb.WriteByte(' ')
+		c.references.render(sb)
+	}
+}
+
+func (c *columnDef) validate() error {
+	if err := checkIdent("column", c.name); err != nil {
+		return err
+	}
+	if err := checkColumnType(c.name, c.typ); err != nil {
+		return err
+	}
+	if c.references != nil {
+		if err := c.references.validate(); err != nil {
+			return fmt.Errorf("column %s: %w", c.name, err)
+		}
+	}
+	return nil
+}
+
+// checkColumnType reports an error when typ is not a plain type name
+func checkColumnType(column, typ string) error {
+	if !columnTypePattern.MatchString(typ) {
+		return fmt.Errorf("invalid type %q for column %s", typ, column)
+	}
+	return nil
+}
+
+func (fk *foreignKey) render(sb *strings.Builder) {
+	sb.WriteString("REFERENCES ")
+	sb.WriteString(fk.table)
+	sb.WriteString(" (")
+	sb.WriteString(strings.Join(fk.refColumns, ", "))
+	sb.WriteByte(')')
+	if fk.onDelete != "" {
+		sb.WriteString(" ON DELETE ")
+		sb.WriteString(fk.onDelete)
+	}
+	if fk.onUpdate != "" {
+		sb.WriteString(" ON UPDATE ")
+		sb.WriteString(fk.onUpdate)
+	}
+}
+
+func (fk *foreignKey) validate() error {
+	if err := checkIdent("table", fk.table); err != nil {
+		return err
+	}
+	for _, name := range fk.refColumns {
+		if err := checkIdent("column", name); err != nil {
+			return err
+		}
+	}
+	for _, action := range []string{fk.onDelete, fk.onUpdate} {
+		if action != "" && !referentialActions[action] {
+			return fmt.Errorf("invalid referential action %q", action)
+		}
+	}
+	return nil
+}
+
+// defaultValue renders a column default
+func (d *Dialect) defaultValue(v interface{}) string {
+	expr, ok := v.(SQLExpression)
+	if !ok {
+		return literal(v, 0)
+	}
+	if d.parenDefaults {
+		return "(" + expr.SQL() + ")"
+	}
+	return expr.SQL()
+}
+
+// execDDL runs a statement created by prepareDDL
+func (s *Stmt) execDDL(ctx context.Context) (sql.Result, error) {
+	c := connFor(s.db, s.tx)
+	if c == nil {
+		return nil, errors.New("DDL statement needs a database or transaction")
+	}
+	return s.hooks.exec(ctx, c, s.info())
+}
+
+// prepareDDL creates a statement running query on the builder's transaction
+// or on db
+func (b *Builder) prepareDDL(db *sql.DB, query, table string) (*Stmt, error) {
+	if b.tx != nil && b.tx.readOnly {
+		return nil, fmt.Errorf("cannot run %s statement: %w", KindDDL, ErrReadOnlyTransaction)
+	}
+
+	stmt := &Stmt{query: query, db: db, kind: KindDDL, table: table, hooks: b.hooks, ctx: b.context()}
+	if b.tx != nil {
+		stmt.tx = b.tx.tx
+	}
+	return stmt, nil
+}
@@ -0,0 +1,371 @@
+package toki
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func usersTable(b *Builder) *CreateTableBuilder {
+	return b.CreateTable("users").
+		Column("id", "BIGSERIAL", PrimaryKey()).
+		Column("email", "TEXT", NotNull(), Unique()).
+		Column("active", "BOOLEAN", NotNull(), Default(true)).
+		Column("role", "VARCHAR(32)", Default("o'member")).
+		Column("created_at", "TIMESTAMPTZ", Default(Raw("CURRENT_TIMESTAMP"))).
+		IfNotExists()
+}
+
+func TestCreateTableGolden(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect *Dialect
+		table   func(*Builder) *CreateTableBuilder
+		want    string
+	}{
+		{
+			name:    "postgres",
+			dialect: Postgres,
+			table:   usersTable,
+			want:    "CREATE TABLE IF NOT EXISTS users (id BIGSERIAL PRIMARY KEY, email TEXT NOT NULL UNIQUE, active BOOLEAN NOT NULL DEFAULT TRUE, role VARCHAR(32) DEFAULT 'o''member', created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP)",
+		},
+		{
+			name:    "sqlite",
+			dialect: SQLite,
+			table:   usersTable,
+			want:    "CREATE TABLE IF NOT EXISTS users (id INTEGER PRIMARY KEY, email TEXT NOT NULL UNIQUE, active BOOLEAN NOT NULL DEFAULT TRUE, role VARCHAR(32) DEFAULT 'o''member', created_at TIMESTAMP DEFAULT (CURRENT_TIMESTAMP))",
+		},
+		{
+			name:    "composite key and foreign keys",
+			dialect: Postgres,
+			table: func(b *Builder) *CreateTableBuilder {
+				return b.CreateTable("memberships").
+					Column("team_id", "BIGINT", NotNull(), References("teams", "id", OnDelete("cascade"))).
+					Column("user_id", "BIGINT", NotNull()).
+					Column("tenant_id", "BIGINT", NotNull()).
+					PrimaryKey("team_id", "user_id").
+					ForeignKey([]string{"tenant_id", "user_id"}, "tenant_users", []string{"tenant_id", "id"}, OnDelete("set null"), OnUpdate("cascade"))
+			},
+			want: "CREATE TABLE memberships (team_id BIGINT NOT NULL REFERENCES teams (id) ON DELETE CASCADE, user_id BIGINT NOT NULL, tenant_id BIGINT NOT NULL, PRIMARY KEY (team_id, user_id), FOREIGN KEY (tenant_id, user_id) REFERENCES tenant_users (tenant_id, id) ON DELETE SET NULL ON UPDATE CASCADE)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := tt.table(New().WithDialect(tt.dialect))
+			assert.NoError(t, table.Validate())
+			assert.Equal(t, tt.want, table.String())
+		})
+	}
+}
+
+func TestCreateTableValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		table *CreateTableBuilder
+		err   string
+	}{
+		{
+			name:  "table name",
+			table: New().CreateTable("users; DROP TABLE x").Column("id", "INT"),
+			err:   `invalid table name "users; DROP TABLE x"`,
+		},
+		{
+			name:  "no columns",
+			table: New().CreateTable("users"),
+			err:   "table users has no columns",
+		},
+		{
+			name:  "column type",
+			table: New().CreateTable("users").Column("id", "INT) --"),
+			err:   `invalid type "INT) --" for column id`,
+		},
+		{
+			name:  "two primary keys",
+			table: New().CreateTable("t").Column("a", "INT", PrimaryKey()).Column("b", "INT").PrimaryKey("a", "b"),
+			err:   "table t has more than one primary key; use PrimaryKey(columns...) for a composite key",
+		},
+		{
+			name:  "referential action",
+			table: New().CreateTable("t").Column("a", "INT", References("u", "id", OnDelete("explode"))),
+			err:   `column a: invalid referential action "EXPLODE"`,
+		},
+		{
+			name:  "foreign key width",
+			table: New().CreateTable("t").Column("a", "INT").ForeignKey([]string{"a"}, "u", []string{"x", "y"}),
+			err:   "foreign key to u needs as many columns as it references",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.EqualError(t, tt.table.Validate(), tt.err)
+			_, err := tt.table.Prepare(nil)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+
+	assert.NoError(t, New().CreateTable("public.t").Column("v", "NUMERIC(10, 2)").Column("tags", "TEXT[]").Column("d", "DOUBLE PRECISION").Validate())
+}
+
+func TestCreateTableExec(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	table := New().CreateTable("tags").Column("name", "TEXT", PrimaryKey())
+
+	mock.ExpectExec("CREATE TABLE tags (name TEXT PRIMARY KEY)").WillReturnResult(sqlmock.NewResult(0, 0))
+	_, err = table.Exec(context.Background(), db)
+	assert.NoError(t, err)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("CREATE TABLE tags (name TEXT PRIMARY KEY)").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	tx, err := Begin(db)
+	assert.NoError(t, err)
+	stmt, err := New().WithTransaction(tx).CreateTable("tags").Column("name", "TEXT", PrimaryKey()).Prepare(nil)
+	assert.NoError(t, err)
+	_, err = stmt.Exec()
+	assert.NoError(t, err)
+	assert.NoError(t, tx.Commit())
+
+	_, err = table.Exec(context.Background(), nil)
+	assert.EqualError(t, err, "DDL statement needs a database or transaction")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSetDialect(t *testing.T) {
+	defer SetDialect(nil)
+
+	assert.Equal(t, Postgres, New().Dialect())
+	SetDialect(SQLite)
+	assert.Equal(t, "sqlite", New().Dialect().String())
+	assert.Equal(t, Postgres, New().WithDialect(Postgres).Dialect())
+	SetDialect(nil)
+	assert.Equal(t, Postgres, New().Dialect())
+}
+
+func TestDropStatements(t *testing.T) {
+	assert.Equal(t, "DROP TABLE users", New().DropTable("users").String())
+	assert.Equal(t, "DROP TABLE IF EXISTS app.users CASCADE", New().DropTable("app.users").IfExists().Cascade().String())
+	assert.Equal(t, "DROP INDEX users_email_idx", New().DropIndex("users_email_idx").String())
+	assert.Equal(t, "DROP INDEX CONCURRENTLY IF EXISTS users_email_idx", New().DropIndex("users_email_idx").IfExists().Concurrently().String())
+
+	assert.NoError(t, New().DropTable("users").Cascade().Validate())
+	assert.NoError(t, New().DropIndex("users_email_idx").Concurrently().Validate())
+
+	assert.EqualError(t, New().DropTable("users; --").Validate(), `invalid table name "users; --"`)
+	assert.EqualError(t, New().DropIndex("1idx").Validate(), `invalid index name "1idx"`)
+	assert.EqualError(t, New().WithDialect(SQLite).DropTable("users").Cascade().Validate(),
+		"DROP TABLE ... CASCADE is not supported by the sqlite dialect")
+	assert.EqualError(t, New().WithDialect(SQLite).DropIndex("idx").Concurrently().Validate(),
+		"DROP INDEX CONCURRENTLY is not supported by the sqlite dialect")
+}
+
+func TestDropIndexConcurrentlyInTransaction(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DROP INDEX IF EXISTS users_email_idx").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+	mock.ExpectExec("DROP INDEX CONCURRENTLY users_email_idx").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	tx, err := Begin(db)
+	assert.NoError(t, err)
+
+	_, err = New().WithTransaction(tx).DropIndex("users_email_idx").Concurrently().Exec(context.Background(), db)
+	assert.ErrorIs(t, err, ErrConcurrentInTransaction)
+	assert.EqualError(t, err, "DROP INDEX: CONCURRENTLY cannot run inside a transaction")
+
+	_, err = New().WithTransaction(tx).DropIndex("users_email_idx").IfExists().Exec(context.Background(), db)
+	assert.NoError(t, err)
+	assert.NoError(t, tx.Rollback())
+
+	_, err = New().DropIndex("users_email_idx").Concurrently().Exec(context.Background(), db)
+	assert.NoError(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAlterTableGolden(t *testing.T) {
+	alter := func(b *Builder) *AlterTableBuilder {
+		return b.AlterTable("users").
+			AddColumn("last_seen", "TIMESTAMPTZ").
+			AddColumn("plan", "TEXT", NotNull(), Default("free")).
+			DropColumn("legacy_flag").
+			RenameColumn("mail", "email").
+			DropColumn("nickname")
+	}
+
+	pg := alter(New().WithDialect(Postgres))
+	assert.NoError(t, pg.Validate())
+	assert.Equal(t, []string{
+		"ALTER TABLE users ADD COLUMN last_seen TIMESTAMPTZ, ADD COLUMN plan TEXT NOT NULL DEFAULT 'free', DROP COLUMN legacy_flag",
+		"ALTER TABLE users RENAME COLUMN mail TO email",
+		"ALTER TABLE users DROP COLUMN nickname",
+	}, pg.Statements())
+
+	lite := alter(New().WithDialect(SQLite))
+	assert.NoError(t, lite.Validate())
+	assert.Equal(t, []string{
+		"ALTER TABLE users ADD COLUMN last_seen TIMESTAMP",
+		"ALTER TABLE users ADD COLUMN plan TEXT NOT NULL DEFAULT 'free'",
+		"ALTER TABLE users DROP COLUMN legacy_flag",
+		"ALTER TABLE users RENAME COLUMN mail TO email",
+		"ALTER TABLE users DROP COLUMN nickname",
+	}, lite.Statements())
+
+	constraints := New().AlterTable("orders").
+		AlterColumnType("total", "NUMERIC(12, 2)", "total::numeric").
+		AddConstraint("orders_total_positive", "CHECK (total > 0)").
+		DropConstraint("orders_old_check")
+	assert.NoError(t, constraints.Validate())
+	assert.Equal(t, "ALTER TABLE orders ALTER COLUMN total TYPE NUMERIC(12, 2) USING total::numeric, ADD CONSTRAINT orders_total_positive CHECK (total > 0), DROP CONSTRAINT orders_old_check", constraints.String())
+}
+
+func TestAlterTableValidate(t *testing.T) {
+	sqlite := New().WithDialect(SQLite)
+
+	assert.EqualError(t, New().AlterTable("users").Validate(), "ALTER TABLE users has no actions")
+	assert.EqualError(t, New().AlterTable("users").RenameColumn("a", "b c").Validate(), `invalid column name "b c"`)
+	assert.EqualError(t, New().AlterTable("users").AddColumn("a", "TEXT; DROP").Validate(), `invalid type "TEXT; DROP" for column a`)
+	assert.EqualError(t, New().AlterTable("users").AddConstraint("users_check", " ").Validate(), "constraint users_check has no definition")
+	assert.EqualError(t, sqlite.AlterTable("users").AlterColumnType("age", "BIGINT", "").Validate(),
+		"ALTER COLUMN ... TYPE is not supported by the sqlite dialect")
+	assert.EqualError(t, sqlite.AlterTable("users").AddConstraint("c", "UNIQUE (email)").Validate(),
+		"ADD CONSTRAINT is not supported by the sqlite dialect")
+	assert.EqualError(t, sqlite.AlterTable("users").DropConstraint("c").Validate(),
+		"DROP CONSTRAINT is not supported by the sqlite dialect")
+}
+
+func TestAlterTableExec(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("ALTER TABLE users ADD COLUMN age INTEGER").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("ALTER TABLE users RENAME COLUMN mail TO email").WillReturnError(sql.ErrConnDone)
+	mock.ExpectRollback()
+
+	tx, err := Begin(db)
+	assert.NoError(t, err)
+
+	err = New().WithDialect(SQLite).WithTransaction(tx).AlterTable("users").
+		AddColumn("age", "INTEGER").
+		RenameColumn("mail", "email").
+		DropColumn("nickname").
+		Exec(context.Background(), db)
+	assert.ErrorIs(t, err, sql.ErrConnDone)
+	assert.NoError(t, tx.Rollback())
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+type failingValuer struct{}
+
+func (failingValuer) Value() (driver.Value, error) { return nil, errors.New("boom") }
+
+func TestCreateIndexGolden(t *testing.T) {
+	tests := []struct {
+		name  string
+		index *CreateIndexBuilder
+		want  string
+	}{
+		{
+			name:  "plain",
+			index: New().CreateIndex("users_email_idx", "users", "email"),
+			want:  "CREATE INDEX users_email_idx ON users (email)",
+		},
+		{
+			name: "unique expression concurrently",
+			index: New().CreateIndex("users_lower_email_key", "users", Raw("lower(email)"), "created_at DESC").
+				Unique().Concurrently().IfNotExists(),
+			want: "CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS users_lower_email_key ON users ((lower(email)), created_at DESC)",
+		},
+		{
+			name:  "method",
+			index: New().CreateIndex("docs_body_idx", "docs", "body").Using("gin"),
+			want:  "CREATE INDEX docs_body_idx ON docs USING gin (body)",
+		},
+		{
+			name: "partial with inlined literals",
+			index: New().CreateIndex("orders_open_idx", "orders", "customer_id").
+				Where("status = ? AND note <> '?'", "it's open").
+				Where("total > ? AND archived = ?", 100, false),
+			want: "CREATE INDEX orders_open_idx ON orders (customer_id) WHERE status = 'it''s open' AND note <> '?' AND total > 100 AND archived = FALSE",
+		},
+		{
+			name:  "sqlite partial",
+			index: New().WithDialect(SQLite).CreateIndex("jobs_pending_idx", "jobs", "run_at").Where("state = ?", "pending"),
+			want:  "CREATE INDEX jobs_pending_idx ON jobs (run_at) WHERE state = 'pending'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NoError(t, tt.index.Validate())
+			assert.Equal(t, tt.want, tt.index.String())
+		})
+	}
+}
+
+func TestCreateIndexValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		index *CreateIndexBuilder
+		err   string
+	}{
+		{"no columns", New().CreateIndex("idx", "t"), "index idx has no columns"},
+		{"column", New().CreateIndex("idx", "t", "a; DROP TABLE t"), `index idx: invalid column "a; DROP TABLE t"`},
+		{"direction", New().CreateIndex("idx", "t", "a SIDEWAYS"), `index idx: invalid column "a SIDEWAYS"`},
+		{"column type", New().CreateIndex("idx", "t", 42), "index idx: index column must be a string or SQLExpression, got int"},
+		{"method", New().CreateIndex("idx", "t", "a").Using("gin)"), `invalid index method name "gin)"`},
+		{"sqlite method", New().WithDialect(SQLite).CreateIndex("idx", "t", "a").Using("gin"), "CREATE INDEX ... USING is not supported by the sqlite dialect"},
+		{"arg count", New().CreateIndex("idx", "t", "a").Where("a > ? AND b < ?", 1), "index condition expects 2 arg(s), got 1: placeholder and argument count mismatch"},
+		{"valuer", New().CreateIndex("idx", "t", "a").Where("a = ?", failingValuer{}), "index idx: condition arg toki.failingValuer: boom"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.EqualError(t, tt.index.Validate(), tt.err)
+		})
+	}
+}
+
+func TestCreateIndexConcurrentlyInTransaction(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	tx, err := Begin(db)
+	assert.NoError(t, err)
+
+	_, err = New().WithTransaction(tx).CreateIndex("idx", "t", "a").Concurrently().Exec(context.Background(), db)
+	assert.ErrorIs(t, err, ErrConcurrentInTransaction)
+	assert.EqualError(t, err, "CREATE INDEX: CONCURRENTLY cannot run inside a transaction")
+	assert.NoError(t, tx.Rollback())
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
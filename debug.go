@@ -0,0 +1,234 @@
+package toki
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// debugValueLimit is the length after which DebugString truncates a value
+const debugValueLimit = 64
+
+// DebugString returns the query with its args substituted as SQL literals,
+// and Sensitive or redacted args as [REDACTED]. It is meant for logs and
+// for pasting into a SQL console only: never execute the result, use String
+// and the args instead.
+func (b *Builder) DebugString() string {
+	return interpolate(b.String(), b.hooks.loggedArgs(b.Args()))
+}
+
+// DebugString returns the statement with its args substituted as SQL
+// literals. It is for debugging only and must never be executed.
+func (s *Stmt) DebugString() string {
+	return interpolate(s.query, s.hooks.loggedArgs(s.args))
+}
+
+// DebugString returns the raw query with its args substituted as SQL
+// literals. Both $N and ? placeholders are substituted. It is for debugging
+// only and must never be executed.
+func (r *RawQuery) DebugString() string {
+	return interpolate(r.sql, r.hooks.loggedArgs(r.args))
+}
+
+// Dump describes the builder over several lines for troubleshooting: the
+// statement kind, table, dialect and transaction, the text of each clause
+// added so far, each arg with its placeholder number and Go type, and the
+// error of a failed chained call. Sensitive and redacted args are shown as
+// [REDACTED]. The format is for people to read and may change.
+func (b *Builder) Dump() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "toki.Builder %s", b.kind)
+	if b.table != "" {
+		fmt.Fprintf(&sb, " %s", b.table)
+	}
+	fmt.Fprintf(&sb, " (dialect %s, ", b.Dialect())
+	switch {
+	case b.tx == nil:
+		sb.WriteString("no transaction)\n")
+	case b.tx.readOnly:
+		sb.WriteString("read-only transaction)\n")
+	default:
+		sb.WriteString("in a transaction)\n")
+	}
+
+	line := func(label, text string) {
+		if text != "" {
+			fmt.Fprintf(&sb, "  %-10s %s\n", label, text)
+		}
+	}
+	c := &b.clauses
+	line("WITH", strings.Join(c.with, ", "))
+	line("HEAD", c.head)
+	line("SET", strings.Join(c.set, ", "))
+	for _, row := range c.values {
+		line("VALUES", row)
+	}
+	if c.selected {
+		columns := strings.Join(c.columns, ", ")
+		if c.distinct {
+			columns = "DISTINCT " + columns
+		}
+		if len(c.hints) > 0 {
+			columns = "/*+ " + strings.Join(c.hints, " ") + " */ " + columns
+		}
+		line("SELECT", columns)
+	}
+	line("FROM", strings.Join(c.from, ", "))
+	for _, join := range c.joins {
+		line("JOIN", join)
+	}
+	line("WHERE", strings.Join(c.where, " "))
+	line("GROUP BY", strings.Join(c.groupBy, ", "))
+	line("HAVING", strings.Join(c.having, " "))
+	line("ORDER BY", strings.Join(c.orderBy, ", "))
+	if b.page != nil {
+		line("PAGE", b.renderPage())
+	}
+	line("CONFLICT", c.conflict)
+	line("RETURNING", strings.Join(c.returning, ", "))
+
+	args := b.hooks.loggedArgs(b.args)
+	if len(args) == 0 {
+		line("args", "none")
+	} else {
+		sb.WriteString("  args\n")
+	}
+	for i, arg := range args {
+		fmt.Fprintf(&sb, "    $%-4d %-16s %s\n", i+1, fmt.Sprintf("%T", arg), debugLiteral(arg))
+	}
+	if b.err != nil {
+		line("error", b.err.Error())
+	}
+	return sb.String()
+}
+
+// GoString returns Dump, so %#v prints the builder for troubleshooting
+func (b *Builder) GoString() string {
+	return b.Dump()
+}
+
+// interpolate replaces $N and ? placeholders outside literals and comments
+// with the matching arg formatted by debugLiteral. Placeholders without a
+// matching arg are left as they are.
+func interpolate(query string, args []interface{}) string {
+	return substitute(query, args, debugLiteral)
+}
+
+// substitute replaces $N, ? and @pN placeholders outside literals and
+// comments with the matching arg formatted by format
+func substitute(query string, args []interface{}, format func(interface{}) string) string {
+	var out strings.Builder
+	out.Grow(len(query))
+	next := 0
+
+	scanSQL(query, func(from, to int, code bool) {
+		if !code {
+			out.WriteString(query[from:to])
+			return
+		}
+		for i := from; i < to; i++ {
+			c := query[i]
+
+			if c == '?' && next < len(args) {
+				out.WriteString(format(args[next]))
+				next++
+				continue
+			}
+
+			if c == '$' && (i == 0 || !isIdentByte(query[i-1])) {
+				j := i + 1
+				for j < to && query[j] >= '0' && query[j] <= '9' {
+					j++
+				}
+				if n, err := strconv.Atoi(query[i+1 : j]); err == nil && n >= 1 && n <= len(args) {
+					out.WriteString(format(args[n-1]))
+					i = j - 1
+					continue
+				}
+			}
+
+			if end := atPlaceholderEnd(query, i, to); end > 0 {
+				if n, err := strconv.Atoi(query[i+2 : end]); err == nil && n >= 1 && n <= len(args) {
+					out.WriteString(format(args[n-1]))
+					i = end - 1
+					continue
+				}
+			}
+
+			out.WriteByte(c)
+		}
+	})
+
+	return out.String()
+}
+
+// debugLiteral formats v as a SQL literal for DebugString
+func debugLiteral(v interface{}) string {
+	if _, ok := v.(SensitiveValue); ok {
+		return Redacted
+	}
+	return literal(v, debugValueLimit)
+}
+
+// literal formats v as a SQL literal, truncating strings and byte slices
+// longer than limit with a marker outside the literal. A limit of 0 keeps
+// values whole.
+func literal(v interface{}, limit int) string {
+	if valuer, ok := v.(driver.Valuer); ok {
+		value, err := valuer.Value()
+		if err != nil {
+			return fmt.Sprintf("/* %T: %v */ NULL", v, err)
+		}
+		v = value
+	}
+
+	switch v := normalizeArg(v).(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return quoteString(v, limit)
+	case []byte:
+		if v == nil {
+			return "NULL"
+		}
+		encoded := hex.EncodeToString(v)
+		if limit > 0 && len(encoded) > limit {
+			return fmt.Sprintf("'\\x%s'... (%d bytes)", encoded[:limit], len(v))
+		}
+		return "'\\x" + encoded + "'"
+	case time.Time:
+		return "'" + v.Format(time.RFC3339Nano) + "'"
+	case bool:
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(v)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	default:
+		return quoteString(fmt.Sprint(v), limit)
+	}
+}
+
+// quoteString quotes s as a SQL string literal, doubling single quotes and
+// truncating values longer than limit with a marker outside the literal
+func quoteString(s string, limit int) string {
+	suffix := ""
+	if limit > 0 && len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		suffix = fmt.Sprintf("... (%d bytes)", len(s))
+		s = s[:cut]
+	}
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'" + suffix
+}
@@ -0,0 +1,102 @@
+package toki
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDebugLiteral(t *testing.T) {
+	name := "zakirkun"
+	var missing *string
+	at := time.Date(2024, 3, 1, 12, 30, 0, 500, time.UTC)
+
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{"nil", nil, "NULL"},
+		{"nil pointer", missing, "NULL"},
+		{"pointer", &name, "'zakirkun'"},
+		{"quotes", "O'Brien'; DROP TABLE users; --", "'O''Brien''; DROP TABLE users; --'"},
+		{"backslash", `a\b`, `'a\b'`},
+		{"int", int64(-42), "-42"},
+		{"float", 1.5, "1.5"},
+		{"bool", true, "TRUE"},
+		{"time", at, "'2024-03-01T12:30:00.0000005Z'"},
+		{"bytes", []byte{0xde, 0xad, 0xbe, 0xef}, `'\xdeadbeef'`},
+		{"nil bytes", []byte(nil), "NULL"},
+		{"valuer", Email{Local: "a", Domain: "b.c"}, "'a@b.c'"},
+		{"long string", strings.Repeat("é", 40), "'" + strings.Repeat("é", 32) + "'... (80 bytes)"},
+		{"long bytes", make([]byte, 40), `'\x` + strings.Repeat("0", 64) + `'... (40 bytes)`},
+		{"stringer", time.Second, "'1s'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, debugLiteral(tt.value))
+		})
+	}
+}
+
+func TestDebugString(t *testing.T) {
+	query := New().
+		Select("*").
+		From("users").
+		Where("name = ?", "O'Brien").
+		AndWhere("note <> '$1'").
+		AndWhere("age > ?", 18)
+
+	assert.Equal(t, "SELECT * FROM users WHERE name = 'O''Brien' AND note <> '$1' AND age > 18", query.DebugString())
+
+	stmt, err := New().Update("users").Set(map[string]interface{}{"name": nil}).Where("id = ?", 1).Prepare(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "UPDATE users SET name = NULL WHERE id = 1", stmt.DebugString())
+
+	raw := New().Raw("SELECT ? /* ? */, $9, ?", "a", true)
+	assert.Equal(t, "SELECT 'a' /* ? */, $9, TRUE", raw.DebugString())
+}
+
+func TestDump(t *testing.T) {
+	b := New().
+		With("recent", New().Select("id").From("orders").Where("created_at > ?", TestTime)).
+		Select("u.id", "u.email").
+		From("users u").
+		Join("recent r", "r.id = u.id").
+		Where("u.status = ?", "active").
+		AndWhere("u.token = ?", Sensitive("secret")).
+		OrderBy("u.id").
+		Limit(10)
+
+	want := `toki.Builder SELECT users u (dialect postgres, no transaction)
+  WITH       recent AS (SELECT id FROM orders WHERE created_at > $1)
+  SELECT     u.id, u.email
+  FROM       users u
+  JOIN       JOIN recent r ON r.id = u.id
+  WHERE      u.status = $2 AND u.token = $3
+  ORDER BY   u.id
+  PAGE       LIMIT $4
+  args
+    $1    time.Time        '2024-12-23T05:45:29Z'
+    $2    string           'active'
+    $3    toki.SensitiveValue [REDACTED]
+    $4    int              10
+`
+	assert.Equal(t, want, b.Dump())
+	assert.Equal(t, want, fmt.Sprintf("%#v", b))
+
+	u := New().Update("users").Set(map[string]interface{}{"name": "zakirkun"}).From("accounts").From("teams").Returning("id")
+	assert.Equal(t, `toki.Builder UPDATE users (dialect postgres, no transaction)
+  HEAD       UPDATE users
+  SET        name = $1
+  FROM       accounts
+  RETURNING  id
+  args
+    $1    string           'zakirkun'
+  error      FROM already set, cannot add teams; use AddFrom for FROM a, b
+`, u.Dump())
+}
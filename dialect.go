@@ -0,0 +1,233 @@
+package toki
+
+import (
+	"strconv"
+	"strings"
+)
+
+// placeholderStyle is how a database marks positional parameters
+type placeholderStyle uint8
+
+const (
+	// dollarPlaceholders numbers parameters $1, $2
+	dollarPlaceholders placeholderStyle = iota
+	// questionPlaceholders marks each parameter with ?
+	questionPlaceholders
+	// atPlaceholders names parameters @p1, @p2
+	atPlaceholders
+)
+
+// Dialect describes the SQL flavour of a database. Builders render for the
+// package-wide dialect, Postgres unless changed with SetDialect, or for the
+// one set with WithDialect.
+type Dialect struct {
+	name string
+	// types maps upper-case type names the database lacks to the type used
+	// instead
+	types map[string]string
+	// parenDefaults wraps expression column defaults in parentheses
+	parenDefaults bool
+	// dropCascade allows DROP TABLE ... CASCADE
+	dropCascade bool
+	// concurrentIndexes allows CREATE and DROP INDEX CONCURRENTLY
+	concurrentIndexes bool
+	// multiAlter allows several actions in one ALTER TABLE
+	multiAlter bool
+	// alterColumns allows changing column types and table constraints
+	alterColumns bool
+	// indexMethods allows CREATE INDEX ... USING
+	indexMethods bool
+	// fetchSyntax paginates with OFFSET ... FETCH instead of LIMIT,
+	// fetchNeedsOrder requires an ORDER BY with it and fetchNeedsOffset an
+	// OFFSET before FETCH
+	fetchSyntax      bool
+	fetchNeedsOrder  bool
+	fetchNeedsOffset bool
+	// ilike has a case-insensitive ILIKE operator
+	ilike bool
+	// returning supports INSERT ... RETURNING
+	returning bool
+	// updateFrom supports UPDATE ... FROM (VALUES ...) AS v (columns)
+	updateFrom bool
+	// merge supports MERGE
+	merge bool
+	// nullSafeEqual is the NULL-safe equality operator used instead of IS
+	// NOT DISTINCT FROM, which the database lacks
+	nullSafeEqual string
+	// rowValues allows (a, b) IN ((1, 2), (3, 4))
+	rowValues bool
+	// aggregateFilter supports aggregate FILTER (WHERE ...)
+	aggregateFilter bool
+	// identQuote quotes identifiers, '"' when unset
+	identQuote byte
+	// castOperator casts with value::type instead of CAST(value AS type)
+	castOperator bool
+	// advisoryLocks holds the advisory lock statements, nil when the
+	// database has none
+	advisoryLocks *advisoryLockSQL
+	// placeholders is the parameter style of built statements and of the
+	// queries RawQuery.Rebind writes
+	placeholders placeholderStyle
+	// identCase normalizes the identifiers given to builders, nil to keep
+	// them as written
+	identCase IdentifierCase
+	// hints supports index hints and /*+ ... */ optimizer hints
+	hints bool
+}
+
+var (
+	// Postgres is the PostgreSQL dialect. MERGE needs PostgreSQL 15 or
+	// later.
+	Postgres = &Dialect{
+		name:              "postgres",
+		dropCascade:       true,
+		concurrentIndexes: true,
+		multiAlter:        true,
+		alterColumns:      true,
+		indexMethods:      true,
+		ilike:             true,
+		returning:         true,
+		updateFrom:        true,
+		merge:             true,
+		rowValues:         true,
+		aggregateFilter:   true,
+		castOperator:      true,
+		advisoryLocks: &advisoryLockSQL{
+			lock: "SELECT pg_advisory_xact_lock($1)",
+			try:  "SELECT pg_try_advisory_xact_lock($1)",
+		},
+	}
+
+	// SQLite is the SQLite dialect, for SQLite 3.35 or later. Serial types
+	// become INTEGER, which is auto-incremented when it is the primary key,
+	// and PostgreSQL types without a SQLite counterpart map to their storage
+	// class.
+	SQLite = &Dialect{
+		name: "sqlite",
+		types: map[string]string{
+			"SMALLSERIAL": "INTEGER",
+			"SERIAL":      "INTEGER",
+			"BIGSERIAL":   "INTEGER",
+			"TIMESTAMPTZ": "TIMESTAMP",
+			"JSONB":       "TEXT",
+			"UUID":        "TEXT",
+			"BYTEA":       "BLOB",
+		},
+		parenDefaults:   true,
+		returning:       true,
+		nullSafeEqual:   "IS",
+		aggregateFilter: true,
+	}
+
+	// MySQL is the MySQL dialect, for MySQL 8.0 or later. Placeholders
+	// render as ?.
+	MySQL = &Dialect{
+		name:          "mysql",
+		placeholders:  questionPlaceholders,
+		multiAlter:    true,
+		nullSafeEqual: "<=>",
+		rowValues:     true,
+		identQuote:    '`',
+		hints:         true,
+		advisoryLocks: &advisoryLockSQL{
+			lock:   "SELECT GET_LOCK(?, -1) = 1",
+			try:    "SELECT GET_LOCK(?, 0) = 1",
+			unlock: "SELECT RELEASE_LOCK(?)",
+			named:  true,
+		},
+	}
+
+	// SQLServer is the SQL Server dialect, for SQL Server 2022 or later.
+	// Pagination renders as OFFSET ... FETCH, which needs an ORDER BY, with
+	// OFFSET 0 ROWS when only Limit is set, and PostgreSQL types map to
+	// their SQL Server counterparts. Placeholders render as @pN.
+	SQLServer = &Dialect{
+		name: "sqlserver",
+		types: map[string]string{
+			"BOOLEAN":     "BIT",
+			"TEXT":        "NVARCHAR(MAX)",
+			"TIMESTAMP":   "DATETIME2",
+			"TIMESTAMPTZ": "DATETIMEOFFSET",
+			"JSON":        "NVARCHAR(MAX)",
+			"JSONB":       "NVARCHAR(MAX)",
+			"UUID":        "UNIQUEIDENTIFIER",
+			"BYTEA":       "VARBINARY(MAX)",
+		},
+		parenDefaults:    true,
+		fetchSyntax:      true,
+		fetchNeedsOrder:  true,
+		fetchNeedsOffset: true,
+		placeholders:     atPlaceholders,
+	}
+)
+
+// defaultDialect is used by builders without their own dialect
+var defaultDialect = Postgres
+
+// SetDialect sets the package-wide dialect, or restores Postgres when d is
+// nil. Call it during initialization, before queries run.
+func SetDialect(d *Dialect) {
+	if d == nil {
+		d = Postgres
+	}
+	defaultDialect = d
+}
+
+// WithDialect sets the dialect this builder renders for
+func (b *Builder) WithDialect(d *Dialect) *Builder {
+	b.changed()
+	b.dialect = d
+	return b
+}
+
+// Dialect returns the dialect the builder renders for
+func (b *Builder) Dialect() *Dialect {
+	if b.dialect != nil {
+		return b.dialect
+	}
+	return defaultDialect
+}
+
+// String returns the dialect name
+func (d *Dialect) String() string {
+	return d.name
+}
+
+// columnType returns the type to declare for typ
+func (d *Dialect) columnType(typ string) string {
+	if mapped, ok := d.types[strings.ToUpper(typ)]; ok {
+		return mapped
+	}
+	return typ
+}
+
+// appendPlaceholder appends the n-th parameter in the dialect's style to buf
+func (d *Dialect) appendPlaceholder(buf []byte, n int) []byte {
+	switch d.placeholders {
+	case questionPlaceholders:
+		return append(buf, '?')
+	case atPlaceholders:
+		buf = append(buf, "@p"...)
+		return strconv.AppendInt(buf, int64(n), 10)
+	}
+	return appendPlaceholder(buf, n)
+}
+
+// countPlaceholders is countPlaceholders for a query written for d, which
+// also counts @pN parameters on SQL Server
+func (d *Dialect) countPlaceholders(query string) int {
+	n := countPlaceholders(query)
+	if n == 0 && d.placeholders == atPlaceholders {
+		n = countAtPlaceholders(query)
+	}
+	return n
+}
+
+// quoteIdent quotes name as an identifier, doubling the quote character
+func (d *Dialect) quoteIdent(name string) string {
+	q := d.identQuote
+	if q == 0 {
+		q = '"'
+	}
+	return string(q) + strings.ReplaceAll(name, string(q), string(q)+string(q)) + string(q)
+}
@@ -0,0 +1,44 @@
+package toki
+
+// WhereDistinctFrom adds a NULL-safe inequality, column IS DISTINCT FROM
+// value: true when exactly one side is NULL or both are non-NULL and differ.
+// An SQLExpression value is rendered as it is, so columns can be compared;
+// anything else, nil included, is bound as an arg. It starts the WHERE
+// clause or is ANDed to it.
+func (b *Builder) WhereDistinctFrom(column string, value interface{}) *Builder {
+	return b.whereDistinct(column, value, true)
+}
+
+// WhereNotDistinctFrom adds a NULL-safe equality, column IS NOT DISTINCT
+// FROM value, which also matches when both sides are NULL. MySQL renders
+// column <=> value and SQLite column IS value.
+func (b *Builder) WhereNotDistinctFrom(column string, value interface{}) *Builder {
+	return b.whereDistinct(column, value, false)
+}
+
+func (b *Builder) whereDistinct(column string, value interface{}, distinct bool) *Builder {
+	operand := "?"
+	var args []interface{}
+	if _, bound := value.(boundExpression); bound {
+		// bound expressions are inlined by Where
+		args = append(args, value)
+	} else if expr, ok := value.(SQLExpression); ok {
+		operand = expr.SQL()
+	} else {
+		args = append(args, value)
+	}
+
+	var condition string
+	switch op := b.Dialect().nullSafeEqual; {
+	case op == "" && distinct:
+		condition = column + " IS DISTINCT FROM " + operand
+	case op == "":
+		condition = column + " IS NOT DISTINCT FROM " + operand
+	case distinct:
+		condition = "NOT (" + column + " " + op + " " + operand + ")"
+	default:
+		condition = column + " " + op + " " + operand
+	}
+
+	return b.Where(condition, args...)
+}
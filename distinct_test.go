@@ -0,0 +1,59 @@
+package toki
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWhereDistinctFrom(t *testing.T) {
+	tests := []struct {
+		name    string
+		builder *Builder
+		want    string
+		args    []interface{}
+	}{
+		{
+			name:    "distinct from nil",
+			builder: New().Select("*").From("users").WhereDistinctFrom("deleted_at", nil),
+			want:    "SELECT * FROM users WHERE deleted_at IS DISTINCT FROM $1",
+			args:    []interface{}{nil},
+		},
+		{
+			name:    "not distinct from nil after where",
+			builder: New().Select("*").From("users").Where("active = ?", true).WhereNotDistinctFrom("manager_id", nil),
+			want:    "SELECT * FROM users WHERE active = $1 AND manager_id IS NOT DISTINCT FROM $2",
+			args:    []interface{}{true, nil},
+		},
+		{
+			name:    "column to column",
+			builder: New().Select("*").From("orders").WhereDistinctFrom("shipped_to", Raw("billed_to")),
+			want:    "SELECT * FROM orders WHERE shipped_to IS DISTINCT FROM billed_to",
+		},
+		{
+			name:    "mysql not distinct",
+			builder: New().WithDialect(MySQL).Select("*").From("users").WhereNotDistinctFrom("manager_id", nil),
+			want:    "SELECT * FROM users WHERE manager_id <=> ?",
+			args:    []interface{}{nil},
+		},
+		{
+			name:    "mysql distinct",
+			builder: New().WithDialect(MySQL).Select("*").From("users").WhereDistinctFrom("manager_id", 7),
+			want:    "SELECT * FROM users WHERE NOT (manager_id <=> ?)",
+			args:    []interface{}{7},
+		},
+		{
+			name:    "sqlite",
+			builder: New().WithDialect(SQLite).Select("*").From("users").WhereDistinctFrom("a", nil).WhereNotDistinctFrom("b", Raw("c")),
+			want:    "SELECT * FROM users WHERE NOT (a IS $1) AND b IS c",
+			args:    []interface{}{nil},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.builder.String())
+			assert.Equal(t, tt.args, tt.builder.Args())
+		})
+	}
+}
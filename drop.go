@@ -0,0 +1,160 @@
+package toki
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrConcurrentInTransaction is returned when a CONCURRENTLY index statement
+// is run from a builder with a transaction attached, which PostgreSQL
+// refuses
+var ErrConcurrentInTransaction = errors.New("CONCURRENTLY cannot run inside a transaction")
+
+// DropTableBuilder builds a DROP TABLE statement
+type DropTableBuilder struct {
+	b        *Builder
+	name     string
+	ifExists bool
+	cascade  bool
+}
+
+// DropTable starts a DROP TABLE statement
+func (b *Builder) DropTable(name string) *DropTableBuilder {
+	return &DropTableBuilder{b: b, name: name}
+}
+
+// IfExists skips dropping the table when it does not exist
+func (d *DropTableBuilder) IfExists() *DropTableBuilder {
+	d.ifExists = true
+	return d
+}
+
+// Cascade also drops objects depending on the table. SQLite does not
+// support it.
+func (d *DropTableBuilder) Cascade() *DropTableBuilder {
+	d.cascade = true
+	return d
+}
+
+// String builds the statement without validating it
+func (d *DropTableBuilder) String() string {
+	query := "DROP TABLE "
+	if d.ifExists {
+		query += "IF EXISTS "
+	}
+	query += d.name
+	if d.cascade {
+		query += " CASCADE"
+	}
+	return query
+}
+
+// Validate checks the table name and the options against the dialect
+func (d *DropTableBuilder) Validate() error {
+	if err := checkIdent("table", d.name); err != nil {
+		return err
+	}
+	if dialect := d.b.Dialect(); d.cascade && !dialect.dropCascade {
+		return fmt.Errorf("DROP TABLE ... CASCADE is not supported by the %s dialect", dialect)
+	}
+	return nil
+}
+
+// Prepare validates the statement and creates a prepared statement for it
+func (d *DropTableBuilder) Prepare(db *sql.DB) (*Stmt, error) {
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+	return d.b.prepareDDL(db, d.String(), d.name)
+}
+
+// Exec validates and executes the statement
+func (d *DropTableBuilder) Exec(ctx context.Context, db *sql.DB) (sql.Result, error) {
+	stmt, err := d.Prepare(db)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.execDDL(ctx)
+}
+
+// DropIndexBuilder builds a DROP INDEX statement
+type DropIndexBuilder struct {
+	b            *Builder
+	name         string
+	ifExists     bool
+	concurrently bool
+}
+
+// DropIndex starts a DROP INDEX statement
+func (b *Builder) DropIndex(name string) *DropIndexBuilder {
+	return &DropIndexBuilder{b: b, name: name}
+}
+
+// IfExists skips dropping the index when it does not exist
+func (d *DropIndexBuilder) IfExists() *DropIndexBuilder {
+	d.ifExists = true
+	return d
+}
+
+// Concurrently drops the index without locking out writes to its table.
+// PostgreSQL only; it cannot run inside a transaction, so the builder must
+// not have one attached.
+func (d *DropIndexBuilder) Concurrently() *DropIndexBuilder {
+	d.concurrently = true
+	return d
+}
+
+// String builds the statement without validating it
+func (d *DropIndexBuilder) String() string {
+	query := "DROP INDEX "
+	if d.concurrently {
+		query += "CONCURRENTLY "
+	}
+	if d.ifExists {
+		query += "IF EXISTS "
+	}
+	return query + d.name
+}
+
+// Validate checks the index name and the options against the dialect and
+// the attached transaction
+func (d *DropIndexBuilder) Validate() error {
+	if err := checkIdent("index", d.name); err != nil {
+		return err
+	}
+	return d.b.checkConcurrently("DROP INDEX", d.concurrently)
+}
+
+// Prepare validates the statement and creates a prepared statement for it
+func (d *DropIndexBuilder) Prepare(db *sql.DB) (*Stmt, error) {
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+	return d.b.prepareDDL(db, d.String(), "")
+}
+
+// Exec validates and executes the statement
+func (d *DropIndexBuilder) Exec(ctx context.Context, db *sql.DB) (sql.Result, error) {
+	stmt, err := d.Prepare(db)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.execDDL(ctx)
+}
+
+// checkConcurrently rejects CONCURRENTLY on dialects without it and on
+// builders with a transaction attached
+func (b *Builder) checkConcurrently(statement string, concurrently bool) error {
+	if !concurrently {
+		return nil
+	}
+	if dialect := b.Dialect(); !dialect.concurrentIndexes {
+		return fmt.Errorf("%s CONCURRENTLY is not supported by the %s dialect", statement, dialect)
+	}
+	if b.tx != nil {
+		return fmt.Errorf("%s: %w", statement, ErrConcurrentInTransaction)
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package toki
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// conn is the database or transaction a statement runs on, implemented by
+// *sql.DB and *sql.Tx
+type conn interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// connFor returns tx when set and db otherwise, or nil when neither is set
+func connFor(db *sql.DB, tx *sql.Tx) conn {
+	if tx != nil {
+		return tx
+	}
+	if db != nil {
+		return db
+	}
+	return nil
+}
+
+// hooks holds the observers and middleware of a builder, statement or raw
+// query. Unset fields fall back to the package-wide defaults.
+type hooks struct {
+	logger     Logger
+	metrics    MetricsCollector
+	slow       *slowQuery
+	comments   []commentTag
+	middleware []Middleware
+	// redact shows every arg as [REDACTED] to observers
+	redact bool
+}
+
+// run sends q to c through the middleware chain. The innermost handler
+// makes the database call and reports it.
+func (h hooks) run(ctx context.Context, c conn, q QueryInfo) (Result, error) {
+	handler := func(ctx context.Context, q QueryInfo) (Result, error) {
+		var (
+			res Result
+			err error
+		)
+
+		args := DriverArgs(q.Args)
+		start := time.Now()
+		switch q.Op {
+		case OpExec:
+			res.Exec, err = c.ExecContext(ctx, q.SQL, args...)
+		case OpQuery:
+			res.Rows, err = c.QueryContext(ctx, q.SQL, args...)
+		case OpQueryRow:
+			// the row is fetched before QueryRowContext returns, only
+			// scanning is left
+			res.Row = c.QueryRowContext(ctx, q.SQL, args...)
+			err = res.Row.Err()
+		}
+		h.observe(ctx, q, time.Since(start), res.Exec, err)
+
+		return res, err
+	}
+
+	// prepared statements got their comment when they were prepared
+	if _, prepared := c.(stmtConn); !prepared {
+		if comment := h.comment(ctx); comment != "" {
+			q.SQL = appendComment(q.SQL, comment)
+		}
+	}
+
+	if len(defaultMiddleware) == 0 && len(h.middleware) == 0 {
+		return handler(ctx, q)
+	}
+
+	mw := make([]Middleware, 0, len(defaultMiddleware)+len(h.middleware))
+	mw = append(append(mw, defaultMiddleware...), h.middleware...)
+	return chain(handler, mw)(ctx, q)
+}
+
+// exec runs q as a statement
+func (h hooks) exec(ctx context.Context, c conn, q QueryInfo) (sql.Result, error) {
+	q.Op = OpExec
+	res, err := h.run(ctx, c, q)
+	return res.Exec, err
+}
+
+// query runs q as a query returning rows
+func (h hooks) query(ctx context.Context, c conn, q QueryInfo) (*sql.Rows, error) {
+	q.Op = OpQuery
+	res, err := h.run(ctx, c, q)
+	return res.Rows, err
+}
+
+// queryRow runs q as a query returning a single row
+func (h hooks) queryRow(ctx context.Context, c conn, q QueryInfo) *Row {
+	q.Op = OpQueryRow
+	res, err := h.run(ctx, c, q)
+	if err != nil {
+		return &Row{err: err}
+	}
+	if res.Row == nil {
+		return &Row{err: errors.New("middleware returned no row")}
+	}
+	return &Row{row: res.Row}
+}
+
+// observe reports a finished database call, with the result of a
+// statement run with Exec
+func (h hooks) observe(ctx context.Context, q QueryInfo, took time.Duration, exec sql.Result, err error) {
+	if logger := h.resolvedLogger(); logger != nil {
+		args := h.loggedArgs(q.Args)
+		if rl, ok := logger.(RowsLogger); ok {
+			rl.LogQueryRows(ctx, q.SQL, args, took, affectedRows(exec, err), err)
+		} else {
+			logger.LogQuery(ctx, q.SQL, args, took, err)
+		}
+	}
+
+	metrics := h.metrics
+	if metrics == nil {
+		metrics = defaultMetrics
+	}
+	if metrics != nil {
+		metrics.ObserveQuery(metricKind(q.Kind), q.Table, took, err)
+	}
+
+	slow := h.slow
+	if slow == nil {
+		slow = defaultSlowQuery
+	}
+	if slow != nil && took > slow.threshold {
+		slow.fn(ctx, q.SQL, h.loggedArgs(q.Args), took)
+	}
+}
+
+// affectedRows returns the rows a successful Exec affected, or -1 when the
+// count is unknown
+func affectedRows(exec sql.Result, err error) int64 {
+	if exec == nil || err != nil {
+		return -1
+	}
+	n, err := exec.RowsAffected()
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// resolvedLogger returns the logger, falling back to the package-wide one
+func (h hooks) resolvedLogger() Logger {
+	if h.logger != nil {
+		return h.logger
+	}
+	return defaultLogger
+}
+
+// begin starts a transaction on db and reports it as BEGIN
+func (h hooks) begin(ctx context.Context, db *sql.DB, opts *sql.TxOptions) (*sql.Tx, error) {
+	start := time.Now()
+	tx, err := db.BeginTx(ctx, opts)
+	h.observe(ctx, QueryInfo{SQL: "BEGIN"}, time.Since(start), nil, err)
+	return tx, err
+}
+
+// commit commits tx and reports it as COMMIT
+func (h hooks) commit(ctx context.Context, tx *sql.Tx) error {
+	start := time.Now()
+	err := tx.Commit()
+	h.observe(ctx, QueryInfo{SQL: "COMMIT"}, time.Since(start), nil, err)
+	return err
+}
+
+// rollback rolls tx back and reports it as ROLLBACK
+func (h hooks) rollback(ctx context.Context, tx *sql.Tx) error {
+	start := time.Now()
+	err := tx.Rollback()
+	h.observe(ctx, QueryInfo{SQL: "ROLLBACK"}, time.Since(start), nil, err)
+	return err
+}
+
+// stmtConn adapts a prepared statement to conn. The query text is only used
+// for reporting, the statement already holds it.
+type stmtConn struct {
+	stmt *sql.Stmt
+}
+
+func (s stmtConn) ExecContext(ctx context.Context, _ string, args ...interface{}) (sql.Result, error) {
+	return s.stmt.ExecContext(ctx, args...)
+}
+
+func (s stmtConn) QueryContext(ctx context.Context, _ string, args ...interface{}) (*sql.Rows, error) {
+	return s.stmt.QueryContext(ctx, args...)
+}
+
+func (s stmtConn) QueryRowContext(ctx context.Context, _ string, args ...interface{}) *sql.Row {
+	return s.stmt.QueryRowContext(ctx, args...)
+}
@@ -0,0 +1,130 @@
+package toki
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ExplainResult is one entry of PostgreSQL's EXPLAIN (FORMAT JSON) output
+type ExplainResult struct {
+	Plan PlanNode `json:"Plan"`
+	// PlanningTime and ExecutionTime are in milliseconds and only reported
+	// with ANALYZE
+	PlanningTime  float64 `json:"Planning Time"`
+	ExecutionTime float64 `json:"Execution Time"`
+}
+
+// PlanNode is a node of a PostgreSQL query plan. Actual* fields are only
+// reported with ANALYZE.
+type PlanNode struct {
+	NodeType          string     `json:"Node Type"`
+	RelationName      string     `json:"Relation Name"`
+	Alias             string     `json:"Alias"`
+	IndexName         string     `json:"Index Name"`
+	JoinType          string     `json:"Join Type"`
+	Filter            string     `json:"Filter"`
+	StartupCost       float64    `json:"Startup Cost"`
+	TotalCost         float64    `json:"Total Cost"`
+	PlanRows          float64    `json:"Plan Rows"`
+	PlanWidth         int        `json:"Plan Width"`
+	ActualStartupTime float64    `json:"Actual Startup Time"`
+	ActualTotalTime   float64    `json:"Actual Total Time"`
+	ActualRows        float64    `json:"Actual Rows"`
+	ActualLoops       float64    `json:"Actual Loops"`
+	Plans             []PlanNode `json:"Plans"`
+}
+
+// Explain runs EXPLAIN for the statement with its bound args and returns the
+// plan text. The statement itself is not executed. It runs on the builder's
+// transaction when it has one, otherwise on db.
+func (b *Builder) Explain(ctx context.Context, db *sql.DB) (string, error) {
+	return b.explainText(ctx, db, "EXPLAIN ")
+}
+
+// ExplainAnalyze runs EXPLAIN ANALYZE and returns the plan text with actual
+// timings. ANALYZE executes the statement, so INSERT, UPDATE and DELETE are
+// refused unless allowWrites is true.
+func (b *Builder) ExplainAnalyze(ctx context.Context, db *sql.DB, allowWrites bool) (string, error) {
+	if err := b.checkAnalyze(allowWrites); err != nil {
+		return "", err
+	}
+	return b.explainText(ctx, db, "EXPLAIN ANALYZE ")
+}
+
+// ExplainJSON runs PostgreSQL's EXPLAIN (FORMAT JSON), with ANALYZE when
+// analyze is true, and decodes the plan. Writes are refused under ANALYZE
+// unless allowWrites is true, as for ExplainAnalyze.
+func (b *Builder) ExplainJSON(ctx context.Context, db *sql.DB, analyze, allowWrites bool) ([]ExplainResult, error) {
+	prefix := "EXPLAIN (FORMAT JSON) "
+	if analyze {
+		if err := b.checkAnalyze(allowWrites); err != nil {
+			return nil, err
+		}
+		prefix = "EXPLAIN (ANALYZE, FORMAT JSON) "
+	}
+
+	lines, err := b.explain(ctx, db, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []ExplainResult
+	if err := json.Unmarshal([]byte(strings.Join(lines, "\n")), &results); err != nil {
+		return nil, fmt.Errorf("failed to decode plan: %w", err)
+	}
+	return results, nil
+}
+
+// checkAnalyze refuses to ANALYZE a write statement without allowWrites
+func (b *Builder) checkAnalyze(allowWrites bool) error {
+	if b.kind.IsWrite() && !allowWrites {
+		return fmt.Errorf("EXPLAIN ANALYZE would execute the %s statement; pass allowWrites to run it", b.kind)
+	}
+	return nil
+}
+
+func (b *Builder) explainText(ctx context.Context, db *sql.DB, prefix string) (string, error) {
+	lines, err := b.explain(ctx, db, prefix)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// explain runs prefix plus the statement and returns the plan lines
+func (b *Builder) explain(ctx context.Context, db *sql.DB, prefix string) ([]string, error) {
+	var tx *sql.Tx
+	if b.tx != nil {
+		tx = b.tx.tx
+	}
+	c := connFor(db, tx)
+	if c == nil {
+		return nil, errors.New("explain needs a database or transaction")
+	}
+
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	query := prefix + b.String()
+	rows, err := b.hooks.query(ctx, c, QueryInfo{SQL: query, Args: b.Args(), Table: b.table})
+	if err != nil {
+		return nil, fmt.Errorf("failed to explain query: %w", err)
+	}
+	defer rows.Close()
+
+	var lines []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return nil, fmt.Errorf("failed to read plan: %w", err)
+		}
+		lines = append(lines, line)
+	}
+
+	return lines, rows.Err()
+}
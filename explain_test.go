@@ -0,0 +1,93 @@
+package toki
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExplain(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("EXPLAIN SELECT \\* FROM users WHERE id = \\$1").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"QUERY PLAN"}).
+			AddRow("Index Scan using users_pkey on users  (cost=0.15..8.17 rows=1 width=40)").
+			AddRow("  Index Cond: (id = 1)"))
+
+	plan, err := New().Select("*").From("users").Where("id = ?", 1).Explain(context.Background(), db)
+	assert.NoError(t, err)
+	assert.Equal(t, "Index Scan using users_pkey on users  (cost=0.15..8.17 rows=1 width=40)\n  Index Cond: (id = 1)", plan)
+
+	mock.ExpectQuery("EXPLAIN ANALYZE SELECT \\* FROM users").
+		WillReturnRows(sqlmock.NewRows([]string{"QUERY PLAN"}).AddRow("Seq Scan on users (actual time=0.010..0.011 rows=3 loops=1)"))
+
+	plan, err = New().Select("*").From("users").ExplainAnalyze(context.Background(), db, false)
+	assert.NoError(t, err)
+	assert.Contains(t, plan, "actual time")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExplainAnalyzeWrites(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	query := New().Delete("users").Where("id = ?", 1)
+
+	_, err = query.ExplainAnalyze(context.Background(), db, false)
+	assert.EqualError(t, err, "EXPLAIN ANALYZE would execute the DELETE statement; pass allowWrites to run it")
+
+	_, err = query.ExplainJSON(context.Background(), db, true, false)
+	assert.Error(t, err)
+
+	// plain EXPLAIN does not execute the statement and is always allowed
+	mock.ExpectQuery("EXPLAIN DELETE FROM users").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"QUERY PLAN"}).AddRow("Delete on users"))
+
+	_, err = query.Explain(context.Background(), db)
+	assert.NoError(t, err)
+
+	mock.ExpectQuery("EXPLAIN ANALYZE DELETE FROM users").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"QUERY PLAN"}).AddRow("Delete on users"))
+
+	_, err = query.ExplainAnalyze(context.Background(), db, true)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExplainJSON(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	plan := `[{"Plan": {"Node Type": "Nested Loop", "Join Type": "Inner", "Startup Cost": 0.29, "Total Cost": 16.4,
+  "Plan Rows": 1, "Plan Width": 72, "Actual Total Time": 0.05, "Actual Rows": 1, "Actual Loops": 1,
+  "Plans": [{"Node Type": "Index Scan", "Relation Name": "users", "Alias": "u", "Index Name": "users_pkey"}]},
+  "Planning Time": 0.1, "Execution Time": 0.2}]`
+
+	mock.ExpectQuery("EXPLAIN \\(ANALYZE, FORMAT JSON\\) SELECT").
+		WillReturnRows(sqlmock.NewRows([]string{"QUERY PLAN"}).AddRow(plan))
+
+	results, err := New().Select("*").From("users u").ExplainJSON(context.Background(), db, true, false)
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, "Nested Loop", results[0].Plan.NodeType)
+	assert.Equal(t, 16.4, results[0].Plan.TotalCost)
+	assert.Equal(t, "users_pkey", results[0].Plan.Plans[0].IndexName)
+	assert.Equal(t, 0.2, results[0].ExecutionTime)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
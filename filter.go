@@ -0,0 +1,233 @@
+package toki
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FilterOp is a comparison a FilterSpec filter allows
+type FilterOp string
+
+const (
+	// FilterEq is column = value
+	FilterEq FilterOp = "eq"
+	// FilterNe is column <> value
+	FilterNe FilterOp = "ne"
+	// FilterLt is column < value
+	FilterLt FilterOp = "lt"
+	// FilterLte is column <= value
+	FilterLte FilterOp = "lte"
+	// FilterGt is column > value
+	FilterGt FilterOp = "gt"
+	// FilterGte is column >= value
+	FilterGte FilterOp = "gte"
+	// FilterLike matches values containing the value, as WhereILike with
+	// MatchContains
+	FilterLike FilterOp = "like"
+	// FilterIn is column IN (values), the values separated by commas
+	FilterIn FilterOp = "in"
+)
+
+// comparisons are the SQL operators of the plain comparison ops
+var comparisons = map[FilterOp]string{
+	FilterEq:  "=",
+	FilterNe:  "<>",
+	FilterLt:  "<",
+	FilterLte: "<=",
+	FilterGt:  ">",
+	FilterGte: ">=",
+}
+
+// FilterSpec whitelists the filters, sort fields and page sizes that
+// ApplyParams accepts from HTTP query parameters:
+//
+//	filter[status]=active          status = $1
+//	filter[price][gte]=10          price >= $1
+//	filter[id][in]=1,2,3           id IN ($1, $2, $3)
+//	sort=-created,name             ORDER BY created_at DESC, name ASC
+//	page=2&page_size=20            LIMIT $1 OFFSET $2
+//
+// Parameter names map to columns defined in the spec, so column names never
+// come from the request, and every value is bound as an arg.
+type FilterSpec struct {
+	filters         map[string]filterField
+	sorts           map[string]string
+	defaultPageSize int
+	maxPageSize     int
+}
+
+type filterField struct {
+	column string
+	ops    []FilterOp
+}
+
+// filterAction is one validated filter, applied once all parameters are
+// checked
+type filterAction struct {
+	column string
+	op     FilterOp
+	value  string
+}
+
+// NewFilterSpec creates a spec without filters or sort fields, paginating
+// by 50 rows and allowing at most 100
+func NewFilterSpec() *FilterSpec {
+	return &FilterSpec{
+		filters:         make(map[string]filterField),
+		sorts:           make(map[string]string),
+		defaultPageSize: 50,
+		maxPageSize:     100,
+	}
+}
+
+// Filter allows filtering column through the name parameter with ops, or
+// only with FilterEq when none are given
+func (s *FilterSpec) Filter(name, column string, ops ...FilterOp) *FilterSpec {
+	if len(ops) == 0 {
+		ops = []FilterOp{FilterEq}
+	}
+	s.filters[name] = filterField{column: column, ops: ops}
+	return s
+}
+
+// Sort allows sorting by column through the name sort field
+func (s *FilterSpec) Sort(name, column string) *FilterSpec {
+	s.sorts[name] = column
+	return s
+}
+
+// PageSize sets the page size used without page_size and the largest one
+// accepted
+func (s *FilterSpec) PageSize(def, max int) *FilterSpec {
+	s.defaultPageSize = def
+	s.maxPageSize = max
+	return s
+}
+
+// ApplyParams adds the filters of values to b as ANDed WHERE conditions,
+// followed by ORDER BY for sort and LIMIT and OFFSET when page or page_size
+// is given. Parameters other than filter[...], sort, page and page_size are
+// ignored. Anything outside the spec is rejected with an error before b is
+// changed. Call it before adding ORDER BY or pagination yourself.
+func (s *FilterSpec) ApplyParams(b *Builder, values url.Values) error {
+	var filters []filterAction
+	for _, key := range sortedParams(values) {
+		if !strings.HasPrefix(key, "filter[") {
+			continue
+		}
+		action, err := s.parseFilter(key, values.Get(key))
+		if err != nil {
+			return err
+		}
+		filters = append(filters, action)
+	}
+
+	var order []string
+	if sortParam := values.Get("sort"); sortParam != "" {
+		for _, field := range strings.Split(sortParam, ",") {
+			dir := " ASC"
+			if strings.HasPrefix(field, "-") {
+				field, dir = field[1:], " DESC"
+			}
+			column, ok := s.sorts[field]
+			if !ok {
+				return fmt.Errorf("unknown sort field %q", field)
+			}
+			order = append(order, column+dir)
+		}
+	}
+
+	page, err := positiveParam(values, "page", 1)
+	if err != nil {
+		return err
+	}
+	size, err := positiveParam(values, "page_size", s.defaultPageSize)
+	if err != nil {
+		return err
+	}
+	if size > s.maxPageSize {
+		return fmt.Errorf("page_size %d exceeds the maximum of %d", size, s.maxPageSize)
+	}
+
+	for _, f := range filters {
+		switch f.op {
+		case FilterLike:
+			b.WhereILike(f.column, f.value, MatchContains)
+		case FilterIn:
+			items := strings.Split(f.value, ",")
+			args := make([]interface{}, len(items))
+			for i, item := range items {
+				args[i] = item
+			}
+			b.Where(f.column+" IN ("+strings.TrimSuffix(strings.Repeat("?, ", len(items)), ", ")+")", args...)
+		default:
+			b.Where(f.column+" "+comparisons[f.op]+" ?", f.value)
+		}
+	}
+	if len(order) > 0 {
+		b.OrderBy(order...)
+	}
+	if values.Has("page") || values.Has("page_size") {
+		b.Limit(size)
+		b.Offset((page - 1) * size)
+	}
+	return nil
+}
+
+// parseFilter validates a filter[name] or filter[name][op] parameter
+func (s *FilterSpec) parseFilter(key, value string) (filterAction, error) {
+	name, op, ok := strings.Cut(strings.TrimPrefix(key, "filter["), "]")
+	if !ok {
+		return filterAction{}, fmt.Errorf("malformed filter parameter %q", key)
+	}
+	switch {
+	case op == "":
+		op = string(FilterEq)
+	case strings.HasPrefix(op, "[") && strings.HasSuffix(op, "]"):
+		op = op[1 : len(op)-1]
+	default:
+		return filterAction{}, fmt.Errorf("malformed filter parameter %q", key)
+	}
+
+	if _, ok := comparisons[FilterOp(op)]; !ok && FilterOp(op) != FilterLike && FilterOp(op) != FilterIn {
+		return filterAction{}, fmt.Errorf("unknown filter operator %q", op)
+	}
+
+	field, ok := s.filters[name]
+	if !ok {
+		return filterAction{}, fmt.Errorf("unknown filter %q", name)
+	}
+	for _, allowed := range field.ops {
+		if string(allowed) == op {
+			return filterAction{column: field.column, op: allowed, value: value}, nil
+		}
+	}
+	return filterAction{}, fmt.Errorf("filter %s does not allow operator %q", name, op)
+}
+
+// sortedParams returns the parameter names of values in sorted order, so
+// conditions are added deterministically
+func sortedParams(values url.Values) []string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// positiveParam parses the name parameter as a positive integer, returning
+// def when it is absent
+func positiveParam(values url.Values, name string, def int) (int, error) {
+	if !values.Has(name) {
+		return def, nil
+	}
+	n, err := strconv.Atoi(values.Get(name))
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid %s %q", name, values.Get(name))
+	}
+	return n, nil
+}
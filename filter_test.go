@@ -0,0 +1,64 @@
+package toki
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func productSpec() *FilterSpec {
+	return NewFilterSpec().
+		Filter("status", "status").
+		Filter("price", "price", FilterGte, FilterLte).
+		Filter("id", "id", FilterIn).
+		Filter("name", "name", FilterLike).
+		Sort("created", "created_at").
+		Sort("name", "name").
+		PageSize(20, 50)
+}
+
+func TestFilterSpecApplyParams(t *testing.T) {
+	values, err := url.ParseQuery("filter[status]=active&filter[price][gte]=10&filter[price][lte]=99" +
+		"&filter[id][in]=1,2,3&filter[name][like]=50%25_off&sort=-created,name&page=3&utm_source=mail")
+	assert.NoError(t, err)
+
+	b := New().Select("*").From("products")
+	assert.NoError(t, productSpec().ApplyParams(b, values))
+	assert.Equal(t, `SELECT * FROM products WHERE id IN ($1, $2, $3) AND name ILIKE $4 ESCAPE '\' `+
+		`AND price >= $5 AND price <= $6 AND status = $7 ORDER BY created_at DESC, name ASC LIMIT $8 OFFSET $9`, b.String())
+	assert.Equal(t, []interface{}{"1", "2", "3", `%50\%\_off%`, "10", "99", "active", 20, 40}, b.args)
+
+	b = New().Select("*").From("products").Where("shop_id = ?", 7)
+	assert.NoError(t, productSpec().ApplyParams(b, url.Values{"filter[status]": {"active"}}))
+	assert.Equal(t, "SELECT * FROM products WHERE shop_id = $1 AND status = $2", b.String())
+}
+
+func TestFilterSpecRejects(t *testing.T) {
+	tests := []struct {
+		query string
+		err   string
+	}{
+		{"filter[password]=x", `unknown filter "password"`},
+		{"filter[price]=10", `filter price does not allow operator "eq"`},
+		{"filter[status][drop]=x", `unknown filter operator "drop"`},
+		{"filter[status=x", `malformed filter parameter "filter[status"`},
+		{"filter[status]x=1", `malformed filter parameter "filter[status]x"`},
+		{"sort=password", `unknown sort field "password"`},
+		{"sort=name%3BDROP+TABLE+products", `unknown sort field "name;DROP TABLE products"`},
+		{"page=0", `invalid page "0"`},
+		{"page_size=abc", `invalid page_size "abc"`},
+		{"page_size=51", "page_size 51 exceeds the maximum of 50"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.query)
+			assert.NoError(t, err)
+
+			b := New().Select("*").From("products")
+			assert.EqualError(t, productSpec().ApplyParams(b, values), tt.err)
+			assert.Equal(t, "SELECT * FROM products", b.String())
+		})
+	}
+}
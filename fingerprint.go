@@ -0,0 +1,123 @@
+package toki
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+)
+
+// FingerprintOption configures Fingerprint
+type FingerprintOption func(*fingerprintConfig)
+
+type fingerprintConfig struct {
+	collapseLists bool
+}
+
+// CollapseLists makes lists of placeholders, such as IN ($1, $2, $3), and
+// repeated VALUES rows fingerprint the same whatever their length, so the
+// same logical query with different list sizes shares a fingerprint
+func CollapseLists() FingerprintOption {
+	return func(c *fingerprintConfig) {
+		c.collapseLists = true
+	}
+}
+
+var (
+	// placeholderList matches a parenthesized list of normalized
+	// placeholders
+	placeholderList = regexp.MustCompile(`\(\?(?:, \?)*\)`)
+	// repeatedLists matches collapsed lists repeated as VALUES rows
+	repeatedLists = regexp.MustCompile(`\(\?\.\.\.\)(?:, \(\?\.\.\.\))+`)
+)
+
+// Fingerprint returns a stable hash of the statement's shape: its kind,
+// table and rendered SQL with placeholders normalized and comments and
+// extra whitespace removed. Bound values do not change it, inline literals
+// do. Use it to key metrics or caches by query.
+func (b *Builder) Fingerprint(opts ...FingerprintOption) string {
+	return fingerprint(b.kind, b.table, b.String(), opts)
+}
+
+// Fingerprint returns a best-effort fingerprint of the raw query: $N, @pN
+// and ? placeholders are normalized, but equivalent queries written differently
+// fingerprint differently
+func (r *RawQuery) Fingerprint(opts ...FingerprintOption) string {
+	return fingerprint(KindRaw, "", r.sql, opts)
+}
+
+// Fingerprint returns the fingerprint of the query, for middleware keying
+// on it. Statements from a Builder fingerprint as Builder.Fingerprint does:
+// the comments added by WithComment are ignored.
+func (q QueryInfo) Fingerprint(opts ...FingerprintOption) string {
+	return fingerprint(q.Kind, q.Table, q.SQL, opts)
+}
+
+func fingerprint(kind StatementKind, table, query string, opts []FingerprintOption) string {
+	var cfg fingerprintConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	normalized := normalizeQuery(query)
+	if cfg.collapseLists {
+		normalized = placeholderList.ReplaceAllString(normalized, "(?...)")
+		normalized = repeatedLists.ReplaceAllString(normalized, "(?...)")
+	}
+
+	h := sha256.New()
+	h.Write([]byte(kind.String()))
+	h.Write([]byte{0})
+	h.Write([]byte(table))
+	h.Write([]byte{0})
+	h.Write([]byte(normalized))
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// normalizeQuery replaces $N, @pN and ? placeholders with ?, drops comments and
+// collapses whitespace outside literals
+func normalizeQuery(query string) string {
+	var out strings.Builder
+	out.Grow(len(query))
+	space := false
+
+	scanSQL(query, func(from, to int, code bool) {
+		if !code {
+			if run := query[from:to]; strings.HasPrefix(run, "--") || strings.HasPrefix(run, "/*") {
+				space = true
+				return
+			}
+			if space && out.Len() > 0 {
+				out.WriteByte(' ')
+			}
+			space = false
+			out.WriteString(query[from:to])
+			return
+		}
+		for i := from; i < to; i++ {
+			c := query[i]
+			switch {
+			case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+				space = true
+				continue
+			case c == '$' && i+1 < to && query[i+1] >= '0' && query[i+1] <= '9' && (i == 0 || !isIdentByte(query[i-1])):
+				for i+1 < to && query[i+1] >= '0' && query[i+1] <= '9' {
+					i++
+				}
+				c = '?'
+			case c == '@':
+				if end := atPlaceholderEnd(query, i, to); end > 0 {
+					i = end - 1
+					c = '?'
+				}
+			}
+			if space && out.Len() > 0 {
+				out.WriteByte(' ')
+			}
+			space = false
+			out.WriteByte(c)
+		}
+	})
+
+	return out.String()
+}
@@ -0,0 +1,62 @@
+package toki
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFingerprint(t *testing.T) {
+	a := New().Select("*").From("users").Where("id = ?", 1)
+	b := New().Select("*").From("users").Where("id = ?", 2)
+	assert.Equal(t, a.Fingerprint(), b.Fingerprint())
+	assert.Len(t, a.Fingerprint(), 32)
+
+	// shape, table and kind all matter
+	assert.NotEqual(t, a.Fingerprint(), New().Select("*").From("users").Where("email = ?", 1).Fingerprint())
+	assert.NotEqual(t, a.Fingerprint(), New().Select("*").From("accounts").Where("id = ?", 1).Fingerprint())
+	assert.NotEqual(t, a.Fingerprint(), New().Select("*").From("users").Where("id = 1").Fingerprint())
+
+	// whitespace and comments do not
+	raw := New().Raw("SELECT *\n  FROM users -- by id\n WHERE id = $1", 9)
+	assert.Equal(t, New().Raw("SELECT * FROM users /* by id */ WHERE id = ?").Fingerprint(), raw.Fingerprint())
+	assert.NotEqual(t, New().Raw("SELECT * FROM users WHERE name = '  $1  '").Fingerprint(),
+		New().Raw("SELECT * FROM users WHERE name = ' $1 '").Fingerprint())
+}
+
+func TestFingerprintCollapseLists(t *testing.T) {
+	in2 := New().Select("*").From("users").Where("id IN (?, ?)", 1, 2)
+	in3 := New().Select("*").From("users").Where("id IN (?, ?, ?)", 1, 2, 3)
+	assert.NotEqual(t, in2.Fingerprint(), in3.Fingerprint())
+	assert.Equal(t, in2.Fingerprint(CollapseLists()), in3.Fingerprint(CollapseLists()))
+
+	one := New().Insert("tags", "name", "color").Values("a", "red")
+	many := New().Insert("tags", "name", "color").Values("a", "red").Values("b", "blue").Values("c", Raw("DEFAULT"))
+	assert.NotEqual(t, one.Fingerprint(CollapseLists()), many.Fingerprint(CollapseLists()))
+	many = New().Insert("tags", "name", "color").Values("a", "red").Values("b", "blue")
+	assert.Equal(t, one.Fingerprint(CollapseLists()), many.Fingerprint(CollapseLists()))
+}
+
+func TestFingerprintInMiddleware(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	var seen []string
+	mw := func(ctx context.Context, q QueryInfo, next Handler) (Result, error) {
+		seen = append(seen, q.Fingerprint())
+		return next(ctx, q)
+	}
+
+	b := New().Use(mw).WithComment("route", "/users").Delete("users").Where("id = ?", 3)
+	mock.ExpectExec("DELETE FROM users WHERE id = $1 /*route='%2Fusers'*/").WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	stmt, err := b.Prepare(db)
+	assert.NoError(t, err)
+	_, err = stmt.Exec()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{b.Fingerprint()}, seen)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
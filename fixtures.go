@@ -0,0 +1,339 @@
+package toki
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FixtureLabel is the key naming a fixture row. It is not inserted; the
+// row's generated id is returned under the label and Ref(label) resolves to
+// it in later rows.
+const FixtureLabel = "_label"
+
+// FixtureRef is the id of a labeled fixture row, made by Ref
+type FixtureRef string
+
+// Ref is the generated id of the fixture row labeled label. The table of
+// that row is loaded first.
+func Ref(label string) FixtureRef {
+	return FixtureRef(label)
+}
+
+// FixtureOption configures LoadFixtures
+type FixtureOption func(*fixtureConfig)
+
+type fixtureConfig struct {
+	deps     map[string][]string
+	truncate bool
+	idColumn string
+}
+
+// DependsOn loads the fixtures of table after those of deps, for foreign
+// keys not expressed with Ref
+func DependsOn(table string, deps ...string) FixtureOption {
+	return func(c *fixtureConfig) {
+		c.deps[table] = append(c.deps[table], deps...)
+	}
+}
+
+// TruncateFirst empties the fixture tables before loading, in reverse
+// dependency order. PostgreSQL runs TRUNCATE ... RESTART IDENTITY CASCADE,
+// other dialects DELETE FROM each table.
+func TruncateFirst() FixtureOption {
+	return func(c *fixtureConfig) {
+		c.truncate = true
+	}
+}
+
+// IDColumn sets the generated key column of labeled rows, id by default
+func IDColumn(column string) FixtureOption {
+	return func(c *fixtureConfig) {
+		c.idColumn = column
+	}
+}
+
+// LoadFixtures inserts fixtures, rows keyed by table, inside one
+// transaction: a savepoint of the builder's transaction when it has one,
+// otherwise a new transaction on db. Tables are loaded in dependency order.
+// Labeled rows are inserted one by one to read their generated ids, which
+// are returned keyed by label; the other rows of a table are inserted with
+// multi-row INSERTs.
+//
+//	ids, err := toki.New().LoadFixtures(ctx, db, map[string][]map[string]interface{}{
+//		"teams": {{toki.FixtureLabel: "core", "name": "Core"}},
+//		"users": {{"name": "ann", "team_id": toki.Ref("core")}},
+//	}, toki.TruncateFirst())
+func (b *Builder) LoadFixtures(ctx context.Context, db *sql.DB, fixtures map[string][]map[string]interface{}, opts ...FixtureOption) (map[string]int64, error) {
+	cfg := fixtureConfig{deps: make(map[string][]string), idColumn: "id"}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	order, err := fixtureOrder(fixtures, cfg.deps)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]int64)
+	load := func(tx *Transaction) error {
+		l := fixtureLoader{b: b, tx: tx, ctx: tx.context(), ids: ids, idColumn: cfg.idColumn}
+		if cfg.truncate {
+			if err := l.truncate(order); err != nil {
+				return err
+			}
+		}
+		for _, table := range order {
+			if err := l.load(table, fixtures[table]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	switch {
+	case b.tx != nil:
+		err = b.tx.RunInTx(load)
+	case db != nil:
+		err = RunInTx(b.context(), db, nil, load)
+	default:
+		return nil, errors.New("LoadFixtures needs a database or transaction")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// fixtureOrder sorts the fixture tables so each comes after the tables it
+// depends on, by declaration or by Ref. Ties are broken by name.
+func fixtureOrder(fixtures map[string][]map[string]interface{}, declared map[string][]string) ([]string, error) {
+	tables := make([]string, 0, len(fixtures))
+	for table := range fixtures {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+
+	labels := make(map[string]string)
+	for _, table := range tables {
+		for i, row := range fixtures[table] {
+			label, ok := row[FixtureLabel]
+			if !ok {
+				continue
+			}
+			name, ok := label.(string)
+			if !ok || name == "" {
+				return nil, fmt.Errorf("%s row %d: %s must be a non-empty string, got %v", table, i, FixtureLabel, label)
+			}
+			if other, dup := labels[name]; dup {
+				return nil, fmt.Errorf("fixture label %q is used in %s and %s", name, other, table)
+			}
+			labels[name] = table
+		}
+	}
+
+	deps := make(map[string]map[string]bool, len(fixtures))
+	for table, rows := range fixtures {
+		deps[table] = make(map[string]bool)
+		for _, dep := range declared[table] {
+			if _, ok := fixtures[dep]; ok && dep != table {
+				deps[table][dep] = true
+			}
+		}
+		for i, row := range rows {
+			for col, v := range row {
+				ref, ok := v.(FixtureRef)
+				if !ok {
+					continue
+				}
+				dep, ok := labels[string(ref)]
+				if !ok {
+					return nil, fmt.Errorf("%s row %d: column %s references unknown fixture label %q", table, i, col, ref)
+				}
+				if dep != table {
+					deps[table][dep] = true
+				}
+			}
+		}
+	}
+
+	order := make([]string, 0, len(fixtures))
+	done := make(map[string]bool, len(fixtures))
+	for len(order) < len(fixtures) {
+		var ready []string
+		for table, needs := range deps {
+			if done[table] {
+				continue
+			}
+			blocked := false
+			for dep := range needs {
+				if !done[dep] {
+					blocked = true
+					break
+				}
+			}
+			if !blocked {
+				ready = append(ready, table)
+			}
+		}
+		if len(ready) == 0 {
+			var left []string
+			for table := range deps {
+				if !done[table] {
+					left = append(left, table)
+				}
+			}
+			sort.Strings(left)
+			return nil, fmt.Errorf("fixture tables have a dependency cycle: %s", strings.Join(left, ", "))
+		}
+		sort.Strings(ready)
+		for _, table := range ready {
+			done[table] = true
+		}
+		order = append(order, ready...)
+	}
+	return order, nil
+}
+
+// fixtureLoader inserts fixture rows on a transaction
+type fixtureLoader struct {
+	b        *Builder
+	tx       *Transaction
+	ctx      context.Context
+	ids      map[string]int64
+	idColumn string
+}
+
+// builder returns a builder for one statement on the transaction
+func (l fixtureLoader) builder() *Builder {
+	return l.b.derive().WithTransaction(l.tx)
+}
+
+func (l fixtureLoader) truncate(order []string) error {
+	if len(order) == 0 {
+		return nil
+	}
+	reversed := make([]string, len(order))
+	for i, table := range order {
+		reversed[len(order)-1-i] = table
+	}
+
+	var statements []string
+	if l.b.Dialect().dropCascade {
+		statements = []string{"TRUNCATE TABLE " + strings.Join(reversed, ", ") + " RESTART IDENTITY CASCADE"}
+	} else {
+		for _, table := range reversed {
+			statements = append(statements, "DELETE FROM "+table)
+		}
+	}
+
+	for _, query := range statements {
+		if _, err := l.b.hooks.exec(l.ctx, l.tx.tx, QueryInfo{SQL: query, Kind: KindDelete}); err != nil {
+			return fmt.Errorf("failed to truncate fixture tables: %w", err)
+		}
+	}
+	return nil
+}
+
+// load inserts the rows of table, labeled rows one by one and runs of
+// unlabeled rows with the same columns in one statement
+func (l fixtureLoader) load(table string, rows []map[string]interface{}) error {
+	var batch *Builder
+	var batchColumns []string
+
+	flush := func() error {
+		if batch == nil {
+			return nil
+		}
+		_, err := l.b.hooks.exec(l.ctx, l.tx.tx, QueryInfo{SQL: batch.String(), Args: batch.Args(), Kind: KindInsert, Table: table})
+		batch = nil
+		if err != nil {
+			return fmt.Errorf("failed to load %s fixtures: %w", table, err)
+		}
+		return nil
+	}
+
+	for i, row := range rows {
+		values, columns, err := l.resolve(table, i, row)
+		if err != nil {
+			return err
+		}
+
+		if label, ok := row[FixtureLabel]; ok {
+			if err := flush(); err != nil {
+				return err
+			}
+			id, err := l.insertReturningID(table, columns, values)
+			if err != nil {
+				return fmt.Errorf("failed to load %s fixture %q: %w", table, label, err)
+			}
+			l.ids[label.(string)] = id
+			continue
+		}
+
+		if batch != nil && strings.Join(columns, ",") != strings.Join(batchColumns, ",") {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		if batch == nil {
+			batch = l.builder().Insert(table, columns...)
+			batchColumns = columns
+		}
+		batch.Values(values...)
+	}
+	return flush()
+}
+
+// resolve returns the columns of row in sorted order and their values, with
+// references replaced by ids
+func (l fixtureLoader) resolve(table string, i int, row map[string]interface{}) ([]interface{}, []string, error) {
+	columns := make([]string, 0, len(row))
+	for col := range row {
+		if col != FixtureLabel {
+			columns = append(columns, col)
+		}
+	}
+	sort.Strings(columns)
+	if len(columns) == 0 {
+		return nil, nil, fmt.Errorf("%s row %d has no columns", table, i)
+	}
+
+	values := make([]interface{}, len(columns))
+	for j, col := range columns {
+		v := row[col]
+		if ref, ok := v.(FixtureRef); ok {
+			id, ok := l.ids[string(ref)]
+			if !ok {
+				return nil, nil, fmt.Errorf("%s row %d: column %s references fixture %q, which is not loaded", table, i, col, ref)
+			}
+			v = id
+		}
+		values[j] = v
+	}
+	return values, columns, nil
+}
+
+// insertReturningID inserts one row and returns its generated id
+func (l fixtureLoader) insertReturningID(table string, columns []string, values []interface{}) (int64, error) {
+	insert := l.builder().Insert(table, columns...).Values(values...)
+
+	var id int64
+	if !l.b.Dialect().returning {
+		res, err := l.b.hooks.exec(l.ctx, l.tx.tx, QueryInfo{SQL: insert.String(), Args: insert.Args(), Kind: KindInsert, Table: table})
+		if err != nil {
+			return 0, err
+		}
+		return res.LastInsertId()
+	}
+
+	insert.Returning(l.idColumn)
+	row := l.b.hooks.queryRow(l.ctx, l.tx.tx, QueryInfo{SQL: insert.String(), Args: insert.Args(), Kind: KindInsert, Table: table})
+	if err := row.Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
@@ -0,0 +1,104 @@
+package toki
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadFixtures(t *testing.T) {
+	db, mock := newMockDB(t)
+
+	fixtures := map[string][]map[string]interface{}{
+		"users": {
+			{FixtureLabel: "ann", "name": "ann", "team_id": Ref("core")},
+			{"name": "bob", "team_id": Ref("core")},
+			{"name": "cid", "team_id": Ref("ops")},
+		},
+		"teams": {
+			{FixtureLabel: "core", "name": "Core"},
+			{FixtureLabel: "ops", "name": "Ops"},
+		},
+		"audit": {
+			{"action": "seed"},
+		},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("TRUNCATE TABLE users, teams, audit RESTART IDENTITY CASCADE").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO audit (action) VALUES ($1)").WithArgs("seed").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("INSERT INTO teams (name) VALUES ($1) RETURNING id").WithArgs("Core").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
+	mock.ExpectQuery("INSERT INTO teams (name) VALUES ($1) RETURNING id").WithArgs("Ops").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(11))
+	mock.ExpectQuery("INSERT INTO users (name, team_id) VALUES ($1, $2) RETURNING id").WithArgs("ann", int64(10)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec("INSERT INTO users (name, team_id) VALUES ($1, $2), ($3, $4)").WithArgs("bob", int64(10), "cid", int64(11)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	ids, err := New().LoadFixtures(context.Background(), db, fixtures, TruncateFirst())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int64{"core": 10, "ops": 11, "ann": 1}, ids)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLoadFixturesDependsOn(t *testing.T) {
+	db, mock := newMockDB(t)
+
+	fixtures := map[string][]map[string]interface{}{
+		"a_orders":   {{"customer": "x"}},
+		"b_customer": {{"name": "x"}},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM a_orders").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM b_customer").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO b_customer (name) VALUES (?)").WithArgs("x").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO a_orders (customer) VALUES (?)").WithArgs("x").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	_, err := New().WithDialect(MySQL).LoadFixtures(context.Background(), db, fixtures,
+		DependsOn("a_orders", "b_customer"), TruncateFirst())
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLoadFixturesRollsBack(t *testing.T) {
+	db, mock := newMockDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO tags (name) VALUES ($1), ($2)").WithArgs("a", "b").WillReturnError(assert.AnError)
+	mock.ExpectRollback()
+
+	_, err := New().LoadFixtures(context.Background(), db, map[string][]map[string]interface{}{
+		"tags": {{"name": "a"}, {"name": "b"}},
+	})
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.EqualError(t, err, "failed to load tags fixtures: "+assert.AnError.Error())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLoadFixturesInvalid(t *testing.T) {
+	_, err := New().LoadFixtures(context.Background(), nil, map[string][]map[string]interface{}{
+		"users": {{"team_id": Ref("nope")}},
+	})
+	assert.EqualError(t, err, `users row 0: column team_id references unknown fixture label "nope"`)
+
+	_, err = New().LoadFixtures(context.Background(), nil, map[string][]map[string]interface{}{
+		"a": {{"x": 1}},
+		"b": {{"y": 1}},
+	}, DependsOn("a", "b"), DependsOn("b", "a"))
+	assert.EqualError(t, err, "fixture tables have a dependency cycle: a, b")
+
+	_, err = New().LoadFixtures(context.Background(), nil, map[string][]map[string]interface{}{
+		"a": {{FixtureLabel: "x", "n": 1}},
+		"b": {{FixtureLabel: "x", "n": 2}},
+	})
+	assert.EqualError(t, err, `fixture label "x" is used in a and b`)
+
+	_, err = New().LoadFixtures(context.Background(), nil, map[string][]map[string]interface{}{"a": {{"n": 1}}})
+	assert.EqualError(t, err, "LoadFixtures needs a database or transaction")
+}
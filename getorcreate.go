@@ -0,0 +1,116 @@
+package toki
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// GetOrCreate loads the row of table matching lookup into dest, a pointer to
+// a struct, inserting it with lookup and defaults first when it does not
+// exist. created reports whether the row was inserted. The insert is an
+// INSERT ... ON CONFLICT (lookup columns) DO NOTHING RETURNING *, so the
+// lookup columns need a unique constraint; concurrent callers then agree on
+// one row instead of racing between a check and an insert. Both statements
+// run on the builder's transaction when it has one, otherwise on db.
+func (b *Builder) GetOrCreate(ctx context.Context, db *sql.DB, table string, lookup, defaults map[string]interface{}, dest interface{}) (created bool, err error) {
+	val := reflect.ValueOf(dest)
+	if val.Kind() != reflect.Ptr || val.IsNil() || val.Elem().Kind() != reflect.Struct {
+		return false, fmt.Errorf("GetOrCreate destination must be a non-nil pointer to a struct, got %T", dest)
+	}
+	if len(lookup) == 0 {
+		return false, errors.New("GetOrCreate needs at least one lookup column")
+	}
+
+	keys := sortedKeys(lookup)
+	for _, col := range keys {
+		if lookup[col] == nil {
+			return false, fmt.Errorf("lookup column %s is nil; NULL never matches an existing row", col)
+		}
+	}
+	if b.tx != nil && b.tx.readOnly {
+		return false, fmt.Errorf("cannot run %s statement: %w", KindInsert, ErrReadOnlyTransaction)
+	}
+
+	var tx *sql.Tx
+	if b.tx != nil {
+		tx = b.tx.tx
+	}
+	c := connFor(db, tx)
+	if c == nil {
+		return false, errors.New("GetOrCreate needs a database or transaction")
+	}
+
+	// lookup values win over defaults for the same column
+	row := make(map[string]interface{}, len(lookup)+len(defaults))
+	for col, v := range defaults {
+		row[col] = v
+	}
+	for col, v := range lookup {
+		row[col] = v
+	}
+	insert := b.derive().InsertMap(table, row).onConflict(keys, nil).Returning("*")
+	found, err := insert.scanOne(ctx, c, dest)
+	if err != nil || found {
+		return found, err
+	}
+
+	// the row existed already, or was inserted by a concurrent caller whose
+	// transaction committed while the insert waited on it
+	sel := b.derive().Select("*").From(table)
+	for _, col := range keys {
+		sel.Where(col+" = ?", lookup[col])
+	}
+	found, err = sel.scanOne(ctx, c, dest)
+	if err != nil {
+		return false, err
+	}
+	if !found {
+		return false, sql.ErrNoRows
+	}
+	return false, nil
+}
+
+// derive returns an empty builder with b's transaction, context, dialect,
+// hooks and time settings, for helpers that run statements of their own
+func (b *Builder) derive() *Builder {
+	d := New()
+	d.tx = b.tx
+	d.ctx = b.ctx
+	d.dialect = b.dialect
+	d.hooks = b.hooks
+	d.timeOptions = b.timeOptions
+	d.timestamps = b.timestamps
+	return d
+}
+
+// scanOne runs the query on c and scans the first row into dest, reporting
+// whether there was one
+func (b *Builder) scanOne(ctx context.Context, c conn, dest interface{}) (bool, error) {
+	rows, err := b.hooks.query(ctx, c, QueryInfo{SQL: b.String(), Args: b.Args(), Kind: b.kind, Table: b.table})
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return false, rows.Err()
+	}
+	if err := ScanStruct(rows, dest); err != nil {
+		return false, err
+	}
+	return true, rows.Close()
+}
+
+// sortedKeys returns the keys of m in sorted order
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,99 @@
+package toki
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+type tag struct {
+	ID    int64  `db:"id"`
+	Name  string `db:"name"`
+	Color string `db:"color"`
+}
+
+const (
+	tagInsert = "INSERT INTO tags (color, name) VALUES ($1, $2) ON CONFLICT (name) DO NOTHING RETURNING *"
+	tagSelect = "SELECT * FROM tags WHERE name = $1"
+)
+
+func TestGetOrCreate(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	lookup := map[string]interface{}{"name": "go"}
+	defaults := map[string]interface{}{"color": "blue", "name": "ignored"}
+	ctx := context.Background()
+
+	mock.ExpectQuery(tagInsert).
+		WithArgs("blue", "go").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "color"}).AddRow(1, "go", "blue"))
+
+	var created tag
+	ok, err := New().GetOrCreate(ctx, db, "tags", lookup, defaults, &created)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, tag{ID: 1, Name: "go", Color: "blue"}, created)
+
+	mock.ExpectQuery(tagInsert).
+		WithArgs("blue", "go").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "color"}))
+	mock.ExpectQuery(tagSelect).
+		WithArgs("go").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "color"}).AddRow(1, "go", "green"))
+
+	var existing tag
+	ok, err = New().GetOrCreate(ctx, db, "tags", lookup, defaults, &existing)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, tag{ID: 1, Name: "go", Color: "green"}, existing)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetOrCreateInTransaction(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO tags (name) VALUES ($1) ON CONFLICT (name) DO NOTHING RETURNING *").
+		WithArgs("go").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "color"}))
+	mock.ExpectQuery(tagSelect).
+		WithArgs("go").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "color"}))
+	mock.ExpectRollback()
+
+	tx, err := Begin(db)
+	assert.NoError(t, err)
+	var got tag
+	ok, err := New().WithTransaction(tx).GetOrCreate(context.Background(), nil, "tags", map[string]interface{}{"name": "go"}, nil, &got)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	assert.False(t, ok)
+	assert.NoError(t, tx.Rollback())
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetOrCreateErrors(t *testing.T) {
+	ctx := context.Background()
+	var got tag
+
+	_, err := New().GetOrCreate(ctx, nil, "tags", map[string]interface{}{"name": "go"}, nil, got)
+	assert.EqualError(t, err, "GetOrCreate destination must be a non-nil pointer to a struct, got toki.tag")
+	_, err = New().GetOrCreate(ctx, nil, "tags", nil, nil, &got)
+	assert.EqualError(t, err, "GetOrCreate needs at least one lookup column")
+	_, err = New().GetOrCreate(ctx, nil, "tags", map[string]interface{}{"name": nil}, nil, &got)
+	assert.EqualError(t, err, "lookup column name is nil; NULL never matches an existing row")
+	_, err = New().GetOrCreate(ctx, nil, "tags", map[string]interface{}{"name": "go"}, nil, &got)
+	assert.EqualError(t, err, "GetOrCreate needs a database or transaction")
+}
@@ -0,0 +1,261 @@
+package toki
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRenderGolden pins the exact SQL rendered for common statements
+func TestRenderGolden(t *testing.T) {
+	tests := []struct {
+		name    string
+		builder *Builder
+		want    string
+		args    []interface{}
+	}{
+		{
+			name: "select",
+			builder: New().
+				Select("u.id", "u.name").
+				From("users u").
+				Where("u.status = ?", "active").
+				AndWhere("u.age > ?", 18).
+				OrWhere("u.role = ?", "admin").
+				OrderBy("u.created_at DESC", "u.id"),
+			want: "SELECT u.id, u.name FROM users u WHERE u.status = $1 AND u.age > $2 OR u.role = $3 ORDER BY u.created_at DESC, u.id",
+			args: []interface{}{"active", 18, "admin"},
+		},
+		{
+			name:    "select star",
+			builder: New().Select("*").From("users"),
+			want:    "SELECT * FROM users",
+		},
+		{
+			name: "update",
+			builder: New().
+				Update("users").
+				Set(map[string]interface{}{"name": "zakirkun", "visits": Raw("visits + 1"), "age": 30}).
+				Where("id = ?", 7),
+			want: "UPDATE users SET age = $1, name = $2, visits = visits + 1 WHERE id = $3",
+			args: []interface{}{30, "zakirkun", 7},
+		},
+		{
+			name: "insert",
+			builder: New().
+				Insert("users", "name", "email", "created_at").
+				Values("zakirkun", "zakir@example.com", Raw("NOW()")).
+				Returning("id"),
+			want: "INSERT INTO users (name, email, created_at) VALUES ($1, $2, NOW()) RETURNING id",
+			args: []interface{}{"zakirkun", "zakir@example.com"},
+		},
+		{
+			name:    "delete",
+			builder: New().Delete("sessions").Where("expires_at < ?", TestTime).Returning("id", "user_id"),
+			want:    "DELETE FROM sessions WHERE expires_at < $1 RETURNING id, user_id",
+			args:    []interface{}{TestTime},
+		},
+		{
+			name: "archive queue",
+			builder: New().
+				With("moved", New().Delete("queue").Where("status = ?", "done").Returning("*")).
+				Insert("archive").
+				Select("*").
+				From("moved").
+				Where("attempts < ?", 3),
+			want: "WITH moved AS (DELETE FROM queue WHERE status = $1 RETURNING *) INSERT INTO archive SELECT * FROM moved WHERE attempts < $2",
+			args: []interface{}{"done", 3},
+		},
+		{
+			name:    "many placeholders",
+			builder: New().Insert("t", "a").Values(1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11),
+			want:    "INSERT INTO t (a) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)",
+			args:    []interface{}{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.builder.String())
+			assert.Equal(t, tt.args, tt.builder.Args())
+		})
+	}
+}
+
+// TestClauseOrder checks that clauses render in SQL order whatever order
+// they are added in
+func TestClauseOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		builder *Builder
+		want    string
+		args    []interface{}
+	}{
+		{
+			name:    "order by before where",
+			builder: New().OrderBy("id").Select("*").Where("a = ?", 1).From("t").Limit(5),
+			want:    "SELECT * FROM t WHERE a = $1 ORDER BY id LIMIT $2",
+			args:    []interface{}{1, 5},
+		},
+		{
+			name:    "where before set",
+			builder: New().Update("t").Where("id = ?", 1).Set(map[string]interface{}{"a": 2}),
+			want:    "UPDATE t SET a = $1 WHERE id = $2",
+			args:    []interface{}{2, 1},
+		},
+		{
+			name:    "repeated clauses",
+			builder: New().Select("a").From("t").Where("x = ?", 1).GroupBy("a").Where("y = ?", 2).GroupBy("b").OrderBy("a").OrderBy("b DESC"),
+			want:    "SELECT a FROM t WHERE x = $1 AND y = $2 GROUP BY a, b ORDER BY a, b DESC",
+			args:    []interface{}{1, 2},
+		},
+		{
+			name: "having before where",
+			builder: New().Select("team_id", "COUNT(*)").From("users").
+				Having("COUNT(*) > ?", 5).
+				GroupBy("team_id").
+				Where("active = ?", true).
+				Having("MAX(age) < ?", 60),
+			want: "SELECT team_id, COUNT(*) FROM users WHERE active = $1 GROUP BY team_id HAVING COUNT(*) > $2 AND MAX(age) < $3",
+			args: []interface{}{true, 5, 60},
+		},
+		{
+			name:    "join after where",
+			builder: New().Select("*").From("users u").Where("p.public").Join("posts p", "p.user_id = u.id"),
+			want:    "SELECT * FROM users u JOIN posts p ON p.user_id = u.id WHERE p.public",
+		},
+		{
+			name:    "with last",
+			builder: New().Select("*").From("recent").With("recent", New().Select("*").From("posts").OrderBy("id DESC").Limit(10)),
+			want:    "WITH recent AS (SELECT * FROM posts ORDER BY id DESC LIMIT $1) SELECT * FROM recent",
+			args:    []interface{}{10},
+		},
+		{
+			name:    "version before set",
+			builder: New().Update("accounts").WhereVersion(3).Set(map[string]interface{}{"balance": 5}),
+			want:    "UPDATE accounts SET balance = $1, version = version + 1 WHERE version = $2",
+			args:    []interface{}{5, int64(3)},
+		},
+		{
+			name: "optimizer hint before distinct",
+			builder: New().WithDialect(MySQL).
+				Select("id", "name").
+				Distinct().
+				OptimizerHint("MAX_EXECUTION_TIME(1000)").
+				From("users").
+				ForceIndex("idx_status").
+				Where("status = ?", "active"),
+			want: "SELECT /*+ MAX_EXECUTION_TIME(1000) */ DISTINCT id, name FROM users FORCE INDEX (idx_status) WHERE status = ?",
+			args: []interface{}{"active"},
+		},
+		{
+			name: "optimizer hints before select",
+			builder: New().WithDialect(MySQL).
+				OptimizerHint("MAX_EXECUTION_TIME(1000)").
+				OptimizerHint(" BKA(u) ").
+				Select("u.id").
+				From("users u").
+				UseIndex("PRIMARY").
+				IgnoreIndex("idx_a", "idx_b").
+				Join("posts p", "p.user_id = u.id AND p.status = ?", "published").
+				Limit(20),
+			want: "SELECT /*+ MAX_EXECUTION_TIME(1000) BKA(u) */ u.id FROM users u USE INDEX (PRIMARY) IGNORE INDEX (idx_a, idx_b) JOIN posts p ON p.user_id = u.id AND p.status = ? LIMIT ?",
+			args: []interface{}{"published", 20},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.builder.String())
+			assert.Equal(t, tt.args, tt.builder.Args())
+		})
+	}
+}
+
+// TestDialectGolden pins the placeholders each dialect renders: numbered in
+// SQL order, with the args reordered to match
+func TestDialectGolden(t *testing.T) {
+	rows := []map[string]interface{}{{"id": 1, "name": "a"}, {"id": 2, "name": "b"}}
+	update := func(d *Dialect) *Builder {
+		b, err := New().WithDialect(d).UpdateBatch("products", "id", rows)
+		assert.NoError(t, err)
+		return b.AndWhere("shop_id = ?", 7)
+	}
+	tuples := func(d *Dialect) *Builder {
+		b, err := New().WithDialect(d).Select("*").From("memberships").
+			WhereTupleIn([]string{"user_id", "team_id"}, [][]interface{}{{1, 2}, {3, 4}})
+		assert.NoError(t, err)
+		return b
+	}
+
+	tests := []struct {
+		name    string
+		builder *Builder
+		want    string
+		args    []interface{}
+	}{
+		{
+			name:    "postgres",
+			builder: New().Update("t").Where("id = ?", 1).Set(map[string]interface{}{"a": 2}).WhereDistinctFrom("b", 3),
+			want:    "UPDATE t SET a = $1 WHERE id = $2 AND b IS DISTINCT FROM $3",
+			args:    []interface{}{2, 1, 3},
+		},
+		{
+			name:    "sqlite",
+			builder: New().WithDialect(SQLite).Update("t").Where("id = ?", 1).Set(map[string]interface{}{"a": 2}).WhereDistinctFrom("b", 3),
+			want:    "UPDATE t SET a = $1 WHERE id = $2 AND NOT (b IS $3)",
+			args:    []interface{}{2, 1, 3},
+		},
+		{
+			name:    "mysql",
+			builder: New().WithDialect(MySQL).Update("t").Where("id = ?", 1).Set(map[string]interface{}{"a": 2}).WhereDistinctFrom("b", 3),
+			want:    "UPDATE t SET a = ? WHERE id = ? AND NOT (b <=> ?)",
+			args:    []interface{}{2, 1, 3},
+		},
+		{
+			name: "mysql select",
+			builder: New().WithDialect(MySQL).Select("id").From("users").
+				Limit(10).Offset(20).
+				With("active", New().Select("id").From("users").Where("status = ?", "active")).
+				Where("id IN (SELECT id FROM active)").
+				Where("age > ?", 18),
+			want: "WITH active AS (SELECT id FROM users WHERE status = ?) SELECT id FROM users WHERE id IN (SELECT id FROM active) AND age > ? LIMIT ? OFFSET ?",
+			args: []interface{}{"active", 18, 10, 20},
+		},
+		{
+			name:    "mysql tuples",
+			builder: tuples(MySQL),
+			want:    "SELECT * FROM memberships WHERE (user_id, team_id) IN ((?, ?), (?, ?))",
+			args:    []interface{}{1, 2, 3, 4},
+		},
+		{
+			name:    "mysql update batch",
+			builder: update(MySQL),
+			want:    "UPDATE products SET name = CASE id WHEN ? THEN ? WHEN ? THEN ? END WHERE id IN (?, ?) AND shop_id = ?",
+			args:    []interface{}{1, "a", 2, "b", 1, 2, 7},
+		},
+		{
+			name: "sqlserver",
+			builder: New().WithDialect(SQLServer).Select("id").From("users").
+				OrderBy("id").Offset(20).Limit(10).
+				Where("status = ?", "active"),
+			want: "SELECT id FROM users WHERE status = @p1 ORDER BY id OFFSET @p2 ROWS FETCH NEXT @p3 ROWS ONLY",
+			args: []interface{}{"active", 20, 10},
+		},
+		{
+			name:    "sqlserver update batch",
+			builder: update(SQLServer),
+			want:    "UPDATE products SET name = CASE id WHEN @p1 THEN @p2 WHEN @p3 THEN @p4 END WHERE id IN (@p5, @p6) AND shop_id = @p7",
+			args:    []interface{}{1, "a", 2, "b", 1, 2, 7},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args, err := tt.builder.ToSQL()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, query)
+			assert.Equal(t, tt.args, args)
+		})
+	}
+}
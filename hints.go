@@ -0,0 +1,87 @@
+package toki
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// UseIndex adds USE INDEX (index, ...) to the last FROM table, so MySQL
+// only considers those indexes. With no index it considers none. Index
+// hints follow the table and its alias, so call it after From. It is
+// MySQL only and fails the builder with another dialect.
+func (b *Builder) UseIndex(index ...string) *Builder {
+	return b.indexHint("USE INDEX", index)
+}
+
+// ForceIndex adds FORCE INDEX (index, ...) to the last FROM table, so MySQL
+// scans the table only when none of the indexes can be used. Like UseIndex
+// it is MySQL only.
+func (b *Builder) ForceIndex(index ...string) *Builder {
+	if len(index) == 0 {
+		b.fail(errors.New("ForceIndex needs an index"))
+		return b
+	}
+	return b.indexHint("FORCE INDEX", index)
+}
+
+// IgnoreIndex adds IGNORE INDEX (index, ...) to the last FROM table, so
+// MySQL does not consider the indexes. Like UseIndex it is MySQL only.
+func (b *Builder) IgnoreIndex(index ...string) *Builder {
+	if len(index) == 0 {
+		b.fail(errors.New("IgnoreIndex needs an index"))
+		return b
+	}
+	return b.indexHint("IGNORE INDEX", index)
+}
+
+func (b *Builder) indexHint(hint string, index []string) *Builder {
+	if !b.Dialect().hints {
+		b.fail(fmt.Errorf("index hints are not supported by the %s dialect", b.Dialect()))
+		return b
+	}
+	from := b.clauses.from
+	if len(from) == 0 {
+		b.fail(fmt.Errorf("%s needs a FROM table before it", hint))
+		return b
+	}
+	for _, name := range index {
+		if !isPlainIdent(name) {
+			b.fail(fmt.Errorf("invalid index name %q", name))
+			return b
+		}
+	}
+
+	b.changed()
+	// copied, so builders sharing the slice are not changed
+	from = append([]string(nil), from...)
+	from[len(from)-1] += " " + hint + " (" + strings.Join(index, ", ") + ")"
+	b.clauses.from = from
+	return b
+}
+
+// OptimizerHint adds a MySQL optimizer hint such as
+// MAX_EXECUTION_TIME(1000), rendered in the /*+ ... */ comment right after
+// SELECT and before DISTINCT. Several hints share one comment. It is MySQL
+// only, fails with a statement other than SELECT, and rejects hints that
+// contain a comment marker.
+func (b *Builder) OptimizerHint(hint string) *Builder {
+	if !b.Dialect().hints {
+		b.fail(fmt.Errorf("optimizer hints are not supported by the %s dialect", b.Dialect()))
+		return b
+	}
+	if b.kind != KindUnknown && b.kind != KindSelect {
+		b.fail(fmt.Errorf("OptimizerHint needs a SELECT statement, got %s", b.kind))
+		return b
+	}
+	hint = strings.TrimSpace(hint)
+	if hint == "" || strings.Contains(hint, "*/") || strings.Contains(hint, "/*") {
+		b.fail(fmt.Errorf("invalid optimizer hint %q", hint))
+		return b
+	}
+
+	b.changed()
+	hints := b.clauses.hints
+	b.clauses.hints = append(hints[:len(hints):len(hints)], hint)
+	return b
+}
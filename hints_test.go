@@ -0,0 +1,68 @@
+package toki
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHints(t *testing.T) {
+	t.Run("Second table", func(t *testing.T) {
+		b := New().WithDialect(MySQL).Select("*").From("users").AddFrom("teams").UseIndex()
+		assert.Equal(t, "SELECT * FROM users, teams USE INDEX ()", b.String())
+	})
+
+	t.Run("Marshaled", func(t *testing.T) {
+		b := New().WithDialect(MySQL).OptimizerHint("NO_ICP(users)").Select("id").From("users").UseIndex("idx_a")
+		restored := roundTrip(t, b)
+		assert.Equal(t, "SELECT /*+ NO_ICP(users) */ id FROM users USE INDEX (idx_a)", restored.String())
+	})
+
+	tests := []struct {
+		name    string
+		builder *Builder
+		err     string
+	}{
+		{
+			name:    "Other dialect",
+			builder: New().Select("*").From("users").UseIndex("idx_a"),
+			err:     "index hints are not supported by the postgres dialect",
+		},
+		{
+			name:    "Optimizer hint with other dialect",
+			builder: New().WithDialect(SQLite).OptimizerHint("MAX_EXECUTION_TIME(1000)").Select("*").From("users"),
+			err:     "optimizer hints are not supported by the sqlite dialect",
+		},
+		{
+			name:    "Before From",
+			builder: New().WithDialect(MySQL).Select("*").ForceIndex("idx_a").From("users"),
+			err:     "FORCE INDEX needs a FROM table before it",
+		},
+		{
+			name:    "No index",
+			builder: New().WithDialect(MySQL).Select("*").From("users").IgnoreIndex(),
+			err:     "IgnoreIndex needs an index",
+		},
+		{
+			name:    "Invalid index",
+			builder: New().WithDialect(MySQL).Select("*").From("users").UseIndex("idx) /*"),
+			err:     `invalid index name "idx) /*"`,
+		},
+		{
+			name:    "Comment terminator",
+			builder: New().WithDialect(MySQL).Select("*").From("users").OptimizerHint("BKA(u) */ DROP TABLE users; /*"),
+			err:     `invalid optimizer hint "BKA(u) */ DROP TABLE users; /*"`,
+		},
+		{
+			name:    "Not a select",
+			builder: New().WithDialect(MySQL).Delete("users").OptimizerHint("MAX_EXECUTION_TIME(1000)"),
+			err:     "OptimizerHint needs a SELECT statement, got DELETE",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := tt.builder.ToSQL()
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package toki
+
+import "strings"
+
+// IdentifierCase normalizes a plain identifier, such as a table or column
+// name, given to a builder
+type IdentifierCase func(name string) string
+
+var (
+	// PreserveCase leaves identifiers as written, the default
+	PreserveCase IdentifierCase = func(name string) string { return name }
+
+	// LowerCase folds identifiers to lower case, as PostgreSQL does with
+	// unquoted names
+	LowerCase IdentifierCase = strings.ToLower
+)
+
+// WithIdentifierCase returns a copy of the dialect that normalizes
+// identifiers with c, for builders without a case of their own:
+//
+//	toki.SetDialect(toki.Postgres.WithIdentifierCase(toki.LowerCase))
+func (d *Dialect) WithIdentifierCase(c IdentifierCase) *Dialect {
+	copied := *d
+	copied.identCase = c
+	return &copied
+}
+
+// WithIdentifierCase normalizes the identifiers given to this builder with
+// c, overriding the dialect's: the tables of From, AddFrom, Insert, Update
+// and Delete, and the columns of Select, Insert, Set, OrderBy and GroupBy.
+// Each part of a qualified name such as u.UserName is normalized on its
+// own, and a trailing ASC or DESC in OrderBy is kept. Anything else, such
+// as a condition, an expression, an alias, a quoted name or *, is passed
+// through as it is.
+func (b *Builder) WithIdentifierCase(c IdentifierCase) *Builder {
+	b.identCase = c
+	return b
+}
+
+// identifierCase returns the case the builder normalizes identifiers with,
+// or nil to keep them
+func (b *Builder) identifierCase() IdentifierCase {
+	if b.identCase != nil {
+		return b.identCase
+	}
+	return b.Dialect().identCase
+}
+
+// ident normalizes name when it is a plain, optionally qualified identifier
+func (b *Builder) ident(name string) string {
+	c := b.identifierCase()
+	if c == nil || !isPlainIdent(name) {
+		return name
+	}
+	if strings.IndexByte(name, '.') < 0 {
+		return c(name)
+	}
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = c(part)
+	}
+	return strings.Join(parts, ".")
+}
+
+// idents normalizes each of names, returning names itself when nothing
+// changes
+func (b *Builder) idents(names []string) []string {
+	if b.identifierCase() == nil {
+		return names
+	}
+	out := make([]string, len(names))
+	for i, name := range names {
+		out[i] = b.ident(name)
+	}
+	return out
+}
+
+// orderIdents normalizes ORDER BY items, keeping a trailing ASC or DESC
+func (b *Builder) orderIdents(items []string) []string {
+	if b.identifierCase() == nil {
+		return items
+	}
+	out := make([]string, len(items))
+	for i, item := range items {
+		out[i] = b.ident(item)
+		if sp := strings.LastIndexByte(item, ' '); sp > 0 {
+			switch strings.ToUpper(item[sp+1:]) {
+			case "ASC", "DESC":
+				out[i] = b.ident(item[:sp]) + item[sp:]
+			}
+		}
+	}
+	return out
+}
+
+// isPlainIdent reports whether name is made of identifier parts joined by
+// dots, with no quotes, spaces, operators or parentheses
+func isPlainIdent(name string) bool {
+	if name == "" || name[0] == '.' || name[len(name)-1] == '.' {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c == '.' {
+			if name[i-1] == '.' {
+				return false
+			}
+			continue
+		}
+		if !isIdentByte(c) {
+			return false
+		}
+	}
+	return name[0] < '0' || name[0] > '9'
+}
@@ -0,0 +1,67 @@
+package toki
+
+import (
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdentifierCase(t *testing.T) {
+	chain := func(c IdentifierCase) []string {
+		sel := New().WithIdentifierCase(c).
+			Select("u.UserID", "UserName", "COUNT(*) AS Total", `"Quoted"`).
+			From("Users").
+			Where("u.Status = ?", "active").
+			GroupBy("u.UserID", "UserName").
+			OrderBy("UserName DESC", "lower(UserName)")
+		ins := New().WithIdentifierCase(c).Insert("Users", "UserName", "Email").Values("zakirkun", "zakir@example.com")
+		upd := New().WithIdentifierCase(c).Update("Users").Set(map[string]interface{}{"UserName": "zakirkun"}).Where("UserID = ?", 7)
+		del := New().WithIdentifierCase(c).Delete("Sessions").Where("ExpiresAt < ?", TestTime)
+		return []string{sel.String(), ins.String(), upd.String(), del.String()}
+	}
+
+	assert.Equal(t, []string{
+		`SELECT u.UserID, UserName, COUNT(*) AS Total, "Quoted" FROM Users WHERE u.Status = $1 GROUP BY u.UserID, UserName ORDER BY UserName DESC, lower(UserName)`,
+		"INSERT INTO Users (UserName, Email) VALUES ($1, $2)",
+		"UPDATE Users SET UserName = $1 WHERE UserID = $2",
+		"DELETE FROM Sessions WHERE ExpiresAt < $1",
+	}, chain(PreserveCase))
+
+	assert.Equal(t, []string{
+		`SELECT u.userid, username, COUNT(*) AS Total, "Quoted" FROM users WHERE u.Status = $1 GROUP BY u.userid, username ORDER BY username DESC, lower(UserName)`,
+		"INSERT INTO users (username, email) VALUES ($1, $2)",
+		"UPDATE users SET username = $1 WHERE UserID = $2",
+		"DELETE FROM sessions WHERE ExpiresAt < $1",
+	}, chain(LowerCase))
+
+	snake := func(name string) string {
+		var sb strings.Builder
+		for i, r := range name {
+			if unicode.IsUpper(r) {
+				if i > 0 {
+					sb.WriteByte('_')
+				}
+				r = unicode.ToLower(r)
+			}
+			sb.WriteRune(r)
+		}
+		return sb.String()
+	}
+	b := New().WithIdentifierCase(snake).Select("o.OrderNumber", "o.CreatedAt").From("Orders o").OrderBy("o.CreatedAt ASC")
+	assert.Equal(t, "SELECT o.order_number, o.created_at FROM Orders o ORDER BY o.created_at ASC", b.String())
+}
+
+func TestDialectIdentifierCase(t *testing.T) {
+	lower := Postgres.WithIdentifierCase(LowerCase)
+	assert.Nil(t, Postgres.identCase)
+	assert.Equal(t, "postgres", lower.String())
+
+	assert.Equal(t, "SELECT username FROM users", New().WithDialect(lower).Select("UserName").From("Users").String())
+	assert.Equal(t, "SELECT UserName FROM Users", New().WithDialect(lower).WithIdentifierCase(PreserveCase).Select("UserName").From("Users").String())
+
+	// expressions pass through untouched
+	b := New().WithDialect(lower).SelectExpr(Expr("MaxID")).From("Users").OrderByExpr(Expr("CreatedAt"))
+	assert.Equal(t, "SELECT MaxID FROM users ORDER BY CreatedAt", b.String())
+}
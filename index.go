@@ -0,0 +1,209 @@
+package toki
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+)
+
+// CreateIndexBuilder builds a CREATE INDEX statement
+type CreateIndexBuilder struct {
+	b            *Builder
+	name         string
+	table        string
+	columns      []interface{}
+	unique       bool
+	ifNotExists  bool
+	concurrently bool
+	method       string
+	conditions   []string
+	args         []interface{}
+}
+
+// CreateIndex starts a CREATE INDEX statement on columns of table. A column
+// is a column name, optionally followed by ASC or DESC, or an SQLExpression
+// for an expression index. It runs on the builder's transaction when it has
+// one and renders for the builder's dialect.
+func (b *Builder) CreateIndex(name, table string, columns ...interface{}) *CreateIndexBuilder {
+	return &CreateIndexBuilder{b: b, name: name, table: table, columns: columns}
+}
+
+// Unique creates a unique index
+func (i *CreateIndexBuilder) Unique() *CreateIndexBuilder {
+	i.unique = true
+	return i
+}
+
+// IfNotExists skips creating the index when it already exists
+func (i *CreateIndexBuilder) IfNotExists() *CreateIndexBuilder {
+	i.ifNotExists = true
+	return i
+}
+
+// Concurrently builds the index without locking out writes to its table.
+// PostgreSQL only; it cannot run inside a transaction, so the builder must
+// not have one attached.
+func (i *CreateIndexBuilder) Concurrently() *CreateIndexBuilder {
+	i.concurrently = true
+	return i
+}
+
+// Using sets the index method, such as gin or brin. SQLite does not support
+// it.
+func (i *CreateIndexBuilder) Using(method string) *CreateIndexBuilder {
+	i.method = method
+	return i
+}
+
+// Where makes the index partial, ANDing repeated conditions. Neither
+// PostgreSQL nor SQLite accept bound parameters in DDL, so each ? or $N in
+// condition is replaced by its arg rendered as a SQL literal: strings are
+// quoted with single quotes doubled, []byte becomes a bytea literal, and
+// time.Time an RFC 3339 string. Prefer plain numbers, booleans and strings.
+func (i *CreateIndexBuilder) Where(condition string, args ...interface{}) *CreateIndexBuilder {
+	if len(args) > 0 {
+		// number ? after the args of earlier conditions
+		condition = (&Builder{argIndex: len(i.args)}).convertPlaceholders(condition)
+	}
+	i.conditions = append(i.conditions, condition)
+	i.args = append(i.args, args...)
+	return i
+}
+
+// String builds the statement without validating it
+func (i *CreateIndexBuilder) String() string {
+	var sb strings.Builder
+	sb.WriteString("CREATE ")
+	if i.unique {
+		sb.WriteString("UNIQUE ")
+	}
+	sb.WriteString("INDEX ")
+	if i.concurrently {
+		sb.WriteString("CONCURRENTLY ")
+	}
+	if i.ifNotExists {
+		sb.WriteString("IF NOT EXISTS ")
+	}
+	sb.WriteString(i.name)
+	sb.WriteString(" ON ")
+	sb.WriteString(i.table)
+	if i.method != "" {
+		sb.WriteString(" USING ")
+		sb.WriteString(i.method)
+	}
+
+	sb.WriteString(" (")
+	for n, col := range i.columns {
+		if n > 0 {
+			sb.WriteString(", ")
+		}
+		switch col := col.(type) {
+		case SQLExpression:
+			sb.WriteByte('(')
+			sb.WriteString(col.SQL())
+			sb.WriteByte(')')
+		default:
+			fmt.Fprint(&sb, col)
+		}
+	}
+	sb.WriteByte(')')
+
+	if len(i.conditions) > 0 {
+		sb.WriteString(" WHERE ")
+		sb.WriteString(substitute(strings.Join(i.conditions, " AND "), i.args, ddlLiteral))
+	}
+
+	return sb.String()
+}
+
+// Validate checks names, columns and condition args and rejects options
+// the dialect or the attached transaction do not allow
+func (i *CreateIndexBuilder) Validate() error {
+	if err := checkIdent("index", i.name); err != nil {
+		return err
+	}
+	if err := checkIdent("table", i.table); err != nil {
+		return err
+	}
+	if len(i.columns) == 0 {
+		return fmt.Errorf("index %s has no columns", i.name)
+	}
+	for _, col := range i.columns {
+		if err := checkIndexColumn(col); err != nil {
+			return fmt.Errorf("index %s: %w", i.name, err)
+		}
+	}
+
+	dialect := i.b.Dialect()
+	if i.method != "" {
+		if !dialect.indexMethods {
+			return fmt.Errorf("CREATE INDEX ... USING is not supported by the %s dialect", dialect)
+		}
+		if err := checkIdent("index method", i.method); err != nil {
+			return err
+		}
+	}
+	if err := i.b.checkConcurrently("CREATE INDEX", i.concurrently); err != nil {
+		return err
+	}
+
+	if n := countPlaceholders(strings.Join(i.conditions, " AND ")); n != len(i.args) {
+		return fmt.Errorf("index condition expects %d arg(s), got %d: %w", n, len(i.args), ErrArgCount)
+	}
+	for _, arg := range i.args {
+		if valuer, ok := arg.(driver.Valuer); ok {
+			if _, err := valuer.Value(); err != nil {
+				return fmt.Errorf("index %s: condition arg %T: %w", i.name, arg, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Prepare validates the statement and creates a prepared statement for it
+func (i *CreateIndexBuilder) Prepare(db *sql.DB) (*Stmt, error) {
+	if err := i.Validate(); err != nil {
+		return nil, err
+	}
+	return i.b.prepareDDL(db, i.String(), i.table)
+}
+
+// Exec validates and executes the statement
+func (i *CreateIndexBuilder) Exec(ctx context.Context, db *sql.DB) (sql.Result, error) {
+	stmt, err := i.Prepare(db)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.execDDL(ctx)
+}
+
+// checkIndexColumn accepts SQLExpressions and column names with an optional
+// ASC or DESC
+func checkIndexColumn(col interface{}) error {
+	switch col := col.(type) {
+	case SQLExpression:
+		return nil
+	case string:
+		fields := strings.Fields(col)
+		if len(fields) == 2 {
+			if dir := strings.ToUpper(fields[1]); dir != "ASC" && dir != "DESC" {
+				return fmt.Errorf("invalid column %q", col)
+			}
+		}
+		if len(fields) == 0 || len(fields) > 2 || !identifier.MatchString(fields[0]) {
+			return fmt.Errorf("invalid column %q", col)
+		}
+		return nil
+	default:
+		return fmt.Errorf("index column must be a string or SQLExpression, got %T", col)
+	}
+}
+
+// ddlLiteral formats v as a complete SQL literal for DDL, which cannot take
+// bound args
+func ddlLiteral(v interface{}) string {
+	return literal(v, 0)
+}
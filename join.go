@@ -0,0 +1,68 @@
+package toki
+
+import (
+	"errors"
+	"strings"
+)
+
+// Join adds an INNER JOIN of table on the condition on. Placeholders in on
+// are numbered in order with the rest of the statement.
+func (b *Builder) Join(table, on string, args ...interface{}) *Builder {
+	return b.join("JOIN", table, on, args)
+}
+
+// LeftJoin adds a LEFT JOIN of table on the condition on
+func (b *Builder) LeftJoin(table, on string, args ...interface{}) *Builder {
+	return b.join("LEFT JOIN", table, on, args)
+}
+
+func (b *Builder) join(kind, table, on string, args []interface{}) *Builder {
+	b.changed()
+	b.clauses.joins = append(b.clauses.joins, kind+" "+b.ident(table)+" ON "+b.convertPlaceholders(on))
+	b.appendArgs(args)
+	return b
+}
+
+// FromFunction adds a FROM clause selecting from a set-returning function
+// call such as generate_series(?, ?, '1 day') or unnest(?::text[]). The ?
+// placeholders in expr are bound to args. Function results need an alias to
+// be referenced, so alias must be a plain identifier; otherwise the builder
+// fails.
+func (b *Builder) FromFunction(expr, alias string, args ...interface{}) *Builder {
+	if err := checkFunction("FromFunction", expr, alias); err != nil {
+		b.fail(err)
+		return b
+	}
+	if len(b.clauses.from) > 0 {
+		b.fail(errors.New("FROM already set, cannot add " + alias + "; FromFunction starts the FROM clause"))
+		return b
+	}
+	b.changed()
+	b.clauses.from = append(b.clauses.from, b.convertPlaceholders(expr)+" AS "+b.ident(alias))
+	b.appendArgs(args)
+	return b
+}
+
+// JoinFunction adds a CROSS JOIN LATERAL of a set-returning function call,
+// which may reference columns of the tables before it, as in
+// unnest(posts.tags). The ? placeholders in expr are bound to args.
+func (b *Builder) JoinFunction(expr, alias string, args ...interface{}) *Builder {
+	if err := checkFunction("JoinFunction", expr, alias); err != nil {
+		b.fail(err)
+		return b
+	}
+	b.changed()
+	b.clauses.joins = append(b.clauses.joins, "CROSS JOIN LATERAL "+b.convertPlaceholders(expr)+" AS "+b.ident(alias))
+	b.appendArgs(args)
+	return b
+}
+
+func checkFunction(method, expr, alias string) error {
+	if strings.TrimSpace(expr) == "" {
+		return errors.New(method + " needs a function call")
+	}
+	if alias == "" {
+		return errors.New(method + " needs an alias")
+	}
+	return checkIdent("alias", alias)
+}
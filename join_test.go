@@ -0,0 +1,48 @@
+package toki
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJoin(t *testing.T) {
+	b := New().Select("u.name", "t.name").From("users u").
+		Join("teams t", "t.id = u.team_id AND t.region = ?", "eu").
+		LeftJoin("badges b", "b.user_id = u.id").
+		Where("u.active = ?", true)
+	assert.Equal(t, "SELECT u.name, t.name FROM users u JOIN teams t ON t.id = u.team_id AND t.region = $1 LEFT JOIN badges b ON b.user_id = u.id WHERE u.active = $2", b.String())
+	assert.Equal(t, []interface{}{"eu", true}, b.args)
+}
+
+func TestFromFunction(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 7)
+
+	b := New().Select("d::date", "COUNT(o.id)").
+		FromFunction("generate_series(?, ?, '1 day')", "d", from, to).
+		LeftJoin("orders o", "o.created_at::date = d::date AND o.status = ?", "paid").
+		Where("d < ?", to)
+	assert.NoError(t, b.Err())
+	assert.Equal(t, "SELECT d::date, COUNT(o.id) FROM generate_series($1, $2, '1 day') AS d LEFT JOIN orders o ON o.created_at::date = d::date AND o.status = $3 WHERE d < $4", b.String())
+	assert.Equal(t, []interface{}{from, to, "paid", to}, b.args)
+
+	b = New().Select("tag").FromFunction("unnest(?::text[])", "tag", "{go,sql}").Join("tags t", "t.name = tag")
+	assert.NoError(t, b.Err())
+	assert.Equal(t, "SELECT tag FROM unnest($1::text[]) AS tag JOIN tags t ON t.name = tag", b.String())
+
+	b = New().Select("p.id", "tag").From("posts p").JoinFunction("unnest(p.tags)", "tag").Where("tag = ?", "go")
+	assert.NoError(t, b.Err())
+	assert.Equal(t, "SELECT p.id, tag FROM posts p CROSS JOIN LATERAL unnest(p.tags) AS tag WHERE tag = $1", b.String())
+
+	// aliases and joined tables follow the identifier case, like From
+	b = New().WithIdentifierCase(LowerCase).Select("Tag").From("Posts").
+		JoinFunction("unnest(tags)", "Tag").Join("Tags", "tags.name = tag")
+	assert.Equal(t, "SELECT tag FROM posts CROSS JOIN LATERAL unnest(tags) AS tag JOIN tags ON tags.name = tag", b.String())
+
+	assert.EqualError(t, New().Select("*").FromFunction("generate_series(1, 3)", "").Err(), "FromFunction needs an alias")
+	assert.EqualError(t, New().Select("*").FromFunction(" ", "d").Err(), "FromFunction needs a function call")
+	assert.EqualError(t, New().Select("*").From("users").JoinFunction("", "d").Err(), "JoinFunction needs a function call")
+	assert.EqualError(t, New().Select("*").FromFunction("generate_series(1, 3)", "d; DROP TABLE users").Err(), `invalid alias name "d; DROP TABLE users"`)
+}
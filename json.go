@@ -0,0 +1,62 @@
+package toki
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+var bytesType = reflect.TypeOf([]byte(nil))
+
+// JSON wraps v so it is bound as its JSON encoding, for use with json and
+// jsonb columns in Values and Set. A nil value binds as NULL. Struct fields
+// tagged db:"name,json" are wrapped automatically.
+func JSON(v interface{}) driver.Valuer {
+	return jsonValue{value: v}
+}
+
+// jsonValue marshals its value when the driver asks for it
+type jsonValue struct {
+	column string
+	value  interface{}
+}
+
+func (j jsonValue) Value() (driver.Value, error) {
+	if j.value == nil {
+		return nil, nil
+	}
+
+	val := reflect.ValueOf(j.value)
+	switch val.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		if val.IsNil() {
+			return nil, nil
+		}
+	}
+
+	data, err := json.Marshal(j.value)
+	if err != nil {
+		if j.column != "" {
+			return nil, fmt.Errorf("failed to marshal %T to JSON for column %q: %w", j.value, j.column, err)
+		}
+		return nil, fmt.Errorf("failed to marshal %T to JSON: %w", j.value, err)
+	}
+
+	// Sent as text so drivers don't encode it as bytea
+	return string(data), nil
+}
+
+// scanJSON decodes data into dst, leaving dst at its zero value for NULL
+func scanJSON(column string, data []byte, dst reflect.Value) error {
+	if data == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+
+	if err := json.Unmarshal(data, dst.Addr().Interface()); err != nil {
+		return fmt.Errorf("failed to unmarshal JSON column %q into %s: %w", column, dst.Type(), err)
+	}
+
+	return nil
+}
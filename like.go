@@ -0,0 +1,60 @@
+package toki
+
+import "strings"
+
+// MatchMode selects where WhereLike and WhereILike allow other text around
+// the searched string
+type MatchMode int
+
+const (
+	// MatchExact matches the whole value
+	MatchExact MatchMode = iota
+	// MatchPrefix matches values starting with the string
+	MatchPrefix
+	// MatchSuffix matches values ending with the string
+	MatchSuffix
+	// MatchContains matches values containing the string
+	MatchContains
+)
+
+// likeEscaper escapes the LIKE wildcards and the escape character itself
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// WhereLike adds a LIKE condition matching s literally: % and _ in s are
+// escaped, the wildcards for mode are added, and the pattern is bound as an
+// arg. It starts the WHERE clause or is ANDed to it.
+func (b *Builder) WhereLike(column, s string, mode MatchMode) *Builder {
+	return b.whereLike(column, s, mode, false)
+}
+
+// WhereILike is WhereLike ignoring case. Dialects without ILIKE compare
+// LOWER(column) LIKE LOWER(pattern).
+func (b *Builder) WhereILike(column, s string, mode MatchMode) *Builder {
+	return b.whereLike(column, s, mode, true)
+}
+
+func (b *Builder) whereLike(column, s string, mode MatchMode, fold bool) *Builder {
+	escaped := likeEscaper.Replace(s)
+	pattern := escaped
+	if mode == MatchSuffix || mode == MatchContains {
+		pattern = "%" + pattern
+	}
+	if mode == MatchPrefix || mode == MatchContains {
+		pattern += "%"
+	}
+
+	var condition string
+	switch {
+	case !fold:
+		condition = column + " LIKE ?"
+	case b.Dialect().ilike:
+		condition = column + " ILIKE ?"
+	default:
+		condition = "LOWER(" + column + ") LIKE LOWER(?)"
+	}
+	if escaped != s {
+		condition += ` ESCAPE '\'`
+	}
+
+	return b.Where(condition, pattern)
+}
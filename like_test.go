@@ -0,0 +1,60 @@
+package toki
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWhereLike(t *testing.T) {
+	tests := []struct {
+		name    string
+		builder *Builder
+		want    string
+		args    []interface{}
+	}{
+		{
+			name:    "contains",
+			builder: New().Select("*").From("users").WhereLike("name", "ann", MatchContains),
+			want:    "SELECT * FROM users WHERE name LIKE $1",
+			args:    []interface{}{"%ann%"},
+		},
+		{
+			name:    "prefix after where",
+			builder: New().Select("*").From("users").Where("active = ?", true).WhereLike("email", "ann", MatchPrefix),
+			want:    "SELECT * FROM users WHERE active = $1 AND email LIKE $2",
+			args:    []interface{}{true, "ann%"},
+		},
+		{
+			name:    "suffix with wildcards",
+			builder: New().Select("*").From("files").WhereLike("name", "100%_done", MatchSuffix),
+			want:    `SELECT * FROM files WHERE name LIKE $1 ESCAPE '\'`,
+			args:    []interface{}{`%100\%\_done`},
+		},
+		{
+			name:    "exact with backslash",
+			builder: New().Select("*").From("files").WhereLike("path", `C:\tmp`, MatchExact),
+			want:    `SELECT * FROM files WHERE path LIKE $1 ESCAPE '\'`,
+			args:    []interface{}{`C:\\tmp`},
+		},
+		{
+			name:    "ilike",
+			builder: New().Select("*").From("users").WhereILike("name", "O'Brien_", MatchContains),
+			want:    `SELECT * FROM users WHERE name ILIKE $1 ESCAPE '\'`,
+			args:    []interface{}{`%O'Brien\_%`},
+		},
+		{
+			name:    "ilike without ILIKE",
+			builder: New().WithDialect(SQLite).Select("*").From("users").WhereILike("name", "ann", MatchPrefix).WhereLike("city", "%", MatchExact),
+			want:    `SELECT * FROM users WHERE LOWER(name) LIKE LOWER($1) AND city LIKE $2 ESCAPE '\'`,
+			args:    []interface{}{"ann%", `\%`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.builder.String())
+			assert.Equal(t, tt.args, tt.builder.args)
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package toki
+
+import "errors"
+
+// pagination holds the LIMIT and OFFSET of a query. Both are bound as args
+// when set; the clause is rendered by String in the builder's syntax.
+type pagination struct {
+	// limit and offset are the placeholder numbers of the bound values,
+	// 0 when unset
+	limit, offset int
+}
+
+// Limit adds a LIMIT, bound as an arg. Limit and Offset share one clause;
+// calling either again replaces its value.
+func (b *Builder) Limit(n int) *Builder {
+	page := b.pagination()
+	page.limit = b.bindPage(page.limit, n)
+	return b
+}
+
+// Offset adds an OFFSET, bound as an arg
+func (b *Builder) Offset(n int) *Builder {
+	page := b.pagination()
+	page.offset = b.bindPage(page.offset, n)
+	return b
+}
+
+// UseFetchSyntax renders Limit and Offset in the ANSI form,
+// "OFFSET n ROWS FETCH NEXT m ROWS ONLY", which SQL Server, DB2 and Oracle
+// expect, or "FETCH FIRST m ROWS ONLY" without an offset. Dialects needing
+// it use it without this option.
+func (b *Builder) UseFetchSyntax() *Builder {
+	b.changed()
+	b.fetch = true
+	return b
+}
+
+// pagination returns the pagination clause, adding it on first use
+func (b *Builder) pagination() *pagination {
+	b.changed()
+	if b.page == nil {
+		b.page = &pagination{}
+	}
+	return b.page
+}
+
+// bindPage binds a LIMIT or OFFSET value, or replaces the value bound to
+// placeholder, and returns its placeholder number
+func (b *Builder) bindPage(placeholder, n int) int {
+	if placeholder > 0 {
+		b.args[placeholder-1] = n
+		return placeholder
+	}
+	b.argIndex++
+	b.addArg(n)
+	return b.argIndex
+}
+
+// fetchSyntax reports whether pagination renders as OFFSET ... FETCH
+func (b *Builder) fetchSyntax() bool {
+	return b.fetch || b.Dialect().fetchSyntax
+}
+
+// checkPagination reports an error when the dialect requires an ORDER BY
+// for OFFSET ... FETCH and the query has none
+func (b *Builder) checkPagination() error {
+	if b.page == nil || !b.fetchSyntax() || !b.Dialect().fetchNeedsOrder || len(b.clauses.orderBy) > 0 {
+		return nil
+	}
+	return errors.New("OFFSET ... FETCH requires an ORDER BY before it with the " + b.Dialect().String() + " dialect")
+}
+
+// renderPage renders the pagination clause in the builder's syntax
+func (b *Builder) renderPage() string {
+	return b.page.render(b.fetchSyntax(), b.Dialect().fetchNeedsOffset)
+}
+
+// render renders the clause as LIMIT and OFFSET, or as OFFSET ... FETCH
+// when fetch is set. A FETCH without an offset starts with OFFSET 0 ROWS
+// when zeroOffset is set, and with FETCH FIRST otherwise.
+func (p *pagination) render(fetch, zeroOffset bool) string {
+	var buf []byte
+
+	if !fetch {
+		if p.limit > 0 {
+			buf = appendPlaceholder(append(buf, "LIMIT "...), p.limit)
+		}
+		if p.offset > 0 {
+			if len(buf) > 0 {
+				buf = append(buf, ' ')
+			}
+			buf = appendPlaceholder(append(buf, "OFFSET "...), p.offset)
+		}
+		return string(buf)
+	}
+
+	if p.offset > 0 {
+		buf = appendPlaceholder(append(buf, "OFFSET "...), p.offset)
+		buf = append(buf, " ROWS"...)
+	} else if p.limit > 0 && zeroOffset {
+		buf = append(buf, "OFFSET 0 ROWS"...)
+	}
+	if p.limit > 0 {
+		if len(buf) > 0 {
+			buf = append(buf, " FETCH NEXT "...)
+		} else {
+			buf = append(buf, "FETCH FIRST "...)
+		}
+		buf = appendPlaceholder(buf, p.limit)
+		buf = append(buf, " ROWS ONLY"...)
+	}
+	return string(buf)
+}
@@ -0,0 +1,102 @@
+package toki
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaginationGolden(t *testing.T) {
+	page := func() *Builder {
+		return New().
+			Select("id", "name").
+			From("users").
+			Where("status = ?", "active").
+			OrderBy("id").
+			Limit(20).
+			Offset(40)
+	}
+
+	tests := []struct {
+		name    string
+		builder *Builder
+		want    string
+		args    []interface{}
+	}{
+		{
+			name:    "limit offset",
+			builder: page(),
+			want:    "SELECT id, name FROM users WHERE status = $1 ORDER BY id LIMIT $2 OFFSET $3",
+			args:    []interface{}{"active", 20, 40},
+		},
+		{
+			name:    "fetch",
+			builder: page().UseFetchSyntax(),
+			want:    "SELECT id, name FROM users WHERE status = $1 ORDER BY id OFFSET $2 ROWS FETCH NEXT $3 ROWS ONLY",
+			args:    []interface{}{"active", 40, 20},
+		},
+		{
+			name:    "fetch first",
+			builder: New().Select("*").From("jobs").OrderBy("run_at").Limit(5).UseFetchSyntax(),
+			want:    "SELECT * FROM jobs ORDER BY run_at FETCH FIRST $1 ROWS ONLY",
+			args:    []interface{}{5},
+		},
+		{
+			name:    "sqlserver limit",
+			builder: New().WithDialect(SQLServer).Select("*").From("jobs").Where("queue = ?", "mail").OrderBy("run_at").Limit(5),
+			want:    "SELECT * FROM jobs WHERE queue = @p1 ORDER BY run_at OFFSET 0 ROWS FETCH NEXT @p2 ROWS ONLY",
+			args:    []interface{}{"mail", 5},
+		},
+		{
+			name:    "sqlserver limit and offset",
+			builder: New().WithDialect(SQLServer).Select("*").From("jobs").OrderBy("run_at").Limit(5).Offset(10),
+			want:    "SELECT * FROM jobs ORDER BY run_at OFFSET @p1 ROWS FETCH NEXT @p2 ROWS ONLY",
+			args:    []interface{}{10, 5},
+		},
+		{
+			name:    "sqlserver offset only",
+			builder: New().WithDialect(SQLServer).Select("*").From("jobs").OrderBy("run_at").Offset(10),
+			want:    "SELECT * FROM jobs ORDER BY run_at OFFSET @p1 ROWS",
+			args:    []interface{}{10},
+		},
+		{
+			name:    "offset only",
+			builder: New().Select("*").From("jobs").Offset(10),
+			want:    "SELECT * FROM jobs OFFSET $1",
+			args:    []interface{}{10},
+		},
+		{
+			name:    "offset rows",
+			builder: New().Select("*").From("jobs").Offset(10).UseFetchSyntax(),
+			want:    "SELECT * FROM jobs OFFSET $1 ROWS",
+			args:    []interface{}{10},
+		},
+		{
+			name:    "replaced values",
+			builder: New().Select("*").From("jobs").Where("a = ?", 1).Limit(5).Offset(0).Limit(50),
+			want:    "SELECT * FROM jobs WHERE a = $1 LIMIT $2 OFFSET $3",
+			args:    []interface{}{1, 50, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.builder.String())
+			assert.Equal(t, tt.args, tt.builder.Args())
+		})
+	}
+}
+
+func TestFetchNeedsOrder(t *testing.T) {
+	ordered := &Dialect{name: "ordered", fetchSyntax: true, fetchNeedsOrder: true}
+
+	_, err := New().WithDialect(ordered).Select("*").From("jobs").Limit(5).Prepare(nil)
+	assert.EqualError(t, err, "OFFSET ... FETCH requires an ORDER BY before it with the ordered dialect")
+
+	stmt, err := New().WithDialect(ordered).Select("*").From("jobs").OrderBy("id").Limit(5).Prepare(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM jobs ORDER BY id FETCH FIRST $1 ROWS ONLY", stmt.query)
+
+	_, err = New().Select("*").From("jobs").Limit(5).UseFetchSyntax().Prepare(nil)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,60 @@
+package toki
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Logger receives every statement toki sends to the database: builder
+// statements, raw queries and transaction control statements. Args are
+// passed as bound, so implementations can redact them. The duration covers
+// only the database call, not scanning the result.
+type Logger interface {
+	LogQuery(ctx context.Context, query string, args []interface{}, duration time.Duration, err error)
+}
+
+// defaultLogger is used by builders and raw queries without their own
+// logger; nil disables logging
+var defaultLogger Logger
+
+// SetLogger sets the package-wide logger, or disables logging when l is nil.
+// Call it during initialization, before queries run.
+func SetLogger(l Logger) {
+	defaultLogger = l
+}
+
+// WithLogger sets the logger for statements run from this builder
+func (b *Builder) WithLogger(l Logger) *Builder {
+	b.hooks.logger = l
+	return b
+}
+
+// WithLogger sets the logger for this raw query
+func (r *RawQuery) WithLogger(l Logger) *RawQuery {
+	r.hooks.logger = l
+	return r
+}
+
+// StdLogger is a Logger that writes each query to a *log.Logger
+type StdLogger struct {
+	Logger *log.Logger
+}
+
+// NewStdLogger returns a Logger writing to l, or to the standard logger when
+// l is nil
+func NewStdLogger(l *log.Logger) *StdLogger {
+	if l == nil {
+		l = log.Default()
+	}
+	return &StdLogger{Logger: l}
+}
+
+// LogQuery implements Logger
+func (s *StdLogger) LogQuery(ctx context.Context, query string, args []interface{}, duration time.Duration, err error) {
+	if err != nil {
+		s.Logger.Printf("toki: %s %v failed after %s: %v", query, args, duration, err)
+		return
+	}
+	s.Logger.Printf("toki: %s %v (%s)", query, args, duration)
+}
@@ -0,0 +1,114 @@
+package toki
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+type loggedQuery struct {
+	query string
+	args  []interface{}
+	err   error
+}
+
+type recordingLogger struct {
+	queries []loggedQuery
+}
+
+func (l *recordingLogger) LogQuery(ctx context.Context, query string, args []interface{}, duration time.Duration, err error) {
+	l.queries = append(l.queries, loggedQuery{query: query, args: args, err: err})
+}
+
+func TestLogger(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	logger := &recordingLogger{}
+	SetLogger(logger)
+	defer SetLogger(nil)
+
+	failure := errors.New("boom")
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE users").WithArgs("zakirkun", 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT name FROM users").WithArgs(1).WillReturnError(failure)
+	mock.ExpectExec("ROLLBACK TO SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	tx, err := Begin(db)
+	assert.NoError(t, err)
+
+	stmt, err := tx.Builder().
+		Update("users").
+		Set(map[string]interface{}{"name": "zakirkun"}).
+		Where("id = ?", 1).
+		Prepare(db)
+	assert.NoError(t, err)
+	_, err = stmt.Exec()
+	assert.NoError(t, err)
+
+	err = tx.RunInTx(func(nested *Transaction) error {
+		_, err := nested.Raw("SELECT name FROM users WHERE id = $1", 1).Query()
+		return err
+	})
+	assert.ErrorIs(t, err, failure)
+	assert.NoError(t, tx.Commit())
+
+	assert.Equal(t, []loggedQuery{
+		{query: "BEGIN"},
+		{query: "UPDATE users SET name = $1 WHERE id = $2", args: []interface{}{"zakirkun", 1}},
+		{query: "SAVEPOINT sp_1"},
+		{query: "SELECT name FROM users WHERE id = $1", args: []interface{}{1}, err: failure},
+		{query: "ROLLBACK TO SAVEPOINT sp_1"},
+		{query: "COMMIT"},
+	}, logger.queries)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBuilderLogger(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	global := &recordingLogger{}
+	SetLogger(global)
+	defer SetLogger(nil)
+
+	mock.ExpectExec("DELETE FROM sessions").WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec("DELETE FROM tokens").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	own := &recordingLogger{}
+	stmt, err := New().WithLogger(own).Delete("sessions").Prepare(db)
+	assert.NoError(t, err)
+	_, err = stmt.Exec()
+	assert.NoError(t, err)
+
+	_, err = New().Raw("DELETE FROM tokens").WithLogger(own).WithDB(db).Exec()
+	assert.NoError(t, err)
+
+	assert.Len(t, own.queries, 2)
+	assert.Empty(t, global.queries)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStdLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewStdLogger(log.New(&buf, "", 0))
+
+	logger.LogQuery(context.Background(), "SELECT $1", []interface{}{1}, 2*time.Millisecond, nil)
+	logger.LogQuery(context.Background(), "SELECT $1", []interface{}{1}, time.Second, errors.New("timeout"))
+
+	assert.Equal(t, "toki: SELECT $1 [1] (2ms)\ntoki: SELECT $1 [1] failed after 1s: timeout\n", buf.String())
+}
@@ -0,0 +1,462 @@
+package toki
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// NestedSeparator joins the db tag of a nested struct field with the columns
+// of the nested struct, so User.ID tagged db:"user" maps to user_id. Set it
+// once at program start.
+var NestedSeparator = "_"
+
+// maxFieldDepth bounds how deep embedded and nested structs are followed,
+// which also breaks cycles through self-referencing pointer fields
+const maxFieldDepth = 8
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	valuerType  = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+)
+
+// fieldInfo describes a struct field mapped to a database column
+type fieldInfo struct {
+	column    string
+	index     []int
+	depth     int
+	omitEmpty bool
+	json      bool
+	lock      bool
+	pk        bool
+}
+
+// structInfo is the mapping metadata of a struct type, computed once per
+// type and shared by every struct-facing API
+type structInfo struct {
+	fields   []fieldInfo
+	byColumn map[string]int
+}
+
+// structInfoKey identifies cached metadata; the separator is part of the key
+// because it is baked into nested column names
+type structInfoKey struct {
+	typ reflect.Type
+	sep string
+}
+
+// structInfoCache maps structInfoKey to *structInfo
+var structInfoCache sync.Map
+
+// getStructInfo returns the cached metadata for typ, computing it on first use
+func getStructInfo(typ reflect.Type) *structInfo {
+	key := structInfoKey{typ: typ, sep: NestedSeparator}
+	if cached, ok := structInfoCache.Load(key); ok {
+		return cached.(*structInfo)
+	}
+
+	info := buildStructInfo(typ, key.sep)
+	actual, _ := structInfoCache.LoadOrStore(key, info)
+	return actual.(*structInfo)
+}
+
+// mixedCaseInitialisms are initialisms written with lowercase letters that
+// snakeCase keeps together as one word
+var mixedCaseInitialisms = []string{"OAuth"}
+
+// snakeCase converts a Go field name to snake_case, keeping initialisms
+// together: UserID becomes user_id and HTMLBody becomes html_body
+func snakeCase(name string) string {
+	var sb strings.Builder
+	sb.Grow(len(name) + 4)
+
+	runes := []rune(name)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		if unicode.IsUpper(r) {
+			if word, ok := mixedCaseInitialism(string(runes[i:])); ok {
+				if i > 0 {
+					sb.WriteByte('_')
+				}
+				sb.WriteString(strings.ToLower(word))
+				i += len([]rune(word)) - 1
+				continue
+			}
+		}
+
+		if !unicode.IsUpper(r) {
+			sb.WriteRune(r)
+			continue
+		}
+
+		if i > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				sb.WriteByte('_')
+			}
+		}
+		sb.WriteRune(unicode.ToLower(r))
+	}
+
+	return sb.String()
+}
+
+// mixedCaseInitialism returns the initialism s starts with, if any, provided
+// it ends at a word boundary
+func mixedCaseInitialism(s string) (string, bool) {
+	for _, word := range mixedCaseInitialisms {
+		if !strings.HasPrefix(s, word) {
+			continue
+		}
+		rest := []rune(s[len(word):])
+		if len(rest) == 0 || !unicode.IsLower(rest[0]) {
+			return word, true
+		}
+	}
+	return "", false
+}
+
+// tagOptions holds the options that follow the column name in a db tag
+type tagOptions struct {
+	omitEmpty bool
+	json      bool
+	// lock marks the version column of optimistic locking
+	lock bool
+	// pk marks a primary key column
+	pk bool
+}
+
+// parseTag splits a db tag into the column name and its options. Unknown
+// options are ignored so tags written for newer versions keep working.
+func parseTag(tag string) (string, tagOptions) {
+	name, rest, _ := strings.Cut(tag, ",")
+
+	var opts tagOptions
+	for rest != "" {
+		var opt string
+		opt, rest, _ = strings.Cut(rest, ",")
+		switch strings.TrimSpace(opt) {
+		case "omitempty":
+			opts.omitEmpty = true
+		case "json":
+			opts.json = true
+		case "lock":
+			opts.lock = true
+		case "pk":
+			opts.pk = true
+		}
+	}
+
+	return name, opts
+}
+
+// column returns the field mapped to the named column
+func (s *structInfo) column(name string) (*fieldInfo, bool) {
+	i, ok := s.byColumn[name]
+	if !ok {
+		return nil, false
+	}
+	return &s.fields[i], true
+}
+
+// keyFields returns the fields of typ tagged pk, in declaration order
+func keyFields(typ reflect.Type) []fieldInfo {
+	var keys []fieldInfo
+	for _, f := range getStructInfo(typ).fields {
+		if f.pk {
+			keys = append(keys, f)
+		}
+	}
+	return keys
+}
+
+// buildStructInfo computes the column-mapped fields of typ in declaration
+// order. Fields tagged db:"-" are not mapped; fields without a db tag map to
+// the snake_case form of their name. Anonymous embedded structs (by value or
+// pointer) are flattened into the result; when two fields map to the same
+// column the shallower one wins. A named field holding a struct with mapped
+// fields of its own is flattened too, using its column name plus sep as a
+// column prefix.
+func buildStructInfo(typ reflect.Type, sep string) *structInfo {
+	var fields []fieldInfo
+	walkFields(typ, nil, 0, "", sep, &fields)
+
+	winner := make(map[string]int, len(fields))
+	for i, f := range fields {
+		if j, ok := winner[f.column]; !ok || f.depth < fields[j].depth {
+			winner[f.column] = i
+		}
+	}
+
+	info := &structInfo{
+		fields:   make([]fieldInfo, 0, len(winner)),
+		byColumn: make(map[string]int, len(winner)),
+	}
+	for i, f := range fields {
+		if winner[f.column] == i {
+			info.byColumn[f.column] = len(info.fields)
+			info.fields = append(info.fields, f)
+		}
+	}
+
+	return info
+}
+
+// walkFields appends the mapped fields of typ, recursing into embedded and
+// prefixed nested structs
+func walkFields(typ reflect.Type, parent []int, depth int, prefix, sep string, fields *[]fieldInfo) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		index := append(append([]int(nil), parent...), i)
+		tag, opts := parseTag(field.Tag.Get("db"))
+		if tag == "-" {
+			continue
+		}
+
+		if field.Anonymous && tag == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct && depth < maxFieldDepth {
+				// Exported fields of an unexported embedded struct are still
+				// promoted, but an unexported embedded pointer can't be set
+				if field.PkgPath != "" && field.Type.Kind() == reflect.Ptr {
+					continue
+				}
+				walkFields(ft, index, depth+1, prefix, sep, fields)
+				continue
+			}
+		}
+
+		if field.PkgPath != "" {
+			continue
+		}
+
+		if tag == "" {
+			tag = snakeCase(field.Name)
+		}
+
+		if !opts.json && isNestedStruct(field.Type) {
+			// Past the depth limit the field is dropped rather than bound
+			// as an opaque struct value
+			if depth >= maxFieldDepth {
+				continue
+			}
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			walkFields(ft, index, depth+1, prefix+tag+sep, sep, fields)
+			continue
+		}
+
+		*fields = append(*fields, fieldInfo{
+			column:    prefix + tag,
+			index:     index,
+			depth:     depth,
+			omitEmpty: opts.omitEmpty,
+			json:      opts.json,
+			lock:      opts.lock,
+			pk:        opts.pk,
+		})
+	}
+}
+
+// isNestedStruct reports whether a field of type t should be flattened into
+// prefixed columns rather than bound as a single value. Only structs with
+// exported or embedded fields of their own qualify; time.Time and types
+// implementing driver.Valuer or sql.Scanner are always single values.
+func isNestedStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct || t == timeType {
+		return false
+	}
+
+	if t.Implements(valuerType) || reflect.PtrTo(t).Implements(valuerType) || reflect.PtrTo(t).Implements(scannerType) {
+		return false
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous || (field.PkgPath == "" && field.Tag.Get("db") != "-") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// fieldByIndex returns the field at index, reporting false when a nil
+// embedded pointer lies on the path
+func fieldByIndex(val reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && val.Kind() == reflect.Ptr {
+			if val.IsNil() {
+				return reflect.Value{}, false
+			}
+			val = val.Elem()
+		}
+		val = val.Field(x)
+	}
+	return val, true
+}
+
+// fieldByIndexAlloc returns the field at index, allocating nil embedded
+// pointers on the way so the field can be set
+func fieldByIndexAlloc(val reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && val.Kind() == reflect.Ptr {
+			if val.IsNil() {
+				val.Set(reflect.New(val.Type().Elem()))
+			}
+			val = val.Elem()
+		}
+		val = val.Field(x)
+	}
+	return val
+}
+
+// structValue dereferences v and returns it if it is a struct
+func structValue(v interface{}) (reflect.Value, bool) {
+	val := reflect.ValueOf(v)
+	if val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return reflect.Value{}, false
+		}
+		val = val.Elem()
+	}
+
+	if val.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+
+	// Work on an addressable copy so Valuer methods with pointer receivers
+	// are found on fields of structs passed by value
+	if !val.CanAddr() {
+		copied := reflect.New(val.Type()).Elem()
+		copied.Set(val)
+		val = copied
+	}
+
+	return val, true
+}
+
+// structColumns returns the mapped columns of v and their values in field
+// order. Fields tagged omitempty are left out while they hold their zero
+// value and fields tagged json are bound as their JSON encoding.
+func structColumns(val reflect.Value) ([]string, []interface{}) {
+	fields := getStructInfo(val.Type()).fields
+	columns := make([]string, 0, len(fields))
+	values := make([]interface{}, 0, len(fields))
+
+	for _, f := range fields {
+		fv, ok := fieldByIndex(val, f.index)
+		if !ok {
+			continue
+		}
+
+		arg := fieldArg(fv)
+		if f.omitEmpty && isEmptyArg(fv, arg) {
+			continue
+		}
+		if f.json {
+			arg = jsonValue{column: f.column, value: arg}
+		}
+		columns = append(columns, f.column)
+		values = append(values, normalizeArg(arg))
+	}
+
+	return columns, values
+}
+
+// fieldArg returns the value of a struct field for binding, preferring the
+// field's address when only the pointer type implements driver.Valuer
+func fieldArg(fv reflect.Value) interface{} {
+	if fv.Kind() != reflect.Ptr && fv.CanAddr() && !fv.Type().Implements(valuerType) && fv.Addr().Type().Implements(valuerType) {
+		return fv.Addr().Interface()
+	}
+	return fv.Interface()
+}
+
+// isEmptyArg reports whether a field counts as empty for omitempty. Values
+// implementing driver.Valuer are opaque: they are empty when nil or when
+// Value returns nil.
+func isEmptyArg(fv reflect.Value, arg interface{}) bool {
+	if fv.Kind() == reflect.Ptr && fv.IsNil() {
+		return true
+	}
+
+	if valuer, ok := arg.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		return err == nil && v == nil
+	}
+
+	return fv.IsZero()
+}
+
+// normalizeArg prepares a value for the driver: nil pointers become an
+// untyped nil (SQL NULL) and non-nil pointers are dereferenced. Values
+// implementing driver.Valuer and other non-pointer values, including the
+// sql.Null* types, are returned unchanged.
+func normalizeArg(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+
+	val := reflect.ValueOf(v)
+	if val.Kind() != reflect.Ptr {
+		return v
+	}
+
+	if val.IsNil() {
+		return nil
+	}
+
+	if _, ok := v.(driver.Valuer); ok {
+		return v
+	}
+
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return nil
+		}
+		val = val.Elem()
+	}
+
+	return val.Interface()
+}
+
+// Columns returns the column names mapped by the struct type of v in field
+// order, for use as Select(toki.Columns(&User{})...). v may be a struct, a
+// pointer to one, or a slice of either. An optional prefix such as "u." is
+// prepended to every column.
+func Columns(v interface{}, prefix ...string) []string {
+	typ := reflect.TypeOf(v)
+	for typ != nil && (typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice) {
+		typ = typ.Elem()
+	}
+
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return nil
+	}
+
+	p := strings.Join(prefix, "")
+	fields := getStructInfo(typ).fields
+	columns := make([]string, len(fields))
+	for i, f := range fields {
+		columns[i] = p + f.column
+	}
+
+	return columns
+}
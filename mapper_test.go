@@ -0,0 +1,644 @@
+package toki
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+type Base struct {
+	ID        int       `db:"id"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type Audit struct {
+	UpdatedBy string `db:"updated_by"`
+}
+
+func TestBindEmbeddedStructs(t *testing.T) {
+	t.Run("Embedded by value", func(t *testing.T) {
+		type User struct {
+			Base
+			Name string `db:"name"`
+		}
+
+		user := User{Base: Base{ID: 7, CreatedAt: TestTime}, Name: TestUser}
+		assert.Equal(t, map[string]interface{}{
+			"id":         7,
+			"created_at": TestTime,
+			"name":       TestUser,
+		}, New().Bind(&user))
+	})
+
+	t.Run("Embedded pointer", func(t *testing.T) {
+		type User struct {
+			Base
+			*Audit
+			Name string `db:"name"`
+		}
+
+		user := User{Base: Base{ID: 7, CreatedAt: TestTime}, Name: TestUser}
+		assert.Equal(t, map[string]interface{}{
+			"id":         7,
+			"created_at": TestTime,
+			"name":       TestUser,
+		}, New().Bind(&user), "nil embedded pointers are skipped")
+
+		user.Audit = &Audit{UpdatedBy: "admin"}
+		assert.Equal(t, "admin", New().Bind(&user)["updated_by"])
+	})
+
+	t.Run("Outer field wins on collision", func(t *testing.T) {
+		type User struct {
+			Base
+			ID   string `db:"id"`
+			Name string `db:"name"`
+		}
+
+		user := User{Base: Base{ID: 7}, ID: "outer", Name: TestUser}
+		bindings := New().Bind(&user)
+		assert.Equal(t, "outer", bindings["id"])
+		assert.Len(t, bindings, 3)
+	})
+
+	t.Log("---- Pass ----")
+}
+
+func TestInsertUpdateStruct(t *testing.T) {
+	type User struct {
+		Base
+		Name  string `db:"name"`
+		Email string `db:"email"`
+	}
+
+	user := User{Base: Base{ID: 1, CreatedAt: TestTime}, Name: TestUser, Email: "zakir@example.com"}
+
+	tests := []struct {
+		name     string
+		build    func(*Builder) *Builder
+		expected string
+		args     []interface{}
+	}{
+		{
+			name: "Insert from struct",
+			build: func(b *Builder) *Builder {
+				return b.InsertStruct("users", &user)
+			},
+			expected: "INSERT INTO users (id, created_at, name, email) VALUES ($1, $2, $3, $4)",
+			args:     []interface{}{1, TestTime, TestUser, "zakir@example.com"},
+		},
+		{
+			name: "Update from struct",
+			build: func(b *Builder) *Builder {
+				return b.UpdateStruct("users", user).Where("id = ?", 1)
+			},
+			expected: "UPDATE users SET created_at = $1, email = $2, id = $3, name = $4 WHERE id = $5",
+			args:     []interface{}{TestTime, "zakir@example.com", 1, TestUser, 1},
+		},
+	}
+
+	runBuilderTests(t, tests)
+}
+
+func TestBindNestedPrefixedStructs(t *testing.T) {
+	type User struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+	type Profile struct {
+		ID  int    `db:"id"`
+		Bio string `db:"bio"`
+	}
+	type UserRow struct {
+		User      User      `db:"user"`
+		Profile   *Profile  `db:"profile"`
+		CreatedAt time.Time `db:"created_at"`
+	}
+
+	row := UserRow{User: User{ID: 1, Name: TestUser}, CreatedAt: TestTime}
+	assert.Equal(t, map[string]interface{}{
+		"user_id":    1,
+		"user_name":  TestUser,
+		"created_at": TestTime,
+	}, New().Bind(&row))
+
+	row.Profile = &Profile{ID: 2, Bio: "gopher"}
+	bindings := New().Bind(&row)
+	assert.Equal(t, 2, bindings["profile_id"])
+	assert.Equal(t, "gopher", bindings["profile_bio"])
+
+	t.Run("Custom separator", func(t *testing.T) {
+		sep := NestedSeparator
+		NestedSeparator = "__"
+		defer func() { NestedSeparator = sep }()
+
+		assert.Equal(t, 1, New().Bind(&row)["user__id"])
+	})
+
+	t.Run("Self-referencing structs terminate", func(t *testing.T) {
+		type Tree struct {
+			ID   int   `db:"id"`
+			Next *Tree `db:"next"`
+		}
+
+		assert.NotPanics(t, func() {
+			bindings := New().Bind(&Tree{ID: 1, Next: &Tree{ID: 2}})
+			assert.Equal(t, 1, bindings["id"])
+			assert.Equal(t, 2, bindings["next_id"])
+		})
+	})
+
+	t.Log("---- Pass ----")
+}
+
+func TestExcludedFields(t *testing.T) {
+	type Post struct {
+		ID        int      `db:"id"`
+		Title     string   `db:"title"`
+		WordCount int      `db:"-"`
+		Tags      []string `db:"-"`
+		Base      `db:"-"`
+	}
+
+	post := Post{ID: 1, Title: "Hello", WordCount: 42, Tags: []string{"go"}, Base: Base{CreatedAt: TestTime}}
+
+	bindings := New().Bind(&post)
+	assert.Equal(t, map[string]interface{}{"id": 1, "title": "Hello"}, bindings)
+
+	insert := New().InsertStruct("posts", &post)
+	assert.Equal(t, "INSERT INTO posts (id, title) VALUES ($1, $2)", insert.String())
+	assert.Equal(t, []interface{}{1, "Hello"}, insert.args)
+
+	update := New().UpdateStruct("posts", &post).Where("id = ?", 1)
+	assert.Equal(t, "UPDATE posts SET id = $1, title = $2 WHERE id = $3", update.String())
+	assert.Equal(t, []interface{}{1, "Hello", 1}, update.args)
+	assert.NotContains(t, update.String(), "-")
+
+	t.Log("---- Pass ----")
+}
+
+func TestParseTag(t *testing.T) {
+	tests := []struct {
+		tag       string
+		name      string
+		omitEmpty bool
+	}{
+		{"name", "name", false},
+		{"name,omitempty", "name", true},
+		{"name,unknown,omitempty", "name", true},
+		{"name,future", "name", false},
+		{"-", "-", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		name, opts := parseTag(tt.tag)
+		assert.Equal(t, tt.name, name, tt.tag)
+		assert.Equal(t, tt.omitEmpty, opts.omitEmpty, tt.tag)
+	}
+
+	t.Log("---- Pass ----")
+}
+
+func TestOmitEmpty(t *testing.T) {
+	type User struct {
+		ID        int        `db:"id,omitempty"`
+		Name      string     `db:"name"`
+		Nickname  *string    `db:"nickname,omitempty"`
+		Tags      []string   `db:"tags,omitempty"`
+		CreatedAt time.Time  `db:"created_at,omitempty"`
+		DeletedAt *time.Time `db:"deleted_at,omitempty"`
+	}
+
+	empty := ""
+	user := User{Name: TestUser, Nickname: &empty}
+
+	assert.Equal(t, map[string]interface{}{
+		"name":     TestUser,
+		"nickname": "",
+	}, New().Bind(&user), "non-nil pointers to zero values are kept")
+
+	insert := New().InsertStruct("users", &user)
+	assert.Equal(t, "INSERT INTO users (name, nickname) VALUES ($1, $2)", insert.String())
+
+	user.ID = 5
+	user.CreatedAt = TestTime
+	update := New().UpdateStruct("users", &user).Where("id = ?", 5)
+	assert.Equal(t, "UPDATE users SET created_at = $1, id = $2, name = $3, nickname = $4 WHERE id = $5", update.String())
+
+	t.Log("---- Pass ----")
+}
+
+func TestSnakeCase(t *testing.T) {
+	tests := map[string]string{
+		"ID":          "id",
+		"Name":        "name",
+		"UserID":      "user_id",
+		"HTMLBody":    "html_body",
+		"APIKey":      "api_key",
+		"OAuth2Token": "oauth2_token",
+		"UserOAuth":   "user_oauth",
+		"Address2":    "address2",
+		"V2Name":      "v2_name",
+		"CreatedAt":   "created_at",
+		"URL":         "url",
+		"MyURLPath":   "my_url_path",
+		"already_ok":  "already_ok",
+	}
+
+	for name, expected := range tests {
+		assert.Equal(t, expected, snakeCase(name), name)
+	}
+
+	t.Log("---- Pass ----")
+}
+
+func TestDerivedColumnNames(t *testing.T) {
+	type Account struct {
+		ID        int
+		UserID    int
+		APIKey    string `db:"api_token"`
+		CreatedAt time.Time
+		Internal  string `db:"-"`
+	}
+
+	account := Account{ID: 1, UserID: 2, APIKey: "key", CreatedAt: TestTime, Internal: "x"}
+
+	assert.Equal(t, map[string]interface{}{
+		"id":         1,
+		"user_id":    2,
+		"api_token":  "key",
+		"created_at": TestTime,
+	}, New().Bind(&account))
+
+	insert := New().InsertStruct("accounts", &account)
+	assert.Equal(t, "INSERT INTO accounts (id, user_id, api_token, created_at) VALUES ($1, $2, $3, $4)", insert.String())
+
+	t.Log("---- Pass ----")
+}
+
+func TestPointerFieldNormalization(t *testing.T) {
+	type Profile struct {
+		ID        int            `db:"id"`
+		Nickname  *string        `db:"nickname"`
+		Age       *int           `db:"age"`
+		BirthDate *time.Time     `db:"birth_date"`
+		Website   sql.NullString `db:"website"`
+	}
+
+	nickname := "zak"
+	age := 30
+	set := Profile{ID: 1, Nickname: &nickname, Age: &age, BirthDate: &TestTime, Website: sql.NullString{String: "https://example.com", Valid: true}}
+	unset := Profile{ID: 2}
+
+	bindings := New().Bind(&unset)
+	assert.Nil(t, bindings["nickname"])
+	assert.Nil(t, bindings["age"])
+	assert.Nil(t, bindings["birth_date"])
+	assert.Equal(t, sql.NullString{}, bindings["website"])
+
+	db, mock, _ := setupTest(t)
+	defer db.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO profiles (id, nickname, age, birth_date, website) VALUES ($1, $2, $3, $4, $5)")).
+		WithArgs(1, "zak", 30, TestTime, "https://example.com").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO profiles (id, nickname, age, birth_date, website) VALUES ($1, $2, $3, $4, $5)")).
+		WithArgs(2, nil, nil, nil, nil).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE profiles SET age = $1, nickname = $2 WHERE id = $3")).
+		WithArgs(nil, "zak", 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	for _, p := range []Profile{set, unset} {
+		stmt, err := New().InsertStruct("profiles", &p).Prepare(db)
+		assert.NoError(t, err)
+		_, err = stmt.Exec()
+		assert.NoError(t, err)
+	}
+
+	var noAge *int
+	stmt, err := New().Update("profiles").
+		Set(map[string]interface{}{"nickname": &nickname, "age": noAge}).
+		Where("id = ?", 1).
+		Prepare(db)
+	assert.NoError(t, err)
+	_, err = stmt.Exec()
+	assert.NoError(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	t.Log("---- Pass ----")
+}
+
+// Email is stored as a single text column but kept split in Go
+type Email struct {
+	Local  string
+	Domain string
+}
+
+func (e Email) Value() (driver.Value, error) {
+	if e.Local == "" {
+		return nil, nil
+	}
+	return e.Local + "@" + e.Domain, nil
+}
+
+func (e *Email) Scan(src interface{}) error {
+	var s string
+	switch v := src.(type) {
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	case nil:
+		*e = Email{}
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into Email", src)
+	}
+	e.Local, e.Domain, _ = strings.Cut(s, "@")
+	return nil
+}
+
+// Secret only implements driver.Valuer on its pointer type
+type Secret struct {
+	Plain string
+}
+
+func (s *Secret) Value() (driver.Value, error) {
+	return "enc:" + s.Plain, nil
+}
+
+// UUID renders its zero value as the nil UUID rather than NULL
+type UUID [16]byte
+
+func (u UUID) Value() (driver.Value, error) {
+	return fmt.Sprintf("%x", u[:]), nil
+}
+
+func TestValuerFields(t *testing.T) {
+	type Member struct {
+		ID     UUID   `db:"id,omitempty"`
+		Email  Email  `db:"email,omitempty"`
+		Backup *Email `db:"backup_email"`
+		Token  Secret `db:"token"`
+	}
+
+	member := Member{Email: Email{Local: "zakir", Domain: "example.com"}, Token: Secret{Plain: "abc"}}
+
+	bindings := New().Bind(member)
+	assert.Equal(t, Email{Local: "zakir", Domain: "example.com"}, bindings["email"], "valuers are bound as-is")
+	assert.Nil(t, bindings["backup_email"])
+	assert.Contains(t, bindings, "id", "omitempty consults Value, not the zero check")
+	assert.IsType(t, &Secret{}, bindings["token"])
+
+	db, mock, _ := setupTest(t)
+	defer db.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO members (id, email, backup_email, token) VALUES ($1, $2, $3, $4)")).
+		WithArgs("00000000000000000000000000000000", "zakir@example.com", nil, "enc:abc").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT email FROM members")).
+		WillReturnRows(sqlmock.NewRows([]string{"email"}).AddRow("zakir@example.com"))
+
+	stmt, err := New().InsertStruct("members", &member).Prepare(db)
+	assert.NoError(t, err)
+	_, err = stmt.Exec()
+	assert.NoError(t, err)
+
+	var scanned Member
+	assert.NoError(t, New().Raw("SELECT email FROM members").WithDB(db).QueryRow().Scan(&scanned.Email))
+	assert.Equal(t, member.Email, scanned.Email)
+
+	noEmail := Member{}
+	assert.NotContains(t, New().Bind(&noEmail), "email", "Value returning nil counts as empty")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	t.Log("---- Pass ----")
+}
+
+func TestStructInfoCacheConcurrent(t *testing.T) {
+	type Order struct {
+		Base
+		CustomerID int
+		Total      float64 `db:"total"`
+		Note       *string `db:"note,omitempty"`
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				bindings := New().Bind(&Order{Base: Base{ID: i}, CustomerID: j})
+				assert.Equal(t, i, bindings["id"])
+				assert.Equal(t, j, bindings["customer_id"])
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	info := getStructInfo(reflect.TypeOf(Order{}))
+	assert.Same(t, info, getStructInfo(reflect.TypeOf(Order{})))
+	assert.Equal(t, 3, info.byColumn["total"])
+
+	t.Log("---- Pass ----")
+}
+
+type benchUser struct {
+	Base
+	Name      string  `db:"name"`
+	Email     string  `db:"email"`
+	Nickname  *string `db:"nickname,omitempty"`
+	Age       int
+	Country   string
+	UpdatedAt time.Time
+}
+
+func BenchmarkStructInfo(b *testing.B) {
+	typ := reflect.TypeOf(benchUser{})
+
+	b.Run("Cached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			getStructInfo(typ)
+		}
+	})
+
+	b.Run("Uncached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			buildStructInfo(typ, NestedSeparator)
+		}
+	})
+}
+
+func BenchmarkBind(b *testing.B) {
+	user := benchUser{Base: Base{ID: 1, CreatedAt: TestTime}, Name: TestUser, Email: "zakir@example.com"}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		New().Bind(&user)
+	}
+}
+
+func TestColumns(t *testing.T) {
+	type User struct {
+		Base
+		Name     string `db:"name"`
+		Email    string `db:"email,omitempty"`
+		IsActive bool
+		Posts    int `db:"-"`
+	}
+
+	expected := []string{"id", "created_at", "name", "email", "is_active"}
+	assert.Equal(t, expected, Columns(&User{}))
+	assert.Equal(t, expected, Columns(User{}))
+	assert.Equal(t, expected, Columns(&[]User{}))
+	assert.Equal(t, expected, Columns([]*User{}))
+	assert.Equal(t, []string{"u.id", "u.created_at", "u.name", "u.email", "u.is_active"}, Columns(&User{}, "u."))
+	assert.Nil(t, Columns(42))
+	assert.Nil(t, Columns(nil))
+
+	query := New().Select(Columns(&User{}, "u.")...).From("users u").String()
+	assert.Equal(t, "SELECT u.id, u.created_at, u.name, u.email, u.is_active FROM users u", query)
+
+	t.Log("---- Pass ----")
+}
+
+func TestAutomaticTimestamps(t *testing.T) {
+	type Post struct {
+		ID        int       `db:"id"`
+		Title     string    `db:"title"`
+		CreatedAt time.Time `db:"created_at"`
+		UpdatedAt time.Time `db:"updated_at,omitempty"`
+	}
+
+	clock := func() time.Time { return TestTime }
+	earlier := TestTime.Add(-time.Hour)
+
+	tests := []struct {
+		name     string
+		build    func(*Builder) *Builder
+		expected string
+		args     []interface{}
+	}{
+		{
+			name: "Insert fills zero timestamps",
+			build: func(b *Builder) *Builder {
+				return b.WithTimestamps(TimestampOptions{Now: clock}).
+					InsertStruct("posts", &Post{ID: 1, Title: "Hello"})
+			},
+			expected: "INSERT INTO posts (id, title, created_at, updated_at) VALUES ($1, $2, $3, $4)",
+			args:     []interface{}{1, "Hello", TestTime, TestTime},
+		},
+		{
+			name: "Explicit values win on insert",
+			build: func(b *Builder) *Builder {
+				return b.WithTimestamps(TimestampOptions{Now: clock}).
+					InsertStruct("posts", &Post{ID: 1, Title: "Hello", CreatedAt: earlier})
+			},
+			expected: "INSERT INTO posts (id, title, created_at, updated_at) VALUES ($1, $2, $3, $4)",
+			args:     []interface{}{1, "Hello", earlier, TestTime},
+		},
+		{
+			name: "Update always refreshes updated_at",
+			build: func(b *Builder) *Builder {
+				return b.WithTimestamps(TimestampOptions{Now: clock}).
+					UpdateStruct("posts", &Post{ID: 1, Title: "Hello", CreatedAt: earlier, UpdatedAt: earlier}).
+					Where("id = ?", 1)
+			},
+			expected: "UPDATE posts SET created_at = $1, id = $2, title = $3, updated_at = $4 WHERE id = $5",
+			args:     []interface{}{earlier, 1, "Hello", TestTime, 1},
+		},
+		{
+			name: "Database time and custom columns",
+			build: func(b *Builder) *Builder {
+				type Legacy struct {
+					ID       int       `db:"id"`
+					Inserted time.Time `db:"inserted"`
+				}
+				return b.WithTimestamps(TimestampOptions{CreatedColumn: "inserted", UseDatabaseTime: true}).
+					InsertStruct("legacy", &Legacy{ID: 1})
+			},
+			expected: "INSERT INTO legacy (id, inserted) VALUES ($1, NOW())",
+			args:     []interface{}{1},
+		},
+		{
+			name: "Disabled by default",
+			build: func(b *Builder) *Builder {
+				return b.InsertStruct("posts", &Post{ID: 1, Title: "Hello"})
+			},
+			expected: "INSERT INTO posts (id, title, created_at) VALUES ($1, $2, $3)",
+			args:     []interface{}{1, "Hello", time.Time{}},
+		},
+	}
+
+	runBuilderTests(t, tests)
+}
+
+func TestPrimaryKeyTag(t *testing.T) {
+	type Membership struct {
+		OrgID  int    `db:"org_id,pk"`
+		UserID int    `db:"user_id,pk"`
+		Role   string `db:"role"`
+	}
+
+	m := Membership{OrgID: 3, UserID: 8, Role: "admin"}
+
+	update := New().UpdateStruct("memberships", m)
+	assert.Equal(t, "UPDATE memberships SET role = $1 WHERE org_id = $2 AND user_id = $3", update.String())
+	assert.Equal(t, []interface{}{"admin", 3, 8}, update.Args())
+
+	del := New().DeleteStruct("memberships", &m)
+	assert.Equal(t, "DELETE FROM memberships WHERE org_id = $1 AND user_id = $2", del.String())
+	assert.Equal(t, []interface{}{3, 8}, del.Args())
+
+	type Versioned struct {
+		ID      int64  `db:"id,pk"`
+		Title   string `db:"title"`
+		Version int    `db:"version,lock"`
+	}
+	update = New().UpdateStruct("posts", Versioned{ID: 1, Title: "Hello", Version: 2})
+	assert.Equal(t, "UPDATE posts SET title = $1, version = version + 1 WHERE id = $2 AND version = $3", update.String())
+}
+
+func TestPrimaryKeyMissing(t *testing.T) {
+	db, _ := newMockDB(t)
+
+	type Note struct {
+		ID   int    `db:"id"`
+		Body string `db:"body"`
+	}
+
+	_, err := New().UpdateStruct("notes", Note{ID: 1, Body: "x"}).Prepare(db)
+	assert.EqualError(t, err, "UpdateStruct of toki.Note has no pk fields and no Where clause; it would update every row")
+
+	_, err = New().UpdateStruct("notes", Note{ID: 1, Body: "x"}).Where("id = ?", 1).Prepare(db)
+	assert.NoError(t, err)
+
+	type LockedNote struct {
+		Body    string `db:"body"`
+		Version int    `db:"version,lock"`
+	}
+	_, err = New().UpdateStruct("notes", LockedNote{Body: "x", Version: 1}).Prepare(db)
+	assert.Error(t, err)
+
+	_, err = New().DeleteStruct("notes", Note{ID: 1}).Prepare(db)
+	assert.EqualError(t, err, "DeleteStruct needs a field of toki.Note tagged pk")
+
+	_, err = NewBatch().Add(New().UpdateStruct("notes", Note{ID: 1})).Queries()
+	assert.Error(t, err)
+}
@@ -0,0 +1,419 @@
+package toki
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// builderJSON is the JSON form of a Builder
+type builderJSON struct {
+	Kind    string      `json:"kind"`
+	Table   string      `json:"table,omitempty"`
+	Dialect string      `json:"dialect"`
+	Clauses clausesJSON `json:"clauses"`
+	Args    []argJSON   `json:"args,omitempty"`
+	// ArgIndex is the number of the last placeholder written
+	ArgIndex int         `json:"argIndex,omitempty"`
+	Rows     [][]argJSON `json:"rows,omitempty"`
+	Page     *pageJSON   `json:"page,omitempty"`
+	Fetch    bool        `json:"fetch,omitempty"`
+	Primary  bool        `json:"primary,omitempty"`
+	Lock     *lockJSON   `json:"lock,omitempty"`
+	Keyless  string      `json:"keyless,omitempty"`
+
+	TimeOptions    *timeOptionsJSON  `json:"timeOptions,omitempty"`
+	Timestamps     *timestampsJSON   `json:"timestamps,omitempty"`
+	ColumnMap      map[string]string `json:"columnMap,omitempty"`
+	IdentifierCase string            `json:"identifierCase,omitempty"`
+	Comments       [][2]string       `json:"comments,omitempty"`
+	Redact         bool              `json:"redact,omitempty"`
+}
+
+type clausesJSON struct {
+	With       []string `json:"with,omitempty"`
+	Head       string   `json:"head,omitempty"`
+	Set        []string `json:"set,omitempty"`
+	Values     []string `json:"values,omitempty"`
+	Selected   bool     `json:"selected,omitempty"`
+	Hints      []string `json:"hints,omitempty"`
+	Distinct   bool     `json:"distinct,omitempty"`
+	Columns    []string `json:"columns,omitempty"`
+	ColumnArgs int      `json:"columnArgs,omitempty"`
+	From       []string `json:"from,omitempty"`
+	Joins      []string `json:"joins,omitempty"`
+	Where      []string `json:"where,omitempty"`
+	GroupBy    []string `json:"groupBy,omitempty"`
+	Having     []string `json:"having,omitempty"`
+	OrderBy    []string `json:"orderBy,omitempty"`
+	Conflict   string   `json:"conflict,omitempty"`
+	Returning  []string `json:"returning,omitempty"`
+}
+
+type pageJSON struct {
+	Limit  int `json:"limit,omitempty"`
+	Offset int `json:"offset,omitempty"`
+}
+
+type lockJSON struct {
+	Column string `json:"column"`
+	Bumped bool   `json:"bumped,omitempty"`
+}
+
+type timeOptionsJSON struct {
+	Location string `json:"location,omitempty"`
+	BindUTC  bool   `json:"bindUTC,omitempty"`
+}
+
+type timestampsJSON struct {
+	CreatedColumn   string `json:"createdColumn,omitempty"`
+	UpdatedColumn   string `json:"updatedColumn,omitempty"`
+	UseDatabaseTime bool   `json:"useDatabaseTime,omitempty"`
+}
+
+// argJSON is an arg with the name of its Go type, so it is restored as the
+// same type
+type argJSON struct {
+	Type   string          `json:"type"`
+	Value  json.RawMessage `json:"value,omitempty"`
+	Column string          `json:"column,omitempty"`
+}
+
+// argTypes are the arg types a Builder can be marshaled with, by the name
+// they are marshaled under
+var argTypes = map[string]reflect.Type{
+	"bool":    reflect.TypeOf(false),
+	"string":  reflect.TypeOf(""),
+	"int":     reflect.TypeOf(int(0)),
+	"int8":    reflect.TypeOf(int8(0)),
+	"int16":   reflect.TypeOf(int16(0)),
+	"int32":   reflect.TypeOf(int32(0)),
+	"int64":   reflect.TypeOf(int64(0)),
+	"uint":    reflect.TypeOf(uint(0)),
+	"uint8":   reflect.TypeOf(uint8(0)),
+	"uint16":  reflect.TypeOf(uint16(0)),
+	"uint32":  reflect.TypeOf(uint32(0)),
+	"uint64":  reflect.TypeOf(uint64(0)),
+	"float32": reflect.TypeOf(float32(0)),
+	"float64": reflect.TypeOf(float64(0)),
+	"bytes":   bytesType,
+	"time":    reflect.TypeOf(time.Time{}),
+}
+
+// argTypeNames maps the types of argTypes back to their names
+var argTypeNames = func() map[reflect.Type]string {
+	names := make(map[reflect.Type]string, len(argTypes))
+	for name, t := range argTypes {
+		names[t] = name
+	}
+	return names
+}()
+
+// builtinDialects are the dialects a marshaled Builder can name
+var builtinDialects = []*Dialect{Postgres, SQLite, MySQL, SQLServer}
+
+// MarshalJSON encodes the statement built so far, to cache it or send it
+// to another process: its clauses, args, dialect and options such as the
+// column map, time options and comment tags. UnmarshalJSON restores a
+// builder rendering the same SQL with the same args.
+//
+// The transaction, context, logger, metrics, slow query callback and
+// middleware are not encoded; set them again on the restored builder. The
+// dialect the builder renders for is recorded by name, so the restored
+// builder keeps it even when the package-wide dialect differs.
+//
+// Args must be nil, a bool, string, integer, float, []byte, time.Time or
+// a toki.JSON value; any other type, including Sensitive values, fails
+// with an error. Times keep their instant and UTC offset but not their
+// location name. A builder holding the error of a failed chained call,
+// timestamp options with a Now function or an identifier case other than
+// PreserveCase and LowerCase cannot be marshaled either.
+func (b *Builder) MarshalJSON() ([]byte, error) {
+	if b.err != nil {
+		return nil, fmt.Errorf("cannot marshal a builder that failed: %w", b.err)
+	}
+
+	d := b.Dialect()
+	if builtinDialect(d.name) == nil {
+		return nil, fmt.Errorf("cannot marshal dialect %q", d.name)
+	}
+	c := &b.clauses
+	out := builderJSON{
+		Kind:    b.kind.String(),
+		Table:   b.table,
+		Dialect: d.name,
+		Clauses: clausesJSON{
+			With:       c.with,
+			Head:       c.head,
+			Set:        c.set,
+			Values:     c.values,
+			Selected:   c.selected,
+			Hints:      c.hints,
+			Distinct:   c.distinct,
+			Columns:    c.columns,
+			ColumnArgs: c.columnArgs,
+			From:       c.from,
+			Joins:      c.joins,
+			Where:      c.where,
+			GroupBy:    c.groupBy,
+			Having:     c.having,
+			OrderBy:    c.orderBy,
+			Conflict:   c.conflict,
+			Returning:  c.returning,
+		},
+		ArgIndex:  b.argIndex,
+		Fetch:     b.fetch,
+		Primary:   b.primary,
+		Keyless:   b.keyless,
+		ColumnMap: b.columnMap,
+		Redact:    b.hooks.redact,
+	}
+
+	var err error
+	if out.Args, err = marshalArgs(b.args, "arg"); err != nil {
+		return nil, err
+	}
+	for i, row := range b.rows {
+		encoded, err := marshalArgs(row, fmt.Sprintf("row %d value", i+1))
+		if err != nil {
+			return nil, err
+		}
+		out.Rows = append(out.Rows, encoded)
+	}
+
+	if b.page != nil {
+		out.Page = &pageJSON{Limit: b.page.limit, Offset: b.page.offset}
+	}
+	if b.lock != nil {
+		out.Lock = &lockJSON{Column: b.lock.column, Bumped: b.lock.bumped}
+	}
+	if opts := b.timeOptions; opts != nil {
+		out.TimeOptions = &timeOptionsJSON{BindUTC: opts.BindUTC}
+		if opts.Location != nil {
+			name := opts.Location.String()
+			if _, err := time.LoadLocation(name); err != nil {
+				return nil, fmt.Errorf("cannot marshal time location %q: %w", name, err)
+			}
+			out.TimeOptions.Location = name
+		}
+	}
+	if opts := b.timestamps; opts != nil {
+		if opts.Now != nil {
+			return nil, errors.New("cannot marshal TimestampOptions with a Now function")
+		}
+		out.Timestamps = &timestampsJSON{
+			CreatedColumn:   opts.CreatedColumn,
+			UpdatedColumn:   opts.UpdatedColumn,
+			UseDatabaseTime: opts.UseDatabaseTime,
+		}
+	}
+	if c := b.identifierCase(); c != nil {
+		name, ok := identifierCaseName(c)
+		if !ok {
+			return nil, errors.New("cannot marshal an identifier case other than PreserveCase and LowerCase")
+		}
+		out.IdentifierCase = name
+	}
+	for _, tag := range b.hooks.comments {
+		out.Comments = append(out.Comments, [2]string{tag.key, tag.value})
+	}
+
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON replaces the builder with one encoded by MarshalJSON
+func (b *Builder) UnmarshalJSON(data []byte) error {
+	var in builderJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	kind, ok := statementKind(in.Kind)
+	if !ok {
+		return fmt.Errorf("unknown statement kind %q", in.Kind)
+	}
+	dialect := builtinDialect(in.Dialect)
+	if dialect == nil {
+		return fmt.Errorf("unknown dialect %q", in.Dialect)
+	}
+
+	c := in.Clauses
+	restored := Builder{
+		clauses: clauses{
+			with:       c.With,
+			head:       c.Head,
+			set:        c.Set,
+			values:     c.Values,
+			selected:   c.Selected,
+			hints:      c.Hints,
+			distinct:   c.Distinct,
+			columns:    c.Columns,
+			columnArgs: c.ColumnArgs,
+			from:       c.From,
+			joins:      c.Joins,
+			where:      c.Where,
+			groupBy:    c.GroupBy,
+			having:     c.Having,
+			orderBy:    c.OrderBy,
+			conflict:   c.Conflict,
+			returning:  c.Returning,
+		},
+		argIndex:  in.ArgIndex,
+		table:     in.Table,
+		kind:      kind,
+		dialect:   dialect,
+		fetch:     in.Fetch,
+		primary:   in.Primary,
+		keyless:   in.Keyless,
+		columnMap: in.ColumnMap,
+	}
+
+	var err error
+	if restored.args, err = unmarshalArgs(in.Args); err != nil {
+		return err
+	}
+	for _, row := range in.Rows {
+		values, err := unmarshalArgs(row)
+		if err != nil {
+			return err
+		}
+		restored.rows = append(restored.rows, values)
+	}
+
+	if in.Page != nil {
+		restored.page = &pagination{limit: in.Page.Limit, offset: in.Page.Offset}
+	}
+	if in.Lock != nil {
+		restored.lock = &versionLock{column: in.Lock.Column, bumped: in.Lock.Bumped}
+	}
+	if opts := in.TimeOptions; opts != nil {
+		restored.timeOptions = &TimeOptions{BindUTC: opts.BindUTC}
+		if opts.Location != "" {
+			if restored.timeOptions.Location, err = time.LoadLocation(opts.Location); err != nil {
+				return err
+			}
+		}
+	}
+	if opts := in.Timestamps; opts != nil {
+		restored.timestamps = &TimestampOptions{
+			CreatedColumn:   opts.CreatedColumn,
+			UpdatedColumn:   opts.UpdatedColumn,
+			UseDatabaseTime: opts.UseDatabaseTime,
+		}
+	}
+	switch in.IdentifierCase {
+	case "":
+	case "preserve":
+		restored.identCase = PreserveCase
+	case "lower":
+		restored.identCase = LowerCase
+	default:
+		return fmt.Errorf("unknown identifier case %q", in.IdentifierCase)
+	}
+	for _, tag := range in.Comments {
+		restored.hooks.comments = append(restored.hooks.comments, commentTag{tag[0], tag[1]})
+	}
+	restored.hooks.redact = in.Redact
+
+	*b = restored
+	return nil
+}
+
+// marshalArgs encodes args, naming them what in errors
+func marshalArgs(args []interface{}, what string) ([]argJSON, error) {
+	out := make([]argJSON, len(args))
+	for i, arg := range args {
+		value := arg
+		switch v := arg.(type) {
+		case nil:
+			out[i].Type = "null"
+			continue
+		case jsonValue:
+			out[i].Type = "json"
+			out[i].Column = v.column
+			value = v.value
+		case SensitiveValue:
+			return nil, fmt.Errorf("cannot marshal %s %d: Sensitive values are not marshaled", what, i+1)
+		default:
+			name, ok := argTypeNames[reflect.TypeOf(arg)]
+			if !ok {
+				return nil, fmt.Errorf("cannot marshal %s %d of type %T: only nil, bool, string, integer, float, []byte, time.Time and toki.JSON args are supported", what, i+1, arg)
+			}
+			out[i].Type = name
+		}
+
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("cannot marshal %s %d: %w", what, i+1, err)
+		}
+		out[i].Value = data
+	}
+	return out, nil
+}
+
+// unmarshalArgs decodes args encoded by marshalArgs
+func unmarshalArgs(in []argJSON) ([]interface{}, error) {
+	if len(in) == 0 {
+		return nil, nil
+	}
+	args := make([]interface{}, len(in))
+	for i, arg := range in {
+		switch arg.Type {
+		case "null":
+			continue
+		case "json":
+			j := jsonValue{column: arg.Column}
+			// a nil value was bound as NULL and is marshaled as null
+			if string(arg.Value) != "null" {
+				j.value = arg.Value
+			}
+			args[i] = j
+			continue
+		}
+
+		t, ok := argTypes[arg.Type]
+		if !ok {
+			return nil, fmt.Errorf("arg %d has unknown type %q", i+1, arg.Type)
+		}
+		v := reflect.New(t)
+		if err := json.Unmarshal(arg.Value, v.Interface()); err != nil {
+			return nil, fmt.Errorf("arg %d: %w", i+1, err)
+		}
+		args[i] = v.Elem().Interface()
+	}
+	return args, nil
+}
+
+// statementKind returns the kind named name by StatementKind.String
+func statementKind(name string) (StatementKind, bool) {
+	for k := KindUnknown; k <= KindMerge; k++ {
+		if k.String() == name {
+			return k, true
+		}
+	}
+	return KindUnknown, false
+}
+
+// builtinDialect returns the built-in dialect named name, or nil
+func builtinDialect(name string) *Dialect {
+	for _, d := range builtinDialects {
+		if d.name == name {
+			return d
+		}
+	}
+	return nil
+}
+
+// identifierCaseName returns the name c is marshaled under, when it is
+// PreserveCase or LowerCase
+func identifierCaseName(c IdentifierCase) (string, bool) {
+	switch reflect.ValueOf(c).Pointer() {
+	case reflect.ValueOf(PreserveCase).Pointer():
+		return "preserve", true
+	case reflect.ValueOf(LowerCase).Pointer():
+		return "lower", true
+	}
+	return "", false
+}
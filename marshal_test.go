@@ -0,0 +1,130 @@
+package toki
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func roundTrip(t *testing.T, b *Builder) *Builder {
+	t.Helper()
+	data, err := json.Marshal(b)
+	if !assert.NoError(t, err) {
+		return nil
+	}
+	restored := New()
+	if !assert.NoError(t, json.Unmarshal(data, restored)) {
+		return nil
+	}
+	return restored
+}
+
+func TestMarshalJSON(t *testing.T) {
+	t.Run("Select", func(t *testing.T) {
+		b := New().
+			With("recent", New().Select("id").From("orders").Where("created_at > ?", TestTime)).
+			Select("u.id", "u.email").
+			From("users u").
+			Join("recent r", "r.id = u.id").
+			Where("u.status = ?", "active").
+			AndWhere("u.score > ?", 1.5).
+			AndWhere("u.flags = ?", int8(3)).
+			AndWhere("u.avatar = ?", []byte("png")).
+			AndWhere("u.deleted_at IS ?", nil).
+			GroupBy("u.id", "u.email").
+			Having("COUNT(r.id) > ?", 2).
+			OrderBy("u.id DESC").
+			Limit(10).
+			Offset(20).
+			WithComment("route", "/users")
+
+		restored := roundTrip(t, b)
+		assert.Equal(t, b.String(), restored.String())
+		assert.Equal(t, b.args, restored.args)
+		assert.Equal(t, KindSelect, restored.Kind())
+		assert.Equal(t, b.hooks.comments, restored.hooks.comments)
+	})
+
+	t.Run("Keeps dialect", func(t *testing.T) {
+		SetDialect(SQLServer)
+		b := New().Select("id").From("users").OrderBy("id").Limit(5)
+		data, err := json.Marshal(b)
+		SetDialect(nil)
+		assert.NoError(t, err)
+
+		restored := New()
+		assert.NoError(t, json.Unmarshal(data, restored))
+		assert.Equal(t, b.WithDialect(SQLServer).String(), restored.String())
+		assert.Equal(t, SQLServer, restored.Dialect())
+	})
+
+	t.Run("Continues building", func(t *testing.T) {
+		template := New().Select("*").From("users").Where("status = ?", "active")
+		restored := roundTrip(t, template)
+		restored.AndWhere("age > ?", 18).Limit(10)
+		assert.Equal(t, "SELECT * FROM users WHERE status = $1 AND age > $2 LIMIT $3", restored.String())
+		assert.Equal(t, []interface{}{"active", 18, 10}, restored.args)
+	})
+
+	t.Run("Insert", func(t *testing.T) {
+		b := New().Insert("events", "name", "payload", "at").
+			Values("signup", JSON(map[string]int{"plan": 2}), TestTime).
+			Values("login", JSON(nil), TestTime.In(time.FixedZone("WIB", 7*3600))).
+			Returning("id")
+
+		restored := roundTrip(t, b)
+		assert.Equal(t, b.String(), restored.String())
+		assert.Len(t, restored.rows, 2)
+		for i, arg := range b.args {
+			want, _ := driver.DefaultParameterConverter.ConvertValue(arg)
+			got, _ := driver.DefaultParameterConverter.ConvertValue(restored.args[i])
+			if at, ok := want.(time.Time); ok {
+				assert.True(t, at.Equal(got.(time.Time)))
+				continue
+			}
+			assert.Equal(t, want, got)
+		}
+	})
+
+	t.Run("Options", func(t *testing.T) {
+		b := New().
+			WithTimeOptions(TimeOptions{Location: time.UTC, BindUTC: true}).
+			WithTimestamps(TimestampOptions{UseDatabaseTime: true}).
+			WithColumnMap(map[string]string{"Email": "email_address"}).
+			WithIdentifierCase(LowerCase).
+			RedactArgs().
+			Select("ID").From("Users")
+
+		restored := roundTrip(t, b)
+		assert.Equal(t, `SELECT id FROM users`, restored.String())
+		assert.Equal(t, b.timeOptions, restored.timeOptions)
+		assert.Equal(t, b.timestamps, restored.timestamps)
+		assert.Equal(t, b.columnMap, restored.columnMap)
+		assert.True(t, restored.hooks.redact)
+		assert.Equal(t, "SELECT id FROM users ORDER BY name", restored.OrderBy("Name").String())
+	})
+
+	t.Run("Unsupported arg", func(t *testing.T) {
+		type status string
+		_, err := json.Marshal(New().Select("*").From("users").Where("status = ?", status("active")))
+		assert.ErrorContains(t, err, "cannot marshal arg 1 of type toki.status")
+	})
+
+	t.Run("Sensitive arg", func(t *testing.T) {
+		_, err := json.Marshal(New().Select("*").From("users").Where("token = ?", Sensitive("secret")))
+		assert.ErrorContains(t, err, "Sensitive values are not marshaled")
+	})
+
+	t.Run("Failed builder", func(t *testing.T) {
+		_, err := json.Marshal(New().Select("*").From("users").From("teams"))
+		assert.ErrorContains(t, err, "cannot marshal a builder that failed: FROM already set")
+	})
+
+	t.Run("Unknown dialect", func(t *testing.T) {
+		err := json.Unmarshal([]byte(`{"kind":"SELECT","dialect":"oracle","clauses":{}}`), New())
+		assert.EqualError(t, err, `unknown dialect "oracle"`)
+	})
+}
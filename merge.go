@@ -0,0 +1,277 @@
+package toki
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ValuesTable is an inline table of rows, usable as a MERGE source. Each row
+// holds one value per column.
+type ValuesTable struct {
+	Columns []string
+	Rows    [][]interface{}
+}
+
+type mergeKind int
+
+const (
+	mergeUpdate mergeKind = iota
+	mergeDelete
+	mergeInsert
+)
+
+type mergeClause struct {
+	kind      mergeKind
+	set       map[string]interface{}
+	columns   []string
+	values    []interface{}
+	condition string
+	args      []interface{}
+}
+
+// MergeBuilder builds a MERGE statement. WHEN clauses are rendered in the
+// order they are added, which is the order the database tries them in.
+type MergeBuilder struct {
+	b       *Builder
+	into    string
+	source  interface{}
+	alias   string
+	on      string
+	clauses []mergeClause
+}
+
+// Merge starts a MERGE into a table. It runs on the builder's transaction
+// when it has one and renders for the builder's dialect.
+func (b *Builder) Merge(into string) *MergeBuilder {
+	return &MergeBuilder{b: b, into: into}
+}
+
+// Using sets the rows merged, joined to the target by the on condition.
+// source is a table name, a SELECT *Builder or a ValuesTable, and alias
+// names it in the on condition and the WHEN clauses.
+func (m *MergeBuilder) Using(source interface{}, alias, on string) *MergeBuilder {
+	m.source = source
+	m.alias = alias
+	m.on = on
+	return m
+}
+
+// WhenMatchedUpdate updates matched rows. Values are bound as args; use Expr
+// to refer to the source, as in Expr("s.name").
+func (m *MergeBuilder) WhenMatchedUpdate(set map[string]interface{}) *MergeBuilder {
+	m.clauses = append(m.clauses, mergeClause{kind: mergeUpdate, set: set})
+	return m
+}
+
+// WhenMatchedDelete deletes matched rows for which condition holds, or all
+// matched rows when it is empty
+func (m *MergeBuilder) WhenMatchedDelete(condition string, args ...interface{}) *MergeBuilder {
+	m.clauses = append(m.clauses, mergeClause{kind: mergeDelete, condition: condition, args: args})
+	return m
+}
+
+// WhenNotMatchedInsert inserts the source rows without a match. Values are
+// bound as args; use Expr to refer to the source.
+func (m *MergeBuilder) WhenNotMatchedInsert(columns []string, values []interface{}) *MergeBuilder {
+	m.clauses = append(m.clauses, mergeClause{kind: mergeInsert, columns: columns, values: values})
+	return m
+}
+
+// String builds the statement without validating it
+func (m *MergeBuilder) String() string {
+	query, _ := m.render()
+	return query
+}
+
+// Args returns the args bound by the statement, in placeholder order
+func (m *MergeBuilder) Args() []interface{} {
+	_, args := m.render()
+	return args
+}
+
+// Validate checks the statement and rejects dialects without MERGE
+func (m *MergeBuilder) Validate() error {
+	dialect := m.b.Dialect()
+	if !dialect.merge {
+		return fmt.Errorf("MERGE is not supported by the %s dialect", dialect)
+	}
+	if err := checkIdent("table", m.into); err != nil {
+		return err
+	}
+
+	switch src := m.source.(type) {
+	case nil:
+		return fmt.Errorf("MERGE into %s has no USING source", m.into)
+	case string:
+		if err := checkIdent("table", src); err != nil {
+			return err
+		}
+	case *Builder:
+		if src.kind != KindSelect {
+			return fmt.Errorf("MERGE source must be a SELECT, got %s", src.kind)
+		}
+	case ValuesTable, *ValuesTable:
+		vt := valuesTable(src)
+		if len(vt.Columns) == 0 || len(vt.Rows) == 0 {
+			return fmt.Errorf("MERGE source for %s has no rows", m.into)
+		}
+		for i, row := range vt.Rows {
+			if len(row) != len(vt.Columns) {
+				return fmt.Errorf("MERGE source row %d has %d value(s), want %d", i, len(row), len(vt.Columns))
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported MERGE source %T", m.source)
+	}
+
+	if err := checkIdent("alias", m.alias); err != nil {
+		return err
+	}
+	if strings.TrimSpace(m.on) == "" {
+		return fmt.Errorf("MERGE into %s has no ON condition", m.into)
+	}
+	if len(m.clauses) == 0 {
+		return fmt.Errorf("MERGE into %s has no WHEN clauses", m.into)
+	}
+
+	for _, c := range m.clauses {
+		switch c.kind {
+		case mergeUpdate:
+			if len(c.set) == 0 {
+				return fmt.Errorf("MERGE into %s updates no columns", m.into)
+			}
+		case mergeInsert:
+			if len(c.columns) == 0 || len(c.columns) != len(c.values) {
+				return fmt.Errorf("MERGE into %s inserts %d column(s) with %d value(s)", m.into, len(c.columns), len(c.values))
+			}
+		case mergeDelete:
+			if n := strings.Count(c.condition, "?"); n != len(c.args) {
+				return fmt.Errorf("MERGE condition expects %d arg(s), got %d: %w", n, len(c.args), ErrArgCount)
+			}
+		}
+	}
+	return nil
+}
+
+// Prepare validates the statement and creates a prepared statement for it
+func (m *MergeBuilder) Prepare(db *sql.DB) (*Stmt, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+
+	d := m.b.derive()
+	d.kind = KindMerge
+	d.table = m.into
+	query, args := m.render()
+	d.changed()
+	d.clauses.head = query
+	d.args = args
+	return d.Prepare(db)
+}
+
+// Exec validates and executes the statement
+func (m *MergeBuilder) Exec(ctx context.Context, db *sql.DB) (sql.Result, error) {
+	stmt, err := m.Prepare(db)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.ExecContext(ctx)
+}
+
+// render builds the statement, numbering placeholders across the source
+// and the WHEN clauses
+func (m *MergeBuilder) render() (string, []interface{}) {
+	r := &Builder{dialect: m.b.dialect}
+
+	var buf []byte
+	buf = append(buf, "MERGE INTO "+m.into+" USING "...)
+
+	switch src := m.source.(type) {
+	case string:
+		buf = append(buf, src...)
+	case *Builder:
+		buf = append(buf, '(')
+		buf = append(buf, shiftPlaceholders(src.statement(), r.argIndex)...)
+		buf = append(buf, ')')
+		for _, arg := range src.args {
+			r.addArg(arg)
+		}
+		r.argIndex += len(src.args)
+	case ValuesTable, *ValuesTable:
+		vt := valuesTable(src)
+		buf = append(buf, "(VALUES "...)
+		for i, row := range vt.Rows {
+			if i > 0 {
+				buf = append(buf, ", "...)
+			}
+			buf = append(buf, '(')
+			for j, val := range row {
+				if j > 0 {
+					buf = append(buf, ", "...)
+				}
+				// the first row types the columns, see updateFromValues
+				buf = r.appendValue(buf, val, i == 0)
+			}
+			buf = append(buf, ')')
+		}
+		buf = append(buf, ')')
+	}
+
+	buf = append(buf, " AS "+m.alias...)
+	if vt := valuesTable(m.source); vt != nil {
+		buf = append(buf, " ("+strings.Join(vt.Columns, ", ")+")"...)
+	}
+	buf = append(buf, " ON "+m.on...)
+
+	for _, c := range m.clauses {
+		switch c.kind {
+		case mergeUpdate:
+			buf = append(buf, " WHEN MATCHED THEN UPDATE SET "...)
+			columns := make([]string, 0, len(c.set))
+			for col := range c.set {
+				columns = append(columns, col)
+			}
+			sort.Strings(columns)
+			for i, col := range columns {
+				if i > 0 {
+					buf = append(buf, ", "...)
+				}
+				buf = append(buf, col+" = "...)
+				buf = r.appendValue(buf, c.set[col], false)
+			}
+		case mergeDelete:
+			buf = append(buf, " WHEN MATCHED"...)
+			if c.condition != "" {
+				buf = append(buf, " AND "...)
+				buf = append(buf, r.convertPlaceholders(c.condition)...)
+				r.appendArgs(c.args)
+			}
+			buf = append(buf, " THEN DELETE"...)
+		case mergeInsert:
+			buf = append(buf, " WHEN NOT MATCHED THEN INSERT ("+strings.Join(c.columns, ", ")+") VALUES ("...)
+			for i, val := range c.values {
+				if i > 0 {
+					buf = append(buf, ", "...)
+				}
+				buf = r.appendValue(buf, val, false)
+			}
+			buf = append(buf, ')')
+		}
+	}
+
+	return string(buf), r.args
+}
+
+// valuesTable returns source as a *ValuesTable, or nil when it is not one
+func valuesTable(source interface{}) *ValuesTable {
+	switch src := source.(type) {
+	case ValuesTable:
+		return &src
+	case *ValuesTable:
+		return src
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package toki
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerge(t *testing.T) {
+	source := New().Select("id", "name", "price").From("staged_products").Where("batch = ?", 42)
+
+	m := New().Merge("products").
+		Using(source, "s", "products.id = s.id").
+		WhenMatchedDelete("s.price < ?", 0).
+		WhenMatchedUpdate(map[string]interface{}{"name": Raw("s.name"), "price": Raw("s.price"), "synced": true}).
+		WhenNotMatchedInsert([]string{"id", "name", "price", "origin"}, []interface{}{Raw("s.id"), Raw("s.name"), Raw("s.price"), "sync"})
+	assert.NoError(t, m.Validate())
+	assert.Equal(t, "MERGE INTO products USING (SELECT id, name, price FROM staged_products WHERE batch = $1) AS s ON products.id = s.id "+
+		"WHEN MATCHED AND s.price < $2 THEN DELETE "+
+		"WHEN MATCHED THEN UPDATE SET name = s.name, price = s.price, synced = $3 "+
+		"WHEN NOT MATCHED THEN INSERT (id, name, price, origin) VALUES (s.id, s.name, s.price, $4)", m.String())
+	assert.Equal(t, []interface{}{42, 0, true, "sync"}, m.Args())
+
+	m = New().Merge("products").
+		Using(ValuesTable{Columns: []string{"id", "price"}, Rows: [][]interface{}{{1, 9.5}, {2, 12.0}}}, "s", "products.id = s.id").
+		WhenMatchedUpdate(map[string]interface{}{"price": Raw("s.price")}).
+		WhenNotMatchedInsert([]string{"id", "price"}, []interface{}{Raw("s.id"), Raw("s.price")})
+	assert.NoError(t, m.Validate())
+	assert.Equal(t, "MERGE INTO products USING (VALUES ($1::bigint, $2::double precision), ($3, $4)) AS s (id, price) ON products.id = s.id "+
+		"WHEN MATCHED THEN UPDATE SET price = s.price "+
+		"WHEN NOT MATCHED THEN INSERT (id, price) VALUES (s.id, s.price)", m.String())
+	assert.Equal(t, []interface{}{1, 9.5, 2, 12.0}, m.Args())
+}
+
+func TestMergeExec(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec("MERGE INTO products USING staged AS s ON products.id = s.id WHEN MATCHED AND s.gone = $1 THEN DELETE").
+		WithArgs(true).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	res, err := New().Merge("products").
+		Using("staged", "s", "products.id = s.id").
+		WhenMatchedDelete("s.gone = ?", true).
+		Exec(context.Background(), db)
+	assert.NoError(t, err)
+	n, _ := res.RowsAffected()
+	assert.Equal(t, int64(3), n)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMergeValidate(t *testing.T) {
+	update := map[string]interface{}{"price": Raw("s.price")}
+
+	tests := []struct {
+		name  string
+		merge *MergeBuilder
+		err   string
+	}{
+		{"dialect", New().WithDialect(SQLite).Merge("products").Using("staged", "s", "products.id = s.id").WhenMatchedUpdate(update),
+			"MERGE is not supported by the sqlite dialect"},
+		{"no source", New().Merge("products").WhenMatchedUpdate(update),
+			"MERGE into products has no USING source"},
+		{"source kind", New().Merge("products").Using(New().Delete("staged"), "s", "products.id = s.id").WhenMatchedUpdate(update),
+			"MERGE source must be a SELECT, got DELETE"},
+		{"source type", New().Merge("products").Using(42, "s", "products.id = s.id").WhenMatchedUpdate(update),
+			"unsupported MERGE source int"},
+		{"row length", New().Merge("products").Using(&ValuesTable{Columns: []string{"id", "price"}, Rows: [][]interface{}{{1}}}, "s", "products.id = s.id").WhenMatchedUpdate(update),
+			"MERGE source row 0 has 1 value(s), want 2"},
+		{"no on", New().Merge("products").Using("staged", "s", " ").WhenMatchedUpdate(update),
+			"MERGE into products has no ON condition"},
+		{"no clauses", New().Merge("products").Using("staged", "s", "products.id = s.id"),
+			"MERGE into products has no WHEN clauses"},
+		{"insert", New().Merge("products").Using("staged", "s", "products.id = s.id").WhenNotMatchedInsert([]string{"id", "price"}, []interface{}{Raw("s.id")}),
+			"MERGE into products inserts 2 column(s) with 1 value(s)"},
+		{"condition args", New().Merge("products").Using("staged", "s", "products.id = s.id").WhenMatchedDelete("s.gone = ?"),
+			"MERGE condition expects 1 arg(s), got 0: placeholder and argument count mismatch"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.EqualError(t, tt.merge.Validate(), tt.err)
+		})
+	}
+}
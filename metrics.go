@@ -0,0 +1,96 @@
+package toki
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// MetricsCollector receives the kind, table and latency of every database
+// call. Kind is "select", "insert", "update", "delete", "raw" for raw
+// queries, or "other" for transaction control and savepoints. Table is empty
+// when unknown.
+type MetricsCollector interface {
+	ObserveQuery(kind string, table string, duration time.Duration, err error)
+}
+
+// defaultMetrics is used by builders and raw queries without their own
+// collector; nil disables metrics
+var defaultMetrics MetricsCollector
+
+// SetMetricsCollector sets the package-wide metrics collector, or disables
+// metrics when m is nil. Call it during initialization, before queries run.
+func SetMetricsCollector(m MetricsCollector) {
+	defaultMetrics = m
+}
+
+// WithMetrics sets the metrics collector for statements run from this
+// builder
+func (b *Builder) WithMetrics(m MetricsCollector) *Builder {
+	b.hooks.metrics = m
+	return b
+}
+
+// WithMetrics sets the metrics collector for this raw query
+func (r *RawQuery) WithMetrics(m MetricsCollector) *RawQuery {
+	r.hooks.metrics = m
+	return r
+}
+
+// metricKind returns the MetricsCollector kind label for k
+func metricKind(k StatementKind) string {
+	if k == KindUnknown {
+		return "other"
+	}
+	return strings.ToLower(k.String())
+}
+
+// QueryStats aggregates the calls observed for one kind and table
+type QueryStats struct {
+	Count  int
+	Errors int
+	Total  time.Duration
+	Max    time.Duration
+}
+
+// MemoryMetrics is a MetricsCollector that keeps totals in memory, keyed by
+// "kind table". It is safe for concurrent use and meant for tests and
+// examples.
+type MemoryMetrics struct {
+	mu    sync.Mutex
+	stats map[string]QueryStats
+}
+
+// ObserveQuery implements MetricsCollector
+func (m *MemoryMetrics) ObserveQuery(kind string, table string, duration time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.stats == nil {
+		m.stats = make(map[string]QueryStats)
+	}
+
+	key := strings.TrimSpace(kind + " " + table)
+	s := m.stats[key]
+	s.Count++
+	if err != nil {
+		s.Errors++
+	}
+	s.Total += duration
+	if duration > s.Max {
+		s.Max = duration
+	}
+	m.stats[key] = s
+}
+
+// Stats returns a copy of the collected stats
+func (m *MemoryMetrics) Stats() map[string]QueryStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := make(map[string]QueryStats, len(m.stats))
+	for k, v := range m.stats {
+		stats[k] = v
+	}
+	return stats
+}
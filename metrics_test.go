@@ -0,0 +1,59 @@
+package toki
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	metrics := &MemoryMetrics{}
+	SetMetricsCollector(metrics)
+	defer SetMetricsCollector(nil)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO users").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO users").WillReturnError(errors.New("duplicate key"))
+	mock.ExpectQuery("SELECT count").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectCommit()
+
+	tx, err := Begin(db)
+	assert.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		stmt, err := tx.Builder().Insert("users", "name").Values("zakirkun").Prepare(db)
+		assert.NoError(t, err)
+		_, _ = stmt.Exec()
+	}
+
+	var count int
+	assert.NoError(t, tx.Raw("SELECT count(*) FROM users").Scalar(&count))
+	assert.NoError(t, tx.Commit())
+
+	stats := metrics.Stats()
+	assert.Equal(t, 2, stats["insert users"].Count)
+	assert.Equal(t, 1, stats["insert users"].Errors)
+	assert.Equal(t, 1, stats["raw"].Count)
+	assert.Equal(t, 2, stats["other"].Count)
+	assert.Len(t, stats, 3)
+
+	// a per-builder collector replaces the package-wide one
+	own := &MemoryMetrics{}
+	mock.ExpectExec("DELETE FROM users").WillReturnResult(sqlmock.NewResult(0, 1))
+	stmt, err := New().WithMetrics(own).Delete("users").Prepare(db)
+	assert.NoError(t, err)
+	_, err = stmt.Exec()
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, own.Stats()["delete users"].Count)
+	assert.Len(t, metrics.Stats(), 3)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,80 @@
+package toki
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Operation is the kind of database call a query is run with
+type Operation int
+
+const (
+	// OpExec runs a statement without returning rows
+	OpExec Operation = iota
+	// OpQuery runs a query returning rows
+	OpQuery
+	// OpQueryRow runs a query returning at most one row
+	OpQueryRow
+)
+
+// QueryInfo describes a query on its way to the database
+type QueryInfo struct {
+	SQL  string
+	Args []interface{}
+	// Kind is the statement kind when built by a Builder and KindRaw for raw
+	// queries
+	Kind StatementKind
+	// Table is the target table when known
+	Table string
+	Op    Operation
+}
+
+// Result is the outcome of a database call. Exec is set for OpExec, Rows for
+// OpQuery and Row for OpQueryRow.
+type Result struct {
+	Exec sql.Result
+	Rows *sql.Rows
+	Row  *sql.Row
+}
+
+// Handler runs a query
+type Handler func(ctx context.Context, q QueryInfo) (Result, error)
+
+// Middleware wraps query execution. It may inspect or rewrite q before
+// calling next, or return an error without calling next to veto the query.
+type Middleware func(ctx context.Context, q QueryInfo, next Handler) (Result, error)
+
+// defaultMiddleware wraps every query, outside any per-builder middleware
+var defaultMiddleware []Middleware
+
+// Use adds package-wide middleware. Middleware runs in registration order,
+// the first registered being the outermost. Call it during initialization,
+// before queries run.
+func Use(mw ...Middleware) {
+	defaultMiddleware = append(defaultMiddleware, mw...)
+}
+
+// Use adds middleware for statements run from this builder. It runs inside
+// the package-wide middleware, in registration order.
+func (b *Builder) Use(mw ...Middleware) *Builder {
+	// cap the slice so raw queries created from b never share its array
+	b.hooks.middleware = append(b.hooks.middleware[:len(b.hooks.middleware):len(b.hooks.middleware)], mw...)
+	return b
+}
+
+// Use adds middleware for this raw query
+func (r *RawQuery) Use(mw ...Middleware) *RawQuery {
+	r.hooks.middleware = append(r.hooks.middleware[:len(r.hooks.middleware):len(r.hooks.middleware)], mw...)
+	return r
+}
+
+// chain wraps h with mw so that mw[0] runs first
+func chain(h Handler, mw []Middleware) Handler {
+	for i := len(mw) - 1; i >= 0; i-- {
+		m, next := mw[i], h
+		h = func(ctx context.Context, q QueryInfo) (Result, error) {
+			return m(ctx, q, next)
+		}
+	}
+	return h
+}
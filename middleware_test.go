@@ -0,0 +1,112 @@
+package toki
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddlewareRewrite(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	var seen []QueryInfo
+	record := func(ctx context.Context, q QueryInfo, next Handler) (Result, error) {
+		seen = append(seen, q)
+		return next(ctx, q)
+	}
+	tenant := func(ctx context.Context, q QueryInfo, next Handler) (Result, error) {
+		if q.Kind == KindSelect && q.Table == "orders" {
+			q.SQL += " AND tenant_id = $2"
+			q.Args = append(q.Args, 42)
+		}
+		return next(ctx, q)
+	}
+
+	mock.ExpectQuery("SELECT \\* FROM orders WHERE status = \\$1 AND tenant_id = \\$2").
+		WithArgs("open", 42).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	stmt, err := New().Use(record, tenant).
+		Select("*").
+		From("orders").
+		Where("status = ?", "open").
+		Prepare(db)
+	assert.NoError(t, err)
+
+	var id int
+	assert.NoError(t, stmt.QueryRow().Scan(&id))
+	assert.Equal(t, 1, id)
+
+	// the first registered middleware runs first and sees the original query
+	assert.Equal(t, []QueryInfo{{
+		SQL:   "SELECT * FROM orders WHERE status = $1",
+		Args:  []interface{}{"open"},
+		Kind:  KindSelect,
+		Table: "orders",
+		Op:    OpQueryRow,
+	}}, seen)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMiddlewareVeto(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	errDDL := errors.New("DDL is disabled")
+	Use(func(ctx context.Context, q QueryInfo, next Handler) (Result, error) {
+		if strings.HasPrefix(strings.ToUpper(q.SQL), "DROP ") {
+			return Result{}, errDDL
+		}
+		return next(ctx, q)
+	})
+	defer func() { defaultMiddleware = nil }()
+
+	var order []string
+	trace := func(name string) Middleware {
+		return func(ctx context.Context, q QueryInfo, next Handler) (Result, error) {
+			order = append(order, name)
+			return next(ctx, q)
+		}
+	}
+
+	_, err = New().Raw("DROP TABLE users").Use(trace("raw")).WithDB(db).Exec()
+	assert.ErrorIs(t, err, errDDL)
+	assert.Empty(t, order)
+
+	row := New().Raw("DROP TABLE users").WithDB(db).QueryRow()
+	assert.ErrorIs(t, row.Err(), errDDL)
+
+	// prepared builders still return *sql.Row, carrying the veto
+	veto := func(ctx context.Context, q QueryInfo, next Handler) (Result, error) {
+		return Result{}, errDDL
+	}
+	stmt, err := New().Use(veto).Select("id").From("users").Where("id = ?", 1).Prepare(db)
+	assert.NoError(t, err)
+	var sqlRow *sql.Row = stmt.QueryRow()
+	var id int
+	assert.ErrorIs(t, sqlRow.Scan(&id), errDDL)
+	assert.ErrorIs(t, stmt.QueryRowContext(context.Background()).Err(), errDDL)
+	assert.ErrorIs(t, stmt.QueryRowWith().Scan(&id), ErrArgCount)
+
+	mock.ExpectExec("DELETE FROM users").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	builder := New().Use(trace("builder"))
+	_, err = builder.Raw("DELETE FROM users").Use(trace("raw")).WithDB(db).Exec()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"builder", "raw"}, order)
+	assert.Len(t, builder.hooks.middleware, 1)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,357 @@
+// Package migrate applies versioned schema migrations using toki
+// transactions. Applied versions are recorded in a table, schema_migrations
+// by default, created on first use.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/zakirkun/toki"
+)
+
+// DefaultTable is the table applied versions are recorded in
+const DefaultTable = "schema_migrations"
+
+// Migration is one versioned schema change. Down may be nil for migrations
+// that cannot be reverted.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(tx *toki.Transaction) error
+	Down    func(tx *toki.Transaction) error
+
+	// upSQL and downSQL hold the scripts of SQL migrations
+	upSQL, downSQL string
+}
+
+// Status reports whether a migration has been applied
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// MigrationError reports the migration that failed
+type MigrationError struct {
+	Version int64
+	Name    string
+	// Direction is "up" or "down"
+	Direction string
+	Err       error
+}
+
+func (e *MigrationError) Error() string {
+	return fmt.Sprintf("migration %d (%s) %s failed: %v", e.Version, e.Name, e.Direction, e.Err)
+}
+
+func (e *MigrationError) Unwrap() error { return e.Err }
+
+// Option configures a Migrator
+type Option func(*Migrator)
+
+// WithTable records applied versions in table instead of DefaultTable
+func WithTable(table string) Option {
+	return func(m *Migrator) { m.table = table }
+}
+
+// WithDialect sets the dialect of the database, which defaults to the
+// package-wide toki dialect
+func WithDialect(d *toki.Dialect) Option {
+	return func(m *Migrator) { m.dialect = d }
+}
+
+// Migrator holds the registered migrations and applies them. Each migration
+// runs in its own transaction together with the update of the versions
+// table. With a dialect that has advisory locks, such as PostgreSQL and
+// MySQL, each of those transactions first takes one, so concurrent runners
+// apply every migration once; on SQLite the single-writer lock and the
+// version primary key make a losing runner fail instead.
+type Migrator struct {
+	table      string
+	dialect    *toki.Dialect
+	migrations map[int64]Migration
+}
+
+// New creates a Migrator without migrations
+func New(opts ...Option) *Migrator {
+	m := &Migrator{
+		table:      DefaultTable,
+		dialect:    toki.New().Dialect(),
+		migrations: make(map[int64]Migration),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Add registers migrations. Versions must be positive and unique.
+func (m *Migrator) Add(migrations ...Migration) error {
+	for _, mig := range migrations {
+		if mig.Version <= 0 {
+			return fmt.Errorf("migration %q has invalid version %d", mig.Name, mig.Version)
+		}
+		if mig.Up == nil && mig.upSQL == "" {
+			return fmt.Errorf("migration %d (%s) has no up migration", mig.Version, mig.Name)
+		}
+		if prev, ok := m.migrations[mig.Version]; ok {
+			return fmt.Errorf("migration %d registered twice: %s and %s", mig.Version, prev.Name, mig.Name)
+		}
+		m.migrations[mig.Version] = mig
+	}
+	return nil
+}
+
+// AddSQL registers a migration from SQL scripts, which may hold several
+// statements. An empty down script makes the migration irreversible.
+func (m *Migrator) AddSQL(version int64, name, up, down string) error {
+	return m.Add(Migration{Version: version, Name: name, upSQL: up, downSQL: down})
+}
+
+// migrationFile matches 0001_create_users.up.sql and its .down.sql pair
+var migrationFile = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// LoadFS registers the SQL migrations in the root of fsys, named
+// <version>_<name>.up.sql with an optional <version>_<name>.down.sql. Use
+// fs.Sub to load from a subdirectory.
+func (m *Migrator) LoadFS(fsys fs.FS) error {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	found := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := migrationFile.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid migration version in %s: %w", entry.Name(), err)
+		}
+		body, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		mig := found[version]
+		if mig == nil {
+			mig = &Migration{Version: version, Name: match[2]}
+			found[version] = mig
+		} else if mig.Name != match[2] {
+			return fmt.Errorf("migration %d has files named %s and %s", version, mig.Name, match[2])
+		}
+		if match[3] == "up" {
+			mig.upSQL = string(body)
+		} else {
+			mig.downSQL = string(body)
+		}
+	}
+
+	versions := make([]int64, 0, len(found))
+	for version := range found {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+
+	for _, version := range versions {
+		if err := m.Add(*found[version]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Up applies every pending migration in version order, stopping at the
+// first failure, which is reported as a *MigrationError
+func (m *Migrator) Up(ctx context.Context, db *sql.DB) error {
+	applied, err := m.applied(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	for _, mig := range m.sorted() {
+		if _, ok := applied[mig.Version]; ok {
+			continue
+		}
+		if err := m.run(ctx, db, mig, true); err != nil {
+			return &MigrationError{Version: mig.Version, Name: mig.Name, Direction: "up", Err: err}
+		}
+	}
+	return nil
+}
+
+// Down reverts the last steps applied migrations, newest first, stopping at
+// the first failure. Reverting a migration without a down migration or one
+// that is not registered fails.
+func (m *Migrator) Down(ctx context.Context, db *sql.DB, steps int) error {
+	if steps <= 0 {
+		return fmt.Errorf("invalid number of steps %d", steps)
+	}
+
+	applied, err := m.applied(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	versions := make([]int64, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+	if steps < len(versions) {
+		versions = versions[:steps]
+	}
+
+	for _, version := range versions {
+		mig, ok := m.migrations[version]
+		if !ok {
+			return &MigrationError{Version: version, Name: applied[version].Name, Direction: "down", Err: errors.New("migration is not registered")}
+		}
+		if mig.Down == nil && mig.downSQL == "" {
+			return &MigrationError{Version: version, Name: mig.Name, Direction: "down", Err: errors.New("migration has no down migration")}
+		}
+		if err := m.run(ctx, db, mig, false); err != nil {
+			return &MigrationError{Version: version, Name: mig.Name, Direction: "down", Err: err}
+		}
+	}
+	return nil
+}
+
+// Status lists the registered migrations and any applied versions that are
+// not registered, in version order
+func (m *Migrator) Status(ctx context.Context, db *sql.DB) ([]Status, error) {
+	applied, err := m.applied(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []Status
+	for _, mig := range m.sorted() {
+		status := Status{Version: mig.Version, Name: mig.Name}
+		if row, ok := applied[mig.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = row.AppliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	for version, row := range applied {
+		if _, ok := m.migrations[version]; !ok {
+			statuses = append(statuses, Status{Version: version, Name: row.Name, Applied: true, AppliedAt: row.AppliedAt})
+		}
+	}
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// appliedRow is a row of the versions table
+type appliedRow struct {
+	Version   int64     `db:"version"`
+	Name      string    `db:"name"`
+	AppliedAt time.Time `db:"applied_at"`
+}
+
+// applied creates the versions table when needed and returns its rows by
+// version
+func (m *Migrator) applied(ctx context.Context, db *sql.DB) (map[int64]appliedRow, error) {
+	_, err := toki.New().WithDialect(m.dialect).
+		CreateTable(m.table).
+		Column("version", "BIGINT", toki.PrimaryKey()).
+		Column("name", "TEXT", toki.NotNull()).
+		Column("applied_at", "TIMESTAMPTZ", toki.NotNull()).
+		IfNotExists().
+		Exec(ctx, db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", m.table, err)
+	}
+
+	var rows []appliedRow
+	err = toki.New().RawQuery("SELECT version, name, applied_at FROM " + m.table).WithDB(db).All(&rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+
+	applied := make(map[int64]appliedRow, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+// sorted returns the registered migrations in version order
+func (m *Migrator) sorted() []Migration {
+	migrations := make([]Migration, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		migrations = append(migrations, mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations
+}
+
+// run applies or reverts mig in a transaction, skipping it when another
+// runner got there first
+func (m *Migrator) run(ctx context.Context, db *sql.DB, mig Migration, up bool) error {
+	fn := func(tx *toki.Transaction) error {
+		var count int
+		if err := m.exec(tx, "SELECT COUNT(*) FROM "+m.table+" WHERE version = $1", mig.Version).Scalar(&count); err != nil {
+			return err
+		}
+		if (count > 0) == up {
+			return nil
+		}
+
+		if up {
+			if err := apply(ctx, tx, mig.Up, mig.upSQL); err != nil {
+				return err
+			}
+			_, err := m.exec(tx, "INSERT INTO "+m.table+" (version, name, applied_at) VALUES ($1, $2, $3)",
+				mig.Version, mig.Name, time.Now().UTC()).Exec()
+			return err
+		}
+
+		if err := apply(ctx, tx, mig.Down, mig.downSQL); err != nil {
+			return err
+		}
+		_, err := m.exec(tx, "DELETE FROM "+m.table+" WHERE version = $1", mig.Version).Exec()
+		return err
+	}
+
+	if m.dialect.SupportsAdvisoryLocks() {
+		return toki.WithAdvisoryLockFor(ctx, db, m.dialect, m.lockKey(), fn)
+	}
+	return toki.RunInTx(ctx, db, nil, fn)
+}
+
+// exec returns the bookkeeping query with its $N placeholders rebound for
+// the migrator's dialect. The queries are written with matching args, so
+// rebinding cannot fail.
+func (m *Migrator) exec(tx *toki.Transaction, query string, args ...interface{}) *toki.RawQuery {
+	r, _ := tx.RawQuery(query, args...).WithDialect(m.dialect).Rebind()
+	return r
+}
+
+// apply runs fn, or script when fn is nil
+func apply(ctx context.Context, tx *toki.Transaction, fn func(tx *toki.Transaction) error, script string) error {
+	if fn != nil {
+		return fn(tx)
+	}
+	return tx.RawQuery(script).ExecScript(ctx)
+}
+
+// lockKey derives the advisory lock key from the versions table, so
+// migrators using different tables do not block each other
+func (m *Migrator) lockKey() int64 {
+	return toki.AdvisoryKey("toki/migrate:" + m.table)
+}
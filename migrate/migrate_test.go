@@ -0,0 +1,225 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/zakirkun/toki"
+)
+
+const (
+	createTable = "CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT PRIMARY KEY, name TEXT NOT NULL, applied_at TIMESTAMPTZ NOT NULL)"
+	selectRows  = "SELECT version, name, applied_at FROM schema_migrations"
+	lock        = "SELECT pg_advisory_xact_lock($1)"
+	countRows   = "SELECT COUNT(*) FROM schema_migrations WHERE version = $1"
+	insertRow   = "INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)"
+	deleteRow   = "DELETE FROM schema_migrations WHERE version = $1"
+)
+
+// newMigrator returns a Postgres migrator with a Go and a SQL migration and
+// a mock expecting the versions table to be created
+func newMigrator(t *testing.T) (*Migrator, *sql.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	t.Cleanup(func() {
+		assert.NoError(t, mock.ExpectationsWereMet())
+		db.Close()
+	})
+
+	m := New(WithDialect(toki.Postgres))
+	err = m.Add(Migration{
+		Version: 1,
+		Name:    "create_users",
+		Up: func(tx *toki.Transaction) error {
+			_, err := tx.Raw("CREATE TABLE users (id BIGSERIAL PRIMARY KEY)").Exec()
+			return err
+		},
+		Down: func(tx *toki.Transaction) error {
+			_, err := tx.Raw("DROP TABLE users").Exec()
+			return err
+		},
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, m.AddSQL(2, "add_email", "ALTER TABLE users ADD COLUMN email TEXT; CREATE INDEX users_email_idx ON users (email)", ""))
+
+	mock.ExpectExec(createTable).WillReturnResult(sqlmock.NewResult(0, 0))
+	return m, db, mock
+}
+
+func TestUp(t *testing.T) {
+	m, db, mock := newMigrator(t)
+
+	mock.ExpectQuery(selectRows).WillReturnRows(sqlmock.NewRows([]string{"version", "name", "applied_at"}))
+
+	mock.ExpectBegin()
+	mock.ExpectExec(lock).WithArgs(m.lockKey()).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(countRows).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec("CREATE TABLE users (id BIGSERIAL PRIMARY KEY)").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(insertRow).WithArgs(int64(1), "create_users", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// another runner applied version 2 while we waited for the lock
+	mock.ExpectBegin()
+	mock.ExpectExec(lock).WithArgs(m.lockKey()).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(countRows).WithArgs(int64(2)).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, m.Up(context.Background(), db))
+}
+
+func TestUpFailure(t *testing.T) {
+	m, db, mock := newMigrator(t)
+
+	mock.ExpectQuery(selectRows).WillReturnRows(sqlmock.NewRows([]string{"version", "name", "applied_at"}).
+		AddRow(1, "create_users", time.Now()))
+
+	failure := errors.New("column exists")
+	mock.ExpectBegin()
+	mock.ExpectExec(lock).WithArgs(m.lockKey()).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(countRows).WithArgs(int64(2)).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec("ALTER TABLE users ADD COLUMN email TEXT").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE INDEX users_email_idx ON users (email)").WillReturnError(failure)
+	mock.ExpectRollback()
+
+	err := m.Up(context.Background(), db)
+	assert.ErrorIs(t, err, failure)
+
+	var migErr *MigrationError
+	assert.True(t, errors.As(err, &migErr))
+	assert.Equal(t, int64(2), migErr.Version)
+	assert.Equal(t, "up", migErr.Direction)
+}
+
+func TestDown(t *testing.T) {
+	m, db, mock := newMigrator(t)
+
+	mock.ExpectQuery(selectRows).WillReturnRows(sqlmock.NewRows([]string{"version", "name", "applied_at"}).
+		AddRow(1, "create_users", time.Now()))
+
+	mock.ExpectBegin()
+	mock.ExpectExec(lock).WithArgs(m.lockKey()).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(countRows).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectExec("DROP TABLE users").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(deleteRow).WithArgs(int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, m.Down(context.Background(), db, 5))
+}
+
+func TestDownIrreversible(t *testing.T) {
+	m, db, mock := newMigrator(t)
+
+	mock.ExpectQuery(selectRows).WillReturnRows(sqlmock.NewRows([]string{"version", "name", "applied_at"}).
+		AddRow(1, "create_users", time.Now()).
+		AddRow(2, "add_email", time.Now()))
+
+	err := m.Down(context.Background(), db, 1)
+	assert.EqualError(t, err, "migration 2 (add_email) down failed: migration has no down migration")
+}
+
+func TestStatus(t *testing.T) {
+	m, db, mock := newMigrator(t)
+
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	mock.ExpectQuery(selectRows).WillReturnRows(sqlmock.NewRows([]string{"version", "name", "applied_at"}).
+		AddRow(1, "create_users", at).
+		AddRow(7, "removed_later", at))
+
+	statuses, err := m.Status(context.Background(), db)
+	assert.NoError(t, err)
+	assert.Equal(t, []Status{
+		{Version: 1, Name: "create_users", Applied: true, AppliedAt: at},
+		{Version: 2, Name: "add_email"},
+		{Version: 7, Name: "removed_later", Applied: true, AppliedAt: at},
+	}, statuses)
+}
+
+func TestAddAndLoadFS(t *testing.T) {
+	m := New()
+	assert.EqualError(t, m.Add(Migration{Version: 0, Name: "zero"}), `migration "zero" has invalid version 0`)
+	assert.EqualError(t, m.Add(Migration{Version: 1, Name: "empty"}), "migration 1 (empty) has no up migration")
+
+	fsys := fstest.MapFS{
+		"0001_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id INT)")},
+		"0001_create_users.down.sql": {Data: []byte("DROP TABLE users")},
+		"0002_add_email.up.sql":      {Data: []byte("ALTER TABLE users ADD COLUMN email TEXT")},
+		"README.md":                  {Data: []byte("not a migration")},
+	}
+	assert.NoError(t, m.LoadFS(fsys))
+	assert.Len(t, m.migrations, 2)
+	assert.Equal(t, "DROP TABLE users", m.migrations[1].downSQL)
+	assert.Equal(t, "add_email", m.migrations[2].Name)
+
+	assert.EqualError(t, m.AddSQL(2, "again", "SELECT 1", ""), "migration 2 registered twice: add_email and again")
+
+	err := New().LoadFS(fstest.MapFS{"0003_orphan.down.sql": {Data: []byte("DROP TABLE x")}})
+	assert.EqualError(t, err, "migration 3 (orphan) has no up migration")
+}
+
+func TestUpDialects(t *testing.T) {
+	up := Migration{
+		Version: 1,
+		Name:    "create_users",
+		Up: func(tx *toki.Transaction) error {
+			_, err := tx.Raw("CREATE TABLE users (id INT)").Exec()
+			return err
+		},
+	}
+
+	t.Run("MySQL", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		if err != nil {
+			t.Fatalf("Failed to create mock: %v", err)
+		}
+		defer db.Close()
+
+		m := New(WithDialect(toki.MySQL))
+		assert.NoError(t, m.Add(up))
+		name := fmt.Sprintf("toki:%d", m.lockKey())
+
+		mock.ExpectExec(createTable).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(selectRows).WillReturnRows(sqlmock.NewRows([]string{"version", "name", "applied_at"}))
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT GET_LOCK(?, -1) = 1").WithArgs(name).WillReturnRows(sqlmock.NewRows([]string{"ok"}).AddRow(1))
+		mock.ExpectQuery("SELECT COUNT(*) FROM schema_migrations WHERE version = ?").WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectExec("CREATE TABLE users (id INT)").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)").
+			WithArgs(int64(1), "create_users", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("SELECT RELEASE_LOCK(?)").WithArgs(name).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		assert.NoError(t, m.Up(context.Background(), db))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Postgres copy", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		if err != nil {
+			t.Fatalf("Failed to create mock: %v", err)
+		}
+		defer db.Close()
+
+		m := New(WithDialect(toki.Postgres.WithIdentifierCase(toki.LowerCase)))
+		assert.NoError(t, m.Add(up))
+
+		mock.ExpectExec(createTable).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(selectRows).WillReturnRows(sqlmock.NewRows([]string{"version", "name", "applied_at"}))
+		mock.ExpectBegin()
+		mock.ExpectExec(lock).WithArgs(m.lockKey()).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(countRows).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectCommit()
+
+		assert.NoError(t, m.Up(context.Background(), db))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
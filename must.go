@@ -0,0 +1,97 @@
+package toki
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// BuildError is the panic value of MustSQL, MustArgs and MustToSQL: the
+// error that kept the statement from being built, the Builder method that
+// reported it, and the SQL rendered so far
+type BuildError struct {
+	// Method is the Builder method the error came from, empty when it was
+	// found while rendering, as for OFFSET ... FETCH without ORDER BY
+	Method string
+	SQL    string
+	Err    error
+}
+
+func (e *BuildError) Error() string {
+	msg := e.Err.Error()
+	if e.Method != "" {
+		msg = e.Method + ": " + msg
+	}
+	return "toki: " + msg + " (SQL so far: " + snippet(e.SQL, 200) + ")"
+}
+
+func (e *BuildError) Unwrap() error { return e.Err }
+
+// ToSQL returns the statement and its args, or the error Prepare would
+// report for them
+func (b *Builder) ToSQL() (string, []interface{}, error) {
+	if err := b.validate(); err != nil {
+		return "", nil, err
+	}
+	return b.String(), b.Args(), nil
+}
+
+// MustToSQL is ToSQL for statements built at init time or in tests, where
+// an error is a programming mistake. It panics with a *BuildError.
+func (b *Builder) MustToSQL() (string, []interface{}) {
+	if err := b.validate(); err != nil {
+		panic(b.buildError(err))
+	}
+	return b.String(), b.Args()
+}
+
+// MustSQL returns the statement, panicking with a *BuildError when it
+// cannot be built
+func (b *Builder) MustSQL() string {
+	query, _ := b.MustToSQL()
+	return query
+}
+
+// MustArgs returns the args of the statement, panicking with a *BuildError
+// when it cannot be built
+func (b *Builder) MustArgs() []interface{} {
+	_, args := b.MustToSQL()
+	return args
+}
+
+func (b *Builder) buildError(err error) *BuildError {
+	e := &BuildError{SQL: b.String(), Err: err}
+	if err == b.err {
+		e.Method = b.errMethod
+	}
+	return e
+}
+
+// builderMethodPrefix starts the names of Builder methods in stack traces
+var builderMethodPrefix = reflect.TypeOf(Builder{}).PkgPath() + ".(*Builder)."
+
+// failingMethod returns the name of the outermost exported Builder method on
+// the stack of fail, so an error raised in a helper is reported against the
+// method that was called
+func failingMethod() string {
+	pc := make([]uintptr, 16)
+	// skip runtime.Callers, failingMethod and fail
+	n := runtime.Callers(3, pc)
+	frames := runtime.CallersFrames(pc[:n])
+
+	method := ""
+	for {
+		frame, more := frames.Next()
+		if name, ok := strings.CutPrefix(frame.Function, builderMethodPrefix); ok {
+			// closures are named Method.func1
+			name, _, _ = strings.Cut(name, ".")
+			if name != "" && name[0] >= 'A' && name[0] <= 'Z' {
+				method = name
+			}
+		}
+		if !more {
+			break
+		}
+	}
+	return method
+}
@@ -0,0 +1,46 @@
+package toki
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMustSQL(t *testing.T) {
+	b := New().Select("id").From("users").Where("id = ?", 7)
+	assert.Equal(t, "SELECT id FROM users WHERE id = $1", b.MustSQL())
+	assert.Equal(t, []interface{}{7}, b.MustArgs())
+	query, args := b.MustToSQL()
+	assert.Equal(t, "SELECT id FROM users WHERE id = $1", query)
+	assert.Equal(t, []interface{}{7}, args)
+
+	b = New().Select("id").From("users").From("accounts").Where("id = ?", 7)
+	_, _, err := b.ToSQL()
+	assert.EqualError(t, err, "FROM already set, cannot add accounts; use AddFrom for FROM a, b")
+
+	defer func() {
+		var buildErr *BuildError
+		if assert.True(t, errors.As(recover().(error), &buildErr)) {
+			assert.Equal(t, "From", buildErr.Method)
+			assert.Equal(t, "SELECT id FROM users WHERE id = $1", buildErr.SQL)
+			assert.Equal(t, "toki: From: FROM already set, cannot add accounts; use AddFrom for FROM a, b (SQL so far: SELECT id FROM users WHERE id = $1)", buildErr.Error())
+		}
+	}()
+	b.MustSQL()
+}
+
+func TestMustSQLHelperMethod(t *testing.T) {
+	// the error is raised by a helper and reported against the method called
+	b := New().Update("accounts").Set(map[string]interface{}{"owner": "zakirkun"}).Where("id = ?", 1).
+		Where("at > ?", Expr("NOW() - ? * ?", 1))
+	assert.PanicsWithError(t,
+		"toki: Where: expression expects 2 arg(s), got 1: placeholder and argument count mismatch (SQL so far: UPDATE accounts SET owner = $1 WHERE id = $2 AND at > NOW() - $3 * $4)",
+		func() { b.MustSQL() })
+
+	// errors found while rendering have no method
+	b = New().WithDialect(SQLServer).Select("*").From("users").Limit(10)
+	assert.PanicsWithError(t,
+		"toki: OFFSET ... FETCH requires an ORDER BY before it with the sqlserver dialect (SQL so far: SELECT * FROM users OFFSET 0 ROWS FETCH NEXT @p1 ROWS ONLY)",
+		func() { b.MustArgs() })
+}
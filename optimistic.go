@@ -0,0 +1,100 @@
+package toki
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ErrStaleRow is returned when an UPDATE checked with WhereVersion matches no
+// row: the row was changed or deleted since its version was read
+var ErrStaleRow = errors.New("row version has changed since it was read")
+
+// versionLock is the optimistic lock of an UPDATE: the version column, and
+// whether the SET clause increments it yet
+type versionLock struct {
+	column string
+	bumped bool
+}
+
+// withBump returns a copy of updates that also increments the version column
+func (l *versionLock) withBump(updates map[string]interface{}) map[string]interface{} {
+	l.bumped = true
+	bumped := make(map[string]interface{}, len(updates)+1)
+	for col, v := range updates {
+		bumped[col] = v
+	}
+	bumped[l.column] = Expr(l.column + " + 1")
+	return bumped
+}
+
+// WhereVersion makes the UPDATE apply only while the version column still
+// holds current, and increments it. Exec on the prepared statement returns
+// ErrStaleRow when no row was updated.
+func (b *Builder) WhereVersion(current int) *Builder {
+	return b.whereVersion("version", int64(current))
+}
+
+func (b *Builder) whereVersion(column string, current int64) *Builder {
+	if b.kind != KindUpdate {
+		b.fail(fmt.Errorf("WhereVersion needs an UPDATE statement, got %s", b.kind))
+		return b
+	}
+	if b.lock != nil {
+		b.fail(errors.New("the version of an UPDATE can only be checked once"))
+		return b
+	}
+
+	b.lock = &versionLock{column: column}
+	if len(b.clauses.set) > 0 {
+		b.changed()
+		b.clauses.set = append(b.clauses.set, column+" = "+column+" + 1")
+		b.lock.bumped = true
+	}
+
+	return b.Where(column+" = ?", current)
+}
+
+// whereVersionField checks the version held by the struct field fv, tagged
+// db:"...,lock"
+func (b *Builder) whereVersionField(column string, fv reflect.Value) *Builder {
+	switch {
+	case !fv.IsValid():
+		b.fail(fmt.Errorf("version column %s is behind a nil embedded pointer", column))
+		return b
+	case fv.CanInt():
+		return b.whereVersion(column, fv.Int())
+	case fv.CanUint():
+		return b.whereVersion(column, int64(fv.Uint()))
+	}
+	b.fail(fmt.Errorf("version column %s maps to a %s field; it must be an integer", column, fv.Type()))
+	return b
+}
+
+// lockField returns the field of typ tagged lock, or nil
+func lockField(typ reflect.Type) *fieldInfo {
+	info := getStructInfo(typ)
+	for i := range info.fields {
+		if info.fields[i].lock {
+			return &info.fields[i]
+		}
+	}
+	return nil
+}
+
+// checkVersion reports ErrStaleRow when the statement has a version check
+// and updated no row
+func (s *Stmt) checkVersion(res sql.Result) error {
+	if s.lock == nil {
+		return nil
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check the updated row count: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("update of %s matched no row: %w", s.table, ErrStaleRow)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package toki
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWhereVersion(t *testing.T) {
+	b := New().Update("accounts").Set(map[string]interface{}{"balance": 90}).Where("id = ?", 4).WhereVersion(7)
+	assert.Equal(t, "UPDATE accounts SET balance = $1, version = version + 1 WHERE id = $2 AND version = $3", b.String())
+	assert.Equal(t, []interface{}{90, 4, int64(7)}, b.Args())
+
+	b = New().Delete("accounts").WhereVersion(7)
+	assert.EqualError(t, b.Err(), "WhereVersion needs an UPDATE statement, got DELETE")
+
+	b = New().Update("accounts").Set(map[string]interface{}{"balance": 90}).WhereVersion(7).WhereVersion(8)
+	assert.Error(t, b.Err())
+}
+
+func TestUpdateStructVersionTag(t *testing.T) {
+	type Account struct {
+		ID      int    `db:"id"`
+		Owner   string `db:"owner"`
+		Version uint   `db:"revision,lock"`
+	}
+
+	b := New().UpdateStruct("accounts", Account{ID: 4, Owner: "zakirkun", Version: 3}).AndWhere("id = ?", 4)
+	assert.Equal(t, "UPDATE accounts SET id = $1, owner = $2, revision = revision + 1 WHERE revision = $3 AND id = $4", b.String())
+	assert.Equal(t, []interface{}{4, "zakirkun", int64(3), 4}, b.Args())
+
+	type Bad struct {
+		Version string `db:"version,lock"`
+	}
+	assert.EqualError(t, New().UpdateStruct("t", Bad{}).Err(), "version column version maps to a string field; it must be an integer")
+}
+
+func TestWhereVersionStale(t *testing.T) {
+	db, mock := newMockDB(t)
+
+	query := "UPDATE accounts SET balance = $1, version = version + 1 WHERE id = $2 AND version = $3"
+	mock.ExpectExec(query).WithArgs(90, 4, int64(7)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(query).WithArgs(90, 4, int64(7)).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	stmt, err := New().Update("accounts").Set(map[string]interface{}{"balance": 90}).Where("id = ?", 4).WhereVersion(7).Prepare(db)
+	assert.NoError(t, err)
+
+	_, err = stmt.Exec()
+	assert.NoError(t, err)
+
+	_, err = stmt.Exec()
+	assert.True(t, errors.Is(err, ErrStaleRow))
+	assert.EqualError(t, err, "update of accounts matched no row: row version has changed since it was read")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,93 @@
+package toki
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// totalColumn is the window column PaginateWithTotal adds to the select list
+const totalColumn = "__total"
+
+// PaginateWithTotal runs the SELECT, appends its rows to dest, a pointer to
+// a slice of structs, and returns how many rows match the query without its
+// LIMIT and OFFSET. The total is read from COUNT(*) OVER () AS __total,
+// added to the select list so one query returns both and both see the same
+// data; the column is not scanned into dest. The database still counts every
+// matching row, as a separate COUNT would.
+//
+// A page past the end has no row to read the total from. When the query has
+// an OFFSET it is then run again with OFFSET 0 and LIMIT 1, a second query
+// that may see rows changed since the first. DISTINCT queries are rejected:
+// the window counts rows before DISTINCT removes duplicates.
+//
+// It runs on the builder's transaction when it has one, otherwise on db.
+// The builder itself is left unchanged.
+func (b *Builder) PaginateWithTotal(ctx context.Context, db *sql.DB, dest interface{}, opts ...ScanOption) (int64, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	if err := b.checkPagination(); err != nil {
+		return 0, err
+	}
+	if b.kind != KindSelect || !b.clauses.selected {
+		return 0, fmt.Errorf("PaginateWithTotal needs a SELECT statement, got %s", b.kind)
+	}
+	if b.clauses.distinct {
+		return 0, errors.New("PaginateWithTotal cannot count a SELECT DISTINCT")
+	}
+
+	val := reflect.ValueOf(dest)
+	if val.Kind() != reflect.Ptr || val.IsNil() || val.Elem().Kind() != reflect.Slice {
+		return 0, fmt.Errorf("PaginateWithTotal destination must be a non-nil pointer to a slice, got %T", dest)
+	}
+
+	var tx *sql.Tx
+	if b.tx != nil {
+		tx = b.tx.tx
+	}
+	c := connFor(db, tx)
+	if c == nil {
+		return 0, errors.New("PaginateWithTotal needs a database or transaction")
+	}
+
+	q := *b
+	q.changed()
+	// a full slice expression, so the caller's select list is not appended to
+	columns := b.clauses.columns
+	q.clauses.columns = append(columns[:len(columns):len(columns)], "COUNT(*) OVER () AS "+totalColumn)
+
+	before := val.Elem().Len()
+	total, err := q.scanTotal(ctx, c, dest, opts)
+	if err != nil || val.Elem().Len() > before || q.page == nil || q.page.offset == 0 {
+		return total, err
+	}
+
+	// the page is past the end: read the total from the first row instead
+	q.changed()
+	q.args = append([]interface{}(nil), q.args...)
+	q.args[q.page.offset-1] = 0
+	if q.page.limit > 0 {
+		q.args[q.page.limit-1] = 1
+	}
+	first := reflect.New(val.Elem().Type())
+	return q.scanTotal(ctx, c, first.Interface(), opts)
+}
+
+// scanTotal runs the query, scanning its rows into dest and its total
+// column into the returned count, 0 when there is no row
+func (b *Builder) scanTotal(ctx context.Context, c conn, dest interface{}, opts []ScanOption) (int64, error) {
+	rows, err := b.hooks.query(ctx, c, QueryInfo{SQL: b.String(), Args: b.Args(), Kind: b.kind, Table: b.table})
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	opts = append(opts[:len(opts):len(opts)], scanExtra(totalColumn, &total))
+	if err := ScanAll(rows, dest, opts...); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
@@ -0,0 +1,59 @@
+package toki
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+type pageUser struct {
+	ID   int    `db:"id"`
+	Name string `db:"name"`
+}
+
+func TestPaginateWithTotal(t *testing.T) {
+	db, mock := newMockDB(t)
+	ctx := context.Background()
+
+	const query = "SELECT id, name, COUNT(*) OVER () AS __total FROM users WHERE active = $1 ORDER BY id LIMIT $2 OFFSET $3"
+	mock.ExpectQuery(query).WithArgs(true, 2, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "__total"}).AddRow(3, "c", 5).AddRow(4, "d", 5))
+
+	b := New().Select("id", "name").From("users").Where("active = ?", true).OrderBy("id").Limit(2).Offset(2)
+	var users []pageUser
+	total, err := b.PaginateWithTotal(ctx, db, &users, Strict())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), total)
+	assert.Equal(t, []pageUser{{3, "c"}, {4, "d"}}, users)
+	assert.Equal(t, "SELECT id, name FROM users WHERE active = $1 ORDER BY id LIMIT $2 OFFSET $3", b.String())
+
+	// past the end, the total comes from the first row
+	mock.ExpectQuery(query).WithArgs(true, 2, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "__total"}))
+	mock.ExpectQuery(query).WithArgs(true, 1, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "__total"}).AddRow(1, "a", 5))
+
+	users = nil
+	total, err = New().Select("id", "name").From("users").Where("active = ?", true).OrderBy("id").Limit(2).Offset(10).
+		PaginateWithTotal(ctx, db, &users)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), total)
+	assert.Empty(t, users)
+
+	// without an OFFSET an empty page means no rows at all
+	mock.ExpectQuery("SELECT id, name, COUNT(*) OVER () AS __total FROM users LIMIT $1").WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "__total"}))
+	total, err = New().Select("id", "name").From("users").Limit(2).PaginateWithTotal(ctx, db, &users)
+	assert.NoError(t, err)
+	assert.Zero(t, total)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	_, err = New().Distinct().Select("city").From("users").PaginateWithTotal(ctx, db, &users)
+	assert.EqualError(t, err, "PaginateWithTotal cannot count a SELECT DISTINCT")
+	_, err = New().Delete("users").PaginateWithTotal(ctx, db, &users)
+	assert.EqualError(t, err, "PaginateWithTotal needs a SELECT statement, got DELETE")
+	_, err = New().Select("*").From("users").PaginateWithTotal(ctx, db, users)
+	assert.Error(t, err)
+}
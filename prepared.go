@@ -0,0 +1,110 @@
+package toki
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// PreparedRaw is a raw query prepared once on the database or transaction
+// and executed many times with different args
+type PreparedRaw struct {
+	stmt   *sql.Stmt
+	query  string
+	params int
+	hooks  hooks
+	// ctx is the context of the raw query, used by calls without one
+	ctx context.Context
+}
+
+// Prepare creates a prepared statement for the raw query on the attached
+// transaction or database. The args given to RawQuery are ignored; pass
+// fresh args to each Exec or Query call, which run with the query's context,
+// set with WithContext or taken from its transaction. Close the PreparedRaw
+// when done.
+func (r *RawQuery) Prepare(ctx context.Context) (*PreparedRaw, error) {
+	var (
+		stmt *sql.Stmt
+		err  error
+	)
+
+	query := r.sql
+	if comment := r.hooks.comment(ctx); comment != "" {
+		query = appendComment(query, comment)
+	}
+
+	switch {
+	case r.tx != nil:
+		stmt, err = r.tx.PrepareContext(ctx, query)
+	case r.db != nil:
+		stmt, err = r.db.PrepareContext(ctx, query)
+	default:
+		return nil, ErrNoExecutor
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare raw query: %w", err)
+	}
+
+	return &PreparedRaw{stmt: stmt, query: query, params: r.Dialect().countPlaceholders(r.sql), hooks: r.hooks, ctx: r.ctx}, nil
+}
+
+// Exec executes the prepared statement with args
+func (p *PreparedRaw) Exec(args ...interface{}) (sql.Result, error) {
+	return p.ExecContext(p.context(), args...)
+}
+
+// Query executes the prepared statement with args and returns rows
+func (p *PreparedRaw) Query(args ...interface{}) (*sql.Rows, error) {
+	return p.QueryContext(p.context(), args...)
+}
+
+// QueryRow executes the prepared statement with args and returns a single
+// row. Errors, including an arg count mismatch, are reported by Scan.
+func (p *PreparedRaw) QueryRow(args ...interface{}) *Row {
+	return p.QueryRowContext(p.context(), args...)
+}
+
+// ExecContext executes the prepared statement with ctx instead of the
+// query's context
+func (p *PreparedRaw) ExecContext(ctx context.Context, args ...interface{}) (sql.Result, error) {
+	if err := p.checkArgs(args); err != nil {
+		return nil, err
+	}
+	return p.hooks.exec(ctx, stmtConn{p.stmt}, QueryInfo{SQL: p.query, Args: args, Kind: KindRaw})
+}
+
+// QueryContext executes the prepared statement with ctx instead of the
+// query's context and returns rows
+func (p *PreparedRaw) QueryContext(ctx context.Context, args ...interface{}) (*sql.Rows, error) {
+	if err := p.checkArgs(args); err != nil {
+		return nil, err
+	}
+	return p.hooks.query(ctx, stmtConn{p.stmt}, QueryInfo{SQL: p.query, Args: args, Kind: KindRaw})
+}
+
+// QueryRowContext executes the prepared statement with ctx instead of the
+// query's context and returns a single row
+func (p *PreparedRaw) QueryRowContext(ctx context.Context, args ...interface{}) *Row {
+	if err := p.checkArgs(args); err != nil {
+		return &Row{err: err}
+	}
+	return p.hooks.queryRow(ctx, stmtConn{p.stmt}, QueryInfo{SQL: p.query, Args: args, Kind: KindRaw})
+}
+
+// String returns the prepared SQL
+func (p *PreparedRaw) String() string {
+	return p.query
+}
+
+// Close releases the prepared statement
+func (p *PreparedRaw) Close() error {
+	return p.stmt.Close()
+}
+
+// checkArgs compares args against the placeholders in the prepared SQL
+func (p *PreparedRaw) checkArgs(args []interface{}) error {
+	if len(args) != p.params {
+		return fmt.Errorf("prepared query expects %d arg(s), got %d: %w", p.params, len(args), ErrArgCount)
+	}
+	return nil
+}
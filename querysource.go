@@ -0,0 +1,118 @@
+package toki
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// nameMarker starts a named query in a .sql file
+var nameMarker = regexp.MustCompile(`^\s*--\s*name:\s*(\S+)\s*$`)
+
+// QuerySource holds named SQL queries loaded from .sql files. Files are
+// parsed once when the source is created. A file may hold several queries,
+// each starting with a "-- name: <name>" line; a file without markers is a
+// single query named after its path without the .sql extension. A trailing
+// semicolon is dropped.
+type QuerySource struct {
+	queries map[string]string
+}
+
+// LoadQueries parses every .sql file in fsys, including subdirectories. Use
+// it with embed.FS to ship queries inside the binary.
+func LoadQueries(fsys fs.FS) (*QuerySource, error) {
+	src := &QuerySource{queries: make(map[string]string)}
+
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || path.Ext(p) != ".sql" {
+			return nil
+		}
+
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		return src.parse(strings.TrimSuffix(p, ".sql"), string(data))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load queries: %w", err)
+	}
+
+	return src, nil
+}
+
+// LoadQueryDir parses every .sql file under dir
+func LoadQueryDir(dir string) (*QuerySource, error) {
+	return LoadQueries(os.DirFS(dir))
+}
+
+// parse adds the queries in one file
+func (s *QuerySource) parse(file, data string) error {
+	name := ""
+	var body []string
+
+	flush := func() error {
+		query := strings.TrimSpace(strings.Join(body, "\n"))
+		body = body[:0]
+		if name == "" {
+			// a header of comments before the first marker is not a query
+			if len(splitStatements(query)) == 0 {
+				return nil
+			}
+			name = file
+		}
+		if _, ok := s.queries[name]; ok {
+			return fmt.Errorf("duplicate query name %q in %s.sql", name, file)
+		}
+		s.queries[name] = strings.TrimSpace(strings.TrimSuffix(query, ";"))
+		return nil
+	}
+
+	for _, line := range strings.Split(data, "\n") {
+		if m := nameMarker.FindStringSubmatch(line); m != nil {
+			if err := flush(); err != nil {
+				return err
+			}
+			name = m[1]
+			continue
+		}
+		body = append(body, strings.TrimRight(line, "\r"))
+	}
+
+	return flush()
+}
+
+// Query returns the SQL of the named query
+func (s *QuerySource) Query(name string) (string, error) {
+	query, ok := s.queries[name]
+	if !ok {
+		return "", fmt.Errorf("unknown query %q, known queries: %s", name, strings.Join(s.Names(), ", "))
+	}
+	return query, nil
+}
+
+// Names returns the names of all loaded queries in sorted order
+func (s *QuerySource) Names() []string {
+	names := make([]string, 0, len(s.queries))
+	for name := range s.queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RawNamed creates a raw query from the named query in source
+func (b *Builder) RawNamed(source *QuerySource, name string, args ...interface{}) (*RawQuery, error) {
+	query, err := source.Query(name)
+	if err != nil {
+		return nil, err
+	}
+	return b.RawQuery(query, args...), nil
+}
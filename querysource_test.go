@@ -0,0 +1,74 @@
+package toki
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var testQueries = fstest.MapFS{
+	"users.sql": {Data: []byte(`-- Queries for the users table
+
+-- name: get-user
+SELECT id, name
+FROM users
+WHERE id = $1;
+
+-- name: count-users
+SELECT count(*) FROM users
+`)},
+	"reports/daily.sql": {Data: []byte("SELECT day, total\r\nFROM daily_totals\r\n")},
+	"README.md":         {Data: []byte("not a query")},
+}
+
+func TestLoadQueries(t *testing.T) {
+	src, err := LoadQueries(testQueries)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"count-users", "get-user", "reports/daily"}, src.Names())
+
+	query, err := src.Query("get-user")
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT id, name\nFROM users\nWHERE id = $1", query)
+
+	query, err = src.Query("reports/daily")
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT day, total\nFROM daily_totals", query)
+
+	_, err = src.Query("get-users")
+	assert.EqualError(t, err, `unknown query "get-users", known queries: count-users, get-user, reports/daily`)
+
+	_, err = LoadQueries(fstest.MapFS{
+		"a.sql": {Data: []byte("-- name: q\nSELECT 1\n-- name: q\nSELECT 2\n")},
+	})
+	assert.EqualError(t, err, `failed to load queries: duplicate query name "q" in a.sql`)
+}
+
+func TestRawNamed(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	src, err := LoadQueries(testQueries)
+	assert.NoError(t, err)
+
+	mock.ExpectQuery("SELECT id, name FROM users WHERE id = \\$1").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "zakirkun"))
+
+	query, err := New().RawNamed(src, "get-user", 1)
+	assert.NoError(t, err)
+
+	var name string
+	var id int
+	assert.NoError(t, query.WithDB(db).QueryRow().Scan(&id, &name))
+	assert.Equal(t, "zakirkun", name)
+
+	_, err = New().RawNamed(src, "missing")
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
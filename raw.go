@@ -1,23 +1,55 @@
 package toki
 
-import "database/sql"
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrNoExecutor is returned when a raw query is run without a database or
+// transaction attached
+var ErrNoExecutor = errors.New("raw query has no database or transaction attached")
+
+// ErrArgCount is wrapped by errors reporting that a query's placeholders and
+// args do not match
+var ErrArgCount = errors.New("placeholder and argument count mismatch")
 
 // RawQuery represents a raw SQL query
 type RawQuery struct {
-	sql  string
-	args []interface{}
-	db   *sql.DB
-	tx   *sql.Tx
+	sql   string
+	args  []interface{}
+	db    *sql.DB
+	tx    *sql.Tx
+	hooks hooks
+	ctx   context.Context
+	// dialect is the builder's dialect, nil for the package-wide one
+	dialect *Dialect
 }
 
-// Raw creates a new raw SQL query
-func (b *Builder) Raw(sql string, args ...interface{}) *RawQuery {
+// RawQuery creates a raw SQL query, sent exactly as written. For an SQL
+// expression inside a built statement use Expr.
+func (b *Builder) RawQuery(sql string, args ...interface{}) *RawQuery {
 	return &RawQuery{
-		sql:  sql,
-		args: args,
+		sql:     sql,
+		args:    args,
+		hooks:   b.hooks,
+		ctx:     b.ctx,
+		dialect: b.dialect,
 	}
 }
 
+// Raw creates a raw SQL query.
+//
+// Deprecated: use RawQuery, which cannot be mistaken for the Raw
+// expression.
+func (b *Builder) Raw(sql string, args ...interface{}) *RawQuery {
+	return b.RawQuery(sql, args...)
+}
+
 // WithDB sets the database connection
 func (r *RawQuery) WithDB(db *sql.DB) *RawQuery {
 	r.db = db
@@ -30,28 +62,205 @@ func (r *RawQuery) WithTx(tx *sql.Tx) *RawQuery {
 	return r
 }
 
+// WithTransaction sets the transaction from a toki Transaction
+func (r *RawQuery) WithTransaction(tx *Transaction) *RawQuery {
+	r.tx = tx.tx
+	if r.ctx == nil {
+		r.ctx = tx.ctx
+	}
+	return r
+}
+
+// WithDialect sets the dialect whose placeholder style Rebind writes
+func (r *RawQuery) WithDialect(d *Dialect) *RawQuery {
+	r.dialect = d
+	return r
+}
+
+// Dialect returns the dialect of the query: the one set with WithDialect or
+// taken from the builder, or the package-wide one
+func (r *RawQuery) Dialect() *Dialect {
+	if r.dialect != nil {
+		return r.dialect
+	}
+	return defaultDialect
+}
+
+// Rebind converts the placeholders of the query to the style of its
+// dialect, skipping string literals, quoted identifiers and comments: $N on
+// PostgreSQL and SQLite, ? on MySQL and @pN on SQL Server. The query may be
+// written with ? or with $N placeholders, so one file of SQL runs on every
+// database; a query already in the dialect's style is left as it is. On
+// MySQL, $N placeholders become ? and the args are reordered to match,
+// repeating an arg referenced more than once. It returns an error, leaving
+// the query unchanged, when the number of placeholders does not match the
+// number of args or the query mixes ? and $N. Queries that are not rebound
+// are sent exactly as written.
+func (r *RawQuery) Rebind() (*RawQuery, error) {
+	query, args, err := rebindFor(r.Dialect(), r.sql, r.args)
+	if err != nil {
+		return r, err
+	}
+
+	r.sql, r.args = query, args
+	return r, nil
+}
+
+// BindNamed expands :name parameters into $N placeholders and binds their
+// values from params, after any positional args. A name referenced several
+// times is bound once. Casts (::int), string literals and comments are not
+// scanned. It returns an error, leaving the query unchanged, when a name has
+// no value in params or a key in params is never referenced.
+func (r *RawQuery) BindNamed(params map[string]interface{}) (*RawQuery, error) {
+	query, names := bindNamed(r.sql, len(r.args))
+
+	var missing []string
+	used := make(map[string]bool, len(names))
+	for _, name := range names {
+		used[name] = true
+		if _, ok := params[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return r, fmt.Errorf("no value for named parameter(s) %s", strings.Join(missing, ", "))
+	}
+
+	var unused []string
+	for key := range params {
+		if !used[key] {
+			unused = append(unused, key)
+		}
+	}
+	if len(unused) > 0 {
+		sort.Strings(unused)
+		return r, fmt.Errorf("named parameter(s) %s not used in query", strings.Join(unused, ", "))
+	}
+
+	for _, name := range names {
+		r.args = append(r.args, params[name])
+	}
+	r.sql = query
+	return r, nil
+}
+
+// Validate checks that the query has as many args as placeholders. The
+// highest $N placeholder sets the count, or the number of ? placeholders when
+// there are none; placeholders inside literals and comments are ignored.
+// Query, QueryRow and Exec validate automatically, unless the query has no
+// args, where a ? is more likely an operator such as jsonb's, or has
+// sql.Named args, which the driver matches by name.
+func (r *RawQuery) Validate() error {
+	if n := r.Dialect().countPlaceholders(r.sql); n != len(r.args) {
+		return fmt.Errorf("raw query expects %d arg(s), got %d: %w", n, len(r.args), ErrArgCount)
+	}
+	return nil
+}
+
 // Query executes the raw query and returns rows
 func (r *RawQuery) Query() (*sql.Rows, error) {
-	if r.tx != nil {
-		return r.tx.Query(r.sql, r.args...)
+	c, err := r.conn()
+	if err != nil {
+		return nil, err
 	}
-	return r.db.Query(r.sql, r.args...)
+	return r.hooks.query(r.context(), c, r.info())
 }
 
-// QueryRow executes the raw query and returns a single row
-func (r *RawQuery) QueryRow() *sql.Row {
-	if r.tx != nil {
-		return r.tx.QueryRow(r.sql, r.args...)
+// QueryRow executes the raw query and returns a single row. Errors,
+// including a missing database or transaction, are reported by the row's
+// Scan.
+func (r *RawQuery) QueryRow() *Row {
+	c, err := r.conn()
+	if err != nil {
+		return &Row{err: err}
 	}
-	return r.db.QueryRow(r.sql, r.args...)
+	return r.hooks.queryRow(r.context(), c, r.info())
 }
 
 // Exec executes the raw query
 func (r *RawQuery) Exec() (sql.Result, error) {
-	if r.tx != nil {
-		return r.tx.Exec(r.sql, r.args...)
+	c, err := r.conn()
+	if err != nil {
+		return nil, err
+	}
+	return r.hooks.exec(r.context(), c, r.info())
+}
+
+// positionalArgs reports whether the query has args, none of them
+// sql.NamedArg
+func (r *RawQuery) positionalArgs() bool {
+	for _, arg := range r.args {
+		if _, ok := arg.(sql.NamedArg); ok {
+			return false
+		}
+	}
+	return len(r.args) > 0
+}
+
+// info describes the raw query for middleware
+func (r *RawQuery) info() QueryInfo {
+	return QueryInfo{SQL: r.sql, Args: r.args, Kind: KindRaw}
+}
+
+// conn validates the query and returns the connection it runs on
+func (r *RawQuery) conn() (conn, error) {
+	if r.positionalArgs() {
+		if err := r.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	c := connFor(r.db, r.tx)
+	if c == nil {
+		return nil, ErrNoExecutor
 	}
-	return r.db.Exec(r.sql, r.args...)
+	return c, nil
+}
+
+// Get runs the query and scans its first row into dest, a pointer to a
+// struct, using the same column mapping as Bind. It returns an error
+// wrapping sql.ErrNoRows when the query selects no rows.
+func (r *RawQuery) Get(dest interface{}, opts ...ScanOption) error {
+	rows, err := r.Query()
+	if err != nil {
+		return r.wrapErr(err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return r.wrapErr(err)
+		}
+		return r.wrapErr(sql.ErrNoRows)
+	}
+
+	if err := ScanStruct(rows, dest, opts...); err != nil {
+		return r.wrapErr(err)
+	}
+	return r.wrapErr(rows.Close())
+}
+
+// All runs the query and scans every row into dest, a pointer to a slice of
+// structs or struct pointers
+func (r *RawQuery) All(dest interface{}, opts ...ScanOption) error {
+	rows, err := r.Query()
+	if err != nil {
+		return r.wrapErr(err)
+	}
+	return r.wrapErr(ScanAll(rows, dest, opts...))
+}
+
+// Scalar runs the query and scans the single column of its first row into
+// dest, for results such as counts
+func (r *RawQuery) Scalar(dest interface{}) error {
+	return r.wrapErr(r.QueryRow().Scan(dest))
+}
+
+// wrapErr adds the SQL text to err. Args are never included.
+func (r *RawQuery) wrapErr(err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("%w (query: %s)", err, snippet(r.sql, 200))
 }
 
 // String returns the SQL query string
@@ -63,3 +272,53 @@ func (r *RawQuery) String() string {
 func (r *RawQuery) Args() []interface{} {
 	return r.args
 }
+
+// Row is the result of RawQuery.QueryRow. It works like *sql.Row and can
+// also carry an error raised before the query reached the database.
+type Row struct {
+	row *sql.Row
+	err error
+}
+
+// Scan copies the columns of the row into dest. It returns sql.ErrNoRows
+// when the query selected no rows.
+func (r *Row) Scan(dest ...interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.row.Scan(dest...)
+}
+
+// Err returns the error, if any, that was encountered running the query
+func (r *Row) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.row.Err()
+}
+
+// sqlRow returns the row as a *sql.Row, for the methods that return one
+func (r *Row) sqlRow() *sql.Row {
+	if r.err != nil {
+		return errRow(r.err)
+	}
+	return r.row
+}
+
+// errRow returns a *sql.Row whose Scan and Err report err. A *sql.Row
+// cannot be built with an error, so it comes from a database that fails
+// to connect with err.
+func errRow(err error) *sql.Row {
+	db := sql.OpenDB(errConnector{err})
+	defer db.Close()
+	return db.QueryRow("")
+}
+
+// errConnector is a driver connector that fails every connection with err
+type errConnector struct{ err error }
+
+func (c errConnector) Connect(context.Context) (driver.Conn, error) { return nil, c.err }
+
+func (c errConnector) Driver() driver.Driver { return c }
+
+func (c errConnector) Open(string) (driver.Conn, error) { return nil, c.err }
@@ -1,7 +1,9 @@
 package toki
 
 import (
+	"context"
 	"database/sql"
+	"regexp"
 	"testing"
 	"time"
 
@@ -223,3 +225,358 @@ func TestRawQueryErrors(t *testing.T) {
 
 	t.Log("---- Pass ----")
 }
+
+func TestRawQueryRebind(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		args    []interface{}
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "Plain placeholders",
+			sql:  "SELECT * FROM users WHERE id = ? AND status = ?",
+			args: []interface{}{1, "active"},
+			want: "SELECT * FROM users WHERE id = $1 AND status = $2",
+		},
+		{
+			name: "Literals and comments are skipped",
+			sql:  "SELECT 'why?', \"col?\" FROM t -- really?\nWHERE a = ? /* b = ? */ AND c = E'it\\'s?' AND d = ?",
+			args: []interface{}{1, 2},
+			want: "SELECT 'why?', \"col?\" FROM t -- really?\nWHERE a = $1 /* b = ? */ AND c = E'it\\'s?' AND d = $2",
+		},
+		{
+			name: "Dollar quoting and casts",
+			sql:  "SELECT $fn$ select ? $fn$, ?::int, 'o''?'",
+			args: []interface{}{1},
+			want: "SELECT $fn$ select ? $fn$, $1::int, 'o''?'",
+		},
+		{
+			name: "No placeholders",
+			sql:  "SELECT 1",
+			want: "SELECT 1",
+		},
+		{
+			name:    "Too few args",
+			sql:     "SELECT * FROM users WHERE id = ? AND status = ?",
+			args:    []interface{}{1},
+			want:    "SELECT * FROM users WHERE id = ? AND status = ?",
+			wantErr: true,
+		},
+		{
+			name: "Already numbered",
+			sql:  "SELECT * FROM users WHERE id = $1",
+			args: []interface{}{1},
+			want: "SELECT * FROM users WHERE id = $1",
+		},
+		{
+			name:    "Mixed styles",
+			sql:     "SELECT * FROM users WHERE id = $1 AND status = ?",
+			args:    []interface{}{1, "active"},
+			want:    "SELECT * FROM users WHERE id = $1 AND status = ?",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := New().Raw(tt.sql, tt.args...).Rebind()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, q.String())
+		})
+	}
+}
+
+func TestRawQueryRebindDialect(t *testing.T) {
+	const written = "SELECT * FROM users WHERE id = ? AND note <> '?' AND status = ?"
+
+	q, err := New().WithDialect(MySQL).RawQuery(written, 1, "active").Rebind()
+	assert.NoError(t, err)
+	assert.Equal(t, written, q.String())
+
+	q, err = New().RawQuery(written, 1, "active").WithDialect(SQLServer).Rebind()
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM users WHERE id = @p1 AND note <> '?' AND status = @p2", q.String())
+	assert.NoError(t, q.Validate())
+
+	// $N becomes ? with the args in reference order
+	q, err = New().WithDialect(MySQL).RawQuery("SELECT * FROM t WHERE b = $2 AND (a = $1 OR owner = $1)", 1, 2).Rebind()
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM t WHERE b = ? AND (a = ? OR owner = ?)", q.String())
+	assert.Equal(t, []interface{}{2, 1, 1}, q.args)
+
+	q, err = New().RawQuery("SELECT * FROM t WHERE b = $2 AND a = $1", 1, 2).WithDialect(SQLServer).Rebind()
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM t WHERE b = @p2 AND a = @p1", q.String())
+	assert.Equal(t, []interface{}{1, 2}, q.args)
+
+	// written for SQL Server already
+	q, err = New().RawQuery("SELECT * FROM t WHERE a = @p1 AND b = @P2", 1, 2).WithDialect(SQLServer).Rebind()
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM t WHERE a = @p1 AND b = @P2", q.String())
+
+	_, err = New().WithDialect(MySQL).RawQuery("SELECT * FROM t WHERE a = $2", 1).Rebind()
+	assert.ErrorIs(t, err, ErrArgCount)
+}
+
+func TestRawQueryRebindExec(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec("UPDATE users SET name = \\$1 WHERE id = \\$2").
+		WithArgs("zakirkun", 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	q, err := New().Raw("UPDATE users SET name = ? WHERE id = ?", "zakirkun", 1).Rebind()
+	assert.NoError(t, err)
+
+	_, err = q.WithDB(db).Exec()
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRawQueryBindNamed(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	q, err := New().Raw(`SELECT id::text, ':skip' FROM events -- :comment
+WHERE org = :org AND created_at > :since AND (owner = :org OR :org = 0)`).
+		BindNamed(map[string]interface{}{"org": 1, "since": since})
+	assert.NoError(t, err)
+	assert.Equal(t, `SELECT id::text, ':skip' FROM events -- :comment
+WHERE org = $1 AND created_at > $2 AND (owner = $1 OR $1 = 0)`, q.String())
+	assert.Equal(t, []interface{}{1, since}, q.Args())
+
+	q, err = New().Raw("SELECT * FROM t WHERE a = $1 AND b = :b", "x").
+		BindNamed(map[string]interface{}{"b": "y"})
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM t WHERE a = $1 AND b = $2", q.String())
+	assert.Equal(t, []interface{}{"x", "y"}, q.Args())
+
+	q, err = New().Raw("SELECT * FROM t WHERE a = :a AND b = :b").
+		BindNamed(map[string]interface{}{"a": 1})
+	assert.EqualError(t, err, "no value for named parameter(s) b")
+	assert.Equal(t, "SELECT * FROM t WHERE a = :a AND b = :b", q.String())
+	assert.Empty(t, q.Args())
+
+	_, err = New().Raw("SELECT * FROM t WHERE a = :a").
+		BindNamed(map[string]interface{}{"a": 1, "z": 2, "b": 3})
+	assert.EqualError(t, err, "named parameter(s) b, z not used in query")
+}
+
+func TestCountPlaceholders(t *testing.T) {
+	assert.Equal(t, 0, countPlaceholders("SELECT 1"))
+	assert.Equal(t, 2, countPlaceholders("SELECT * FROM t WHERE a = ? AND b = ? AND c = '?'"))
+	assert.Equal(t, 3, countPlaceholders("SELECT $3, $1 /* $9 */, '$5', $$ $7 $$"))
+	assert.Equal(t, 1, countPlaceholders("SELECT price$1 FROM t WHERE id = $1"))
+}
+
+func TestShiftPlaceholders(t *testing.T) {
+	assert.Equal(t, "SELECT 1", shiftPlaceholders("SELECT 1", 3))
+	assert.Equal(t, "SELECT $4, $12 /* $1 */, '$2', $$ $3 $$, price$1", shiftPlaceholders("SELECT $1, $9 /* $1 */, '$2', $$ $3 $$, price$1", 3))
+	assert.Equal(t, "WHERE a = $1", shiftPlaceholders("WHERE a = $1", 0))
+}
+
+func TestPreparedRaw(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	prep := mock.ExpectPrepare("UPDATE users SET name = \\$1 WHERE id = \\$2")
+	prep.ExpectExec().WithArgs("a", 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	prep.ExpectExec().WithArgs("b", 2).WillReturnResult(sqlmock.NewResult(0, 1))
+	prep.WillBeClosed()
+
+	stmt, err := New().Raw("UPDATE users SET name = $1 WHERE id = $2").WithDB(db).Prepare(context.Background())
+	assert.NoError(t, err)
+
+	_, err = stmt.Exec("a", 1)
+	assert.NoError(t, err)
+	_, err = stmt.Exec("b", 2)
+	assert.NoError(t, err)
+
+	_, err = stmt.Exec("c")
+	assert.EqualError(t, err, "prepared query expects 2 arg(s), got 1: placeholder and argument count mismatch")
+	_, err = stmt.Query("c", 3, 4)
+	assert.ErrorIs(t, err, ErrArgCount)
+
+	assert.NoError(t, stmt.Close())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPreparedRawTransaction(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	prep := mock.ExpectPrepare("SELECT name FROM users WHERE id = \\?")
+	prep.ExpectQuery().WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("zakirkun"))
+	mock.ExpectCommit()
+
+	tx, err := Begin(db)
+	assert.NoError(t, err)
+
+	stmt, err := tx.Raw("SELECT name FROM users WHERE id = ?").Prepare(context.Background())
+	assert.NoError(t, err)
+	defer stmt.Close()
+
+	var name string
+	assert.NoError(t, stmt.QueryRow(1).Scan(&name))
+	assert.Equal(t, "zakirkun", name)
+	assert.NoError(t, tx.Commit())
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	_, err = New().Raw("SELECT 1").Prepare(context.Background())
+	assert.ErrorIs(t, err, ErrNoExecutor)
+}
+
+func TestPreparedRawContext(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	prep := mock.ExpectPrepare("DELETE FROM sessions WHERE id = $1")
+	prep.ExpectExec().WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stmt, err := New().Raw("DELETE FROM sessions WHERE id = $1").WithDB(db).WithContext(ctx).Prepare(context.Background())
+	assert.NoError(t, err)
+	defer stmt.Close()
+
+	// calls without a context run with the query's
+	cancel()
+	_, err = stmt.Exec(1)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = stmt.Query(1)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = stmt.ExecContext(context.Background(), 2)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRawQueryNoExecutor(t *testing.T) {
+	query := New().Raw("SELECT * FROM users WHERE id = $1", 1)
+
+	rows, err := query.Query()
+	assert.ErrorIs(t, err, ErrNoExecutor)
+	assert.Nil(t, rows)
+
+	result, err := query.Exec()
+	assert.ErrorIs(t, err, ErrNoExecutor)
+	assert.Nil(t, result)
+
+	var id int
+	row := query.QueryRow()
+	assert.ErrorIs(t, row.Err(), ErrNoExecutor)
+	assert.EqualError(t, row.Scan(&id), "raw query has no database or transaction attached")
+
+	assert.ErrorIs(t, New().Raw("DELETE FROM users").ExecScript(context.Background()), ErrNoExecutor)
+}
+
+func TestRawQueryScanHelpers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	columns := []string{"id", "name", "email", "created_at"}
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery("SELECT (.+) FROM users WHERE id").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "zakirkun", "zakir@example.com", created))
+
+	var user scanUser
+	err = New().Raw("SELECT id, name, email, created_at FROM users WHERE id = $1", 1).WithDB(db).Get(&user)
+	assert.NoError(t, err)
+	assert.Equal(t, "zakirkun", user.Name)
+
+	mock.ExpectQuery("SELECT (.+) FROM users WHERE id").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows(columns))
+
+	err = New().Raw("SELECT id, name, email, created_at FROM users WHERE id = $1", 2).WithDB(db).Get(&user)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	assert.Contains(t, err.Error(), "(query: SELECT id, name, email, created_at FROM users WHERE id = $1)")
+
+	mock.ExpectQuery("SELECT (.+) FROM users").
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(1, "zakirkun", "zakir@example.com", created).
+			AddRow(2, "rudi", "rudi@example.com", created))
+
+	var users []scanUser
+	err = New().Raw("SELECT id, name, email, created_at FROM users").WithDB(db).All(&users)
+	assert.NoError(t, err)
+	assert.Len(t, users, 2)
+
+	mock.ExpectQuery("SELECT count").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(42))
+
+	var count int
+	err = New().Raw("SELECT count(*) FROM users").WithDB(db).Scalar(&count)
+	assert.NoError(t, err)
+	assert.Equal(t, 42, count)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRawQueryValidate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	assert.NoError(t, New().Raw("SELECT * FROM t WHERE a = $2 AND b = $1", 1, 2).Validate())
+	assert.NoError(t, New().Raw("SELECT * FROM t WHERE a = ? AND b = '?' -- ?", 1).Validate())
+	assert.NoError(t, New().Raw("SELECT '$1', $tag$ $2 $tag$").Validate())
+
+	err = New().Raw("SELECT * FROM t WHERE a = $1 AND b = $3", 1, 2).Validate()
+	assert.EqualError(t, err, "raw query expects 3 arg(s), got 2: placeholder and argument count mismatch")
+	assert.ErrorIs(t, err, ErrArgCount)
+
+	// mismatches are caught before anything is sent to the database
+	query := New().Raw("UPDATE t SET a = $1 WHERE id = $2", 1).WithDB(db)
+
+	_, err = query.Exec()
+	assert.ErrorIs(t, err, ErrArgCount)
+	_, err = query.Query()
+	assert.ErrorIs(t, err, ErrArgCount)
+	assert.ErrorIs(t, query.QueryRow().Err(), ErrArgCount)
+
+	// named args and queries without args are left to the database
+	mock.ExpectExec("UPDATE t SET a = @a").
+		WithArgs(sql.Named("a", 1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	_, err = New().Raw("UPDATE t SET a = @a", sql.Named("a", 1)).WithDB(db).Exec()
+	assert.NoError(t, err)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM docs WHERE data ? 'k'")).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	_, err = New().Raw("SELECT id FROM docs WHERE data ? 'k'").WithDB(db).Query()
+	assert.NoError(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRawQueryMethod(t *testing.T) {
+	r := New().RawQuery("SELECT * FROM users WHERE id = $1", 7)
+	assert.Equal(t, "SELECT * FROM users WHERE id = $1", r.String())
+	assert.Equal(t, r, New().Raw("SELECT * FROM users WHERE id = $1", 7))
+}
@@ -0,0 +1,115 @@
+package toki
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+// Redacted is how a sensitive arg is shown to loggers and in DebugString
+const Redacted = "[REDACTED]"
+
+// SensitiveValue is an arg that is bound as its value but shown as
+// [REDACTED] to loggers, slow query callbacks and DebugString, made by
+// Sensitive
+type SensitiveValue struct {
+	value interface{}
+}
+
+// Sensitive marks value, such as a password or token, to be kept out of
+// logs: Where("token = ?", toki.Sensitive(token)). The value itself is sent
+// to the database. Middleware sees the wrapper; DriverArgs unwraps it.
+func Sensitive(value interface{}) SensitiveValue {
+	return SensitiveValue{value: value}
+}
+
+// Unwrap returns the value sent to the database
+func (s SensitiveValue) Unwrap() interface{} { return s.value }
+
+// String returns Redacted
+func (s SensitiveValue) String() string { return Redacted }
+
+// Format prints Redacted for every verb, so %v, %+v and %#v do not leak the
+// value
+func (s SensitiveValue) Format(f fmt.State, verb rune) {
+	io.WriteString(f, Redacted)
+}
+
+// LogValue implements slog.LogValuer
+func (s SensitiveValue) LogValue() slog.Value { return slog.StringValue(Redacted) }
+
+// MarshalJSON renders Redacted, for loggers encoding args as JSON
+func (s SensitiveValue) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + Redacted + `"`), nil
+}
+
+// Value converts the value for drivers reached without DriverArgs, such as
+// an adapter queueing args itself
+func (s SensitiveValue) Value() (driver.Value, error) {
+	return driver.DefaultParameterConverter.ConvertValue(s.value)
+}
+
+// defaultRedact redacts every arg of builders and raw queries that do not
+// redact on their own
+var defaultRedact bool
+
+// SetRedactArgs makes loggers, slow query callbacks and DebugString show
+// every arg as [REDACTED], not only those marked Sensitive. Call it during
+// initialization, before queries run.
+func SetRedactArgs(redact bool) {
+	defaultRedact = redact
+}
+
+// RedactArgs shows every arg of statements run from this builder as
+// [REDACTED] to loggers, slow query callbacks and DebugString
+func (b *Builder) RedactArgs() *Builder {
+	b.hooks.redact = true
+	return b
+}
+
+// RedactArgs shows every arg of this raw query as [REDACTED] to loggers,
+// slow query callbacks and DebugString
+func (r *RawQuery) RedactArgs() *RawQuery {
+	r.hooks.redact = true
+	return r
+}
+
+// DriverArgs returns args as they are sent to the database, with Sensitive
+// values unwrapped, for adapters passing args to a driver themselves. It
+// returns args itself when nothing is wrapped.
+func DriverArgs(args []interface{}) []interface{} {
+	var out []interface{}
+	for i, arg := range args {
+		s, ok := arg.(SensitiveValue)
+		if !ok {
+			continue
+		}
+		if out == nil {
+			out = make([]interface{}, len(args))
+			copy(out, args)
+		}
+		out[i] = s.value
+	}
+	if out == nil {
+		return args
+	}
+	return out
+}
+
+// loggedArgs returns args as loggers see them: wrapped in SensitiveValue
+// when every arg is redacted
+func (h hooks) loggedArgs(args []interface{}) []interface{} {
+	if !h.redact && !defaultRedact || len(args) == 0 {
+		return args
+	}
+	out := make([]interface{}, len(args))
+	for i, arg := range args {
+		if s, ok := arg.(SensitiveValue); ok {
+			out[i] = s
+			continue
+		}
+		out[i] = Sensitive(arg)
+	}
+	return out
+}
@@ -0,0 +1,94 @@
+package toki
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSensitive(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	var out bytes.Buffer
+	logger := &recordingLogger{}
+	mock.ExpectExec("UPDATE users SET password = \\$1 WHERE email = \\$2").
+		WithArgs("hunter2", "zakir@example.com").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE users").WithArgs("hunter2", "zakir@example.com").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	b := New().WithLogger(logger).
+		Update("users").
+		Set(map[string]interface{}{"password": Sensitive("hunter2")}).
+		Where("email = ?", "zakir@example.com")
+	assert.Equal(t, "UPDATE users SET password = [REDACTED] WHERE email = 'zakir@example.com'", b.DebugString())
+
+	stmt, err := b.Prepare(db)
+	assert.NoError(t, err)
+	_, err = stmt.Exec()
+	assert.NoError(t, err)
+
+	stmt.hooks.logger = NewStdLogger(log.New(&out, "", 0))
+	_, err = stmt.Exec()
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	if assert.Len(t, logger.queries, 1) {
+		args := logger.queries[0].args
+		assert.Equal(t, Sensitive("hunter2"), args[0])
+		assert.Equal(t, "[[REDACTED] zakir@example.com]", fmt.Sprint(args))
+		assert.Equal(t, "[REDACTED]", fmt.Sprintf("%#v", args[0]))
+	}
+	assert.Contains(t, out.String(), "[[REDACTED] zakir@example.com]")
+	assert.NotContains(t, out.String(), "hunter2")
+
+	encoded, err := json.Marshal(Sensitive("hunter2"))
+	assert.NoError(t, err)
+	assert.Equal(t, `"[REDACTED]"`, string(encoded))
+
+	args := []interface{}{1, Sensitive("hunter2")}
+	assert.Equal(t, []interface{}{1, "hunter2"}, DriverArgs(args))
+	assert.Equal(t, Sensitive("hunter2"), args[1])
+}
+
+func TestRedactArgs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	logger := &recordingLogger{}
+	var slowArgs []interface{}
+	mock.ExpectExec("DELETE FROM sessions").WithArgs("tok-1", 7).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	q := New().WithLogger(logger).RedactArgs().
+		WithSlowQueryThreshold(-1, func(ctx context.Context, query string, args []interface{}, took time.Duration) {
+			slowArgs = args
+		}).
+		RawQuery("DELETE FROM sessions WHERE token = $1 AND user_id = $2", "tok-1", 7)
+	assert.Equal(t, "DELETE FROM sessions WHERE token = [REDACTED] AND user_id = [REDACTED]", q.DebugString())
+
+	_, err = q.WithDB(db).Exec()
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	if assert.Len(t, logger.queries, 1) {
+		assert.Equal(t, "[[REDACTED] [REDACTED]]", fmt.Sprint(logger.queries[0].args))
+	}
+	assert.Equal(t, "[[REDACTED] [REDACTED]]", fmt.Sprint(slowArgs))
+
+	SetRedactArgs(true)
+	defer SetRedactArgs(false)
+	assert.Equal(t, "SELECT * FROM users WHERE id = [REDACTED]", New().Select("*").From("users").Where("id = ?", 1).DebugString())
+}
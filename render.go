@@ -0,0 +1,159 @@
+package toki
+
+import (
+	"strconv"
+	"strings"
+)
+
+// joinClause renders keyword followed by items joined with ", " in a single
+// allocation
+func joinClause(keyword string, items []string) string {
+	n := len(keyword) + 1
+	for _, item := range items {
+		n += len(item) + 2
+	}
+
+	var sb strings.Builder
+	sb.Grow(n)
+	sb.WriteString(keyword)
+	sb.WriteByte(' ')
+	for i, item := range items {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(item)
+	}
+	return sb.String()
+}
+
+// appendPlaceholder appends $n to buf
+func appendPlaceholder(buf []byte, n int) []byte {
+	buf = append(buf, '$')
+	return strconv.AppendInt(buf, int64(n), 10)
+}
+
+// placeholderWidth estimates the bytes needed for count placeholders
+// numbered after start
+func placeholderWidth(start, count int) int {
+	return count * (2 + len(strconv.Itoa(start+count)))
+}
+
+// clauses holds the clauses of a statement by kind. Each is rendered when
+// its method is called, numbering its placeholders in call order, and
+// String joins them in SQL order, so the calls can come in any order.
+type clauses struct {
+	with []string
+	// head starts an INSERT, UPDATE or DELETE, or holds a whole MERGE
+	head   string
+	set    []string
+	values []string
+	// selected is set once Select is called, with the select list in
+	// columns
+	selected bool
+	// hints are the optimizer hints rendered after SELECT
+	hints    []string
+	distinct bool
+	columns  []string
+	// columnArgs counts the args bound by the select list
+	columnArgs int
+	from       []string
+	joins      []string
+	// where holds the conditions with the AND and OR between them
+	where   []string
+	groupBy []string
+	// having holds the HAVING conditions with the AND between them
+	having    []string
+	orderBy   []string
+	conflict  string
+	returning []string
+}
+
+// write writes the statement to w, with the rendered pagination clause
+// page
+func (c *clauses) write(w *clauseWriter, page string) {
+	w.clause("WITH", c.with, ", ")
+	w.single(c.head)
+	w.clause("SET", c.set, ", ")
+	w.clause("VALUES", c.values, ", ")
+	if c.selected {
+		w.single("SELECT")
+		if len(c.hints) > 0 {
+			w.single("/*+ " + strings.Join(c.hints, " ") + " */")
+		}
+		if c.distinct {
+			w.single("DISTINCT")
+		}
+		w.list(c.columns)
+	}
+	w.clause("FROM", c.from, ", ")
+	w.clause("", c.joins, " ")
+	w.clause("WHERE", c.where, " ")
+	w.clause("GROUP BY", c.groupBy, ", ")
+	w.clause("HAVING", c.having, " ")
+	w.clause("ORDER BY", c.orderBy, ", ")
+	w.single(page)
+	w.single(c.conflict)
+	w.clause("RETURNING", c.returning, ", ")
+}
+
+// clauseWriter joins clauses with spaces into sb, or only counts their
+// length in n while sb is nil
+type clauseWriter struct {
+	sb      *strings.Builder
+	n       int
+	started bool
+}
+
+func (w *clauseWriter) write(s string) {
+	if w.sb == nil {
+		w.n += len(s)
+		return
+	}
+	w.sb.WriteString(s)
+}
+
+// single writes a clause rendered in full, unless it is empty
+func (w *clauseWriter) single(s string) {
+	if s == "" {
+		return
+	}
+	if w.started {
+		w.write(" ")
+	}
+	w.started = true
+	w.write(s)
+}
+
+// list continues the current clause with items joined with ", "
+func (w *clauseWriter) list(items []string) {
+	for i, item := range items {
+		if i > 0 {
+			w.write(", ")
+		} else {
+			w.write(" ")
+		}
+		w.write(item)
+	}
+}
+
+// clause writes keyword followed by items joined with sep, unless there
+// are no items
+func (w *clauseWriter) clause(keyword string, items []string, sep string) {
+	if len(items) == 0 {
+		return
+	}
+	if w.started {
+		w.write(" ")
+	}
+	w.started = true
+	if keyword != "" {
+		w.write(keyword)
+		w.write(" ")
+	}
+	for i, item := range items {
+		if i > 0 {
+			w.write(sep)
+		}
+		w.write(item)
+	}
+}
@@ -0,0 +1,94 @@
+package toki
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// RetryError is returned by RunInTxRetry when the transaction could not be
+// completed. It records how many attempts were made and the last error.
+type RetryError struct {
+	Attempts int
+	Err      error
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("transaction failed after %d attempt(s): %v", e.Attempts, e.Err)
+}
+
+func (e *RetryError) Unwrap() error { return e.Err }
+
+// retryBackoff returns the delay before the given retry attempt (1-based)
+var retryBackoff = func(attempt int) time.Duration {
+	base := 10 * time.Millisecond << uint(attempt-1)
+	if base > time.Second {
+		base = time.Second
+	}
+	return base/2 + time.Duration(rand.Int63n(int64(base/2)+1))
+}
+
+// RunInTxRetry runs fn inside a transaction like RunInTx, retrying the whole
+// transaction up to maxAttempts times when it fails with a serialization
+// failure (SQLSTATE 40001) or a deadlock (SQLSTATE 40P01). Each attempt gets a
+// fresh Transaction and waits a jittered backoff after the previous one.
+//
+// IMPORTANT: fn may run several times. It must not have side effects outside
+// the database (sending email, publishing events, mutating shared state),
+// because those cannot be rolled back between attempts.
+func RunInTxRetry(ctx context.Context, db *sql.DB, opts *TransactionOptions, maxAttempts int, fn func(tx *Transaction) error) error {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		err = RunInTx(ctx, db, opts, fn)
+		if err == nil {
+			return nil
+		}
+
+		if !IsRetryable(err) || attempt == maxAttempts {
+			return &RetryError{Attempts: attempt, Err: err}
+		}
+
+		select {
+		case <-ctx.Done():
+			return &RetryError{Attempts: attempt, Err: errors.Join(err, ctx.Err())}
+		case <-time.After(retryBackoff(attempt)):
+		}
+	}
+
+	return &RetryError{Attempts: maxAttempts, Err: err}
+}
+
+// IsRetryable reports whether err is a serialization failure or deadlock.
+// It uses the driver error's SQLState method when available and falls back to
+// inspecting the error message.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var state interface{ SQLState() string }
+	if errors.As(err, &state) {
+		switch state.SQLState() {
+		case "40001", "40P01":
+			return true
+		}
+		return false
+	}
+
+	msg := err.Error()
+	for _, marker := range []string{"40001", "40P01", "could not serialize access", "deadlock detected"} {
+		if strings.Contains(msg, marker) {
+			return true
+		}
+	}
+
+	return false
+}
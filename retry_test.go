@@ -0,0 +1,102 @@
+package toki
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// sqlStateError mimics driver errors exposing a SQLSTATE code
+type sqlStateError struct {
+	code string
+}
+
+func (e *sqlStateError) Error() string    { return "pq: error " + e.code }
+func (e *sqlStateError) SQLState() string { return e.code }
+
+func TestRunInTxRetry(t *testing.T) {
+	backoff := retryBackoff
+	retryBackoff = func(int) time.Duration { return 0 }
+	defer func() { retryBackoff = backoff }()
+
+	t.Run("Retries serialization failures", func(t *testing.T) {
+		db, mock, _ := setupTest(t)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE accounts").WillReturnError(&sqlStateError{code: "40001"})
+		mock.ExpectRollback()
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE accounts").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		calls := 0
+		err := RunInTxRetry(context.Background(), db, nil, 3, func(tx *Transaction) error {
+			calls++
+			_, err := tx.Raw("UPDATE accounts SET balance = balance - $1 WHERE id = $2", 10, 1).Exec()
+			return err
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, calls)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Reports attempts and last error", func(t *testing.T) {
+		db, mock, _ := setupTest(t)
+		defer db.Close()
+
+		for i := 0; i < 2; i++ {
+			mock.ExpectBegin()
+			mock.ExpectCommit().WillReturnError(errors.New("ERROR: deadlock detected (SQLSTATE 40P01)"))
+		}
+
+		err := RunInTxRetry(context.Background(), db, nil, 2, func(tx *Transaction) error {
+			return nil
+		})
+
+		var retryErr *RetryError
+		assert.ErrorAs(t, err, &retryErr)
+		assert.Equal(t, 2, retryErr.Attempts)
+		assert.Contains(t, retryErr.Err.Error(), "deadlock detected")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Does not retry other errors", func(t *testing.T) {
+		db, mock, _ := setupTest(t)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		errBoom := errors.New("boom")
+		calls := 0
+		err := RunInTxRetry(context.Background(), db, nil, 5, func(tx *Transaction) error {
+			calls++
+			return errBoom
+		})
+
+		var retryErr *RetryError
+		assert.ErrorAs(t, err, &retryErr)
+		assert.ErrorIs(t, err, errBoom)
+		assert.Equal(t, 1, retryErr.Attempts)
+		assert.Equal(t, 1, calls)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Log("---- Pass ----")
+}
+
+func TestIsRetryable(t *testing.T) {
+	assert.True(t, IsRetryable(&sqlStateError{code: "40001"}))
+	assert.True(t, IsRetryable(&sqlStateError{code: "40P01"}))
+	assert.False(t, IsRetryable(&sqlStateError{code: "23505"}))
+	assert.True(t, IsRetryable(errors.New("could not serialize access due to concurrent update")))
+	assert.False(t, IsRetryable(errors.New("syntax error")))
+	assert.False(t, IsRetryable(nil))
+
+	t.Log("---- Pass ----")
+}
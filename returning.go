@@ -0,0 +1,179 @@
+package toki
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ErrNotInsert is returned by ExecReturningID for a statement other than
+// an INSERT
+var ErrNotInsert = errors.New("statement is not an INSERT")
+
+// KeyColumn sets the key column ExecReturningID returns, id by default. It
+// is only used by dialects with RETURNING; the others return the
+// auto-increment value.
+func KeyColumn(column string) ExecOption {
+	return func(c *execConfig) {
+		c.idColumn = column
+	}
+}
+
+// ExecReturningID runs an INSERT of one row and returns its generated
+// integer key. Dialects with RETURNING append RETURNING id, or the column
+// set with KeyColumn, and read it from the returned row; the others run the
+// statement and return LastInsertId. An INSERT that inserts nothing, such
+// as one skipped by ON CONFLICT DO NOTHING, returns a *NotFoundError with
+// RETURNING. It runs on the builder's transaction when it has one,
+// otherwise on db, and wraps ErrNotInsert for other statements.
+func (b *Builder) ExecReturningID(ctx context.Context, db *sql.DB, opts ...ExecOption) (int64, error) {
+	if b.kind != KindInsert {
+		return 0, fmt.Errorf("ExecReturningID needs an INSERT statement, got %s: %w", b.kind, ErrNotInsert)
+	}
+	if len(b.rows) > 1 {
+		return 0, fmt.Errorf("ExecReturningID needs a single row, got %d", len(b.rows))
+	}
+	if len(b.clauses.returning) > 0 {
+		return 0, errors.New("ExecReturningID adds its own RETURNING clause")
+	}
+	if b.tx != nil && b.tx.readOnly {
+		return 0, fmt.Errorf("cannot run %s statement: %w", b.kind, ErrReadOnlyTransaction)
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+
+	var tx *sql.Tx
+	if b.tx != nil {
+		tx = b.tx.tx
+	}
+	c := connFor(db, tx)
+	if c == nil {
+		return 0, errors.New("ExecReturningID needs a database or transaction")
+	}
+
+	cfg := execConfig{idColumn: "id"}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	q := QueryInfo{SQL: b.String(), Args: b.Args(), Kind: b.kind, Table: b.table}
+	if !b.Dialect().returning {
+		res, err := b.hooks.exec(ctx, c, q)
+		if err != nil {
+			return 0, err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return 0, fmt.Errorf("failed to read last insert id: %w", err)
+		}
+		return id, nil
+	}
+
+	q.SQL += " RETURNING " + cfg.idColumn
+	var id int64
+	err := scanValue(b.hooks.queryRow(ctx, c, q), q.SQL, &id)
+	return id, err
+}
+
+// ExecReturning runs the INSERT and writes the values generated by the
+// database, such as ids and column defaults, into dest, a pointer to a
+// struct, usually the one given to InsertStruct. It appends RETURNING for
+// columns, or for every column mapped by dest when none are given, and scans
+// the returned row using the same mapping as Bind. Dialects without
+// RETURNING can only fill a single integer column, from LastInsertId. It
+// runs on the builder's transaction when it has one, otherwise on db.
+func (b *Builder) ExecReturning(ctx context.Context, db *sql.DB, dest interface{}, columns ...string) error {
+	if b.kind != KindInsert {
+		return fmt.Errorf("ExecReturning needs an INSERT statement, got %s", b.kind)
+	}
+	val := reflect.ValueOf(dest)
+	if val.Kind() != reflect.Ptr || val.IsNil() || val.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("ExecReturning destination must be a non-nil pointer to a struct, got %T", dest)
+	}
+	if len(b.clauses.returning) > 0 {
+		return errors.New("ExecReturning adds its own RETURNING clause")
+	}
+	if b.tx != nil && b.tx.readOnly {
+		return fmt.Errorf("cannot run %s statement: %w", b.kind, ErrReadOnlyTransaction)
+	}
+	if b.err != nil {
+		return b.err
+	}
+
+	var tx *sql.Tx
+	if b.tx != nil {
+		tx = b.tx.tx
+	}
+	c := connFor(db, tx)
+	if c == nil {
+		return errors.New("ExecReturning needs a database or transaction")
+	}
+
+	if len(columns) == 0 {
+		columns = Columns(dest)
+	}
+
+	dialect := b.Dialect()
+	if !dialect.returning {
+		return b.execLastInsertID(ctx, c, val.Elem(), columns)
+	}
+
+	q := QueryInfo{SQL: b.String() + " " + joinClause("RETURNING", columns), Args: b.Args(), Kind: b.kind, Table: b.table}
+	rows, err := b.hooks.query(ctx, c, q)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+	if err := ScanStruct(rows, dest); err != nil {
+		return err
+	}
+	return rows.Close()
+}
+
+// execLastInsertID runs the INSERT and sets the single integer column from
+// LastInsertId
+func (b *Builder) execLastInsertID(ctx context.Context, c conn, val reflect.Value, columns []string) error {
+	if len(columns) != 1 {
+		return fmt.Errorf("the %s dialect has no RETURNING; pass the single integer key column to fill from LastInsertId, got %s",
+			b.Dialect(), strings.Join(columns, ", "))
+	}
+
+	field, ok := getStructInfo(val.Type()).column(columns[0])
+	if !ok {
+		return fmt.Errorf("no field of %s maps to column %s", val.Type(), columns[0])
+	}
+	fv := fieldByIndexAlloc(val, field.index)
+	switch fv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+	default:
+		return fmt.Errorf("column %s maps to a %s field; LastInsertId can only fill integers", columns[0], fv.Type())
+	}
+
+	res, err := b.hooks.exec(ctx, c, QueryInfo{SQL: b.String(), Args: b.Args(), Kind: b.kind, Table: b.table})
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to read last insert id: %w", err)
+	}
+
+	if fv.CanInt() {
+		fv.SetInt(id)
+	} else {
+		fv.SetUint(uint64(id))
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package toki
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+type returningUser struct {
+	ID        int64     `db:"id,omitempty"`
+	Name      string    `db:"name"`
+	CreatedAt time.Time `db:"created_at,omitempty"`
+}
+
+func TestExecReturning(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	created := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	mock.ExpectQuery("INSERT INTO users (name) VALUES ($1) RETURNING id, name, created_at").
+		WithArgs("zakirkun").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "created_at"}).AddRow(42, "zakirkun", created))
+	mock.ExpectQuery("INSERT INTO users (name) VALUES ($1) RETURNING id").
+		WithArgs("ann").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(43))
+
+	user := returningUser{Name: "zakirkun"}
+	assert.NoError(t, New().InsertStruct("users", &user).ExecReturning(context.Background(), db, &user))
+	assert.Equal(t, returningUser{ID: 42, Name: "zakirkun", CreatedAt: created}, user)
+
+	other := returningUser{Name: "ann"}
+	assert.NoError(t, New().InsertStruct("users", &other).ExecReturning(context.Background(), db, &other, "id"))
+	assert.Equal(t, int64(43), other.ID)
+	assert.True(t, other.CreatedAt.IsZero())
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExecReturningLastInsertID(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	legacy := &Dialect{name: "legacy"}
+	mock.ExpectExec("INSERT INTO users (name) VALUES ($1)").
+		WithArgs("zakirkun").
+		WillReturnResult(sqlmock.NewResult(7, 1))
+
+	user := returningUser{Name: "zakirkun"}
+	assert.NoError(t, New().WithDialect(legacy).InsertStruct("users", &user).ExecReturning(context.Background(), db, &user, "id"))
+	assert.Equal(t, int64(7), user.ID)
+
+	err = New().WithDialect(legacy).InsertStruct("users", &user).ExecReturning(context.Background(), db, &user)
+	assert.EqualError(t, err, "the legacy dialect has no RETURNING; pass the single integer key column to fill from LastInsertId, got id, name, created_at")
+	err = New().WithDialect(legacy).InsertStruct("users", &user).ExecReturning(context.Background(), db, &user, "created_at")
+	assert.EqualError(t, err, "column created_at maps to a time.Time field; LastInsertId can only fill integers")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExecReturningErrors(t *testing.T) {
+	user := returningUser{Name: "zakirkun"}
+	ctx := context.Background()
+
+	err := New().Update("users").Set(map[string]interface{}{"name": "x"}).ExecReturning(ctx, nil, &user)
+	assert.EqualError(t, err, "ExecReturning needs an INSERT statement, got UPDATE")
+	err = New().InsertStruct("users", &user).ExecReturning(ctx, nil, user)
+	assert.EqualError(t, err, "ExecReturning destination must be a non-nil pointer to a struct, got toki.returningUser")
+	err = New().InsertStruct("users", &user).Returning("id").ExecReturning(ctx, nil, &user)
+	assert.EqualError(t, err, "ExecReturning adds its own RETURNING clause")
+	err = New().InsertStruct("users", &user).ExecReturning(ctx, nil, &user)
+	assert.EqualError(t, err, "ExecReturning needs a database or transaction")
+}
+
+func TestExecReturningID(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	mock.ExpectQuery("INSERT INTO users (name) VALUES ($1) RETURNING id").
+		WithArgs("zakirkun").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(42))
+	id, err := New().Insert("users", "name").Values("zakirkun").ExecReturningID(ctx, db)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), id)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO users (name) VALUES ($1) RETURNING user_id").
+		WithArgs("ann").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(43))
+	mock.ExpectCommit()
+	tx, err := Begin(db)
+	assert.NoError(t, err)
+	id, err = tx.Builder().Insert("users", "name").Values("ann").ExecReturningID(ctx, nil, KeyColumn("user_id"))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(43), id)
+	assert.NoError(t, tx.Commit())
+
+	mock.ExpectExec("INSERT INTO users (name) VALUES (?)").
+		WithArgs("bob").
+		WillReturnResult(sqlmock.NewResult(7, 1))
+	id, err = New().WithDialect(MySQL).Insert("users", "name").Values("bob").ExecReturningID(ctx, db, KeyColumn("user_id"))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), id)
+
+	type tag struct {
+		Name string `db:"name"`
+	}
+	mock.ExpectQuery("INSERT INTO tags (name) VALUES ($1) ON CONFLICT (name) DO NOTHING RETURNING id").
+		WithArgs("go").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	_, err = New().UpsertStruct("tags", tag{Name: "go"}, []string{"name"}, nil).ExecReturningID(ctx, db)
+	var notFound *NotFoundError
+	assert.ErrorAs(t, err, &notFound)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExecReturningIDMySQL(t *testing.T) {
+	db, mock := newMockDB(t)
+	ctx := context.Background()
+
+	var sent []string
+	capture := func(ctx context.Context, q QueryInfo, next Handler) (Result, error) {
+		sent = append(sent, q.SQL)
+		return next(ctx, q)
+	}
+
+	mock.ExpectExec("INSERT INTO t (a) VALUES (?)").
+		WithArgs("x").
+		WillReturnResult(sqlmock.NewResult(9, 1))
+	id, err := New().WithDialect(MySQL).Use(capture).Insert("t", "a").Values("x").ExecReturningID(ctx, db)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(9), id)
+
+	mock.ExpectExec("INSERT INTO users (name) VALUES (?)").
+		WithArgs("ann").
+		WillReturnResult(sqlmock.NewResult(10, 1))
+	user := returningUser{Name: "ann"}
+	assert.NoError(t, New().WithDialect(MySQL).Use(capture).InsertStruct("users", &user).ExecReturning(ctx, db, &user, "id"))
+	assert.Equal(t, int64(10), user.ID)
+
+	assert.Equal(t, []string{"INSERT INTO t (a) VALUES (?)", "INSERT INTO users (name) VALUES (?)"}, sent)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExecReturningIDErrors(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := New().Update("users").Set(map[string]interface{}{"name": "x"}).ExecReturningID(ctx, nil)
+	assert.EqualError(t, err, "ExecReturningID needs an INSERT statement, got UPDATE: statement is not an INSERT")
+	assert.ErrorIs(t, err, ErrNotInsert)
+	_, err = New().Insert("users", "name").Values("a").Values("b").ExecReturningID(ctx, nil)
+	assert.EqualError(t, err, "ExecReturningID needs a single row, got 2")
+	_, err = New().Insert("users", "name").Values("a").Returning("id").ExecReturningID(ctx, nil)
+	assert.EqualError(t, err, "ExecReturningID adds its own RETURNING clause")
+	_, err = New().Insert("users", "name").Values("a").ExecReturningID(ctx, nil)
+	assert.EqualError(t, err, "ExecReturningID needs a database or transaction")
+}
+
+func TestReturningPosition(t *testing.T) {
+	tests := []struct {
+		name    string
+		builder *Builder
+		want    string
+	}{
+		{
+			name:    "delete returning before where",
+			builder: New().Delete("users").Returning("id").Where("id = ?", 1),
+			want:    "DELETE FROM users WHERE id = $1 RETURNING id",
+		},
+		{
+			name:    "update returning before set",
+			builder: New().Update("users").Returning("id", "name").Set(map[string]interface{}{"name": "x"}).Where("id = ?", 1),
+			want:    "UPDATE users SET name = $1 WHERE id = $2 RETURNING id, name",
+		},
+		{
+			name:    "insert returning before values",
+			builder: New().Insert("users", "name").Returning("id").Values("x"),
+			want:    "INSERT INTO users (name) VALUES ($1) RETURNING id",
+		},
+		{
+			name:    "upsert returning before conflict",
+			builder: New().Returning("id").UpsertStruct("tags", struct{ Name string }{"go"}, []string{"name"}, nil),
+			want:    "INSERT INTO tags (name) VALUES ($1) ON CONFLICT (name) DO NOTHING RETURNING id",
+		},
+		{
+			name:    "called twice",
+			builder: New().Delete("users").Returning("id").Where("id = ?", 1).Returning("email"),
+			want:    "DELETE FROM users WHERE id = $1 RETURNING id, email",
+		},
+		{
+			name:    "no columns",
+			builder: New().Delete("users").Returning().Where("id = ?", 1),
+			want:    "DELETE FROM users WHERE id = $1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.builder.String())
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package toki
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// ReplicaError reports a query that failed on a replica. It is passed to
+// the logger before the query is retried on the primary.
+type ReplicaError struct {
+	// Replica is the index of the replica given to NewRoutingDB
+	Replica int
+	Err     error
+}
+
+func (e *ReplicaError) Error() string {
+	return fmt.Sprintf("replica %d failed, retrying on primary: %v", e.Replica, e.Err)
+}
+
+func (e *ReplicaError) Unwrap() error { return e.Err }
+
+// RoutingDB splits reads from writes over a primary database and its read
+// replicas. SELECT statements prepared through it run on the replicas in
+// turn and on the primary when a replica fails. Everything else, statements
+// bound to a transaction and builders marked with ForcePrimary run on the
+// primary.
+type RoutingDB struct {
+	primary  *sql.DB
+	replicas []*sql.DB
+	next     atomic.Uint64
+}
+
+// NewRoutingDB creates a RoutingDB. Without replicas everything runs on the
+// primary.
+func NewRoutingDB(primary *sql.DB, replicas ...*sql.DB) *RoutingDB {
+	return &RoutingDB{primary: primary, replicas: replicas}
+}
+
+// Primary returns the primary database, for raw queries and transactions
+func (r *RoutingDB) Primary() *sql.DB {
+	return r.primary
+}
+
+// Replica returns the next replica, or the primary when there are none, for
+// raw queries known to only read
+func (r *RoutingDB) Replica() *sql.DB {
+	if len(r.replicas) == 0 {
+		return r.primary
+	}
+	_, db := r.pick()
+	return db
+}
+
+// Prepare creates a prepared statement for b, routed by its statement kind
+func (r *RoutingDB) Prepare(b *Builder) (*Stmt, error) {
+	stmt, err := b.Prepare(r.primary)
+	if err != nil {
+		return nil, err
+	}
+	if b.kind == KindSelect && b.tx == nil && !b.primary && len(r.replicas) > 0 {
+		stmt.router = r
+	}
+	return stmt, nil
+}
+
+// RawQuery creates a raw query on the primary. Raw SQL is not classified;
+// pass Replica to WithDB to send a read elsewhere.
+func (r *RoutingDB) RawQuery(sql string, args ...interface{}) *RawQuery {
+	return New().RawQuery(sql, args...).WithDB(r.primary)
+}
+
+// Raw creates a raw query on the primary.
+//
+// Deprecated: use RawQuery.
+func (r *RoutingDB) Raw(sql string, args ...interface{}) *RawQuery {
+	return r.RawQuery(sql, args...)
+}
+
+// ForcePrimary makes RoutingDB run the statement on the primary even when it
+// only reads, for reading rows just written
+func (b *Builder) ForcePrimary() *Builder {
+	b.primary = true
+	return b
+}
+
+// pick returns the next replica in round-robin order
+func (r *RoutingDB) pick() (int, *sql.DB) {
+	i := int((r.next.Add(1) - 1) % uint64(len(r.replicas)))
+	return i, r.replicas[i]
+}
+
+// replicaConn runs a read on the next replica, retrying it on the primary
+// when the replica fails
+type replicaConn struct {
+	r     *RoutingDB
+	hooks hooks
+}
+
+func (c replicaConn) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return c.r.primary.ExecContext(ctx, query, args...)
+}
+
+func (c replicaConn) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	i, db := c.r.pick()
+	start := time.Now()
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err == nil || ctx.Err() != nil {
+		return rows, err
+	}
+	c.report(ctx, query, args, time.Since(start), i, err)
+	return c.r.primary.QueryContext(ctx, query, args...)
+}
+
+func (c replicaConn) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	i, db := c.r.pick()
+	start := time.Now()
+	row := db.QueryRowContext(ctx, query, args...)
+	err := row.Err()
+	if err == nil || errors.Is(err, sql.ErrNoRows) || ctx.Err() != nil {
+		return row
+	}
+	c.report(ctx, query, args, time.Since(start), i, err)
+	return c.r.primary.QueryRowContext(ctx, query, args...)
+}
+
+// report logs the failure of replica i
+func (c replicaConn) report(ctx context.Context, query string, args []interface{}, took time.Duration, i int, err error) {
+	if logger := c.hooks.resolvedLogger(); logger != nil {
+		logger.LogQuery(ctx, query, args, took, &ReplicaError{Replica: i, Err: err})
+	}
+}
@@ -0,0 +1,107 @@
+package toki
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoutingDB(t *testing.T) {
+	primary, primaryMock := newMockDB(t)
+	replica0, replica0Mock := newMockDB(t)
+	replica1, replica1Mock := newMockDB(t)
+	r := NewRoutingDB(primary, replica0, replica1)
+
+	rows := func() *sqlmock.Rows { return sqlmock.NewRows([]string{"id"}).AddRow(1) }
+	replica0Mock.ExpectQuery("SELECT id FROM users").WillReturnRows(rows())
+	replica1Mock.ExpectQuery("SELECT id FROM users").WillReturnRows(rows())
+	replica0Mock.ExpectQuery("SELECT id FROM users").WillReturnRows(rows())
+	primaryMock.ExpectExec("DELETE FROM users WHERE id = $1").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	primaryMock.ExpectQuery("SELECT id FROM users").WillReturnRows(rows())
+	primaryMock.ExpectQuery("SELECT now()").WillReturnRows(rows())
+
+	stmt, err := r.Prepare(New().Select("id").From("users"))
+	assert.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		rs, err := stmt.Query()
+		assert.NoError(t, err)
+		rs.Close()
+	}
+	var id int
+	assert.NoError(t, stmt.QueryRow().Scan(&id))
+
+	stmt, err = r.Prepare(New().Delete("users").Where("id = ?", 1))
+	assert.NoError(t, err)
+	_, err = stmt.Exec()
+	assert.NoError(t, err)
+
+	stmt, err = r.Prepare(New().Select("id").From("users").ForcePrimary())
+	assert.NoError(t, err)
+	assert.NoError(t, stmt.QueryRow().Scan(&id))
+
+	assert.NoError(t, r.Raw("SELECT now()").QueryRow().Scan(&id))
+
+	for _, mock := range []sqlmock.Sqlmock{primaryMock, replica0Mock, replica1Mock} {
+		assert.NoError(t, mock.ExpectationsWereMet())
+	}
+}
+
+func TestRoutingDBTransaction(t *testing.T) {
+	primary, primaryMock := newMockDB(t)
+	replica, replicaMock := newMockDB(t)
+	r := NewRoutingDB(primary, replica)
+
+	primaryMock.ExpectBegin()
+	primaryMock.ExpectQuery("SELECT id FROM users").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	primaryMock.ExpectCommit()
+
+	tx, err := Begin(r.Primary())
+	assert.NoError(t, err)
+	stmt, err := r.Prepare(tx.Builder().Select("id").From("users"))
+	assert.NoError(t, err)
+	var id int
+	assert.NoError(t, stmt.QueryRow().Scan(&id))
+	assert.NoError(t, tx.Commit())
+
+	assert.NoError(t, primaryMock.ExpectationsWereMet())
+	assert.NoError(t, replicaMock.ExpectationsWereMet())
+}
+
+func TestRoutingDBReplicaFallback(t *testing.T) {
+	primary, primaryMock := newMockDB(t)
+	replica, replicaMock := newMockDB(t)
+	r := NewRoutingDB(primary, replica)
+
+	failure := errors.New("connection refused")
+	replicaMock.ExpectQuery("SELECT id FROM users").WillReturnError(failure)
+	primaryMock.ExpectQuery("SELECT id FROM users").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	replicaMock.ExpectQuery("SELECT id FROM users WHERE id = $1").WithArgs(2).WillReturnError(failure)
+	primaryMock.ExpectQuery("SELECT id FROM users WHERE id = $1").WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	logger := &recordingLogger{}
+	stmt, err := r.Prepare(New().WithLogger(logger).Select("id").From("users"))
+	assert.NoError(t, err)
+	rows, err := stmt.Query()
+	assert.NoError(t, err)
+	rows.Close()
+
+	stmt, err = r.Prepare(New().WithLogger(logger).Select("id").From("users").Where("id = ?", 2))
+	assert.NoError(t, err)
+	var id int
+	assert.ErrorIs(t, stmt.QueryRow().Scan(&id), sql.ErrNoRows)
+
+	if assert.Len(t, logger.queries, 4) {
+		var replicaErr *ReplicaError
+		assert.ErrorAs(t, logger.queries[0].err, &replicaErr)
+		assert.Equal(t, 0, replicaErr.Replica)
+		assert.EqualError(t, replicaErr, "replica 0 failed, retrying on primary: connection refused")
+		assert.NoError(t, logger.queries[1].err)
+		assert.ErrorAs(t, logger.queries[2].err, &replicaErr)
+	}
+
+	assert.NoError(t, primaryMock.ExpectationsWereMet())
+	assert.NoError(t, replicaMock.ExpectationsWereMet())
+}
@@ -0,0 +1,150 @@
+package toki
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// NotFoundError is returned by the scalar helpers when the query selects no
+// rows. It wraps sql.ErrNoRows.
+type NotFoundError struct {
+	Query string
+}
+
+func (e *NotFoundError) Error() string {
+	return "no rows found (query: " + snippet(e.Query, 200) + ")"
+}
+
+func (e *NotFoundError) Unwrap() error { return sql.ErrNoRows }
+
+// ScanValue runs the query and scans the single column of its first row into
+// dest, a pointer such as *int64, *string or *time.Time. database/sql
+// converts textual driver values into numbers and booleans; time.Time and
+// *time.Time destinations also accept the textual timestamps some drivers
+// return, in the package-wide time location. It runs on the builder's
+// transaction when it has one, otherwise on db, and returns a *NotFoundError
+// when there is no row.
+func (b *Builder) ScanValue(ctx context.Context, db *sql.DB, dest interface{}) error {
+	if b.err != nil {
+		return b.err
+	}
+	if err := b.checkPagination(); err != nil {
+		return err
+	}
+
+	var tx *sql.Tx
+	if b.tx != nil {
+		tx = b.tx.tx
+	}
+	c := connFor(db, tx)
+	if c == nil {
+		return errors.New("ScanValue needs a database or transaction")
+	}
+
+	q := QueryInfo{SQL: b.String(), Args: b.Args(), Kind: b.kind, Table: b.table}
+	return scanValue(b.hooks.queryRow(ctx, c, q), q.SQL, dest)
+}
+
+// ScanInt64 runs the query and returns the integer in its first row, for
+// counts and MAX(id)
+func (b *Builder) ScanInt64(ctx context.Context, db *sql.DB) (int64, error) {
+	var v int64
+	err := b.ScanValue(ctx, db, &v)
+	return v, err
+}
+
+// ScanString runs the query and returns the string in its first row
+func (b *Builder) ScanString(ctx context.Context, db *sql.DB) (string, error) {
+	var v string
+	err := b.ScanValue(ctx, db, &v)
+	return v, err
+}
+
+// ScanBool runs the query and returns the boolean in its first row, for
+// EXISTS subqueries
+func (b *Builder) ScanBool(ctx context.Context, db *sql.DB) (bool, error) {
+	var v bool
+	err := b.ScanValue(ctx, db, &v)
+	return v, err
+}
+
+// ScanTime runs the query and returns the time in its first row
+func (b *Builder) ScanTime(ctx context.Context, db *sql.DB) (time.Time, error) {
+	var v time.Time
+	err := b.ScanValue(ctx, db, &v)
+	return v, err
+}
+
+// ScanValue runs the query and scans the single column of its first row into
+// dest, converting values like Builder.ScanValue. It returns a
+// *NotFoundError when there is no row.
+func (r *RawQuery) ScanValue(dest interface{}) error {
+	err := scanValue(r.QueryRow(), r.sql, dest)
+	var notFound *NotFoundError
+	if errors.As(err, &notFound) {
+		return err
+	}
+	return r.wrapErr(err)
+}
+
+// ScanInt64 runs the query and returns the integer in its first row
+func (r *RawQuery) ScanInt64() (int64, error) {
+	var v int64
+	err := r.ScanValue(&v)
+	return v, err
+}
+
+// ScanString runs the query and returns the string in its first row
+func (r *RawQuery) ScanString() (string, error) {
+	var v string
+	err := r.ScanValue(&v)
+	return v, err
+}
+
+// ScanBool runs the query and returns the boolean in its first row
+func (r *RawQuery) ScanBool() (bool, error) {
+	var v bool
+	err := r.ScanValue(&v)
+	return v, err
+}
+
+// ScanTime runs the query and returns the time in its first row
+func (r *RawQuery) ScanTime() (time.Time, error) {
+	var v time.Time
+	err := r.ScanValue(&v)
+	return v, err
+}
+
+// scanValue scans row into dest, parsing textual times and turning
+// sql.ErrNoRows into a *NotFoundError for query
+func scanValue(row *Row, query string, dest interface{}) error {
+	var err error
+	switch d := dest.(type) {
+	case *time.Time:
+		h := &timeHolder{column: "value", loc: defaultTimeOptions.Location}
+		if err = row.Scan(h); err == nil {
+			if !h.valid {
+				return errors.New("the value is NULL; scan into a *time.Time to accept NULL")
+			}
+			*d = h.time
+		}
+	case **time.Time:
+		h := &timeHolder{column: "value", loc: defaultTimeOptions.Location}
+		if err = row.Scan(h); err == nil {
+			*d = nil
+			if h.valid {
+				t := h.time
+				*d = &t
+			}
+		}
+	default:
+		err = row.Scan(dest)
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return &NotFoundError{Query: query}
+	}
+	return err
+}
@@ -0,0 +1,81 @@
+package toki
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuilderScalars(t *testing.T) {
+	db, mock := newMockDB(t)
+	ctx := context.Background()
+
+	mock.ExpectQuery("SELECT COUNT(*) FROM users WHERE active = $1").WithArgs(true).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow([]byte("42")))
+	n, err := New().Select("COUNT(*)").From("users").Where("active = ?", true).ScanInt64(ctx, db)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), n)
+
+	mock.ExpectQuery("SELECT value FROM settings WHERE key = $1").WithArgs("theme").
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow([]byte("dark")))
+	s, err := New().Select("value").From("settings").Where("key = ?", "theme").ScanString(ctx, db)
+	assert.NoError(t, err)
+	assert.Equal(t, "dark", s)
+
+	mock.ExpectQuery("SELECT EXISTS (SELECT 1 FROM users)").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(int64(1)))
+	ok, err := New().Select("EXISTS (SELECT 1 FROM users)").ScanBool(ctx, db)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	mock.ExpectQuery("SELECT MAX(created_at) FROM users").
+		WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow("2024-03-01 12:30:00"))
+	ts, err := New().Select("MAX(created_at)").From("users").ScanTime(ctx, db)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC), ts)
+
+	mock.ExpectQuery("SELECT MAX(deleted_at) FROM users").
+		WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(nil))
+	deleted := &TestTime
+	assert.NoError(t, New().Select("MAX(deleted_at)").From("users").ScanValue(ctx, db, &deleted))
+	assert.Nil(t, deleted)
+
+	mock.ExpectQuery("SELECT id FROM users WHERE email = $1").WithArgs("x@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	_, err = New().Select("id").From("users").Where("email = ?", "x@example.com").ScanInt64(ctx, db)
+	var notFound *NotFoundError
+	assert.ErrorAs(t, err, &notFound)
+	assert.True(t, errors.Is(err, sql.ErrNoRows))
+	assert.Equal(t, "no rows found (query: SELECT id FROM users WHERE email = $1)", err.Error())
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRawQueryScalars(t *testing.T) {
+	db, mock := newMockDB(t)
+
+	mock.ExpectQuery("SELECT count(*) FROM users").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(3)))
+	n, err := New().Raw("SELECT count(*) FROM users").WithDB(db).ScanInt64()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+
+	mock.ExpectQuery("SELECT name FROM users WHERE id = $1").WithArgs(9).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}))
+	_, err = New().Raw("SELECT name FROM users WHERE id = $1", 9).WithDB(db).ScanString()
+	assert.True(t, errors.Is(err, sql.ErrNoRows))
+	assert.Equal(t, "no rows found (query: SELECT name FROM users WHERE id = $1)", err.Error())
+
+	mock.ExpectQuery("SELECT flag FROM settings").
+		WillReturnRows(sqlmock.NewRows([]string{"flag"}).AddRow("maybe"))
+	_, err = New().Raw("SELECT flag FROM settings").WithDB(db).ScanBool()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "(query: SELECT flag FROM settings)")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
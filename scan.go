@@ -0,0 +1,480 @@
+package toki
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ScanOption configures how rows are scanned into structs
+type ScanOption func(*scanConfig)
+
+type scanConfig struct {
+	mode      ScanMode
+	prefixes  map[string]string
+	location  *time.Location
+	columnMap map[string]string
+	// extras scans result columns into targets of their own instead of
+	// struct fields
+	extras map[string]interface{}
+}
+
+// ScanMode controls what scanning does when the result columns and the
+// struct fields do not match
+type ScanMode int
+
+const (
+	// ScanLenient ignores result columns without a destination field and
+	// fields without a result column
+	ScanLenient ScanMode = iota
+	// ScanStrict fails when a result column has no destination field
+	ScanStrict
+	// ScanStrictFields fails like ScanStrict, and also when a field has no
+	// result column
+	ScanStrictFields
+)
+
+// defaultScanMode is used by scans without a mode of their own
+var defaultScanMode = ScanLenient
+
+// SetScanMode sets the package-wide scan mode, ScanLenient by default. Call
+// it during initialization, before queries run.
+func SetScanMode(mode ScanMode) {
+	defaultScanMode = mode
+}
+
+// WithScanMode scans with mode instead of the package-wide mode
+func WithScanMode(mode ScanMode) ScanOption {
+	return func(c *scanConfig) {
+		c.mode = mode
+	}
+}
+
+// Strict makes scanning fail when a result column has no destination field.
+// It is short for WithScanMode(ScanStrict).
+func Strict() ScanOption {
+	return WithScanMode(ScanStrict)
+}
+
+// Lenient ignores unmatched columns and fields even when the package-wide
+// mode is strict. It is short for WithScanMode(ScanLenient).
+func Lenient() ScanOption {
+	return WithScanMode(ScanLenient)
+}
+
+// ScanMismatchError reports the result columns and struct fields that did
+// not match in a strict scan
+type ScanMismatchError struct {
+	// Type is the destination struct type
+	Type reflect.Type
+	// Columns are the result columns without a destination field
+	Columns []string
+	// Fields are the fields without a result column, with ScanStrictFields
+	Fields []string
+	// Mapped lists every field of Type with the column it maps to
+	Mapped []string
+}
+
+func (e *ScanMismatchError) Error() string {
+	var problems []string
+	if len(e.Columns) > 0 {
+		problems = append(problems, "columns "+strings.Join(e.Columns, ", ")+" have no destination field")
+	}
+	if len(e.Fields) > 0 {
+		problems = append(problems, "fields "+strings.Join(e.Fields, ", ")+" have no result column")
+	}
+	return fmt.Sprintf("%s in %s, which maps %s", strings.Join(problems, "; "), e.Type, strings.Join(e.Mapped, ", "))
+}
+
+// WithPrefix scans result columns starting with prefix into the nested
+// struct field with the given Go field name, matching the remainder of the
+// column name against the nested struct's columns. Nested fields tagged with
+// a db name are matched by "<tag><NestedSeparator>" without this option.
+func WithPrefix(field, prefix string) ScanOption {
+	return func(c *scanConfig) {
+		if c.prefixes == nil {
+			c.prefixes = make(map[string]string)
+		}
+		c.prefixes[prefix] = field
+	}
+}
+
+// scanExtra scans the result column col into target instead of the struct
+func scanExtra(col string, target interface{}) ScanOption {
+	return func(c *scanConfig) {
+		if c.extras == nil {
+			c.extras = make(map[string]interface{})
+		}
+		c.extras[col] = target
+	}
+}
+
+// ScanStruct scans the current row of rows into dest, which must be a
+// pointer to a struct. Columns are matched to fields using the same mapping
+// as Bind. Call rows.Next before each ScanStruct.
+func ScanStruct(rows *sql.Rows, dest interface{}, opts ...ScanOption) error {
+	val := reflect.ValueOf(dest)
+	if val.Kind() != reflect.Ptr || val.IsNil() || val.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("scan destination must be a non-nil pointer to a struct, got %T", dest)
+	}
+
+	plan, err := newScanPlan(rows, val.Elem().Type(), newScanConfig(opts))
+	if err != nil {
+		return err
+	}
+
+	return plan.scan(rows, val.Elem())
+}
+
+// ScanAll scans every remaining row of rows into dest, which must be a
+// pointer to a slice of structs or struct pointers, and closes rows. The
+// column to field plan is built once for the whole result set.
+func ScanAll(rows *sql.Rows, dest interface{}, opts ...ScanOption) error {
+	defer rows.Close()
+
+	val := reflect.ValueOf(dest)
+	if val.Kind() != reflect.Ptr || val.IsNil() || val.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("scan destination must be a non-nil pointer to a slice, got %T", dest)
+	}
+
+	slice := val.Elem()
+	elemType := slice.Type().Elem()
+	isPtr := elemType.Kind() == reflect.Ptr
+	structType := elemType
+	if isPtr {
+		structType = elemType.Elem()
+	}
+
+	if structType.Kind() != reflect.Struct {
+		return fmt.Errorf("scan destination must be a slice of structs, got %T", dest)
+	}
+
+	plan, err := newScanPlan(rows, structType, newScanConfig(opts))
+	if err != nil {
+		return err
+	}
+
+	for rows.Next() {
+		elem := reflect.New(structType)
+		if err := plan.scan(rows, elem.Elem()); err != nil {
+			return err
+		}
+
+		if isPtr {
+			slice.Set(reflect.Append(slice, elem))
+		} else {
+			slice.Set(reflect.Append(slice, elem.Elem()))
+		}
+	}
+
+	return rows.Err()
+}
+
+func newScanConfig(opts []ScanOption) *scanConfig {
+	cfg := &scanConfig{mode: defaultScanMode, location: defaultTimeOptions.Location}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// scanPlan maps result columns to struct fields
+type scanPlan struct {
+	columns  []string
+	fields   []*fieldInfo
+	location *time.Location
+	// extras[i] is the target of column i when it has one outside the
+	// struct
+	extras []interface{}
+	// group[i] is the index into groups of the optional pointer struct that
+	// column i lives under, or -1
+	group  []int
+	groups [][]int
+}
+
+// newScanPlan matches the columns of rows against the fields of typ
+func newScanPlan(rows *sql.Rows, typ reflect.Type, cfg *scanConfig) (*scanPlan, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read result columns: %w", err)
+	}
+
+	info := getStructInfo(typ)
+	plan := &scanPlan{
+		columns:  columns,
+		fields:   make([]*fieldInfo, len(columns)),
+		location: cfg.location,
+		group:    make([]int, len(columns)),
+	}
+	seen := make(map[string]bool, len(columns))
+	groupIDs := make(map[string]int)
+
+	var unmatched []string
+	for i, col := range columns {
+		plan.group[i] = -1
+
+		if target, ok := cfg.extras[col]; ok {
+			if plan.extras == nil {
+				plan.extras = make([]interface{}, len(columns))
+			}
+			plan.extras[i] = target
+			continue
+		}
+
+		field, ok := prefixedField(typ, col, cfg.prefixes)
+		if !ok {
+			if name, mapped := resultColumn(cfg.columnMap, col); mapped {
+				field, ok = info.column(name)
+			}
+		}
+		if !ok || seen[col] {
+			unmatched = append(unmatched, col)
+			continue
+		}
+		seen[col] = true
+		plan.fields[i] = field
+
+		if path := optionalPath(typ, field.index); path != nil {
+			key := fmt.Sprint(path)
+			id, ok := groupIDs[key]
+			if !ok {
+				id = len(plan.groups)
+				groupIDs[key] = id
+				plan.groups = append(plan.groups, path)
+			}
+			plan.group[i] = id
+		}
+	}
+
+	if cfg.mode == ScanLenient {
+		return plan, nil
+	}
+
+	var missing []string
+	if cfg.mode == ScanStrictFields {
+		missing = plan.missingFields(typ, info, cfg.columnMap)
+	}
+	if len(unmatched) > 0 || len(missing) > 0 {
+		mapped := make([]string, 0, len(info.fields))
+		for _, f := range info.fields {
+			if column, ok := mapColumn(cfg.columnMap, f.column); ok {
+				mapped = append(mapped, fieldPath(typ, f.index)+" ("+column+")")
+			}
+		}
+		return nil, &ScanMismatchError{Type: typ, Columns: unmatched, Fields: missing, Mapped: mapped}
+	}
+
+	return plan, nil
+}
+
+// missingFields returns the mapped fields of typ that no result column
+// scans into, as "Field (column)". Fields left out by columnMap are not
+// missing.
+func (p *scanPlan) missingFields(typ reflect.Type, info *structInfo, columnMap map[string]string) []string {
+	scanned := make(map[string]bool, len(p.fields))
+	for _, f := range p.fields {
+		if f != nil {
+			scanned[fmt.Sprint(f.index)] = true
+		}
+	}
+
+	var missing []string
+	for _, f := range info.fields {
+		column, ok := mapColumn(columnMap, f.column)
+		if !ok {
+			continue
+		}
+		if !scanned[fmt.Sprint(f.index)] {
+			missing = append(missing, fieldPath(typ, f.index)+" ("+column+")")
+		}
+	}
+	return missing
+}
+
+// fieldPath returns the Go name of the field at index, such as Author.Name
+// for a nested field
+func fieldPath(typ reflect.Type, index []int) string {
+	names := make([]string, len(index))
+	for i, x := range index {
+		if typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+		field := typ.Field(x)
+		names[i] = field.Name
+		typ = field.Type
+	}
+	return strings.Join(names, ".")
+}
+
+// prefixedField resolves col through a WithPrefix mapping
+func prefixedField(typ reflect.Type, col string, prefixes map[string]string) (*fieldInfo, bool) {
+	for prefix, name := range prefixes {
+		if !strings.HasPrefix(col, prefix) {
+			continue
+		}
+
+		sf, ok := typ.FieldByName(name)
+		if !ok || len(sf.Index) != 1 {
+			continue
+		}
+
+		nested := sf.Type
+		if nested.Kind() == reflect.Ptr {
+			nested = nested.Elem()
+		}
+		if nested.Kind() != reflect.Struct {
+			continue
+		}
+
+		inner, ok := getStructInfo(nested).column(strings.TrimPrefix(col, prefix))
+		if !ok {
+			continue
+		}
+
+		field := *inner
+		field.column = col
+		field.index = append([]int{sf.Index[0]}, inner.index...)
+		field.depth++
+		return &field, true
+	}
+
+	return nil, false
+}
+
+// optionalPath returns the index path of the outermost pointer struct that
+// index passes through, or nil when the field is reached without one
+func optionalPath(typ reflect.Type, index []int) []int {
+	for i, x := range index[:len(index)-1] {
+		field := typ.Field(x)
+		if field.Type.Kind() == reflect.Ptr {
+			return index[:i+1]
+		}
+		typ = field.Type
+	}
+	return nil
+}
+
+// scan reads the current row into the struct val. Every column is scanned
+// through a holder so NULLs can be reported by column name and so a pointer
+// struct whose columns are all NULL (a LEFT JOIN miss) is left nil.
+func (p *scanPlan) scan(rows *sql.Rows, val reflect.Value) error {
+	targets := make([]interface{}, len(p.columns))
+	holders := make([]reflect.Value, len(p.columns))
+
+	for i, f := range p.fields {
+		if f == nil {
+			if p.extras != nil && p.extras[i] != nil {
+				targets[i] = p.extras[i]
+			} else {
+				targets[i] = new(interface{})
+			}
+			continue
+		}
+
+		ft := val.Type().FieldByIndex(f.index).Type
+		if f.json {
+			holders[i] = reflect.New(bytesType)
+		} else if ft == timeType || ft == reflect.PtrTo(timeType) {
+			holders[i] = reflect.ValueOf(&timeHolder{column: p.columns[i], loc: p.location})
+		} else if nilable(ft) {
+			holders[i] = reflect.New(ft)
+		} else {
+			holders[i] = reflect.New(reflect.PtrTo(ft))
+		}
+		targets[i] = holders[i].Interface()
+	}
+
+	if err := rows.Scan(targets...); err != nil {
+		return fmt.Errorf("failed to scan row: %w", err)
+	}
+
+	present := make([]bool, len(p.groups))
+	for i, holder := range holders {
+		if holder.IsValid() && p.group[i] >= 0 && !holderNull(holder) {
+			present[p.group[i]] = true
+		}
+	}
+
+	for id, path := range p.groups {
+		if !present[id] {
+			ptr := fieldByIndexAlloc(val, path)
+			ptr.Set(reflect.Zero(ptr.Type()))
+		}
+	}
+
+	for i, holder := range holders {
+		if !holder.IsValid() || (p.group[i] >= 0 && !present[p.group[i]]) {
+			continue
+		}
+
+		dst := fieldByIndexAlloc(val, p.fields[i].index)
+		if th, ok := holder.Interface().(*timeHolder); ok {
+			if err := setTime(th, dst); err != nil {
+				return err
+			}
+			continue
+		}
+
+		value := holder.Elem()
+		switch {
+		case p.fields[i].json:
+			if err := scanJSON(p.columns[i], value.Bytes(), dst); err != nil {
+				return err
+			}
+		case nilable(dst.Type()):
+			dst.Set(value)
+		case value.IsNil():
+			if !nullable(dst.Type()) {
+				return fmt.Errorf("column %q is NULL but field type %s is not nullable", p.columns[i], dst.Type())
+			}
+			dst.Set(reflect.Zero(dst.Type()))
+		default:
+			dst.Set(value.Elem())
+		}
+	}
+
+	return nil
+}
+
+// holderNull reports whether the column scanned into holder was NULL
+func holderNull(holder reflect.Value) bool {
+	if th, ok := holder.Interface().(*timeHolder); ok {
+		return !th.valid
+	}
+	return holder.Elem().IsNil()
+}
+
+// setTime stores a scanned time into a time.Time or *time.Time field
+func setTime(th *timeHolder, dst reflect.Value) error {
+	if dst.Kind() == reflect.Ptr {
+		if !th.valid {
+			dst.Set(reflect.Zero(dst.Type()))
+			return nil
+		}
+		t := th.time
+		dst.Set(reflect.ValueOf(&t))
+		return nil
+	}
+
+	if !th.valid {
+		return fmt.Errorf("column %q is NULL but field type %s is not nullable", th.column, dst.Type())
+	}
+	dst.Set(reflect.ValueOf(th.time))
+	return nil
+}
+
+// nilable reports whether t has a nil value that represents NULL
+func nilable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+		return true
+	}
+	return false
+}
+
+// nullable reports whether a field of type t can receive NULL
+func nullable(t reflect.Type) bool {
+	return nilable(t) || reflect.PtrTo(t).Implements(scannerType)
+}
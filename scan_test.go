@@ -0,0 +1,461 @@
+package toki
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+type scanUser struct {
+	Base
+	Name     string  `db:"name"`
+	Nickname *string `db:"nickname"`
+	Email    sql.NullString
+	Visits   int `db:"-"`
+}
+
+func TestScanStruct(t *testing.T) {
+	db, mock, _ := setupTest(t)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "nickname", "email", "created_at", "extra"}).
+			AddRow(1, TestUser, nil, "zakir@example.com", TestTime, "ignored"))
+
+	rows, err := db.Query("SELECT * FROM users")
+	assert.NoError(t, err)
+	defer rows.Close()
+
+	var user scanUser
+	assert.True(t, rows.Next())
+	assert.NoError(t, ScanStruct(rows, &user))
+
+	assert.Equal(t, 1, user.ID)
+	assert.Equal(t, TestTime, user.CreatedAt)
+	assert.Equal(t, TestUser, user.Name)
+	assert.Nil(t, user.Nickname)
+	assert.Equal(t, sql.NullString{String: "zakir@example.com", Valid: true}, user.Email)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	t.Log("---- Pass ----")
+}
+
+func TestScanAll(t *testing.T) {
+	db, mock, _ := setupTest(t)
+	defer db.Close()
+
+	nickname := "zak"
+	mock.ExpectQuery("SELECT").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "nickname"}).
+			AddRow(1, TestUser, nickname).
+			AddRow(2, "gopher", nil))
+	mock.ExpectQuery("SELECT").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).
+			AddRow(3, "ptr"))
+
+	rows, err := db.Query("SELECT id, name, nickname FROM users")
+	assert.NoError(t, err)
+
+	var users []scanUser
+	assert.NoError(t, ScanAll(rows, &users))
+	assert.Len(t, users, 2)
+	assert.Equal(t, &nickname, users[0].Nickname)
+	assert.Equal(t, "gopher", users[1].Name)
+	assert.Nil(t, users[1].Nickname)
+
+	rows, err = db.Query("SELECT id, name FROM users")
+	assert.NoError(t, err)
+
+	var ptrs []*scanUser
+	assert.NoError(t, ScanAll(rows, &ptrs))
+	assert.Len(t, ptrs, 1)
+	assert.Equal(t, 3, ptrs[0].ID)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	t.Log("---- Pass ----")
+}
+
+func TestScanErrors(t *testing.T) {
+	t.Run("NULL into non-pointer field names the column", func(t *testing.T) {
+		db, mock, _ := setupTest(t)
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, nil))
+
+		rows, err := db.Query("SELECT id, name FROM users")
+		assert.NoError(t, err)
+
+		var users []scanUser
+		err = ScanAll(rows, &users)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), `column "name" is NULL`)
+	})
+
+	t.Run("Strict mode reports unmatched columns", func(t *testing.T) {
+		db, mock, _ := setupTest(t)
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "full_name", "age"}).AddRow(1, "x", 3))
+
+		rows, err := db.Query("SELECT id, full_name, age FROM users")
+		assert.NoError(t, err)
+
+		var users []scanUser
+		err = ScanAll(rows, &users, Strict())
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "full_name, age")
+	})
+
+	t.Run("Strict fields mode reports fields without a column", func(t *testing.T) {
+		db, mock, _ := setupTest(t)
+		defer db.Close()
+
+		type account struct {
+			ID    int    `db:"id"`
+			Owner string `db:"owner"`
+		}
+
+		mock.ExpectQuery("SELECT").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "owner_name"}).AddRow(1, "x"))
+
+		rows, err := db.Query("SELECT id, owner_name FROM accounts")
+		assert.NoError(t, err)
+
+		var accounts []account
+		err = ScanAll(rows, &accounts, WithScanMode(ScanStrictFields))
+		var mismatch *ScanMismatchError
+		assert.ErrorAs(t, err, &mismatch)
+		assert.Equal(t, []string{"owner_name"}, mismatch.Columns)
+		assert.Equal(t, []string{"Owner (owner)"}, mismatch.Fields)
+		assert.EqualError(t, err, "columns owner_name have no destination field; fields Owner (owner) have no result column "+
+			"in toki.account, which maps ID (id), Owner (owner)")
+	})
+
+	t.Run("Package-wide mode and per-call override", func(t *testing.T) {
+		db, mock, _ := setupTest(t)
+		defer db.Close()
+
+		SetScanMode(ScanStrict)
+		defer SetScanMode(ScanLenient)
+
+		mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"id", "extra"}).AddRow(1, 2))
+		mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"id", "extra"}).AddRow(1, 2))
+
+		rows, err := db.Query("SELECT id, extra FROM users")
+		assert.NoError(t, err)
+		var users []scanUser
+		assert.Error(t, ScanAll(rows, &users))
+
+		rows, err = db.Query("SELECT id, extra FROM users")
+		assert.NoError(t, err)
+		assert.NoError(t, ScanAll(rows, &users, Lenient()))
+		assert.Len(t, users, 1)
+	})
+
+	t.Run("Invalid destinations", func(t *testing.T) {
+		db, mock, _ := setupTest(t)
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+		rows, err := db.Query("SELECT id FROM users")
+		assert.NoError(t, err)
+		defer rows.Close()
+		assert.True(t, rows.Next())
+
+		var user scanUser
+		assert.Error(t, ScanStruct(rows, user))
+		assert.Error(t, ScanStruct(rows, (*scanUser)(nil)))
+		assert.Error(t, ScanAll(rows, &user))
+
+		var ints []int
+		assert.Error(t, ScanAll(rows, &ints))
+	})
+
+	t.Log("---- Pass ----")
+}
+
+func TestScanValuerScanner(t *testing.T) {
+	type Member struct {
+		ID     int    `db:"id"`
+		Email  Email  `db:"email"`
+		Backup *Email `db:"backup_email"`
+		Seen   time.Time
+	}
+
+	db, mock, _ := setupTest(t)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "backup_email", "seen"}).
+			AddRow(1, []byte("zakir@example.com"), nil, TestTime))
+
+	rows, err := db.Query("SELECT * FROM members")
+	assert.NoError(t, err)
+
+	var members []Member
+	assert.NoError(t, ScanAll(rows, &members))
+	assert.Equal(t, Email{Local: "zakir", Domain: "example.com"}, members[0].Email)
+	assert.Nil(t, members[0].Backup)
+	assert.Equal(t, TestTime, members[0].Seen)
+
+	t.Log("---- Pass ----")
+}
+
+func TestScanJoinedRows(t *testing.T) {
+	type User struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+	type Profile struct {
+		ID  int    `db:"id"`
+		Bio string `db:"bio"`
+	}
+
+	t.Run("Prefixes from nested field tags", func(t *testing.T) {
+		type UserWithProfile struct {
+			User    User     `db:"u"`
+			Profile *Profile `db:"p"`
+		}
+
+		db, mock, _ := setupTest(t)
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT").
+			WillReturnRows(sqlmock.NewRows([]string{"u_id", "u_name", "p_id", "p_bio"}).
+				AddRow(1, TestUser, 10, "gopher").
+				AddRow(2, "nobody", nil, nil))
+
+		rows, err := db.Query("SELECT u.id AS u_id, u.name AS u_name, p.id AS p_id, p.bio AS p_bio FROM users u LEFT JOIN profiles p ON p.user_id = u.id")
+		assert.NoError(t, err)
+
+		var results []UserWithProfile
+		assert.NoError(t, ScanAll(rows, &results, Strict()))
+		assert.Len(t, results, 2)
+
+		assert.Equal(t, User{ID: 1, Name: TestUser}, results[0].User)
+		assert.Equal(t, &Profile{ID: 10, Bio: "gopher"}, results[0].Profile)
+
+		assert.Equal(t, User{ID: 2, Name: "nobody"}, results[1].User)
+		assert.Nil(t, results[1].Profile, "all-NULL LEFT JOIN columns leave the pointer nil")
+	})
+
+	t.Run("Explicit prefix mapping", func(t *testing.T) {
+		type UserWithProfile struct {
+			U User     `db:"-"`
+			P *Profile `db:"-"`
+		}
+
+		db, mock, _ := setupTest(t)
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT").
+			WillReturnRows(sqlmock.NewRows([]string{"usr.id", "usr.name", "prof.id", "prof.bio"}).
+				AddRow(1, TestUser, 10, "gopher"))
+
+		rows, err := db.Query("SELECT * FROM users_with_profiles")
+		assert.NoError(t, err)
+
+		var results []UserWithProfile
+		assert.NoError(t, ScanAll(rows, &results, WithPrefix("U", "usr."), WithPrefix("P", "prof."), Strict()))
+		assert.Equal(t, User{ID: 1, Name: TestUser}, results[0].U)
+		assert.Equal(t, &Profile{ID: 10, Bio: "gopher"}, results[0].P)
+	})
+
+	t.Log("---- Pass ----")
+}
+
+// Level marshals itself as a JSON string
+type Level int
+
+func (l Level) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf(`"level-%d"`, int(l))), nil
+}
+
+func (l *Level) UnmarshalJSON(data []byte) error {
+	_, err := fmt.Sscanf(string(data), `"level-%d"`, (*int)(l))
+	return err
+}
+
+func TestJSONColumns(t *testing.T) {
+	type Settings struct {
+		Theme string `json:"theme"`
+		Level Level  `json:"level"`
+	}
+	type Account struct {
+		ID       int               `db:"id"`
+		Settings Settings          `db:"settings,json"`
+		Labels   map[string]string `db:"labels,json,omitempty"`
+		Extra    *Settings         `db:"extra,json"`
+	}
+
+	account := Account{ID: 1, Settings: Settings{Theme: "dark", Level: 3}}
+
+	db, mock, _ := setupTest(t)
+	defer db.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO accounts (id, settings, extra) VALUES ($1, $2, $3)")).
+		WithArgs(1, `{"theme":"dark","level":"level-3"}`, nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE accounts SET labels = $1 WHERE id = $2")).
+		WithArgs(`{"env":"prod"}`, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "settings", "labels", "extra"}).
+			AddRow(1, []byte(`{"theme":"dark","level":"level-3"}`), `{"env":"prod"}`, nil))
+	mock.ExpectQuery("SELECT").
+		WillReturnRows(sqlmock.NewRows([]string{"settings"}).AddRow("not json"))
+
+	stmt, err := New().InsertStruct("accounts", &account).Prepare(db)
+	assert.NoError(t, err)
+	_, err = stmt.Exec()
+	assert.NoError(t, err)
+
+	stmt, err = New().Update("accounts").
+		Set(map[string]interface{}{"labels": JSON(map[string]string{"env": "prod"})}).
+		Where("id = ?", 1).
+		Prepare(db)
+	assert.NoError(t, err)
+	_, err = stmt.Exec()
+	assert.NoError(t, err)
+
+	rows, err := db.Query("SELECT id, settings, labels, extra FROM accounts")
+	assert.NoError(t, err)
+
+	var accounts []Account
+	assert.NoError(t, ScanAll(rows, &accounts))
+	assert.Equal(t, account.Settings, accounts[0].Settings)
+	assert.Equal(t, map[string]string{"env": "prod"}, accounts[0].Labels)
+	assert.Nil(t, accounts[0].Extra)
+
+	rows, err = db.Query("SELECT settings FROM accounts")
+	assert.NoError(t, err)
+	err = ScanAll(rows, &accounts)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `column "settings"`)
+	assert.Contains(t, err.Error(), "Settings")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	t.Log("---- Pass ----")
+}
+
+func TestJSONMarshalError(t *testing.T) {
+	type Broken struct {
+		Payload interface{} `db:"payload,json"`
+	}
+
+	bindings := New().Bind(&Broken{Payload: make(chan int)})
+	_, err := bindings["payload"].(driver.Valuer).Value()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `column "payload"`)
+	assert.Contains(t, err.Error(), "chan int")
+
+	t.Log("---- Pass ----")
+}
+
+func TestScanTimeOptions(t *testing.T) {
+	type Event struct {
+		Day       time.Time  `db:"day"`
+		StartsAt  time.Time  `db:"starts_at"`
+		CreatedAt *time.Time `db:"created_at"`
+		EndsAt    *time.Time `db:"ends_at"`
+	}
+
+	jakarta := time.FixedZone("WIB", 7*60*60)
+
+	newRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"day", "starts_at", "created_at", "ends_at"}).
+			// DATE as bytes, TIMESTAMP as text, TIMESTAMPTZ as time.Time
+			AddRow([]byte("2024-12-23"), "2024-12-23 05:45:29.5", TestTime, nil)
+	}
+
+	t.Run("Driver values without location", func(t *testing.T) {
+		db, mock, _ := setupTest(t)
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT").WillReturnRows(newRows())
+		rows, err := db.Query("SELECT * FROM events")
+		assert.NoError(t, err)
+
+		var events []Event
+		assert.NoError(t, ScanAll(rows, &events))
+		assert.Equal(t, time.Date(2024, 12, 23, 0, 0, 0, 0, time.UTC), events[0].Day)
+		assert.Equal(t, time.Date(2024, 12, 23, 5, 45, 29, 500000000, time.UTC), events[0].StartsAt)
+		assert.Equal(t, TestTime, *events[0].CreatedAt)
+		assert.Nil(t, events[0].EndsAt)
+	})
+
+	t.Run("Scan location", func(t *testing.T) {
+		db, mock, _ := setupTest(t)
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT").WillReturnRows(newRows())
+		rows, err := db.Query("SELECT * FROM events")
+		assert.NoError(t, err)
+
+		var events []Event
+		assert.NoError(t, ScanAll(rows, &events, WithLocation(jakarta)))
+		assert.Equal(t, time.Date(2024, 12, 23, 0, 0, 0, 0, jakarta), events[0].Day, "dates are midnight in the target location")
+		assert.Equal(t, jakarta, events[0].CreatedAt.Location())
+		assert.True(t, TestTime.Equal(*events[0].CreatedAt))
+	})
+
+	t.Run("Package-level location", func(t *testing.T) {
+		SetTimeOptions(TimeOptions{Location: jakarta})
+		defer SetTimeOptions(TimeOptions{})
+
+		db, mock, _ := setupTest(t)
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT").WillReturnRows(newRows())
+		rows, err := db.Query("SELECT * FROM events")
+		assert.NoError(t, err)
+
+		var events []Event
+		assert.NoError(t, ScanAll(rows, &events))
+		assert.Equal(t, jakarta, events[0].StartsAt.Location())
+	})
+
+	t.Run("Unparseable text names the column", func(t *testing.T) {
+		db, mock, _ := setupTest(t)
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"day"}).AddRow("yesterday"))
+		rows, err := db.Query("SELECT day FROM events")
+		assert.NoError(t, err)
+
+		var events []Event
+		err = ScanAll(rows, &events)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), `column "day"`)
+	})
+
+	t.Log("---- Pass ----")
+}
+
+func TestBindTimeUTC(t *testing.T) {
+	jakarta := time.FixedZone("WIB", 7*60*60)
+	local := TestTime.In(jakarta)
+
+	b := New().WithTimeOptions(TimeOptions{BindUTC: true}).
+		Insert("events", "starts_at").
+		Values(local)
+	assert.Equal(t, time.UTC, b.args[0].(time.Time).Location())
+	assert.True(t, TestTime.Equal(b.args[0].(time.Time)))
+
+	b = New().Select("*").From("events").Where("starts_at > ?", local)
+	assert.Equal(t, jakarta, b.args[0].(time.Time).Location(), "no conversion unless configured")
+
+	t.Log("---- Pass ----")
+}
@@ -0,0 +1,103 @@
+package toki
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ExecOption configures ExecScript and ExecChunked
+type ExecOption func(*execConfig)
+
+type execConfig struct {
+	inTx bool
+	// idColumn is the key column ExecReturningID returns
+	idColumn string
+}
+
+// InTransaction runs all the statements inside one transaction when no
+// transaction is attached, so a failing statement leaves no partial changes
+// behind.
+func InTransaction() ExecOption {
+	return func(c *execConfig) {
+		c.inTx = true
+	}
+}
+
+// ScriptError reports the statement of a script that failed to execute
+type ScriptError struct {
+	// Index is the 0-based position of the statement in the script. The
+	// error message counts from 1.
+	Index     int
+	Statement string
+	Err       error
+}
+
+func (e *ScriptError) Error() string {
+	return fmt.Sprintf("script statement %d (%s) failed: %v", e.Index+1, snippet(e.Statement, 60), e.Err)
+}
+
+func (e *ScriptError) Unwrap() error { return e.Err }
+
+// ExecScript splits the query into statements on semicolons and executes
+// them in order through the attached transaction or database. Semicolons
+// inside string literals, dollar-quoted bodies and comments do not split, so
+// function definitions survive intact. Execution stops at the first failing
+// statement, which is reported as a *ScriptError. Scripts take no args.
+func (r *RawQuery) ExecScript(ctx context.Context, opts ...ExecOption) error {
+	if len(r.args) > 0 {
+		return fmt.Errorf("script takes no args, got %d", len(r.args))
+	}
+
+	cfg := &execConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	statements := splitStatements(r.sql)
+
+	if r.tx != nil {
+		return r.execStatements(ctx, r.tx, statements)
+	}
+	if r.db == nil {
+		return ErrNoExecutor
+	}
+	if !cfg.inTx {
+		return r.execStatements(ctx, r.db, statements)
+	}
+
+	tx, err := r.hooks.begin(ctx, r.db, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin script transaction: %w", err)
+	}
+
+	if err := r.execStatements(ctx, tx, statements); err != nil {
+		if rbErr := r.hooks.rollback(ctx, tx); rbErr != nil {
+			return errors.Join(err, rbErr)
+		}
+		return err
+	}
+
+	return r.hooks.commit(ctx, tx)
+}
+
+// execStatements runs statements one by one, stopping at the first error
+func (r *RawQuery) execStatements(ctx context.Context, c conn, statements []string) error {
+	for i, stmt := range statements {
+		if _, err := r.hooks.exec(ctx, c, QueryInfo{SQL: stmt, Kind: KindRaw}); err != nil {
+			return &ScriptError{Index: i, Statement: stmt, Err: err}
+		}
+	}
+	return nil
+}
+
+// snippet collapses whitespace in s and shortens it to at most n bytes for
+// error messages
+func snippet(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
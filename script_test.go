@@ -0,0 +1,106 @@
+package toki
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+const testScript = `
+-- create the schema
+CREATE TABLE users (id SERIAL PRIMARY KEY, note TEXT DEFAULT 'a;b');
+
+CREATE FUNCTION touch() RETURNS trigger AS $$
+BEGIN
+    NEW.updated_at = now();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+/* seed; data */
+INSERT INTO users (note) VALUES ('it''s; fine');
+-- trailing comment;
+`
+
+func TestSplitStatements(t *testing.T) {
+	statements := splitStatements(testScript)
+
+	assert.Equal(t, []string{
+		"-- create the schema\nCREATE TABLE users (id SERIAL PRIMARY KEY, note TEXT DEFAULT 'a;b')",
+		"CREATE FUNCTION touch() RETURNS trigger AS $$\nBEGIN\n    NEW.updated_at = now();\n    RETURN NEW;\nEND;\n$$ LANGUAGE plpgsql",
+		"/* seed; data */\nINSERT INTO users (note) VALUES ('it''s; fine')",
+	}, statements)
+}
+
+func TestExecScript(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec("CREATE TABLE users").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE FUNCTION touch").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO users").WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err = New().Raw(testScript).WithDB(db).ExecScript(context.Background())
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExecScriptError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	failure := errors.New("syntax error")
+	mock.ExpectBegin()
+	mock.ExpectExec("CREATE TABLE users").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE FUNCTION touch").WillReturnError(failure)
+	mock.ExpectRollback()
+
+	err = New().Raw(testScript).WithDB(db).ExecScript(context.Background(), InTransaction())
+
+	var scriptErr *ScriptError
+	assert.True(t, errors.As(err, &scriptErr))
+	assert.Equal(t, 1, scriptErr.Index)
+	assert.ErrorIs(t, err, failure)
+	assert.Contains(t, err.Error(), "script statement 2 (CREATE FUNCTION touch() RETURNS trigger AS $$ BEGIN NEW.upda...)")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExecScriptInTransaction(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM a").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM b").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = New().Raw("DELETE FROM a; DELETE FROM b;").WithDB(db).ExecScript(context.Background(), InTransaction())
+	assert.NoError(t, err)
+
+	// an attached transaction is used as is
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM a").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	tx, err := Begin(db)
+	assert.NoError(t, err)
+	err = tx.Raw("DELETE FROM a").ExecScript(context.Background(), InTransaction())
+	assert.NoError(t, err)
+	assert.NoError(t, tx.Commit())
+
+	err = New().Raw("DELETE FROM a WHERE id = $1", 1).WithDB(db).ExecScript(context.Background())
+	assert.EqualError(t, err, "script takes no args, got 1")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,91 @@
+package toki
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// RowsLogger is a Logger that is also told how many rows a statement
+// affected. LogQueryRows is called instead of LogQuery, with rows -1 when
+// the count is unknown: for queries, failures and transaction control
+// statements.
+type RowsLogger interface {
+	Logger
+	LogQueryRows(ctx context.Context, query string, args []interface{}, duration time.Duration, rows int64, err error)
+}
+
+// SlogLogger is a Logger that writes each query as a structured record with
+// the message "query" and the attributes sql, args (the number of args),
+// duration_ms, rows when known and err on failure. Records are logged with
+// the query's context, so handlers can add attributes taken from it.
+type SlogLogger struct {
+	Logger *slog.Logger
+	// Level is the level of statements that succeed, slog.LevelDebug when
+	// nil
+	Level slog.Leveler
+	// SlowThreshold logs statements that succeed but take longer at
+	// SlowLevel, slog.LevelWarn when nil. Zero disables it.
+	SlowThreshold time.Duration
+	SlowLevel     slog.Leveler
+	// ErrorLevel is the level of statements that fail, slog.LevelError when
+	// nil
+	ErrorLevel slog.Leveler
+}
+
+// NewSlogLogger returns a Logger writing to l, or to the default slog logger
+// when l is nil
+func NewSlogLogger(l *slog.Logger) *SlogLogger {
+	if l == nil {
+		l = slog.Default()
+	}
+	return &SlogLogger{Logger: l}
+}
+
+// LogQuery implements Logger
+func (s *SlogLogger) LogQuery(ctx context.Context, query string, args []interface{}, duration time.Duration, err error) {
+	s.LogQueryRows(ctx, query, args, duration, -1, err)
+}
+
+// LogQueryRows implements RowsLogger
+func (s *SlogLogger) LogQueryRows(ctx context.Context, query string, args []interface{}, duration time.Duration, rows int64, err error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	level := s.level(duration, err)
+	if !s.Logger.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := make([]slog.Attr, 0, 5)
+	attrs = append(attrs,
+		slog.String("sql", query),
+		slog.Int("args", len(args)),
+		slog.Float64("duration_ms", float64(duration)/float64(time.Millisecond)),
+	)
+	if rows >= 0 {
+		attrs = append(attrs, slog.Int64("rows", rows))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.Any("err", err))
+	}
+	s.Logger.LogAttrs(ctx, level, "query", attrs...)
+}
+
+// level returns the level to log a statement at
+func (s *SlogLogger) level(duration time.Duration, err error) slog.Level {
+	switch {
+	case err != nil:
+		return levelOr(s.ErrorLevel, slog.LevelError)
+	case s.SlowThreshold > 0 && duration > s.SlowThreshold:
+		return levelOr(s.SlowLevel, slog.LevelWarn)
+	}
+	return levelOr(s.Level, slog.LevelDebug)
+}
+
+func levelOr(l slog.Leveler, fallback slog.Level) slog.Level {
+	if l == nil {
+		return fallback
+	}
+	return l.Level()
+}
@@ -0,0 +1,110 @@
+package toki
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+type requestIDKey struct{}
+
+// captureHandler keeps the records it handles, adding the request ID found
+// in the context
+type captureHandler struct {
+	level   slog.Level
+	records []slog.Record
+}
+
+func (h *captureHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *captureHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	h.records = append(h.records, r)
+	return nil
+}
+
+func (h *captureHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h *captureHandler) WithGroup(string) slog.Handler      { return h }
+
+func recordAttrs(r slog.Record) map[string]interface{} {
+	attrs := make(map[string]interface{})
+	r.Attrs(func(a slog.Attr) bool {
+		attrs[a.Key] = a.Value.Any()
+		return true
+	})
+	return attrs
+}
+
+func TestSlogLogger(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	h := &captureHandler{level: slog.LevelDebug}
+	logger := NewSlogLogger(slog.New(h))
+
+	failure := errors.New("boom")
+	mock.ExpectExec("UPDATE users").WithArgs("zakirkun", 1).WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectQuery("SELECT name FROM users").WithArgs(1).WillReturnError(failure)
+
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "req-7")
+	stmt, err := New().WithLogger(logger).WithContext(ctx).
+		Update("users").Set(map[string]interface{}{"name": "zakirkun"}).Where("id = ?", 1).Prepare(db)
+	assert.NoError(t, err)
+	_, err = stmt.Exec()
+	assert.NoError(t, err)
+
+	_, err = New().WithLogger(logger).RawQuery("SELECT name FROM users WHERE id = $1", 1).WithDB(db).Query()
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	if assert.Len(t, h.records, 2) {
+		ok := h.records[0]
+		assert.Equal(t, "query", ok.Message)
+		assert.Equal(t, slog.LevelDebug, ok.Level)
+		attrs := recordAttrs(ok)
+		assert.Equal(t, "UPDATE users SET name = $1 WHERE id = $2", attrs["sql"])
+		assert.Equal(t, int64(2), attrs["args"])
+		assert.Equal(t, int64(3), attrs["rows"])
+		assert.Equal(t, "req-7", attrs["request_id"])
+		assert.Contains(t, attrs, "duration_ms")
+		assert.NotContains(t, attrs, "err")
+
+		failed := h.records[1]
+		assert.Equal(t, slog.LevelError, failed.Level)
+		attrs = recordAttrs(failed)
+		assert.Equal(t, failure, attrs["err"])
+		assert.NotContains(t, attrs, "rows")
+	}
+}
+
+func TestSlogLoggerLevels(t *testing.T) {
+	h := &captureHandler{level: slog.LevelInfo}
+	logger := NewSlogLogger(slog.New(h))
+	logger.SlowThreshold = 100 * time.Millisecond
+
+	// fast statements log at Debug, below the handler's level
+	logger.LogQuery(context.Background(), "SELECT 1", nil, time.Millisecond, nil)
+	assert.Empty(t, h.records)
+
+	logger.LogQuery(context.Background(), "SELECT pg_sleep(1)", nil, time.Second, nil)
+	if assert.Len(t, h.records, 1) {
+		assert.Equal(t, slog.LevelWarn, h.records[0].Level)
+		assert.Equal(t, 1000.0, recordAttrs(h.records[0])["duration_ms"])
+	}
+
+	logger.Level = slog.LevelInfo
+	logger.LogQuery(context.Background(), "SELECT 1", nil, time.Millisecond, nil)
+	assert.Len(t, h.records, 2)
+}
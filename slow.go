@@ -0,0 +1,48 @@
+package toki
+
+import (
+	"context"
+	"time"
+)
+
+// SlowQueryFunc is called with a query that ran longer than the slow query
+// threshold
+type SlowQueryFunc func(ctx context.Context, query string, args []interface{}, took time.Duration)
+
+// slowQuery is a threshold and the callback it triggers
+type slowQuery struct {
+	threshold time.Duration
+	fn        SlowQueryFunc
+}
+
+// defaultSlowQuery is used by builders and raw queries without their own
+// threshold; nil disables the callback
+var defaultSlowQuery *slowQuery
+
+// SetSlowQueryThreshold calls fn after every database call that takes longer
+// than d, including calls that fail, such as timeouts. It is independent of
+// the Logger. A nil fn disables the callback. Call it during initialization,
+// before queries run.
+func SetSlowQueryThreshold(d time.Duration, fn SlowQueryFunc) {
+	defaultSlowQuery = newSlowQuery(d, fn)
+}
+
+// WithSlowQueryThreshold sets the slow query callback for statements run
+// from this builder
+func (b *Builder) WithSlowQueryThreshold(d time.Duration, fn SlowQueryFunc) *Builder {
+	b.hooks.slow = newSlowQuery(d, fn)
+	return b
+}
+
+// WithSlowQueryThreshold sets the slow query callback for this raw query
+func (r *RawQuery) WithSlowQueryThreshold(d time.Duration, fn SlowQueryFunc) *RawQuery {
+	r.hooks.slow = newSlowQuery(d, fn)
+	return r
+}
+
+func newSlowQuery(d time.Duration, fn SlowQueryFunc) *slowQuery {
+	if fn == nil {
+		return nil
+	}
+	return &slowQuery{threshold: d, fn: fn}
+}
@@ -0,0 +1,82 @@
+package toki
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlowQueryThreshold(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	var mu sync.Mutex
+	var slow []string
+	SetSlowQueryThreshold(20*time.Millisecond, func(ctx context.Context, query string, args []interface{}, took time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		slow = append(slow, query)
+	})
+	defer SetSlowQueryThreshold(0, nil)
+
+	mock.ExpectExec("UPDATE fast").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE slow").WillDelayFor(30 * time.Millisecond).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT timeout").WillDelayFor(30 * time.Millisecond).WillReturnError(errors.New("canceling statement due to statement timeout"))
+
+	_, err = New().Raw("UPDATE fast SET a = 1").WithDB(db).Exec()
+	assert.NoError(t, err)
+	_, err = New().Raw("UPDATE slow SET a = 1").WithDB(db).Exec()
+	assert.NoError(t, err)
+	_, err = New().Raw("SELECT timeout").WithDB(db).Query()
+	assert.Error(t, err)
+
+	assert.Equal(t, []string{"UPDATE slow SET a = 1", "SELECT timeout"}, slow)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBuilderSlowQueryThreshold(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	// a slow callback does not hold up other queries
+	release := make(chan struct{})
+	called := make(chan time.Duration, 1)
+	onSlow := func(ctx context.Context, query string, args []interface{}, took time.Duration) {
+		called <- took
+		<-release
+	}
+
+	mock.MatchExpectationsInOrder(false)
+	mock.ExpectExec("DELETE FROM a").WillDelayFor(20 * time.Millisecond).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM b").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	stmt, err := New().WithSlowQueryThreshold(10*time.Millisecond, onSlow).Delete("a").Prepare(db)
+	assert.NoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = stmt.Exec()
+	}()
+
+	took := <-called
+	assert.GreaterOrEqual(t, took, 20*time.Millisecond)
+
+	_, err = New().Raw("DELETE FROM b").WithDB(db).Exec()
+	assert.NoError(t, err)
+
+	close(release)
+	<-done
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
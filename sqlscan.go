@@ -0,0 +1,504 @@
+package toki
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// scanSQL splits query into runs of SQL code and runs of string literals,
+// quoted identifiers and comments, calling fn with the byte range of each
+// run. Placeholder handling only ever looks inside code runs, so a '?' or
+// ':name' inside 'text', "identifiers", -- comments, /* comments */ or
+// $tag$ dollar-quoted bodies $tag$ is left alone.
+func scanSQL(query string, fn func(start, end int, code bool)) {
+	codeStart := 0
+	i := 0
+
+	flush := func(end int) {
+		if end > codeStart {
+			fn(codeStart, end, true)
+		}
+	}
+
+	for i < len(query) {
+		// jump to the next byte that can open a literal or comment
+		next := strings.IndexAny(query[i:], "'\"`-/$")
+		if next < 0 {
+			break
+		}
+		i += next
+
+		var end int
+
+		switch c := query[i]; {
+		case c == '\'':
+			// E'...' strings allow backslash escapes
+			escapes := i > 0 && (query[i-1] == 'E' || query[i-1] == 'e') && (i == 1 || !isIdentByte(query[i-2]))
+			end = quotedEnd(query, i, '\'', escapes)
+		case c == '"' || c == '`':
+			end = quotedEnd(query, i, c, false)
+		case c == '-' && i+1 < len(query) && query[i+1] == '-':
+			end = strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query)
+			} else {
+				end += i + 1
+			}
+		case c == '/' && i+1 < len(query) && query[i+1] == '*':
+			end = blockCommentEnd(query, i)
+		case c == '$' && (i == 0 || !isIdentByte(query[i-1])):
+			end = dollarQuoteEnd(query, i)
+		}
+
+		if end == 0 {
+			i++
+			continue
+		}
+
+		flush(i)
+		fn(i, end, false)
+		i = end
+		codeStart = end
+	}
+
+	flush(len(query))
+}
+
+// quotedEnd returns the offset just past the quoted run starting at i. A
+// doubled quote character is an escaped quote.
+func quotedEnd(query string, i int, quote byte, backslash bool) int {
+	for j := i + 1; j < len(query); j++ {
+		switch query[j] {
+		case '\\':
+			if backslash {
+				j++
+			}
+		case quote:
+			if j+1 < len(query) && query[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return len(query)
+}
+
+// blockCommentEnd returns the offset just past the (possibly nested) block
+// comment starting at i
+func blockCommentEnd(query string, i int) int {
+	depth := 0
+	for j := i; j+1 < len(query); j++ {
+		switch {
+		case query[j] == '/' && query[j+1] == '*':
+			depth++
+			j++
+		case query[j] == '*' && query[j+1] == '/':
+			depth--
+			j++
+			if depth == 0 {
+				return j + 1
+			}
+		}
+	}
+	return len(query)
+}
+
+// dollarQuoteEnd returns the offset just past the dollar-quoted string
+// starting at i, or 0 if i does not start one (for example a $1 placeholder)
+func dollarQuoteEnd(query string, i int) int {
+	j := i + 1
+	for j < len(query) && isIdentByte(query[j]) {
+		if j == i+1 && query[j] >= '0' && query[j] <= '9' {
+			return 0
+		}
+		j++
+	}
+
+	if j >= len(query) || query[j] != '$' {
+		return 0
+	}
+
+	tag := query[i : j+1]
+	end := strings.Index(query[j+1:], tag)
+	if end < 0 {
+		return len(query)
+	}
+	return j + 1 + end + len(tag)
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
+
+// rebind replaces every ? placeholder outside literals and comments with
+// $N, numbering from start+1. It returns the rewritten query and the number
+// of placeholders replaced. A query without '?' is returned as is.
+func rebind(query string, start int) (string, int) {
+	marks := strings.Count(query, "?")
+	if marks == 0 {
+		return query, 0
+	}
+
+	var out strings.Builder
+	// each ? grows by at most the digits of the highest number
+	out.Grow(len(query) + placeholderWidth(start, marks) - marks)
+	n := start
+	var num [20]byte
+
+	scanSQL(query, func(from, to int, code bool) {
+		if !code {
+			out.WriteString(query[from:to])
+			return
+		}
+		for from < to {
+			i := strings.IndexByte(query[from:to], '?')
+			if i < 0 {
+				out.WriteString(query[from:to])
+				return
+			}
+			out.WriteString(query[from : from+i])
+			n++
+			out.Write(appendPlaceholder(num[:0], n))
+			from += i + 1
+		}
+	})
+
+	return out.String(), n - start
+}
+
+// rebindFor rewrites the ? or $N placeholders of query outside literals and
+// comments in the placeholder style of d, leaving a query already written in
+// that style as it is. Turning $N into ? reorders args to match, repeating
+// those referenced more than once. It returns the query and args to send,
+// or an error when the placeholders do not match the args.
+func rebindFor(d *Dialect, query string, args []interface{}) (string, []interface{}, error) {
+	marks, highest := 0, 0
+	// refs holds the $N numbers in query order
+	var refs []int
+	var out strings.Builder
+	out.Grow(len(query) + placeholderWidth(0, len(args)))
+	var num [20]byte
+
+	scanSQL(query, func(from, to int, code bool) {
+		if !code {
+			out.WriteString(query[from:to])
+			return
+		}
+		for i := from; i < to; i++ {
+			c := query[i]
+			if c == '?' {
+				marks++
+				out.Write(d.appendPlaceholder(num[:0], marks))
+				continue
+			}
+			if c != '$' || i > 0 && isIdentByte(query[i-1]) {
+				out.WriteByte(c)
+				continue
+			}
+			j := i + 1
+			for j < to && query[j] >= '0' && query[j] <= '9' {
+				j++
+			}
+			n, err := strconv.Atoi(query[i+1 : j])
+			if err != nil {
+				out.WriteByte(c)
+				continue
+			}
+			highest = max(highest, n)
+			refs = append(refs, n)
+			out.Write(d.appendPlaceholder(num[:0], n))
+			i = j - 1
+		}
+	})
+
+	switch {
+	case marks > 0 && highest > 0:
+		return query, args, errors.New("raw query mixes ? and $N placeholders")
+	case highest > 0:
+		if highest != len(args) {
+			return query, args, fmt.Errorf("raw query expects %d arg(s), got %d: %w", highest, len(args), ErrArgCount)
+		}
+		if d.placeholders == questionPlaceholders {
+			ordered := make([]interface{}, len(refs))
+			for i, n := range refs {
+				ordered[i] = args[n-1]
+			}
+			args = ordered
+		}
+	case marks > 0:
+		if marks != len(args) {
+			return query, args, fmt.Errorf("raw query expects %d arg(s), got %d: %w", marks, len(args), ErrArgCount)
+		}
+	default:
+		if n := d.countPlaceholders(query); n != len(args) {
+			return query, args, fmt.Errorf("raw query expects %d arg(s), got %d: %w", n, len(args), ErrArgCount)
+		}
+	}
+	return out.String(), args, nil
+}
+
+// renumber numbers the $N placeholders of a built statement in the order
+// they appear, in the placeholder style of d, and returns args in that
+// order. Builders number placeholders as their methods are called, so a
+// Where called before Set gives SET a = $2 WHERE id = $1, which becomes
+// SET a = $1 WHERE id = $2 with its args swapped. A placeholder referenced
+// twice keeps one number, or repeats its arg for ? placeholders.
+func renumber(d *Dialect, query string, args []interface{}) (string, []interface{}) {
+	if len(args) == 0 {
+		return query, args
+	}
+
+	var out strings.Builder
+	out.Grow(len(query) + placeholderWidth(0, len(args)))
+	var num [20]byte
+	// numbers maps each $N of query to its new number, 0 until it is seen
+	numbers := make([]int, len(args)+1)
+	ordered := make([]interface{}, 0, len(args))
+	same := true
+
+	scanSQL(query, func(from, to int, code bool) {
+		if !code {
+			out.WriteString(query[from:to])
+			return
+		}
+		for i := from; i < to; i++ {
+			c := query[i]
+			if c != '$' || i > 0 && isIdentByte(query[i-1]) {
+				out.WriteByte(c)
+				continue
+			}
+			j := i + 1
+			for j < to && query[j] >= '0' && query[j] <= '9' {
+				j++
+			}
+			n, err := strconv.Atoi(query[i+1 : j])
+			if err != nil || n < 1 || n > len(args) {
+				out.WriteByte(c)
+				continue
+			}
+			next := numbers[n]
+			if next == 0 || d.placeholders == questionPlaceholders {
+				ordered = append(ordered, args[n-1])
+				next = len(ordered)
+				if numbers[n] == 0 {
+					numbers[n] = next
+				}
+			}
+			same = same && next == n
+			out.Write(d.appendPlaceholder(num[:0], next))
+			i = j - 1
+		}
+	})
+
+	if same && len(ordered) == len(args) && d.placeholders == dollarPlaceholders {
+		return query, args
+	}
+	return out.String(), ordered
+}
+
+// shiftPlaceholders adds offset to every $N placeholder outside literals and
+// comments, so a rendered statement can be embedded after offset args
+func shiftPlaceholders(query string, offset int) string {
+	if offset == 0 || strings.IndexByte(query, '$') < 0 {
+		return query
+	}
+
+	var out strings.Builder
+	out.Grow(len(query) + 8)
+	var num [20]byte
+
+	scanSQL(query, func(from, to int, code bool) {
+		if !code {
+			out.WriteString(query[from:to])
+			return
+		}
+		for i := from; i < to; i++ {
+			c := query[i]
+			if c != '$' || i > 0 && isIdentByte(query[i-1]) {
+				out.WriteByte(c)
+				continue
+			}
+			j := i + 1
+			for j < to && query[j] >= '0' && query[j] <= '9' {
+				j++
+			}
+			n, err := strconv.Atoi(query[i+1 : j])
+			if err != nil {
+				out.WriteByte(c)
+				continue
+			}
+			out.Write(appendPlaceholder(num[:0], n+offset))
+			i = j - 1
+		}
+	})
+
+	return out.String()
+}
+
+// bindNamed replaces :name parameters outside literals and comments with $N
+// placeholders numbered from start+1. Each distinct name gets one
+// placeholder, so repeated references share a single arg. Casts (::type) are
+// not parameters. It returns the rewritten query and the names in
+// placeholder order.
+func bindNamed(query string, start int) (string, []string) {
+	var out strings.Builder
+	out.Grow(len(query))
+	var names []string
+	index := make(map[string]int)
+
+	scanSQL(query, func(from, to int, code bool) {
+		if !code {
+			out.WriteString(query[from:to])
+			return
+		}
+		for i := from; i < to; i++ {
+			c := query[i]
+			if c != ':' {
+				out.WriteByte(c)
+				continue
+			}
+
+			if i+1 < to && query[i+1] == ':' {
+				out.WriteString("::")
+				i++
+				continue
+			}
+
+			j := i + 1
+			for j < to && isIdentByte(query[j]) {
+				j++
+			}
+			if j == i+1 || query[i+1] >= '0' && query[i+1] <= '9' {
+				out.WriteByte(c)
+				continue
+			}
+
+			name := query[i+1 : j]
+			n, ok := index[name]
+			if !ok {
+				names = append(names, name)
+				n = start + len(names)
+				index[name] = n
+			}
+			out.WriteByte('$')
+			out.WriteString(strconv.Itoa(n))
+			i = j - 1
+		}
+	})
+
+	return out.String(), names
+}
+
+// splitStatements splits script on semicolons outside literals, dollar-quoted
+// bodies and comments. Statements are trimmed, and those containing nothing
+// but whitespace and comments are dropped.
+func splitStatements(script string) []string {
+	var statements []string
+	start := 0
+	hasCode := false
+
+	scanSQL(script, func(from, to int, code bool) {
+		if !code {
+			return
+		}
+		for i := from; i < to; i++ {
+			switch script[i] {
+			case ';':
+				if hasCode {
+					statements = append(statements, strings.TrimSpace(script[start:i]))
+				}
+				start = i + 1
+				hasCode = false
+			case ' ', '\t', '\n', '\r':
+			default:
+				hasCode = true
+			}
+		}
+	})
+
+	if hasCode {
+		statements = append(statements, strings.TrimSpace(script[start:]))
+	}
+
+	return statements
+}
+
+// countPlaceholders returns the number of args query expects: the highest
+// $N placeholder, or the number of ? placeholders when it has no $N ones.
+// Placeholders inside literals and comments are not counted.
+func countPlaceholders(query string) int {
+	highest, marks := 0, 0
+
+	scanSQL(query, func(from, to int, code bool) {
+		if !code {
+			return
+		}
+		for i := from; i < to; i++ {
+			switch query[i] {
+			case '?':
+				marks++
+			case '$':
+				if i > 0 && isIdentByte(query[i-1]) {
+					continue
+				}
+				j := i + 1
+				for j < to && query[j] >= '0' && query[j] <= '9' {
+					j++
+				}
+				if n, err := strconv.Atoi(query[i+1 : j]); err == nil && n > highest {
+					highest = n
+				}
+				i = j - 1
+			}
+		}
+	})
+
+	if highest > 0 {
+		return highest
+	}
+	return marks
+}
+
+// atPlaceholderEnd returns the offset just past the @pN placeholder at i
+// in the code run ending at to, or 0 if there is none
+func atPlaceholderEnd(query string, i, to int) int {
+	if i+2 >= to || query[i] != '@' || query[i+1] != 'p' && query[i+1] != 'P' || i > 0 && isIdentByte(query[i-1]) {
+		return 0
+	}
+	j := i + 2
+	for j < to && query[j] >= '0' && query[j] <= '9' {
+		j++
+	}
+	if j == i+2 {
+		return 0
+	}
+	return j
+}
+
+// countAtPlaceholders returns the highest @pN placeholder outside literals
+// and comments
+func countAtPlaceholders(query string) int {
+	highest := 0
+	scanSQL(query, func(from, to int, code bool) {
+		if !code {
+			return
+		}
+		for i := from; i+2 < to; i++ {
+			if query[i] != '@' || query[i+1] != 'p' && query[i+1] != 'P' || i > 0 && isIdentByte(query[i-1]) {
+				continue
+			}
+			j := i + 2
+			for j < to && query[j] >= '0' && query[j] <= '9' {
+				j++
+			}
+			if n, err := strconv.Atoi(query[i+2 : j]); err == nil && n > highest {
+				highest = n
+			}
+			i = j - 1
+		}
+	})
+	return highest
+}
@@ -1,6 +1,10 @@
 package toki
 
-import "database/sql"
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
 
 // Stmt represents a prepared SQL statement
 type Stmt struct {
@@ -8,16 +12,43 @@ type Stmt struct {
 	args  []interface{}
 	db    *sql.DB
 	tx    *sql.Tx
+	kind  StatementKind
+	table string
+	// params is the number of placeholders in query
+	params int
+	hooks  hooks
+	// lock makes Exec report ErrStaleRow when no row was updated
+	lock *versionLock
+	// ctx is the builder's context, used by the calls without one
+	ctx context.Context
+	// router runs the statement on a replica when set
+	router *RoutingDB
 }
 
-// Prepare creates a prepared statement
+// Prepare creates a prepared statement. Write statements are rejected when
+// the builder is bound to a read-only transaction, OFFSET ... FETCH without
+// ORDER BY when the dialect requires one, and builders whose chained calls
+// failed, as reported by Err.
 func (b *Builder) Prepare(db *sql.DB) (*Stmt, error) {
+	if b.tx != nil && b.tx.readOnly && b.kind.IsWrite() {
+		return nil, fmt.Errorf("cannot run %s statement: %w", b.kind, ErrReadOnlyTransaction)
+	}
+	if err := b.validate(); err != nil {
+		return nil, err
+	}
+
 	query := b.String()
 
 	stmt := &Stmt{
-		query: query,
-		args:  b.args,
-		db:    db,
+		query:  query,
+		args:   b.Args(),
+		db:     db,
+		kind:   b.kind,
+		table:  b.table,
+		params: b.Dialect().countPlaceholders(query),
+		hooks:  b.hooks,
+		lock:   b.lock,
+		ctx:    b.context(),
 	}
 
 	if b.tx != nil {
@@ -29,24 +60,94 @@ func (b *Builder) Prepare(db *sql.DB) (*Stmt, error) {
 
 // Query executes the query and returns rows
 func (s *Stmt) Query() (*sql.Rows, error) {
-	if s.tx != nil {
-		return s.tx.Query(s.query, s.args...)
-	}
-	return s.db.Query(s.query, s.args...)
+	return s.QueryContext(s.context())
 }
 
 // QueryRow executes the query and returns a single row
 func (s *Stmt) QueryRow() *sql.Row {
-	if s.tx != nil {
-		return s.tx.QueryRow(s.query, s.args...)
-	}
-	return s.db.QueryRow(s.query, s.args...)
+	return s.QueryRowContext(s.context())
 }
 
 // Exec executes the statement
 func (s *Stmt) Exec() (sql.Result, error) {
-	if s.tx != nil {
-		return s.tx.Exec(s.query, s.args...)
+	return s.ExecContext(s.context())
+}
+
+// QueryContext executes the query with ctx instead of the builder's context
+// and returns rows
+func (s *Stmt) QueryContext(ctx context.Context) (*sql.Rows, error) {
+	return s.hooks.query(ctx, s.conn(), s.info())
+}
+
+// QueryRowContext executes the query with ctx instead of the builder's
+// context and returns a single row
+func (s *Stmt) QueryRowContext(ctx context.Context) *sql.Row {
+	return s.hooks.queryRow(ctx, s.conn(), s.info()).sqlRow()
+}
+
+// ExecContext executes the statement with ctx instead of the builder's
+// context
+func (s *Stmt) ExecContext(ctx context.Context) (sql.Result, error) {
+	res, err := s.hooks.exec(ctx, s.conn(), s.info())
+	if err != nil {
+		return res, err
+	}
+	return res, s.checkVersion(res)
+}
+
+// QueryWith executes the query with args in place of the ones captured by
+// Prepare and returns rows
+func (s *Stmt) QueryWith(args ...interface{}) (*sql.Rows, error) {
+	if err := s.checkArgs(args); err != nil {
+		return nil, err
 	}
-	return s.db.Exec(s.query, s.args...)
+	return s.hooks.query(s.context(), s.conn(), s.infoWith(args))
+}
+
+// QueryRowWith executes the query with args in place of the ones captured
+// by Prepare and returns a single row. Errors, including an arg count
+// mismatch, are reported by Scan.
+func (s *Stmt) QueryRowWith(args ...interface{}) *sql.Row {
+	if err := s.checkArgs(args); err != nil {
+		return errRow(err)
+	}
+	return s.hooks.queryRow(s.context(), s.conn(), s.infoWith(args)).sqlRow()
+}
+
+// ExecWith executes the statement with args in place of the ones captured by
+// Prepare
+func (s *Stmt) ExecWith(args ...interface{}) (sql.Result, error) {
+	if err := s.checkArgs(args); err != nil {
+		return nil, err
+	}
+	res, err := s.hooks.exec(s.context(), s.conn(), s.infoWith(args))
+	if err != nil {
+		return res, err
+	}
+	return res, s.checkVersion(res)
+}
+
+// conn returns what the statement runs on
+func (s *Stmt) conn() conn {
+	if s.router != nil {
+		return replicaConn{r: s.router, hooks: s.hooks}
+	}
+	return connFor(s.db, s.tx)
+}
+
+// checkArgs compares args against the placeholders in the statement
+func (s *Stmt) checkArgs(args []interface{}) error {
+	if len(args) != s.params {
+		return fmt.Errorf("statement expects %d arg(s), got %d: %w", s.params, len(args), ErrArgCount)
+	}
+	return nil
+}
+
+// info describes the statement for middleware
+func (s *Stmt) info() QueryInfo {
+	return s.infoWith(s.args)
+}
+
+func (s *Stmt) infoWith(args []interface{}) QueryInfo {
+	return QueryInfo{SQL: s.query, Args: args, Kind: s.kind, Table: s.table}
 }
@@ -0,0 +1,123 @@
+package toki
+
+import (
+	"fmt"
+	"time"
+)
+
+// TimeOptions controls how time.Time values are scanned and bound
+type TimeOptions struct {
+	// Location is applied to scanned time values. Nil keeps the location
+	// returned by the driver, and textual values without a zone are parsed
+	// as UTC.
+	Location *time.Location
+	// BindUTC converts time.Time arguments to UTC before they are bound
+	BindUTC bool
+}
+
+// defaultTimeOptions is used by scanning and by builders without their own
+// time options
+var defaultTimeOptions TimeOptions
+
+// SetTimeOptions sets the package-wide time options. Call it during
+// initialization, before queries run.
+func SetTimeOptions(opts TimeOptions) {
+	defaultTimeOptions = opts
+}
+
+// WithLocation converts scanned time values to loc
+func WithLocation(loc *time.Location) ScanOption {
+	return func(c *scanConfig) {
+		c.location = loc
+	}
+}
+
+// WithTimeOptions sets the time options used when binding this builder's
+// arguments
+func (b *Builder) WithTimeOptions(opts TimeOptions) *Builder {
+	b.timeOptions = &opts
+	return b
+}
+
+// bindTime converts time arguments according to the builder's options
+func (b *Builder) bindTime(v interface{}) interface{} {
+	opts := defaultTimeOptions
+	if b.timeOptions != nil {
+		opts = *b.timeOptions
+	}
+
+	if t, ok := v.(time.Time); ok && opts.BindUTC {
+		return t.UTC()
+	}
+	if s, ok := v.(SensitiveValue); ok {
+		s.value = b.bindTime(s.value)
+		return s
+	}
+
+	return v
+}
+
+// timeLayouts are the textual forms drivers commonly return for DATE,
+// TIMESTAMP and TIMESTAMPTZ columns
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999Z07",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02",
+}
+
+// parseTime parses a textual date or timestamp, interpreting values without
+// a zone in loc
+func parseTime(s string, loc *time.Location) (time.Time, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognized time format %q", s)
+}
+
+// timeHolder scans time values from time.Time, []byte or string sources
+type timeHolder struct {
+	column string
+	loc    *time.Location
+	time   time.Time
+	valid  bool
+}
+
+func (h *timeHolder) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		h.valid = false
+		return nil
+	case time.Time:
+		h.time = v
+	case []byte:
+		t, err := parseTime(string(v), h.loc)
+		if err != nil {
+			return fmt.Errorf("column %q: %w", h.column, err)
+		}
+		h.time = t
+	case string:
+		t, err := parseTime(v, h.loc)
+		if err != nil {
+			return fmt.Errorf("column %q: %w", h.column, err)
+		}
+		h.time = t
+	default:
+		return fmt.Errorf("cannot scan %T into time for column %q", src, h.column)
+	}
+
+	if h.loc != nil {
+		h.time = h.time.In(h.loc)
+	}
+	h.valid = true
+	return nil
+}
@@ -0,0 +1,132 @@
+package toki
+
+import (
+	"reflect"
+	"time"
+)
+
+// TimestampOptions enables automatic created_at/updated_at handling in
+// InsertStruct and UpdateStruct
+type TimestampOptions struct {
+	// CreatedColumn is set on insert when zero, "created_at" if empty
+	CreatedColumn string
+	// UpdatedColumn is set on insert when zero and on every update,
+	// "updated_at" if empty
+	UpdatedColumn string
+	// Now returns the current time, time.Now if nil
+	Now func() time.Time
+	// UseDatabaseTime renders NOW() instead of binding the current time
+	UseDatabaseTime bool
+}
+
+// defaultTimestamps is used by builders without their own timestamp options;
+// nil disables the feature
+var defaultTimestamps *TimestampOptions
+
+// SetTimestampOptions enables automatic timestamps package-wide, or disables
+// them when opts is nil. Call it during initialization.
+func SetTimestampOptions(opts *TimestampOptions) {
+	defaultTimestamps = opts
+}
+
+// WithTimestamps enables automatic timestamps for this builder's
+// InsertStruct and UpdateStruct calls
+func (b *Builder) WithTimestamps(opts TimestampOptions) *Builder {
+	b.timestamps = &opts
+	return b
+}
+
+// timestampOptions returns the active options, or nil when disabled
+func (b *Builder) timestampOptions() *TimestampOptions {
+	if b.timestamps != nil {
+		return b.timestamps
+	}
+	return defaultTimestamps
+}
+
+func (o *TimestampOptions) createdColumn() string {
+	if o.CreatedColumn == "" {
+		return "created_at"
+	}
+	return o.CreatedColumn
+}
+
+func (o *TimestampOptions) updatedColumn() string {
+	if o.UpdatedColumn == "" {
+		return "updated_at"
+	}
+	return o.UpdatedColumn
+}
+
+// now returns the value to store for the current time
+func (o *TimestampOptions) now() interface{} {
+	if o.UseDatabaseTime {
+		return Expr("NOW()")
+	}
+	if o.Now != nil {
+		return o.Now()
+	}
+	return time.Now()
+}
+
+// applyInsertTimestamps fills the timestamp columns of an insert when the
+// struct maps them and the supplied value is zero. Explicit values win.
+func (b *Builder) applyInsertTimestamps(typ reflect.Type, columns []string, values []interface{}) ([]string, []interface{}) {
+	opts := b.timestampOptions()
+	if opts == nil {
+		return columns, values
+	}
+
+	info := getStructInfo(typ)
+	for _, col := range []string{opts.createdColumn(), opts.updatedColumn()} {
+		if _, ok := info.column(col); !ok {
+			continue
+		}
+
+		i := indexOf(columns, col)
+		if i < 0 {
+			// Dropped by omitempty
+			columns = append(columns, col)
+			values = append(values, opts.now())
+			continue
+		}
+
+		if isZeroTime(values[i]) {
+			values[i] = opts.now()
+		}
+	}
+
+	return columns, values
+}
+
+// applyUpdateTimestamps refreshes the updated column when the struct maps it
+func (b *Builder) applyUpdateTimestamps(typ reflect.Type, updates map[string]interface{}) {
+	opts := b.timestampOptions()
+	if opts == nil {
+		return
+	}
+
+	if _, ok := getStructInfo(typ).column(opts.updatedColumn()); ok {
+		updates[opts.updatedColumn()] = opts.now()
+	}
+}
+
+// isZeroTime reports whether v is NULL or the zero time
+func isZeroTime(v interface{}) bool {
+	switch t := v.(type) {
+	case nil:
+		return true
+	case time.Time:
+		return t.IsZero()
+	}
+	return false
+}
+
+func indexOf(items []string, item string) int {
+	for i, it := range items {
+		if it == item {
+			return i
+		}
+	}
+	return -1
+}
@@ -1,31 +1,106 @@
 package toki
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
-	"sync"
 )
 
 // Builder represents the main query builder structure
 type Builder struct {
-	parts    []string
+	clauses  clauses
 	args     []interface{}
 	argIndex int
-	pool     *sync.Pool
 	table    string
 	tx       *Transaction
+	kind     StatementKind
+	dialect  *Dialect
+	page     *pagination
+	fetch    bool
+	ctx      context.Context
+	// primary makes RoutingDB run the statement on the primary
+	primary bool
+	// err is the first error of a chained call, reported by Prepare, and
+	// errMethod the Builder method it came from
+	err       error
+	errMethod string
+	// lock is the optimistic version check of an UPDATE
+	lock *versionLock
+	// keyless names the struct type of an UpdateStruct without pk fields,
+	// which must not run without a WHERE clause
+	keyless string
+
+	timeOptions *TimeOptions
+	timestamps  *TimestampOptions
+	hooks       hooks
+	// columnMap renames struct columns in Bind and the struct helpers
+	columnMap map[string]string
+	// identCase normalizes identifiers, overriding the dialect's
+	identCase IdentifierCase
+	// rendered caches the statement String rendered and renderedArgs its
+	// args, until the next change
+	rendered     string
+	renderedArgs []interface{}
+
+	// rows holds the Values rows of an INSERT, so bulk inserts can be
+	// re-rendered in chunks
+	rows [][]interface{}
+}
+
+// StatementKind identifies the type of statement a Builder produces
+type StatementKind int
+
+const (
+	// KindUnknown is used before any statement-starting method is called
+	KindUnknown StatementKind = iota
+	// KindSelect is a SELECT statement
+	KindSelect
+	// KindInsert is an INSERT statement
+	KindInsert
+	// KindUpdate is an UPDATE statement
+	KindUpdate
+	// KindDelete is a DELETE statement
+	KindDelete
+	// KindRaw is a hand-written query run through RawQuery
+	KindRaw
+	// KindDDL is a schema statement such as CREATE TABLE
+	KindDDL
+	// KindMerge is a MERGE statement
+	KindMerge
+)
+
+func (k StatementKind) String() string {
+	switch k {
+	case KindSelect:
+		return "SELECT"
+	case KindInsert:
+		return "INSERT"
+	case KindUpdate:
+		return "UPDATE"
+	case KindDelete:
+		return "DELETE"
+	case KindRaw:
+		return "RAW"
+	case KindDDL:
+		return "DDL"
+	case KindMerge:
+		return "MERGE"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// IsWrite reports whether the statement modifies data or schema
+func (k StatementKind) IsWrite() bool {
+	return k == KindInsert || k == KindUpdate || k == KindDelete || k == KindDDL || k == KindMerge
 }
 
 // New creates a new query builder
 func New() *Builder {
-	return &Builder{
-		pool: &sync.Pool{
-			New: func() interface{} {
-				return &strings.Builder{}
-			},
-		},
-	}
+	return &Builder{}
 }
 
 // WithTransaction sets the transaction for the builder
@@ -34,96 +109,308 @@ func (b *Builder) WithTransaction(tx *Transaction) *Builder {
 	return b
 }
 
-// Select initializes a SELECT query
+// Kind returns the kind of statement being built
+func (b *Builder) Kind() StatementKind {
+	return b.kind
+}
+
+// setKind records the statement kind unless one is already known, so that
+// INSERT ... SELECT stays an INSERT
+func (b *Builder) setKind(kind StatementKind) {
+	if b.kind == KindUnknown {
+		b.kind = kind
+	}
+}
+
+// Select initializes a SELECT query. Calling it again adds columns to the
+// same select list.
 func (b *Builder) Select(columns ...string) *Builder {
-	b.parts = append(b.parts, fmt.Sprintf("SELECT %s", strings.Join(columns, ", ")))
+	return b.selectColumns(b.idents(columns))
+}
+
+func (b *Builder) selectColumns(columns []string) *Builder {
+	b.setKind(KindSelect)
+	b.changed()
+	b.clauses.selected = true
+	b.clauses.columns = append(b.clauses.columns, columns...)
 	return b
 }
 
-// From adds FROM clause
-func (b *Builder) From(table string) *Builder {
-	b.table = table
-	b.parts = append(b.parts, fmt.Sprintf("FROM %s", b.table))
+// ReplaceSelect replaces the select list with columns, keeping Distinct, for
+// helpers that reuse a query with different columns. A select list with
+// bound args, added by SelectExpr, cannot be replaced.
+func (b *Builder) ReplaceSelect(columns ...string) *Builder {
+	if b.clauses.columnArgs > 0 {
+		b.fail(errors.New("ReplaceSelect cannot drop a select list with bound args"))
+		return b
+	}
+	b.changed()
+	b.clauses.columns = nil
+	return b.Select(columns...)
+}
+
+// Distinct makes the query SELECT DISTINCT
+func (b *Builder) Distinct() *Builder {
+	b.changed()
+	b.clauses.distinct = true
 	return b
 }
 
-// Where adds WHERE conditions
-func (b *Builder) Where(condition string, args ...interface{}) *Builder {
-	if len(b.parts) > 0 && !strings.HasSuffix(b.parts[len(b.parts)-1], "WHERE") {
-		b.parts = append(b.parts, "WHERE")
+// SelectExpr initializes a SELECT query of expressions, such as aggregates
+// with bound args, rendered for the builder's dialect
+func (b *Builder) SelectExpr(exprs ...SQLExpression) *Builder {
+	bound := len(b.args)
+	columns := make([]string, len(exprs))
+	for i, e := range exprs {
+		columns[i] = b.expression(e)
+	}
+	b.clauses.columnArgs += len(b.args) - bound
+	return b.selectColumns(columns)
+}
+
+// From sets the FROM clause. Calling it again is an error, reported by
+// Err and Prepare; use AddFrom to select from several tables.
+func (b *Builder) From(table string) *Builder {
+	if len(b.clauses.from) > 0 {
+		b.fail(fmt.Errorf("FROM already set, cannot add %s; use AddFrom for FROM a, b", table))
+		return b
+	}
+	return b.AddFrom(table)
+}
+
+// AddFrom adds table to the FROM clause, rendering FROM a, b for an
+// implicit join, or starts the clause like From
+func (b *Builder) AddFrom(table string) *Builder {
+	table = b.ident(table)
+	if b.table == "" {
+		b.table = table
 	}
-	b.parts = append(b.parts, b.convertPlaceholders(condition))
-	b.args = append(b.args, args...)
+	b.changed()
+	b.clauses.from = append(b.clauses.from, table)
 	return b
 }
 
-// AndWhere adds AND condition
+// Where starts the WHERE clause with condition, or ANDs it to the
+// conditions already added
+func (b *Builder) Where(condition string, args ...interface{}) *Builder {
+	return b.addWhere("AND", condition, args)
+}
+
+// AndWhere ANDs condition to the WHERE clause, or starts the clause when
+// there is no condition yet
 func (b *Builder) AndWhere(condition string, args ...interface{}) *Builder {
-	b.parts = append(b.parts, "AND", b.convertPlaceholders(condition))
-	b.args = append(b.args, args...)
-	return b
+	return b.addWhere("AND", condition, args)
 }
 
-// OrWhere adds OR condition
+// OrWhere ORs condition to the WHERE clause, or starts the clause when
+// there is no condition yet
 func (b *Builder) OrWhere(condition string, args ...interface{}) *Builder {
-	b.parts = append(b.parts, "OR", b.convertPlaceholders(condition))
-	b.args = append(b.args, args...)
+	return b.addWhere("OR", condition, args)
+}
+
+// addWhere appends condition to the WHERE clause, after op unless it is
+// the first condition
+func (b *Builder) addWhere(op, condition string, args []interface{}) *Builder {
+	condition, args = b.inlineArgs(condition, args)
+	b.changed()
+	if b.clauses.where == nil {
+		b.clauses.where = make([]string, 0, 8)
+	}
+	if len(b.clauses.where) > 0 {
+		b.clauses.where = append(b.clauses.where, op)
+	}
+	b.clauses.where = append(b.clauses.where, b.convertPlaceholders(condition))
+	b.appendArgs(args)
 	return b
 }
 
-// OrderBy adds ORDER BY clause
+// OrderBy adds columns to the ORDER BY clause
 func (b *Builder) OrderBy(columns ...string) *Builder {
-	b.parts = append(b.parts, fmt.Sprintf("ORDER BY %s", strings.Join(columns, ", ")))
+	b.changed()
+	b.clauses.orderBy = append(b.clauses.orderBy, b.orderIdents(columns)...)
+	return b
+}
+
+// OrderByExpr adds an ORDER BY clause of expressions, such as Collate,
+// rendered for the builder's dialect
+func (b *Builder) OrderByExpr(exprs ...SQLExpression) *Builder {
+	columns := make([]string, len(exprs))
+	for i, e := range exprs {
+		columns[i] = b.expression(e)
+	}
+	b.changed()
+	b.clauses.orderBy = append(b.clauses.orderBy, columns...)
+	return b
+}
+
+// GroupBy adds columns to the GROUP BY clause
+func (b *Builder) GroupBy(columns ...string) *Builder {
+	b.changed()
+	b.clauses.groupBy = append(b.clauses.groupBy, b.idents(columns)...)
+	return b
+}
+
+// GroupByExpr adds a GROUP BY clause of expressions, such as DateTrunc,
+// rendered for the builder's dialect
+func (b *Builder) GroupByExpr(exprs ...SQLExpression) *Builder {
+	columns := make([]string, len(exprs))
+	for i, e := range exprs {
+		columns[i] = b.expression(e)
+	}
+	b.changed()
+	b.clauses.groupBy = append(b.clauses.groupBy, columns...)
+	return b
+}
+
+// Having starts the HAVING clause with condition, or ANDs it to the
+// conditions already added. Like Where, its placeholders are numbered when
+// it is called.
+func (b *Builder) Having(condition string, args ...interface{}) *Builder {
+	condition, args = b.inlineArgs(condition, args)
+	b.changed()
+	if len(b.clauses.having) > 0 {
+		b.clauses.having = append(b.clauses.having, "AND")
+	}
+	b.clauses.having = append(b.clauses.having, b.convertPlaceholders(condition))
+	b.appendArgs(args)
 	return b
 }
 
 // Update initializes an UPDATE query
 func (b *Builder) Update(table string) *Builder {
-	b.parts = append(b.parts, fmt.Sprintf("UPDATE %s", table))
+	table = b.ident(table)
+	b.setKind(KindUpdate)
+	b.table = table
+	b.changed()
+	b.clauses.head = "UPDATE " + table
 	return b
 }
 
-// Set adds SET clause for UPDATE
+// Set adds SET clause for UPDATE. Columns are rendered in sorted order so
+// the generated statement is deterministic; calling Set again adds to the
+// same clause. Null sets NULL; UseDefault makes the statement fail when it
+// runs.
 func (b *Builder) Set(updates map[string]interface{}) *Builder {
+	if b.lock != nil && !b.lock.bumped {
+		updates = b.lock.withBump(updates)
+	}
 
-	sets := make([]string, 0, len(updates))
-	for col, val := range updates {
-		if expr, ok := val.(SQLExpression); ok {
-			sets = append(sets, fmt.Sprintf("%s = %s", col, expr.SQL()))
-		} else {
-			b.argIndex++
-			sets = append(sets, fmt.Sprintf("%s = $%d", col, b.argIndex))
-			b.args = append(b.args, val)
+	columns := make([]string, 0, len(updates))
+	for col := range updates {
+		columns = append(columns, col)
+	}
+	sort.Strings(columns)
+
+	buf := make([]byte, 0, len(columns)*8+placeholderWidth(b.argIndex, len(columns)))
+	for i, col := range columns {
+		if i > 0 {
+			buf = append(buf, ", "...)
+		}
+		buf = append(buf, b.ident(col)...)
+		buf = append(buf, " = "...)
+
+		val := updates[col]
+		// UseDefault is bound so addArg fails the builder, DEFAULT being
+		// for INSERT only
+		if expr, ok := val.(SQLExpression); ok && val != UseDefault {
+			buf = append(buf, b.expression(expr)...)
+			continue
 		}
+		b.argIndex++
+		buf = appendPlaceholder(buf, b.argIndex)
+		b.addArg(normalizeArg(val))
 	}
 
-	b.parts = append(b.parts, fmt.Sprintf("SET %s", strings.Join(sets, ", ")))
+	if len(buf) > 0 {
+		b.changed()
+		b.clauses.set = append(b.clauses.set, string(buf))
+	}
 	return b
 }
 
-// Insert initializes an INSERT query
+// Insert initializes an INSERT query. Without columns the values must cover
+// every column of table, as in INSERT INTO archive SELECT * FROM moved.
 func (b *Builder) Insert(table string, columns ...string) *Builder {
-	b.parts = append(b.parts, fmt.Sprintf("INSERT INTO %s (%s)", table, strings.Join(columns, ", ")))
+	table, columns = b.ident(table), b.idents(columns)
+	b.setKind(KindInsert)
+	b.table = table
+	b.changed()
+	if len(columns) == 0 {
+		b.clauses.head = "INSERT INTO " + table
+		return b
+	}
+	b.clauses.head = "INSERT INTO " + table + " (" + strings.Join(columns, ", ") + ")"
 
 	return b
 }
 
-// Values adds VALUES clause for INSERT
+// InsertMap initializes an INSERT of one row from values keyed by column.
+// Columns are rendered in sorted order so the generated statement is
+// deterministic.
+func (b *Builder) InsertMap(table string, values map[string]interface{}) *Builder {
+	columns := sortedKeys(values)
+	row := make([]interface{}, len(columns))
+	for i, col := range columns {
+		row[i] = values[col]
+	}
+	return b.Insert(table, columns...).Values(row...)
+}
+
+// Values adds VALUES clause for INSERT. SQL expressions, Null and UseDefault
+// are rendered inline, nil pointers are bound as NULL and other pointers are
+// dereferenced. Calling
+// Values again adds another row to the same clause:
+// VALUES ($1, $2), ($3, $4).
 func (b *Builder) Values(values ...interface{}) *Builder {
-	placeholders := make([]string, len(values))
-	for i := range values {
-		b.argIndex++
-		placeholders[i] = fmt.Sprintf("$%d", b.argIndex)
+	row := make([]interface{}, len(values))
+	for i, val := range values {
+		if _, ok := val.(SQLExpression); ok {
+			row[i] = val
+			continue
+		}
+		row[i] = b.bindTime(normalizeArg(val))
 	}
 
-	b.parts = append(b.parts, fmt.Sprintf("VALUES (%s)", strings.Join(placeholders, ", ")))
-	b.args = append(b.args, values...)
+	b.changed()
+	b.clauses.values = append(b.clauses.values, b.renderRow(row))
+	b.rows = append(b.rows, row)
 	return b
 }
 
+// renderRow renders row as a parenthesized list, binding its values, which
+// must already be normalized
+func (b *Builder) renderRow(row []interface{}) string {
+	var sb strings.Builder
+	sb.Grow(2 + placeholderWidth(b.argIndex, len(row)) + 2*len(row))
+
+	var num [20]byte
+	sb.WriteByte('(')
+	for i, val := range row {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		if expr, ok := val.(SQLExpression); ok {
+			sb.WriteString(b.expression(expr))
+			continue
+		}
+		b.argIndex++
+		sb.Write(appendPlaceholder(num[:0], b.argIndex))
+		if b.args == nil {
+			b.args = make([]interface{}, 0, 8)
+		}
+		b.args = append(b.args, val)
+	}
+	sb.WriteByte(')')
+	return sb.String()
+}
+
 // Delete initializes a DELETE query
 func (b *Builder) Delete(table string) *Builder {
-	b.parts = append(b.parts, fmt.Sprintf("DELETE FROM %s", table))
+	table = b.ident(table)
+	b.setKind(KindDelete)
+	b.table = table
+	b.changed()
+	b.clauses.head = "DELETE FROM " + table
 	return b
 }
 
@@ -132,69 +419,259 @@ func (b *Builder) DeleteFrom(table string) *Builder {
 	return b.Delete(table)
 }
 
-// Returning adds a RETURNING clause to the DELETE statement
+// Returning adds columns to the RETURNING clause of an INSERT, UPDATE or
+// DELETE, which is rendered at the end of the statement wherever Returning
+// is called. Without columns it does nothing.
 func (b *Builder) Returning(columns ...string) *Builder {
-	if len(columns) > 0 {
-		b.parts = append(b.parts, "RETURNING", strings.Join(columns, ", "))
-	}
+	b.changed()
+	b.clauses.returning = append(b.clauses.returning, columns...)
 	return b
 }
 
-// String builds the final query string
+// String builds the final query string, with its clauses in SQL order
+// whatever order they were added in and its placeholders numbered in that
+// order, in the style of the builder's dialect: $N, ? for MySQL or @pN for
+// SQL Server. The result is kept until the next change to the builder, so
+// rendering again for logging, Prepare and helpers costs nothing.
 func (b *Builder) String() string {
-	sb := b.pool.Get().(*strings.Builder)
-	defer func() {
-		sb.Reset()
-		b.pool.Put(sb)
-	}()
+	query, _ := b.render()
+	return query
+}
 
-	for i, part := range b.parts {
-		if i > 0 {
-			sb.WriteByte(' ')
-		}
-		sb.WriteString(part)
+// render returns the statement String renders and its args, caching both
+func (b *Builder) render() (string, []interface{}) {
+	if b.rendered == "" {
+		b.rendered, b.renderedArgs = renumber(b.Dialect(), b.statement(), b.args)
+	}
+	return b.rendered, b.renderedArgs
+}
+
+// statement renders the clauses with their placeholders numbered as they
+// were bound, matching b.args. Builders embedding another one, such as
+// With, shift its placeholders and take its args as they are.
+func (b *Builder) statement() string {
+	// the pagination clause is rendered here, once the syntax is known
+	page := ""
+	if b.page != nil {
+		page = b.renderPage()
 	}
 
+	size := clauseWriter{}
+	b.clauses.write(&size, page)
+
+	var sb strings.Builder
+	sb.Grow(size.n)
+	b.clauses.write(&clauseWriter{sb: &sb}, page)
 	return sb.String()
 }
 
-// Bind creates a struct binding for database columns
-func (b *Builder) Bind(dest interface{}) map[string]interface{} {
-	val := reflect.ValueOf(dest)
-	if val.Kind() == reflect.Ptr {
-		val = val.Elem()
-	}
+// changed drops the rendering String cached, before a change to the
+// statement
+func (b *Builder) changed() {
+	b.rendered = ""
+	b.renderedArgs = nil
+}
+
+// Args returns the args of the statement String renders, in placeholder
+// order
+func (b *Builder) Args() []interface{} {
+	_, args := b.render()
+	return args
+}
 
-	typ := val.Type()
+// Bind creates a struct binding for database columns. Fields of embedded
+// structs are flattened into the result, with outer fields winning on
+// column collisions. Unexported fields are skipped; a nil pointer or a
+// non-struct value yields an empty map.
+func (b *Builder) Bind(dest interface{}) map[string]interface{} {
 	result := make(map[string]interface{})
 
-	for i := 0; i < val.NumField(); i++ {
-		field := typ.Field(i)
-		tag := field.Tag.Get("db")
-		if tag != "" {
-			result[tag] = val.Field(i).Interface()
-		}
+	val, ok := structValue(dest)
+	if !ok {
+		return result
+	}
+
+	columns, values := b.mapColumns(structColumns(val))
+	for i, col := range columns {
+		result[col] = values[i]
 	}
 
 	if b.table == "" {
-		b.table = strings.ToLower(typ.Name())
+		b.table = strings.ToLower(val.Type().Name())
 	}
 
 	return result
 }
 
-// convertPlaceholders converts ? placeholders to $1, $2, etc.
-func (b *Builder) convertPlaceholders(query string) string {
-	result := strings.Builder{}
-
-	for _, c := range query {
-		if c == '?' {
-			b.argIndex++
-			result.WriteString(fmt.Sprintf("$%d", b.argIndex))
-		} else {
-			result.WriteRune(c)
+// InsertStruct initializes an INSERT query from the mapped fields of v
+func (b *Builder) InsertStruct(table string, v interface{}) *Builder {
+	val, ok := structValue(v)
+	if !ok {
+		return b.Insert(table)
+	}
+
+	columns, values := structColumns(val)
+	columns, values = b.mapColumns(b.applyInsertTimestamps(val.Type(), columns, values))
+	return b.Insert(table, columns...).Values(values...)
+}
+
+// UpdateStruct initializes an UPDATE query setting the mapped fields of v.
+// Fields tagged db:"id,pk" are not set but matched in the WHERE clause,
+// ANDed for a composite key; add further conditions with AndWhere. Without
+// pk fields the statement needs a Where clause of its own and fails to run
+// without one, rather than updating every row. A field tagged
+// db:"version,lock" is not set but checked and incremented as with
+// WhereVersion.
+func (b *Builder) UpdateStruct(table string, v interface{}) *Builder {
+	updates := make(map[string]interface{})
+	val, ok := structValue(v)
+	if !ok {
+		return b.Update(table).Set(updates)
+	}
+
+	columns, values := structColumns(val)
+	for i, col := range columns {
+		updates[col] = values[i]
+	}
+	b.applyUpdateTimestamps(val.Type(), updates)
+
+	keys := keyFields(val.Type())
+	for _, key := range keys {
+		delete(updates, key.column)
+	}
+	lock := lockField(val.Type())
+	if lock != nil {
+		delete(updates, lock.column)
+	}
+	updates = b.mapUpdates(updates)
+
+	b.Update(table).Set(updates)
+	if len(keys) == 0 {
+		b.keyless = val.Type().String()
+	}
+	b.whereKeys(val, keys)
+	if lock != nil {
+		version, _ := fieldByIndex(val, lock.index)
+		column, ok := mapColumn(b.columnMap, lock.column)
+		if !ok {
+			b.fail(fmt.Errorf("version column %s cannot be excluded by the column map", lock.column))
+			return b
 		}
+		b.whereVersionField(column, version)
 	}
+	return b
+}
+
+// DeleteStruct initializes a DELETE query for the row of v, matched by its
+// fields tagged db:"id,pk". A struct without pk fields is an error.
+func (b *Builder) DeleteStruct(table string, v interface{}) *Builder {
+	b.Delete(table)
+	val, ok := structValue(v)
+	if !ok {
+		b.fail(fmt.Errorf("DeleteStruct needs a struct, got %T", v))
+		return b
+	}
+
+	keys := keyFields(val.Type())
+	if len(keys) == 0 {
+		b.fail(fmt.Errorf("DeleteStruct needs a field of %s tagged pk", val.Type()))
+		return b
+	}
+	return b.whereKeys(val, keys)
+}
+
+// whereKeys matches the pk fields of val
+func (b *Builder) whereKeys(val reflect.Value, keys []fieldInfo) *Builder {
+	for _, key := range keys {
+		fv, ok := fieldByIndex(val, key.index)
+		if !ok {
+			b.fail(fmt.Errorf("pk column %s is behind a nil embedded pointer", key.column))
+			return b
+		}
+		column, ok := mapColumn(b.columnMap, key.column)
+		if !ok {
+			b.fail(fmt.Errorf("pk column %s cannot be excluded by the column map", key.column))
+			return b
+		}
+		b.Where(column+" = ?", normalizeArg(fieldArg(fv)))
+	}
+	return b
+}
+
+// checkKeyed rejects an UpdateStruct of a struct without pk fields that got
+// no Where clause, which would update every row
+func (b *Builder) checkKeyed() error {
+	if b.keyless == "" {
+		return nil
+	}
+	// the version check of a lock field does not pick a row
+	need := 1
+	if b.lock != nil {
+		need++
+	}
+	for _, part := range b.clauses.where {
+		if part != "AND" && part != "OR" {
+			need--
+		}
+	}
+	if need <= 0 {
+		return nil
+	}
+	return fmt.Errorf("UpdateStruct of %s has no pk fields and no Where clause; it would update every row", b.keyless)
+}
+
+// appendArgs appends condition arguments
+func (b *Builder) appendArgs(args []interface{}) {
+	for _, arg := range args {
+		b.addArg(arg)
+	}
+}
+
+// addArg binds one argument. The first one reserves room for a few more so
+// a typical statement appends without regrowing. UseDefault fails the
+// builder, as it is only valid in INSERT values.
+func (b *Builder) addArg(arg interface{}) {
+	if _, ok := arg.(defaultMarker); ok {
+		b.fail(errUseDefault)
+	}
+	if b.args == nil {
+		b.args = make([]interface{}, 0, 8)
+	}
+	b.args = append(b.args, b.bindTime(arg))
+}
+
+// Err returns the first error of a chained call that could not be applied,
+// such as an expression the dialect cannot render. Prepare and the Exec
+// helpers return it too.
+func (b *Builder) Err() error {
+	return b.err
+}
 
-	return result.String()
+// fail records err unless an earlier call already failed
+func (b *Builder) fail(err error) {
+	if b.err == nil {
+		b.err = err
+		b.errMethod = failingMethod()
+	}
+}
+
+// validate returns the error Prepare reports before running the statement:
+// a failed chained call, OFFSET ... FETCH without a required ORDER BY, or
+// an UpdateStruct that would update every row
+func (b *Builder) validate() error {
+	if b.err != nil {
+		return b.err
+	}
+	if err := b.checkPagination(); err != nil {
+		return err
+	}
+	return b.checkKeyed()
+}
+
+// convertPlaceholders converts ? placeholders to $1, $2, etc. Question
+// marks inside string literals, quoted identifiers and comments are kept.
+func (b *Builder) convertPlaceholders(query string) string {
+	query, n := rebind(query, b.argIndex)
+	b.argIndex += n
+	return query
 }
@@ -3,7 +3,9 @@ package toki
 import (
 	"database/sql"
 	"reflect"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -29,6 +31,17 @@ func setupTest(t *testing.T) (*sql.DB, sqlmock.Sqlmock, *Builder) {
 	return db, mock, builder
 }
 
+// newMockDB returns a mock database that matches queries exactly
+func newMockDB(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, mock
+}
+
 func TestSelect(t *testing.T) {
 
 	tests := []struct {
@@ -200,6 +213,36 @@ func TestTransaction(t *testing.T) {
 	t.Log("---- Pass ----")
 }
 
+func TestStmtWithArgs(t *testing.T) {
+	db, mock, builder := setupTest(t)
+	defer db.Close()
+
+	for _, name := range []string{"alice", "bob"} {
+		mock.ExpectExec("INSERT INTO users \\(name, email\\) VALUES \\(\\$1, \\$2\\)").
+			WithArgs(name, name+"@example.com").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+	}
+
+	stmt, err := builder.
+		Insert("users", "name", "email").
+		Values("", "").
+		Prepare(db)
+	assert.NoError(t, err)
+
+	for _, name := range []string{"alice", "bob"} {
+		_, err = stmt.ExecWith(name, name+"@example.com")
+		assert.NoError(t, err)
+	}
+
+	_, err = stmt.ExecWith("carol")
+	assert.EqualError(t, err, "statement expects 2 arg(s), got 1: placeholder and argument count mismatch")
+	_, err = stmt.QueryWith()
+	assert.ErrorIs(t, err, ErrArgCount)
+	assert.ErrorIs(t, stmt.QueryRowWith(1, 2, 3).Scan(), ErrArgCount)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestStructBinding(t *testing.T) {
 	type User struct {
 		ID        int       `db:"id"`
@@ -232,6 +275,47 @@ func TestStructBinding(t *testing.T) {
 	t.Log("---- Pass ----")
 }
 
+func TestStructBindingUnexportedFields(t *testing.T) {
+	type Account struct {
+		ID       int    `db:"id"`
+		Name     string `db:"name"`
+		password string
+		secret   string `db:"secret"`
+	}
+
+	account := Account{ID: 1, Name: "zakirkun", password: "hunter2", secret: "s3cr3t"}
+
+	var bindings map[string]interface{}
+	assert.NotPanics(t, func() {
+		bindings = New().Bind(&account)
+	})
+
+	expected := map[string]interface{}{
+		"id":   1,
+		"name": "zakirkun",
+	}
+	assert.Equal(t, expected, bindings)
+
+	t.Log("---- Pass ----")
+}
+
+func TestStructBindingInvalidInput(t *testing.T) {
+	type User struct {
+		ID int `db:"id"`
+	}
+
+	var nilUser *User
+	inputs := []interface{}{nil, nilUser, 42, "users", []User{{ID: 1}}}
+
+	for _, input := range inputs {
+		assert.NotPanics(t, func() {
+			assert.Empty(t, New().Bind(input))
+		})
+	}
+
+	t.Log("---- Pass ----")
+}
+
 // Test Raw SQL expressions
 func TestRawExpression(t *testing.T) {
 	expr := Raw("NOW()")
@@ -242,6 +326,57 @@ func TestRawExpression(t *testing.T) {
 	t.Log("---- Pass ----")
 }
 
+// Test Null and UseDefault markers
+func TestNullAndDefaultMarkers(t *testing.T) {
+	b := New().Insert("items", "name", "status", "note", "qty").
+		Values("x", UseDefault, Null, 3).
+		Values("y", "open", UseDefault, 4)
+	assert.Equal(t, "INSERT INTO items (name, status, note, qty) VALUES ($1, DEFAULT, NULL, $2), ($3, $4, DEFAULT, $5)", b.String())
+	assert.Equal(t, []interface{}{"x", 3, "y", "open", 4}, b.args)
+
+	b = New().InsertMap("items", map[string]interface{}{"name": "x", "status": UseDefault, "note": Null, "qty": 3})
+	assert.Equal(t, "INSERT INTO items (name, note, qty, status) VALUES ($1, NULL, $2, DEFAULT)", b.String())
+	assert.Equal(t, []interface{}{"x", 3}, b.args)
+
+	b = New().Update("items").Set(map[string]interface{}{"note": Null, "qty": 5}).Where("id = ?", 1)
+	assert.Equal(t, "UPDATE items SET note = NULL, qty = $1 WHERE id = $2", b.String())
+
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	// as an arg Null binds NULL and UseDefault is rejected
+	mock.ExpectExec("UPDATE items SET qty = $1 WHERE note IS NOT DISTINCT FROM $2").
+		WithArgs(5, nil).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	stmt, err := New().Update("items").Set(map[string]interface{}{"qty": 5}).Where("note IS NOT DISTINCT FROM ?", Null).Prepare(db)
+	assert.NoError(t, err)
+	_, err = stmt.Exec()
+	assert.NoError(t, err)
+
+	// outside INSERT values UseDefault fails the builder
+	b = New().Update("items").Set(map[string]interface{}{"status": UseDefault})
+	assert.EqualError(t, b.Err(), "toki.UseDefault is only valid in INSERT values")
+	_, _, err = b.ToSQL()
+	assert.EqualError(t, err, "toki.UseDefault is only valid in INSERT values")
+	_, err = b.Prepare(db)
+	assert.EqualError(t, err, "toki.UseDefault is only valid in INSERT values")
+	assert.PanicsWithError(t, "toki: Set: toki.UseDefault is only valid in INSERT values (SQL so far: UPDATE items SET status = $1)",
+		func() { b.MustSQL() })
+
+	b = New().Select("*").From("items").Where("status = ?", UseDefault)
+	assert.EqualError(t, b.Err(), "toki.UseDefault is only valid in INSERT values")
+
+	// a raw query is sent as written, so the driver rejects it
+	_, err = New().RawQuery("UPDATE items SET status = $1", UseDefault).WithDB(db).Exec()
+	assert.ErrorContains(t, err, "toki.UseDefault is only valid in INSERT values")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 // Test placeholder conversion
 func TestPlaceholderConversion(t *testing.T) {
 	builder := New()
@@ -252,6 +387,13 @@ func TestPlaceholderConversion(t *testing.T) {
 		t.Errorf("Placeholder conversion failed.\nExpected: %s\nGot: %s", expected, query)
 	}
 
+	query = builder.convertPlaceholders("note <> 'why?' /* ? */ AND tag = ?")
+	expected = "note <> 'why?' /* ? */ AND tag = $3"
+
+	if query != expected {
+		t.Errorf("Placeholder conversion failed.\nExpected: %s\nGot: %s", expected, query)
+	}
+
 	t.Log("---- Pass ----")
 }
 
@@ -321,3 +463,200 @@ func scanRows(rows *sql.Rows) []map[string]interface{} {
 
 	return results
 }
+
+func BenchmarkSelectComplex(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = New().
+			Select("u.id", "u.name", "u.email", "p.bio").
+			From("users u LEFT JOIN profiles p ON p.user_id = u.id").
+			Where("u.status = ?", "active").
+			AndWhere("u.age > ?", 18).
+			AndWhere("u.created_at > ?", TestTime).
+			OrWhere("u.role = ?", "admin").
+			OrderBy("u.created_at DESC", "u.id").
+			String()
+	}
+}
+
+// BenchmarkRepeatedString renders one builder four times, as logging,
+// Prepare and a helper would, with and without the cached rendering
+func BenchmarkRepeatedString(b *testing.B) {
+	query := New().
+		Select("u.id", "u.name", "u.email").
+		From("users u").
+		Join("teams t", "t.id = u.team_id").
+		Where("u.status = ?", "active").
+		AndWhere("u.age > ?", 18).
+		OrderBy("u.created_at DESC").
+		Limit(20)
+
+	b.Run("cached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for j := 0; j < 4; j++ {
+				_ = query.String()
+			}
+		}
+	})
+	b.Run("uncached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for j := 0; j < 4; j++ {
+				query.changed()
+				_ = query.String()
+			}
+		}
+	})
+}
+
+func BenchmarkInsertBulk(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		q := New().Insert("events", "user_id", "kind", "payload", "created_at")
+		for row := 0; row < 100; row++ {
+			q.Values(row, "click", "{}", TestTime)
+		}
+		_ = q.String()
+	}
+}
+
+func BenchmarkConvertPlaceholders(b *testing.B) {
+	for _, n := range []int{0, 1, 10, 1000} {
+		query := "SELECT * FROM t WHERE id IN (" + strings.TrimSuffix(strings.Repeat("?, ", n), ", ") + ")"
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				builder := Builder{}
+				_ = builder.convertPlaceholders(query)
+			}
+		})
+	}
+}
+
+func BenchmarkNewString(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = New().Select("id", "name").From("users").String()
+	}
+}
+
+func TestConcurrentString(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			table := "t" + strconv.Itoa(i)
+			for j := 0; j < 100; j++ {
+				query := New().Select("*").From(table).Where("id = ?", j)
+				assert.Equal(t, "SELECT * FROM "+table+" WHERE id = $1", query.String())
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestSelectList(t *testing.T) {
+	base := func() *Builder {
+		return New().Select("id", "name").From("users").Where("active = ?", true)
+	}
+
+	assert.Equal(t, "SELECT id, name, COUNT(*) OVER () AS total FROM users WHERE active = $1",
+		base().Select("COUNT(*) OVER () AS total").String())
+	assert.Equal(t, "SELECT COUNT(*) FROM users WHERE active = $1", base().ReplaceSelect("COUNT(*)").String())
+
+	assert.Equal(t, "SELECT DISTINCT city FROM users", New().Distinct().Select("city").From("users").String())
+	assert.Equal(t, "SELECT DISTINCT country FROM users", New().Select("city").Distinct().From("users").ReplaceSelect("country").String())
+
+	b := New().Select("region").From("orders").SelectExpr(Sum("amount").Filter("status = ?", "paid").As("paid"))
+	assert.Equal(t, "SELECT region, SUM(amount) FILTER (WHERE status = $1) AS paid FROM orders", b.String())
+	assert.EqualError(t, b.ReplaceSelect("region").Err(), "ReplaceSelect cannot drop a select list with bound args")
+}
+
+func TestFromTwice(t *testing.T) {
+	b := New().Select("*").From("users").From("accounts")
+	assert.EqualError(t, b.Err(), "FROM already set, cannot add accounts; use AddFrom for FROM a, b")
+	_, err := b.Prepare(nil)
+	assert.Error(t, err)
+
+	b = New().Select("u.name", "a.balance").From("users u").AddFrom("accounts a").Where("a.user_id = u.id")
+	assert.NoError(t, b.Err())
+	assert.Equal(t, "SELECT u.name, a.balance FROM users u, accounts a WHERE a.user_id = u.id", b.String())
+
+	// AddFrom alone starts the clause
+	assert.Equal(t, "SELECT * FROM users", New().Select("*").AddFrom("users").String())
+
+	err = New().Select("*").From("users").FromFunction("generate_series(1, 3)", "n").Err()
+	assert.EqualError(t, err, "FROM already set, cannot add n; FromFunction starts the FROM clause")
+}
+
+func TestConnectorFirst(t *testing.T) {
+	b := New().Select("*").From("orders").AndWhere("status = ?", "paid")
+	assert.Equal(t, "SELECT * FROM orders WHERE status = $1", b.String())
+
+	b = New().Select("*").From("orders").OrWhere("status = ?", "paid").AndWhere("total > ?", 10)
+	assert.Equal(t, "SELECT * FROM orders WHERE status = $1 AND total > $2", b.String())
+
+	b = New().Select("*").From("orders").AndWhere("status = ?", "paid").OrWhere("refunded").Where("total > ?", 10)
+	assert.Equal(t, "SELECT * FROM orders WHERE status = $1 OR refunded AND total > $2", b.String())
+	assert.Equal(t, []interface{}{"paid", 10}, b.args)
+
+	// with a Where first the connectors are kept
+	b = New().Delete("orders").Where("status = ?", "void").OrWhere("total = ?", 0)
+	assert.Equal(t, "DELETE FROM orders WHERE status = $1 OR total = $2", b.String())
+}
+
+func TestExpr(t *testing.T) {
+	b := New().Update("prices").
+		Set(map[string]interface{}{"amount": Expr("amount * ?", 1.1)}).
+		Where("valid_until < ?", Expr("NOW() + ?::interval", "7 days"))
+	assert.NoError(t, b.Err())
+	assert.Equal(t, "UPDATE prices SET amount = amount * $1 WHERE valid_until < NOW() + $2::interval", b.String())
+	assert.Equal(t, []interface{}{1.1, "7 days"}, b.args)
+
+	// without args the SQL is kept as written, like Raw
+	b = New().Select("*").From("docs").SelectExpr(Expr("data ? 'tag' AS tagged"), Raw("NOW()"))
+	assert.Equal(t, "SELECT *, data ? 'tag' AS tagged, NOW() FROM docs", b.String())
+
+	// nested expressions splice their args in place
+	b = New().Select("*").From("events").Where("id = ? AND at > ?", 3, Expr("? - interval '1 day'", Cast("2024-01-01", "date")))
+	assert.Equal(t, "SELECT * FROM events WHERE id = $1 AND at > $2::date - interval '1 day'", b.String())
+	assert.Equal(t, []interface{}{3, "2024-01-01"}, b.args)
+
+	b = New().Select("*").From("events").Where("at > ?", Expr("NOW() - ? * ?", 1))
+	assert.ErrorIs(t, b.Err(), ErrArgCount)
+	assert.EqualError(t, b.Err(), "expression expects 2 arg(s), got 1: placeholder and argument count mismatch")
+}
+
+func TestRenderCache(t *testing.T) {
+	b := New().Select("id").From("users").Where("active = ?", true)
+	first := b.String()
+	assert.Equal(t, "SELECT id FROM users WHERE active = $1", first)
+	assert.Equal(t, first, b.String())
+
+	steps := []struct {
+		change func()
+		want   string
+	}{
+		{func() { b.Select("name") }, "SELECT id, name FROM users WHERE active = $1"},
+		{func() { b.Distinct() }, "SELECT DISTINCT id, name FROM users WHERE active = $1"},
+		{func() { b.AndWhere("age > ?", 18) }, "SELECT DISTINCT id, name FROM users WHERE active = $1 AND age > $2"},
+		{func() { b.Join("teams t", "t.id = users.team_id") }, "SELECT DISTINCT id, name FROM users JOIN teams t ON t.id = users.team_id WHERE active = $1 AND age > $2"},
+		{func() { b.OrderBy("id") }, "SELECT DISTINCT id, name FROM users JOIN teams t ON t.id = users.team_id WHERE active = $1 AND age > $2 ORDER BY id"},
+		{func() { b.Limit(10) }, "SELECT DISTINCT id, name FROM users JOIN teams t ON t.id = users.team_id WHERE active = $1 AND age > $2 ORDER BY id LIMIT $3"},
+		{func() { b.Offset(5) }, "SELECT DISTINCT id, name FROM users JOIN teams t ON t.id = users.team_id WHERE active = $1 AND age > $2 ORDER BY id LIMIT $3 OFFSET $4"},
+		{func() { b.UseFetchSyntax() }, "SELECT DISTINCT id, name FROM users JOIN teams t ON t.id = users.team_id WHERE active = $1 AND age > $2 ORDER BY id OFFSET $3 ROWS FETCH NEXT $4 ROWS ONLY"},
+		{func() { b.ReplaceSelect("COUNT(*)") }, "SELECT DISTINCT COUNT(*) FROM users JOIN teams t ON t.id = users.team_id WHERE active = $1 AND age > $2 ORDER BY id OFFSET $3 ROWS FETCH NEXT $4 ROWS ONLY"},
+	}
+	for _, step := range steps {
+		step.change()
+		assert.Equal(t, step.want, b.String())
+		assert.Equal(t, step.want, b.String())
+	}
+
+	u := New().Update("users").Set(map[string]interface{}{"name": "zakirkun"})
+	assert.Equal(t, "UPDATE users SET name = $1", u.String())
+	u.Where("id = ?", 1).Returning("id")
+	assert.Equal(t, "UPDATE users SET name = $1 WHERE id = $2 RETURNING id", u.String())
+}
@@ -0,0 +1,53 @@
+package tokipgx
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/zakirkun/toki"
+)
+
+// BatchSender sends a pgx batch, implemented by *pgxpool.Pool, pgx.Tx and
+// *pgx.Conn
+type BatchSender interface {
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+}
+
+// ExecBatch runs the statements of batch in one pgx pipeline, a single
+// round trip, and returns their command tags. PostgreSQL runs a pipeline
+// outside a transaction as one implicit transaction, so a failing statement
+// rolls back the others whether or not NoTransaction is set; on a pgx.Tx
+// the statements join it. The failing statement is reported as a
+// *toki.BatchError. Statements run directly on pgx, without toki's logging,
+// metrics and middleware.
+func ExecBatch(ctx context.Context, s BatchSender, batch *toki.Batch) ([]pgconn.CommandTag, error) {
+	queries, err := batch.Queries()
+	if err != nil {
+		return nil, err
+	}
+	if len(queries) == 0 {
+		return nil, nil
+	}
+
+	pb := &pgx.Batch{}
+	for _, q := range queries {
+		pb.Queue(q.SQL, toki.DriverArgs(q.Args)...)
+	}
+
+	results := s.SendBatch(ctx, pb)
+	tags := make([]pgconn.CommandTag, 0, len(queries))
+	for i, q := range queries {
+		tag, err := results.Exec()
+		if err != nil {
+			results.Close()
+			return tags, &toki.BatchError{Index: i, Statement: q.SQL, Err: err}
+		}
+		tags = append(tags, tag)
+	}
+	if err := results.Close(); err != nil {
+		return tags, fmt.Errorf("failed to finish batch: %w", err)
+	}
+	return tags, nil
+}
@@ -0,0 +1,90 @@
+package tokipgx
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/zakirkun/toki"
+)
+
+// fakeSender answers a batch with one result per queued statement
+type fakeSender struct {
+	batch   *pgx.Batch
+	results *fakeBatchResults
+}
+
+func (s *fakeSender) SendBatch(_ context.Context, b *pgx.Batch) pgx.BatchResults {
+	s.batch = b
+	return s.results
+}
+
+// fakeBatchResults returns tags in order, failing at index fail
+type fakeBatchResults struct {
+	pgx.BatchResults
+	tags   []pgconn.CommandTag
+	fail   int
+	err    error
+	next   int
+	closed bool
+}
+
+func (r *fakeBatchResults) Exec() (pgconn.CommandTag, error) {
+	i := r.next
+	r.next++
+	if r.err != nil && i == r.fail {
+		return pgconn.CommandTag{}, r.err
+	}
+	return r.tags[i], nil
+}
+
+func (r *fakeBatchResults) Close() error {
+	r.closed = true
+	return nil
+}
+
+func TestExecBatch(t *testing.T) {
+	results := &fakeBatchResults{tags: []pgconn.CommandTag{
+		pgconn.NewCommandTag("UPDATE 2"),
+		pgconn.NewCommandTag("DELETE 1"),
+	}}
+	sender := &fakeSender{results: results}
+
+	batch := toki.NewBatch().Add(
+		toki.New().Update("users").Set(map[string]interface{}{"active": false}).Where("last_seen < ?", "2024-01-01"),
+		toki.New().Delete("sessions").Where("user_id = ?", 7),
+	)
+
+	tags, err := ExecBatch(context.Background(), sender, batch)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, sender.batch.Len())
+	assert.Equal(t, "UPDATE users SET active = $1 WHERE last_seen < $2", sender.batch.QueuedQueries[0].SQL)
+	assert.Equal(t, []any{7}, sender.batch.QueuedQueries[1].Arguments)
+	assert.Equal(t, int64(2), tags[0].RowsAffected())
+	assert.Equal(t, int64(1), tags[1].RowsAffected())
+	assert.True(t, results.closed)
+
+	results = &fakeBatchResults{
+		tags: []pgconn.CommandTag{pgconn.NewCommandTag("UPDATE 2")},
+		fail: 1,
+		err:  errors.New("deadlock detected"),
+	}
+	sender = &fakeSender{results: results}
+
+	tags, err = ExecBatch(context.Background(), sender, batch)
+	var batchErr *toki.BatchError
+	assert.True(t, errors.As(err, &batchErr))
+	assert.Equal(t, 1, batchErr.Index)
+	assert.Equal(t, "DELETE FROM sessions WHERE user_id = $1", batchErr.Statement)
+	assert.Len(t, tags, 1)
+	assert.True(t, results.closed)
+
+	broken := toki.New().WithDialect(toki.MySQL).SelectExpr(toki.Aggregate("json_arrayagg", "x").Filter("y")).From("t")
+	sender = &fakeSender{}
+	_, err = ExecBatch(context.Background(), sender, toki.NewBatch().Add(broken))
+	assert.Error(t, err)
+	assert.Nil(t, sender.batch)
+}
@@ -0,0 +1,93 @@
+package tokipgx
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// rows adapts pgx.Rows to driver.Rows, so database/sql, and with it toki's
+// scanning helpers, can read them
+type rows struct {
+	rows    pgx.Rows
+	columns []string
+}
+
+func newRows(r pgx.Rows) *rows {
+	fields := r.FieldDescriptions()
+	columns := make([]string, len(fields))
+	for i, f := range fields {
+		columns[i] = f.Name
+	}
+	return &rows{rows: r, columns: columns}
+}
+
+func (r *rows) Columns() []string {
+	return r.columns
+}
+
+func (r *rows) Close() error {
+	r.rows.Close()
+	return r.rows.Err()
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return err
+		}
+		return io.EOF
+	}
+
+	vals, err := r.rows.Values()
+	if err != nil {
+		return err
+	}
+	fields := r.rows.FieldDescriptions()
+	for i := range dest {
+		dest[i], err = driverValue(vals[i], fields[i].DataTypeOID)
+		if err != nil {
+			return fmt.Errorf("tokipgx: column %s: %w", r.columns[i], err)
+		}
+	}
+	return nil
+}
+
+// driverValue converts a value decoded by pgx to one database/sql can scan
+func driverValue(v any, oid uint32) (driver.Value, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if oid == pgtype.JSONOID || oid == pgtype.JSONBOID {
+		return json.Marshal(v)
+	}
+
+	switch v := v.(type) {
+	case int8:
+		return int64(v), nil
+	case int16:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case uint32:
+		return int64(v), nil
+	case float32:
+		return float64(v), nil
+	case [16]byte:
+		return pgtype.UUID{Bytes: v, Valid: true}.Value()
+	case netip.Prefix:
+		return v.String(), nil
+	case driver.Valuer:
+		return v.Value()
+	}
+
+	if driver.IsValue(v) {
+		return v, nil
+	}
+	return nil, fmt.Errorf("unsupported type %T", v)
+}
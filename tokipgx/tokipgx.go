@@ -0,0 +1,257 @@
+// Package tokipgx runs toki statements over pgx v5. Wrap adapts a
+// pgxpool.Pool, a pgx.Tx or a *pgx.Conn to a *sql.DB whose calls are made
+// with pgx, so builders, raw queries, transactions and the scanning helpers
+// work unchanged:
+//
+//	db := tokipgx.Wrap(pool)
+//	stmt, err := toki.New().Select("*").From("users").Prepare(db)
+//
+// It lives in its own module so the toki package does not depend on pgx.
+package tokipgx
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Querier is what the adapter needs from pgx, implemented by
+// *pgxpool.Pool, pgx.Tx and *pgx.Conn
+type Querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// txBeginner is implemented by queriers that can start a transaction with
+// options, *pgxpool.Pool and *pgx.Conn
+type txBeginner interface {
+	BeginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error)
+}
+
+// Wrap returns a *sql.DB running its statements on q. Args are passed to
+// pgx as they are, so pgx encodes them. Transactions begun on the *sql.DB
+// are pgx transactions, or savepoints when q is a pgx.Tx. Anything but a
+// pool cannot run statements concurrently, so the *sql.DB then uses a single
+// connection. Closing the *sql.DB does not close q.
+func Wrap(q Querier) *sql.DB {
+	db := sql.OpenDB(&connector{q: q})
+	if _, ok := q.(*pgxpool.Pool); !ok {
+		db.SetMaxOpenConns(1)
+	}
+	return db
+}
+
+type connector struct {
+	q Querier
+}
+
+func (c *connector) Connect(context.Context) (driver.Conn, error) {
+	return &conn{q: c.q}, nil
+}
+
+func (c *connector) Driver() driver.Driver {
+	return drv{}
+}
+
+// drv only exists to satisfy driver.Connector; connections come from Wrap
+type drv struct{}
+
+func (drv) Open(string) (driver.Conn, error) {
+	return nil, errors.New("tokipgx: use Wrap to create a database")
+}
+
+// conn runs statements on q, or on tx while a transaction is open
+type conn struct {
+	q  Querier
+	tx pgx.Tx
+}
+
+var (
+	_ driver.ExecerContext      = (*conn)(nil)
+	_ driver.QueryerContext     = (*conn)(nil)
+	_ driver.ConnBeginTx        = (*conn)(nil)
+	_ driver.ConnPrepareContext = (*conn)(nil)
+	_ driver.NamedValueChecker  = (*conn)(nil)
+)
+
+func (c *conn) querier() Querier {
+	if c.tx != nil {
+		return c.tx
+	}
+	return c.q
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	tag, err := c.querier().Exec(ctx, query, values(args)...)
+	if err != nil {
+		return nil, err
+	}
+	return result{tag}, nil
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	r, err := c.querier().Query(ctx, query, values(args)...)
+	if err != nil {
+		return nil, err
+	}
+	return newRows(r), nil
+}
+
+// CheckNamedValue accepts every arg, leaving the conversion to pgx
+func (c *conn) CheckNamedValue(*driver.NamedValue) error {
+	return nil
+}
+
+// PrepareContext returns a statement run like an unprepared one; pgx
+// prepares and caches statements itself
+func (c *conn) PrepareContext(_ context.Context, query string) (driver.Stmt, error) {
+	return &stmt{c: c, query: query}, nil
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if c.tx != nil {
+		return nil, errors.New("tokipgx: transaction already open on this connection")
+	}
+
+	var (
+		tx  pgx.Tx
+		err error
+	)
+	if opts.Isolation == driver.IsolationLevel(sql.LevelDefault) && !opts.ReadOnly {
+		tx, err = c.q.Begin(ctx)
+	} else if b, ok := c.q.(txBeginner); ok {
+		var txOpts pgx.TxOptions
+		txOpts.IsoLevel, err = isoLevel(sql.IsolationLevel(opts.Isolation))
+		if err != nil {
+			return nil, err
+		}
+		if opts.ReadOnly {
+			txOpts.AccessMode = pgx.ReadOnly
+		}
+		tx, err = b.BeginTx(ctx, txOpts)
+	} else {
+		return nil, errors.New("tokipgx: transaction options need a pool or connection, not a pgx.Tx")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c.tx = tx
+	return &transaction{c: c}, nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+// Close leaves q open; it belongs to the caller of Wrap
+func (c *conn) Close() error {
+	return nil
+}
+
+// isoLevel maps a database/sql isolation level to pgx
+func isoLevel(level sql.IsolationLevel) (pgx.TxIsoLevel, error) {
+	switch level {
+	case sql.LevelDefault:
+		return "", nil
+	case sql.LevelReadUncommitted:
+		return pgx.ReadUncommitted, nil
+	case sql.LevelReadCommitted:
+		return pgx.ReadCommitted, nil
+	case sql.LevelRepeatableRead, sql.LevelSnapshot:
+		return pgx.RepeatableRead, nil
+	case sql.LevelSerializable:
+		return pgx.Serializable, nil
+	}
+	return "", fmt.Errorf("tokipgx: unsupported isolation level %s", level)
+}
+
+type transaction struct {
+	c *conn
+}
+
+func (t *transaction) Commit() error {
+	defer func() { t.c.tx = nil }()
+	return t.c.tx.Commit(context.Background())
+}
+
+func (t *transaction) Rollback() error {
+	defer func() { t.c.tx = nil }()
+	return t.c.tx.Rollback(context.Background())
+}
+
+type stmt struct {
+	c     *conn
+	query string
+}
+
+var (
+	_ driver.StmtExecContext  = (*stmt)(nil)
+	_ driver.StmtQueryContext = (*stmt)(nil)
+)
+
+func (s *stmt) Close() error {
+	return nil
+}
+
+// NumInput returns -1; pgx checks the arg count
+func (s *stmt) NumInput() int {
+	return -1
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.c.ExecContext(ctx, s.query, args)
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.c.QueryContext(ctx, s.query, args)
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), named(args))
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), named(args))
+}
+
+// result reports the rows affected from the command tag. PostgreSQL has no
+// last insert id; use RETURNING.
+type result struct {
+	tag pgconn.CommandTag
+}
+
+func (r result) LastInsertId() (int64, error) {
+	return 0, errors.New("tokipgx: LastInsertId is not supported, use RETURNING")
+}
+
+func (r result) RowsAffected() (int64, error) {
+	return r.tag.RowsAffected(), nil
+}
+
+func values(args []driver.NamedValue) []any {
+	out := make([]any, len(args))
+	for i, arg := range args {
+		out[i] = arg.Value
+	}
+	return out
+}
+
+func named(args []driver.Value) []driver.NamedValue {
+	out := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		out[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return out
+}
@@ -0,0 +1,188 @@
+package tokipgx
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/zakirkun/toki"
+)
+
+// call is a statement received by fakeQuerier
+type call struct {
+	sql  string
+	args []any
+}
+
+// fakeQuerier records statements and answers queries with rows
+type fakeQuerier struct {
+	calls []call
+	rows  *fakeRows
+	tag   pgconn.CommandTag
+	tx    *fakeTx
+}
+
+func (f *fakeQuerier) Exec(_ context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	f.calls = append(f.calls, call{sql, args})
+	return f.tag, nil
+}
+
+func (f *fakeQuerier) Query(_ context.Context, sql string, args ...any) (pgx.Rows, error) {
+	f.calls = append(f.calls, call{sql, args})
+	return f.rows, nil
+}
+
+func (f *fakeQuerier) Begin(context.Context) (pgx.Tx, error) {
+	f.tx = &fakeTx{fakeQuerier: &fakeQuerier{tag: f.tag, rows: f.rows}}
+	return f.tx, nil
+}
+
+// fakeTx is a pgx.Tx recording how it ended; the methods the adapter does
+// not use come from the nil embedded interface
+type fakeTx struct {
+	pgx.Tx
+	*fakeQuerier
+	committed, rolledBack bool
+}
+
+func (t *fakeTx) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	return t.fakeQuerier.Exec(ctx, sql, args...)
+}
+
+func (t *fakeTx) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return t.fakeQuerier.Query(ctx, sql, args...)
+}
+
+func (t *fakeTx) Begin(ctx context.Context) (pgx.Tx, error) {
+	return t.fakeQuerier.Begin(ctx)
+}
+
+func (t *fakeTx) Commit(context.Context) error {
+	t.committed = true
+	return nil
+}
+
+func (t *fakeTx) Rollback(context.Context) error {
+	t.rolledBack = true
+	return nil
+}
+
+// fakeRows serves values as pgx decodes them; the methods the adapter does
+// not use come from the nil embedded interface
+type fakeRows struct {
+	pgx.Rows
+	fields []pgconn.FieldDescription
+	values [][]any
+	next   int
+	closed bool
+}
+
+func (r *fakeRows) FieldDescriptions() []pgconn.FieldDescription { return r.fields }
+func (r *fakeRows) Err() error                                   { return nil }
+func (r *fakeRows) Close()                                       { r.closed = true }
+
+func (r *fakeRows) Next() bool {
+	if r.next >= len(r.values) {
+		return false
+	}
+	r.next++
+	return true
+}
+
+func (r *fakeRows) Values() ([]any, error) {
+	return r.values[r.next-1], nil
+}
+
+type account struct {
+	ID       int64          `db:"id"`
+	Name     string         `db:"name"`
+	Balance  string         `db:"balance"`
+	Token    string         `db:"token"`
+	Settings map[string]any `db:"settings,json"`
+	Opened   time.Time      `db:"opened"`
+}
+
+func TestWrapQuery(t *testing.T) {
+	opened := time.Date(2024, 3, 4, 5, 6, 7, 0, time.UTC)
+	var balance pgtype.Numeric
+	assert.NoError(t, balance.Scan("12.50"))
+
+	q := &fakeQuerier{rows: &fakeRows{
+		fields: []pgconn.FieldDescription{
+			{Name: "id", DataTypeOID: pgtype.Int4OID},
+			{Name: "name", DataTypeOID: pgtype.TextOID},
+			{Name: "balance", DataTypeOID: pgtype.NumericOID},
+			{Name: "token", DataTypeOID: pgtype.UUIDOID},
+			{Name: "settings", DataTypeOID: pgtype.JSONBOID},
+			{Name: "opened", DataTypeOID: pgtype.TimestamptzOID},
+		},
+		values: [][]any{{
+			int32(7), "zakirkun", balance,
+			[16]byte{0x6b, 0xa7, 0xb8, 0x10, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8},
+			map[string]any{"theme": "dark"}, opened,
+		}},
+	}}
+	db := Wrap(q)
+	defer db.Close()
+
+	stmt, err := toki.New().Select("*").From("accounts").Where("id = ?", 7).Prepare(db)
+	assert.NoError(t, err)
+	rows, err := stmt.Query()
+	assert.NoError(t, err)
+
+	var accounts []account
+	assert.NoError(t, toki.ScanAll(rows, &accounts))
+	assert.Equal(t, []account{{
+		ID: 7, Name: "zakirkun", Balance: "12.50", Token: "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+		Settings: map[string]any{"theme": "dark"}, Opened: opened,
+	}}, accounts)
+	assert.Equal(t, []call{{"SELECT * FROM accounts WHERE id = $1", []any{7}}}, q.calls)
+	assert.True(t, q.rows.closed)
+}
+
+func TestWrapExec(t *testing.T) {
+	q := &fakeQuerier{tag: pgconn.NewCommandTag("UPDATE 3")}
+	db := Wrap(q)
+	defer db.Close()
+
+	res, err := toki.New().Raw("UPDATE accounts SET name = $1", "x").WithDB(db).Exec()
+	assert.NoError(t, err)
+	n, err := res.RowsAffected()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+	_, err = res.LastInsertId()
+	assert.EqualError(t, err, "tokipgx: LastInsertId is not supported, use RETURNING")
+
+	// args reach pgx unconverted, so it can encode them itself
+	id := int32(5)
+	_, err = toki.New().Raw("DELETE FROM accounts WHERE id = $1", &id).WithDB(db).Exec()
+	assert.NoError(t, err)
+	assert.Same(t, &id, q.calls[1].args[0])
+}
+
+func TestWrapTransaction(t *testing.T) {
+	q := &fakeQuerier{tag: pgconn.NewCommandTag("INSERT 0 1")}
+	db := Wrap(q)
+	defer db.Close()
+
+	err := toki.RunInTx(context.Background(), db, nil, func(tx *toki.Transaction) error {
+		stmt, err := tx.Builder().Insert("accounts", "name").Values("zakirkun").Prepare(nil)
+		if err != nil {
+			return err
+		}
+		_, err = stmt.Exec()
+		return err
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, q.calls)
+	assert.True(t, q.tx.committed)
+	assert.Equal(t, []call{{"INSERT INTO accounts (name) VALUES ($1)", []any{"zakirkun"}}}, q.tx.calls)
+
+	_, err = toki.BeginTx(context.Background(), db, &toki.TransactionOptions{Isolation: sql.LevelSerializable})
+	assert.EqualError(t, err, "failed to begin transaction: tokipgx: transaction options need a pool or connection, not a pgx.Tx")
+}
@@ -0,0 +1,83 @@
+package tokitest
+
+import (
+	"database/sql/driver"
+	"regexp"
+	"strings"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/zakirkun/toki"
+)
+
+// Expectation is the sqlmock expectation added by ExpectFrom: Query for
+// statements returning rows, Exec otherwise
+type Expectation struct {
+	Query *sqlmock.ExpectedQuery
+	Exec  *sqlmock.ExpectedExec
+}
+
+// ExpectFrom expects the statement b renders, with b's args, on a mock
+// using the default regexp matcher. SELECT statements and statements with
+// RETURNING are expected as queries, everything else as execs.
+func ExpectFrom(mock sqlmock.Sqlmock, b *toki.Builder) Expectation {
+	query := "^" + regexp.QuoteMeta(b.String()) + "$"
+	args := make([]driver.Value, len(b.Args()))
+	for i, arg := range b.Args() {
+		args[i] = arg
+	}
+
+	if returnsRows(b) {
+		return Expectation{Query: mock.ExpectQuery(query).WithArgs(args...)}
+	}
+	return Expectation{Exec: mock.ExpectExec(query).WithArgs(args...)}
+}
+
+// returnsRows reports whether b is run with Query rather than Exec: it is a
+// SELECT or has a RETURNING clause outside parentheses, so one in a WITH
+// query does not count
+func returnsRows(b *toki.Builder) bool {
+	if b.Kind() == toki.KindSelect {
+		return true
+	}
+	query := b.String()
+	depth := 0
+	for i := 0; i < len(query); i++ {
+		switch query[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ' ':
+			if depth == 0 && strings.HasPrefix(query[i:], " RETURNING ") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// WillReturnRows sets the rows of a query expectation
+func (e Expectation) WillReturnRows(rows ...*sqlmock.Rows) Expectation {
+	if e.Query != nil {
+		e.Query.WillReturnRows(rows...)
+	}
+	return e
+}
+
+// WillReturnResult sets the result of an exec expectation
+func (e Expectation) WillReturnResult(result driver.Result) Expectation {
+	if e.Exec != nil {
+		e.Exec.WillReturnResult(result)
+	}
+	return e
+}
+
+// WillReturnError makes the statement fail with err
+func (e Expectation) WillReturnError(err error) Expectation {
+	if e.Query != nil {
+		e.Query.WillReturnError(err)
+	} else {
+		e.Exec.WillReturnError(err)
+	}
+	return e
+}
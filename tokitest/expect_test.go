@@ -0,0 +1,37 @@
+package tokitest
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/zakirkun/toki"
+)
+
+func TestExpectFromKinds(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	returning := toki.New().Delete("sessions").Where("user_id = ?", 3).Returning("id")
+	e := ExpectFrom(mock, returning).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	assert.NotNil(t, e.Query)
+
+	stmt, err := returning.Prepare(db)
+	assert.NoError(t, err)
+	rows, err := stmt.Query()
+	assert.NoError(t, err)
+	rows.Close()
+
+	// the RETURNING of a WITH query does not make the statement return rows
+	moved := toki.New().Delete("queue").Where("done").Returning("*")
+	archive := toki.New().With("moved", moved).Insert("archive").Select("*").From("moved")
+	e = ExpectFrom(mock, archive).WillReturnError(assert.AnError)
+	assert.NotNil(t, e.Exec)
+
+	stmt, err = archive.Prepare(db)
+	assert.NoError(t, err)
+	_, err = stmt.Exec()
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
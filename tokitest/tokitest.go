@@ -0,0 +1,166 @@
+// Package tokitest provides assertions for the SQL and args produced by toki
+// builders.
+package tokitest
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/zakirkun/toki"
+)
+
+// TestingT is the subset of *testing.T the assertions use
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// AssertSQL checks that b renders wantSQL and binds wantArgs. Whitespace
+// runs outside string literals compare equal, so wantSQL can be split over
+// lines. On mismatch it reports where the SQL first differs and each
+// placeholder whose arg differs, and returns false.
+func AssertSQL(t TestingT, b *toki.Builder, wantSQL string, wantArgs ...interface{}) bool {
+	t.Helper()
+	return assertSQL(t, b, wantSQL, wantArgs, false)
+}
+
+// AssertSQLUnnumbered is AssertSQL treating every $N, @pN and ? placeholder
+// as the same, so only placeholder positions are compared. Use it when the
+// numbering depends on how sub-builders were composed.
+func AssertSQLUnnumbered(t TestingT, b *toki.Builder, wantSQL string, wantArgs ...interface{}) bool {
+	t.Helper()
+	return assertSQL(t, b, wantSQL, wantArgs, true)
+}
+
+func assertSQL(t TestingT, b *toki.Builder, wantSQL string, wantArgs []interface{}, unnumbered bool) bool {
+	t.Helper()
+
+	got, want := Normalize(b.String()), Normalize(wantSQL)
+	if unnumbered {
+		got, want = unnumber(got), unnumber(want)
+	}
+
+	var report []string
+	if got != want {
+		report = append(report, sqlDiff(got, want))
+	}
+	if diff := argsDiff(b.Args(), wantArgs); diff != "" {
+		report = append(report, diff)
+	}
+	if len(report) == 0 {
+		return true
+	}
+
+	t.Errorf("%s", strings.Join(report, "\n"))
+	return false
+}
+
+// Normalize collapses whitespace runs outside string literals and quoted
+// identifiers into single spaces and trims the ends
+func Normalize(query string) string {
+	var sb strings.Builder
+	sb.Grow(len(query))
+
+	var quote byte
+	space := false
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		if quote != 0 {
+			sb.WriteByte(c)
+			if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch c {
+		case ' ', '\t', '\n', '\r':
+			space = true
+			continue
+		case '\'', '"', '`':
+			quote = c
+		}
+		if space && sb.Len() > 0 {
+			sb.WriteByte(' ')
+		}
+		space = false
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
+
+// unnumber replaces $N and @pN placeholders outside literals with ?
+func unnumber(query string) string {
+	var sb strings.Builder
+	sb.Grow(len(query))
+
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '$' && i+1 < len(query) && isDigit(query[i+1]) && (i == 0 || !isIdent(query[i-1])):
+			for i+1 < len(query) && isDigit(query[i+1]) {
+				i++
+			}
+			c = '?'
+		case c == '@' && i+2 < len(query) && (query[i+1] == 'p' || query[i+1] == 'P') && isDigit(query[i+2]) && (i == 0 || !isIdent(query[i-1])):
+			i++
+			for i+1 < len(query) && isDigit(query[i+1]) {
+				i++
+			}
+			c = '?'
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
+
+// sqlDiff shows both statements and marks where they first differ
+func sqlDiff(got, want string) string {
+	at := 0
+	for at < len(got) && at < len(want) && got[at] == want[at] {
+		at++
+	}
+	return fmt.Sprintf("SQL differs at byte %d:\n  got:  %s\n  want: %s\n        %s^",
+		at, got, want, strings.Repeat(" ", at))
+}
+
+// argsDiff lists the placeholders whose args differ, or returns ""
+func argsDiff(got, want []interface{}) string {
+	var lines []string
+	for i := 0; i < len(got) || i < len(want); i++ {
+		switch {
+		case i >= len(want):
+			lines = append(lines, fmt.Sprintf("  $%d: got %s, want nothing", i+1, describe(got[i])))
+		case i >= len(got):
+			lines = append(lines, fmt.Sprintf("  $%d: got nothing, want %s", i+1, describe(want[i])))
+		case !reflect.DeepEqual(got[i], want[i]):
+			lines = append(lines, fmt.Sprintf("  $%d: got %s, want %s", i+1, describe(got[i]), describe(want[i])))
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("args differ (got %d, want %d):\n%s", len(got), len(want), strings.Join(lines, "\n"))
+}
+
+func describe(v interface{}) string {
+	if v == nil {
+		return "nil"
+	}
+	return fmt.Sprintf("%#v (%T)", v, v)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdent(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || isDigit(c)
+}
@@ -0,0 +1,55 @@
+package tokitest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zakirkun/toki"
+)
+
+// recorder is a TestingT keeping the reported failure
+type recorder struct {
+	failure string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.failure = fmt.Sprintf(format, args...)
+}
+
+func TestAssertSQL(t *testing.T) {
+	b := toki.New().Select("id", "name").From("users").Where("age > ?", 18).AndWhere("name = ?", "a  b")
+
+	AssertSQL(t, b, `
+		SELECT id, name
+		FROM users
+		WHERE age > $1 AND name = $2`, 18, "a  b")
+
+	r := &recorder{}
+	assert.False(t, AssertSQL(r, b, "SELECT id, name FROM users WHERE age >= $1 AND name = $2", int64(18), "a  b", true))
+	assert.Equal(t, `SQL differs at byte 38:
+  got:  SELECT id, name FROM users WHERE age > $1 AND name = $2
+  want: SELECT id, name FROM users WHERE age >= $1 AND name = $2
+                                              ^
+args differ (got 2, want 3):
+  $1: got 18 (int), want 18 (int64)
+  $3: got nothing, want true (bool)`, r.failure)
+}
+
+func TestAssertSQLUnnumbered(t *testing.T) {
+	sub := toki.New().Select("user_id").From("orders").Where("total > ?", 100)
+	b := toki.New().With("big", sub).Select("*").From("users").Where("id IN (SELECT user_id FROM big)").AndWhere("active = ?", true)
+
+	AssertSQLUnnumbered(t, b, "WITH big AS (SELECT user_id FROM orders WHERE total > ?) SELECT * FROM users WHERE id IN (SELECT user_id FROM big) AND active = ?", 100, true)
+
+	r := &recorder{}
+	assert.False(t, AssertSQL(r, b, "WITH big AS (SELECT user_id FROM orders WHERE total > ?) SELECT * FROM users WHERE id IN (SELECT user_id FROM big) AND active = ?", 100, true))
+	assert.Contains(t, r.failure, "SQL differs at byte 54")
+}
+
+func TestNormalize(t *testing.T) {
+	assert.Equal(t, "SELECT 'a  b' FROM \"my  table\"", Normalize("  SELECT\n\t'a  b'   FROM \"my  table\"\n"))
+	assert.Equal(t, "x = ? AND y = '$2' AND z = ?", unnumber("x = $1 AND y = '$2' AND z = $10"))
+}
@@ -3,13 +3,63 @@ package toki
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"regexp"
 )
 
-// Transaction represents a database transaction
+// ErrTxDone is returned when rolling back a transaction that has already been
+// committed or rolled back, mirroring sql.ErrTxDone
+var ErrTxDone = errors.New("transaction has already been committed or rolled back")
+
+// ErrReadOnlyTransaction is returned when a builder tries to run an INSERT,
+// UPDATE or DELETE through a read-only transaction
+var ErrReadOnlyTransaction = errors.New("write statement in read-only transaction")
+
+// TxState describes where a Transaction is in its lifecycle
+type TxState int
+
+const (
+	// TxActive means the transaction is still open
+	TxActive TxState = iota
+	// TxCommitted means the transaction was committed
+	TxCommitted
+	// TxRolledBack means the transaction was rolled back
+	TxRolledBack
+)
+
+func (s TxState) String() string {
+	switch s {
+	case TxActive:
+		return "active"
+	case TxCommitted:
+		return "committed"
+	case TxRolledBack:
+		return "rolled back"
+	default:
+		return fmt.Sprintf("TxState(%d)", int(s))
+	}
+}
+
+// savepointName matches the identifiers accepted as savepoint names
+var savepointName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Transaction represents a database transaction. Transactions started from
+// another Transaction are nested and backed by savepoints.
 type Transaction struct {
-	tx   *sql.Tx
-	done bool
+	tx        *sql.Tx
+	state     TxState
+	parent    *Transaction
+	depth     int
+	savepoint string
+	seq       int
+	readOnly  bool
+	hooks     hooks
+	// ctx is the context the transaction was begun with
+	ctx context.Context
+
+	onCommit   []func()
+	onRollback []func()
 }
 
 // TransactionOptions represents options for starting a new transaction
@@ -33,38 +83,280 @@ func BeginTx(ctx context.Context, db *sql.DB, opts *TransactionOptions) (*Transa
 		}
 	}
 
-	tx, err := db.BeginTx(ctx, txOpts)
+	var h hooks
+	tx, err := h.begin(ctx, db, txOpts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 
-	return &Transaction{tx: tx}, nil
+	return &Transaction{tx: tx, readOnly: opts != nil && opts.ReadOnly, hooks: h, ctx: ctx}, nil
+}
+
+// BeginReadOnly starts a read-only transaction. Builders bound to it refuse to
+// run INSERT, UPDATE and DELETE statements before they reach the database.
+// Raw queries cannot be classified reliably and are not checked.
+func BeginReadOnly(ctx context.Context, db *sql.DB) (*Transaction, error) {
+	return BeginTx(ctx, db, &TransactionOptions{ReadOnly: true})
+}
+
+// RunInTx runs fn inside a transaction. The transaction is committed when fn
+// returns nil and rolled back when fn returns an error or panics; a panic is
+// re-raised after the rollback.
+func RunInTx(ctx context.Context, db *sql.DB, opts *TransactionOptions, fn func(tx *Transaction) error) error {
+	tx, err := BeginTx(ctx, db, opts)
+	if err != nil {
+		return err
+	}
+
+	return runTx(tx, fn)
+}
+
+// Begin starts a nested transaction. The nested level is backed by an
+// automatically named savepoint: committing it releases the savepoint and
+// rolling it back returns the outer transaction to the state it had when the
+// nested level began.
+func (t *Transaction) Begin() (*Transaction, error) {
+	if t.Done() {
+		return nil, fmt.Errorf("transaction already %s: %w", t.state, ErrTxDone)
+	}
+
+	root := t.root()
+	root.seq++
+	name := fmt.Sprintf("sp_%d", root.seq)
+
+	if err := t.Savepoint(name); err != nil {
+		return nil, err
+	}
+
+	return &Transaction{
+		tx:        t.tx,
+		parent:    t,
+		depth:     t.depth + 1,
+		savepoint: name,
+		readOnly:  t.readOnly,
+		hooks:     t.hooks,
+		ctx:       t.ctx,
+	}, nil
+}
+
+// RunInTx runs fn inside a nested transaction, see RunInTx and Begin
+func (t *Transaction) RunInTx(fn func(tx *Transaction) error) error {
+	nested, err := t.Begin()
+	if err != nil {
+		return err
+	}
+
+	return runTx(nested, fn)
+}
+
+// Builder returns a new query builder bound to the transaction, running its
+// statements with the context the transaction was begun with
+func (t *Transaction) Builder() *Builder {
+	return New().WithTransaction(t)
+}
+
+// RawQuery returns a raw SQL query bound to the transaction
+func (t *Transaction) RawQuery(sql string, args ...interface{}) *RawQuery {
+	return New().RawQuery(sql, args...).WithTransaction(t)
+}
+
+// Raw returns a raw SQL query bound to the transaction.
+//
+// Deprecated: use RawQuery.
+func (t *Transaction) Raw(sql string, args ...interface{}) *RawQuery {
+	return t.RawQuery(sql, args...)
+}
+
+// ReadOnly reports whether the transaction was started as read-only
+func (t *Transaction) ReadOnly() bool {
+	return t.readOnly
+}
+
+// Depth returns the nesting level, 0 for a real database transaction
+func (t *Transaction) Depth() int {
+	return t.depth
+}
+
+// root returns the outermost transaction
+func (t *Transaction) root() *Transaction {
+	for t.parent != nil {
+		t = t.parent
+	}
+	return t
+}
+
+// runTx calls fn and commits or rolls back tx depending on its outcome
+func runTx(tx *Transaction, fn func(tx *Transaction) error) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	return tx.CommitOrRollback(fn(tx))
 }
 
 // Commit commits the transaction
 func (t *Transaction) Commit() error {
-	if t.done {
-		return fmt.Errorf("transaction already committed")
+	if t.Done() {
+		return fmt.Errorf("transaction already %s: %w", t.state, ErrTxDone)
+	}
+
+	if t.parent != nil {
+		if err := t.ReleaseSavepoint(t.savepoint); err != nil {
+			return fmt.Errorf("failed to commit nested transaction: %w", err)
+		}
+		t.state = TxCommitted
+		// The work is only durable once the outer transaction commits
+		t.parent.onCommit = append(t.parent.onCommit, t.onCommit...)
+		t.parent.onRollback = append(t.parent.onRollback, t.onRollback...)
+		return nil
 	}
 
-	if err := t.tx.Commit(); err != nil {
+	if err := t.hooks.commit(t.context(), t.tx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	t.done = true
+	t.state = TxCommitted
+	runHooks(t.onCommit)
 	return nil
 }
 
-// Rollback rolls back the transaction
+// Rollback rolls back the transaction. Rolling back a transaction that is
+// already committed or rolled back does nothing and returns ErrTxDone, so
+// defer tx.Rollback() is safe.
 func (t *Transaction) Rollback() error {
-	if t.done {
-		return fmt.Errorf("transaction already rolled back")
+	if t.Done() {
+		return ErrTxDone
+	}
+
+	if t.parent != nil {
+		if err := t.RollbackTo(t.savepoint); err != nil {
+			return fmt.Errorf("failed to rollback nested transaction: %w", err)
+		}
+		t.state = TxRolledBack
+		runHooks(t.onRollback)
+		return nil
 	}
 
-	if err := t.tx.Rollback(); err != nil {
+	if err := t.hooks.rollback(t.context(), t.tx); err != nil {
 		return fmt.Errorf("failed to rollback transaction: %w", err)
 	}
 
-	t.done = true
+	t.state = TxRolledBack
+	runHooks(t.onRollback)
+	return nil
+}
+
+// OnCommit registers fn to run after the transaction commits successfully.
+// Hooks run in registration order and never run if the commit fails. Hooks
+// registered on a nested transaction run when the outermost transaction
+// commits.
+func (t *Transaction) OnCommit(fn func()) error {
+	if t.Done() {
+		return fmt.Errorf("cannot register commit hook: transaction already %s: %w", t.state, ErrTxDone)
+	}
+
+	t.onCommit = append(t.onCommit, fn)
+	return nil
+}
+
+// OnRollback registers fn to run after the transaction is rolled back.
+// Hooks run in registration order and never run if the rollback fails.
+func (t *Transaction) OnRollback(fn func()) error {
+	if t.Done() {
+		return fmt.Errorf("cannot register rollback hook: transaction already %s: %w", t.state, ErrTxDone)
+	}
+
+	t.onRollback = append(t.onRollback, fn)
+	return nil
+}
+
+// runHooks calls every hook even if one panics. The transaction state is
+// already final at this point; the first panic is re-raised afterwards.
+func runHooks(hooks []func()) {
+	var panicked interface{}
+	for _, fn := range hooks {
+		func() {
+			defer func() {
+				if p := recover(); p != nil && panicked == nil {
+					panicked = p
+				}
+			}()
+			fn()
+		}()
+	}
+
+	if panicked != nil {
+		panic(panicked)
+	}
+}
+
+// RollbackUnlessCommitted rolls back the transaction unless it has already
+// been committed or rolled back, in which case it does nothing. It only
+// returns an error when the rollback itself fails, which makes it suitable
+// for defer tx.RollbackUnlessCommitted().
+func (t *Transaction) RollbackUnlessCommitted() error {
+	if t.Done() {
+		return nil
+	}
+
+	return t.Rollback()
+}
+
+// CommitOrRollback commits the transaction when err is nil and rolls it back
+// otherwise, returning err (joined with any rollback failure)
+func (t *Transaction) CommitOrRollback(err error) error {
+	if err == nil {
+		return t.Commit()
+	}
+
+	if rbErr := t.Rollback(); rbErr != nil {
+		return fmt.Errorf("%w (rollback also failed: %w)", err, rbErr)
+	}
+
+	return err
+}
+
+// Done reports whether the transaction has been committed or rolled back
+func (t *Transaction) Done() bool {
+	return t.state != TxActive
+}
+
+// State returns the lifecycle state of the transaction
+func (t *Transaction) State() TxState {
+	return t.state
+}
+
+// Savepoint creates a savepoint with the given name
+func (t *Transaction) Savepoint(name string) error {
+	return t.savepointExec("SAVEPOINT", name)
+}
+
+// RollbackTo rolls back to the named savepoint, keeping the transaction open
+func (t *Transaction) RollbackTo(name string) error {
+	return t.savepointExec("ROLLBACK TO SAVEPOINT", name)
+}
+
+// ReleaseSavepoint releases the named savepoint
+func (t *Transaction) ReleaseSavepoint(name string) error {
+	return t.savepointExec("RELEASE SAVEPOINT", name)
+}
+
+// savepointExec validates name and runs a savepoint statement
+func (t *Transaction) savepointExec(command, name string) error {
+	if !savepointName.MatchString(name) {
+		return fmt.Errorf("invalid savepoint name %q", name)
+	}
+
+	if t.Done() {
+		return fmt.Errorf("transaction already %s: %w", t.state, ErrTxDone)
+	}
+
+	if _, err := t.hooks.exec(t.context(), t.tx, QueryInfo{SQL: command + " " + name}); err != nil {
+		return fmt.Errorf("failed to execute %s %s: %w", command, name, err)
+	}
+
 	return nil
 }
@@ -0,0 +1,61 @@
+package toki_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/zakirkun/toki"
+	"github.com/zakirkun/toki/tokitest"
+)
+
+func TestTransactionBuilder(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	insert := toki.New().Insert("users", "name", "email").Values(toki.TestUser, "zakir@example.com")
+	find := toki.New().Select("id", "name").From("users").Where("name = ?", toki.TestUser)
+	count := toki.New().Select("COUNT(*)").From("users")
+
+	mock.ExpectBegin()
+	tokitest.ExpectFrom(mock, insert).WillReturnResult(sqlmock.NewResult(1, 1))
+	tokitest.ExpectFrom(mock, find).WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, toki.TestUser))
+	tokitest.ExpectFrom(mock, count).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectCommit()
+
+	err = toki.RunInTx(context.Background(), db, nil, func(tx *toki.Transaction) error {
+		stmt, err := insert.WithTransaction(tx).Prepare(db)
+		if err != nil {
+			return err
+		}
+		if _, err := stmt.Exec(); err != nil {
+			return err
+		}
+
+		stmt, err = find.WithTransaction(tx).Prepare(db)
+		if err != nil {
+			return err
+		}
+
+		var id int
+		var name string
+		if err := stmt.QueryRow().Scan(&id, &name); err != nil {
+			return err
+		}
+		assert.Equal(t, 1, id)
+		assert.Equal(t, toki.TestUser, name)
+
+		stmt, err = count.WithTransaction(tx).Prepare(db)
+		if err != nil {
+			return err
+		}
+		var n int
+		return stmt.QueryRow().Scan(&n)
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
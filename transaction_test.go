@@ -0,0 +1,514 @@
+package toki
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunInTx(t *testing.T) {
+	t.Run("Commits on success", func(t *testing.T) {
+		db, mock, _ := setupTest(t)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO users .*").
+			WithArgs(TestUser, "zakir@example.com").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE profiles").
+			WithArgs(1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err := RunInTx(context.Background(), db, nil, func(tx *Transaction) error {
+			stmt, err := New().
+				WithTransaction(tx).
+				Insert("users", "name", "email").
+				Values(TestUser, "zakir@example.com").
+				Prepare(db)
+			if err != nil {
+				return err
+			}
+			if _, err := stmt.Exec(); err != nil {
+				return err
+			}
+
+			_, err = New().Raw("UPDATE profiles SET verified = true WHERE user_id = $1", 1).
+				WithTransaction(tx).
+				Exec()
+			return err
+		})
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Rolls back on error", func(t *testing.T) {
+		db, mock, _ := setupTest(t)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		errBoom := errors.New("boom")
+		err := RunInTx(context.Background(), db, nil, func(tx *Transaction) error {
+			return errBoom
+		})
+		assert.ErrorIs(t, err, errBoom)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Preserves original error when rollback fails", func(t *testing.T) {
+		db, mock, _ := setupTest(t)
+		defer db.Close()
+
+		errRollback := errors.New("connection lost")
+		mock.ExpectBegin()
+		mock.ExpectRollback().WillReturnError(errRollback)
+
+		errBoom := errors.New("boom")
+		err := RunInTx(context.Background(), db, nil, func(tx *Transaction) error {
+			return errBoom
+		})
+		assert.ErrorIs(t, err, errBoom)
+		assert.ErrorIs(t, err, errRollback)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Rolls back and re-panics", func(t *testing.T) {
+		db, mock, _ := setupTest(t)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		assert.PanicsWithValue(t, "boom", func() {
+			_ = RunInTx(context.Background(), db, nil, func(tx *Transaction) error {
+				panic("boom")
+			})
+		})
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Reports commit failure", func(t *testing.T) {
+		db, mock, _ := setupTest(t)
+		defer db.Close()
+
+		errCommit := errors.New("commit refused")
+		mock.ExpectBegin()
+		mock.ExpectCommit().WillReturnError(errCommit)
+
+		err := RunInTx(context.Background(), db, nil, func(tx *Transaction) error {
+			return nil
+		})
+		assert.ErrorIs(t, err, errCommit)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Log("---- Pass ----")
+}
+
+func TestSavepoint(t *testing.T) {
+	db, mock, _ := setupTest(t)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("SAVEPOINT optional_work")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO audit").
+		WillReturnError(errors.New("constraint violation"))
+	mock.ExpectExec(regexp.QuoteMeta("ROLLBACK TO SAVEPOINT optional_work")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("RELEASE SAVEPOINT optional_work")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	tx, err := Begin(db)
+	assert.NoError(t, err)
+
+	assert.NoError(t, tx.Savepoint("optional_work"))
+
+	_, err = New().Raw("INSERT INTO audit(event) VALUES($1)", "login").
+		WithTransaction(tx).
+		Exec()
+	assert.Error(t, err)
+
+	assert.NoError(t, tx.RollbackTo("optional_work"))
+	assert.NoError(t, tx.ReleaseSavepoint("optional_work"))
+	assert.NoError(t, tx.Commit())
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	t.Log("---- Pass ----")
+}
+
+func TestSavepointInvalidName(t *testing.T) {
+	db, mock, _ := setupTest(t)
+	defer db.Close()
+
+	mock.ExpectBegin()
+
+	tx, err := Begin(db)
+	assert.NoError(t, err)
+
+	for _, name := range []string{"", "1abc", "sp; DROP TABLE users", "sp--", "sp name"} {
+		assert.Error(t, tx.Savepoint(name), name)
+		assert.Error(t, tx.RollbackTo(name), name)
+		assert.Error(t, tx.ReleaseSavepoint(name), name)
+	}
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	t.Log("---- Pass ----")
+}
+
+func TestNestedTransaction(t *testing.T) {
+	t.Run("Depth and savepoint names", func(t *testing.T) {
+		db, mock, _ := setupTest(t)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("SAVEPOINT sp_1")).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta("SAVEPOINT sp_2")).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta("RELEASE SAVEPOINT sp_2")).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta("RELEASE SAVEPOINT sp_1")).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta("SAVEPOINT sp_3")).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta("RELEASE SAVEPOINT sp_3")).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		tx, err := Begin(db)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, tx.Depth())
+
+		inner, err := tx.Begin()
+		assert.NoError(t, err)
+		assert.Equal(t, 1, inner.Depth())
+
+		innermost, err := inner.Begin()
+		assert.NoError(t, err)
+		assert.Equal(t, 2, innermost.Depth())
+
+		assert.NoError(t, innermost.Commit())
+		assert.NoError(t, inner.Commit())
+
+		sibling, err := tx.Begin()
+		assert.NoError(t, err)
+		assert.NoError(t, sibling.Commit())
+
+		assert.NoError(t, tx.Commit())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Inner rollback followed by outer commit", func(t *testing.T) {
+		db, mock, _ := setupTest(t)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO users").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("SAVEPOINT sp_1")).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO audit").WillReturnError(errors.New("constraint violation"))
+		mock.ExpectExec(regexp.QuoteMeta("ROLLBACK TO SAVEPOINT sp_1")).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err := RunInTx(context.Background(), db, nil, func(tx *Transaction) error {
+			if _, err := New().Raw("INSERT INTO users(name) VALUES($1)", TestUser).
+				WithTransaction(tx).
+				Exec(); err != nil {
+				return err
+			}
+
+			innerErr := tx.RunInTx(func(inner *Transaction) error {
+				_, err := New().Raw("INSERT INTO audit(event) VALUES($1)", "signup").
+					WithTransaction(inner).
+					Exec()
+				return err
+			})
+			assert.Error(t, innerErr)
+
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Inner failure propagates to outer rollback", func(t *testing.T) {
+		db, mock, _ := setupTest(t)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("SAVEPOINT sp_1")).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta("ROLLBACK TO SAVEPOINT sp_1")).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		errBoom := errors.New("boom")
+		err := RunInTx(context.Background(), db, nil, func(tx *Transaction) error {
+			return tx.RunInTx(func(inner *Transaction) error {
+				return errBoom
+			})
+		})
+		assert.ErrorIs(t, err, errBoom)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Log("---- Pass ----")
+}
+
+func TestTransactionState(t *testing.T) {
+	t.Run("Rollback after commit is a no-op", func(t *testing.T) {
+		db, mock, _ := setupTest(t)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+
+		tx, err := Begin(db)
+		assert.NoError(t, err)
+		assert.Equal(t, TxActive, tx.State())
+		assert.False(t, tx.Done())
+
+		assert.NoError(t, tx.Commit())
+		assert.Equal(t, TxCommitted, tx.State())
+		assert.True(t, tx.Done())
+
+		assert.ErrorIs(t, tx.Rollback(), ErrTxDone)
+		assert.Equal(t, TxCommitted, tx.State())
+
+		err = tx.Commit()
+		assert.ErrorIs(t, err, ErrTxDone)
+		assert.Contains(t, err.Error(), "already committed")
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Commit after rollback reports rollback", func(t *testing.T) {
+		db, mock, _ := setupTest(t)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		tx, err := Begin(db)
+		assert.NoError(t, err)
+		assert.NoError(t, tx.Rollback())
+		assert.Equal(t, TxRolledBack, tx.State())
+
+		err = tx.Commit()
+		assert.ErrorIs(t, err, ErrTxDone)
+		assert.Contains(t, err.Error(), "already rolled back")
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("CommitOrRollback", func(t *testing.T) {
+		db, mock, _ := setupTest(t)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		tx, err := Begin(db)
+		assert.NoError(t, err)
+		assert.NoError(t, tx.CommitOrRollback(nil))
+		assert.Equal(t, TxCommitted, tx.State())
+
+		tx, err = Begin(db)
+		assert.NoError(t, err)
+		errBoom := errors.New("boom")
+		assert.ErrorIs(t, tx.CommitOrRollback(errBoom), errBoom)
+		assert.Equal(t, TxRolledBack, tx.State())
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Log("---- Pass ----")
+}
+
+func TestRollbackUnlessCommitted(t *testing.T) {
+	db, mock, _ := setupTest(t)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectRollback().WillReturnError(errors.New("connection lost"))
+
+	committed, err := Begin(db)
+	assert.NoError(t, err)
+	assert.NoError(t, committed.Commit())
+	assert.NoError(t, committed.RollbackUnlessCommitted())
+	assert.Equal(t, TxCommitted, committed.State())
+
+	abandoned, err := Begin(db)
+	assert.NoError(t, err)
+	assert.NoError(t, abandoned.RollbackUnlessCommitted())
+	assert.Equal(t, TxRolledBack, abandoned.State())
+	assert.NoError(t, abandoned.RollbackUnlessCommitted())
+
+	broken, err := Begin(db)
+	assert.NoError(t, err)
+	assert.Error(t, broken.RollbackUnlessCommitted())
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	t.Log("---- Pass ----")
+}
+
+func TestTransactionHooks(t *testing.T) {
+	t.Run("Commit hooks run in order after commit", func(t *testing.T) {
+		db, mock, _ := setupTest(t)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+
+		var events []string
+		err := RunInTx(context.Background(), db, nil, func(tx *Transaction) error {
+			assert.NoError(t, tx.OnCommit(func() { events = append(events, "first") }))
+			assert.NoError(t, tx.OnCommit(func() { events = append(events, "second") }))
+			assert.NoError(t, tx.OnRollback(func() { events = append(events, "rollback") }))
+			assert.Empty(t, events)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"first", "second"}, events)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Commit hooks do not fire when commit fails", func(t *testing.T) {
+		db, mock, _ := setupTest(t)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectCommit().WillReturnError(errors.New("commit refused"))
+
+		fired := false
+		tx, err := Begin(db)
+		assert.NoError(t, err)
+		assert.NoError(t, tx.OnCommit(func() { fired = true }))
+		assert.Error(t, tx.Commit())
+		assert.False(t, fired)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Rollback hooks", func(t *testing.T) {
+		db, mock, _ := setupTest(t)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		var events []string
+		err := RunInTx(context.Background(), db, nil, func(tx *Transaction) error {
+			assert.NoError(t, tx.OnCommit(func() { events = append(events, "commit") }))
+			assert.NoError(t, tx.OnRollback(func() { events = append(events, "rollback") }))
+			return errors.New("boom")
+		})
+		assert.Error(t, err)
+		assert.Equal(t, []string{"rollback"}, events)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Nested hooks wait for the outer commit", func(t *testing.T) {
+		db, mock, _ := setupTest(t)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("SAVEPOINT sp_1")).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta("RELEASE SAVEPOINT sp_1")).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		var events []string
+		err := RunInTx(context.Background(), db, nil, func(tx *Transaction) error {
+			err := tx.RunInTx(func(inner *Transaction) error {
+				return inner.OnCommit(func() { events = append(events, "inner") })
+			})
+			assert.Empty(t, events)
+			return err
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"inner"}, events)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Panicking hook keeps state consistent", func(t *testing.T) {
+		db, mock, _ := setupTest(t)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+
+		tx, err := Begin(db)
+		assert.NoError(t, err)
+
+		secondRan := false
+		assert.NoError(t, tx.OnCommit(func() { panic("hook failed") }))
+		assert.NoError(t, tx.OnCommit(func() { secondRan = true }))
+
+		assert.PanicsWithValue(t, "hook failed", func() { _ = tx.Commit() })
+		assert.True(t, secondRan)
+		assert.Equal(t, TxCommitted, tx.State())
+		assert.ErrorIs(t, tx.Rollback(), ErrTxDone)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Registering after completion fails", func(t *testing.T) {
+		db, mock, _ := setupTest(t)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+
+		tx, err := Begin(db)
+		assert.NoError(t, err)
+		assert.NoError(t, tx.Commit())
+		assert.ErrorIs(t, tx.OnCommit(func() {}), ErrTxDone)
+		assert.ErrorIs(t, tx.OnRollback(func() {}), ErrTxDone)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Log("---- Pass ----")
+}
+
+func TestReadOnlyTransaction(t *testing.T) {
+	db, mock, _ := setupTest(t)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM users WHERE id = $1")).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectRollback()
+
+	tx, err := BeginReadOnly(context.Background(), db)
+	assert.NoError(t, err)
+	defer tx.RollbackUnlessCommitted()
+	assert.True(t, tx.ReadOnly())
+
+	stmt, err := tx.Builder().Select("id").From("users").Where("id = ?", 1).Prepare(db)
+	assert.NoError(t, err)
+	var id int
+	assert.NoError(t, stmt.QueryRow().Scan(&id))
+
+	writes := []*Builder{
+		tx.Builder().Insert("users", "name").Values(TestUser),
+		tx.Builder().Update("users").Set(map[string]interface{}{"name": TestUser}).Where("id = ?", 1),
+		tx.Builder().Delete("users").Where("id = ?", 1),
+	}
+	for _, b := range writes {
+		_, err := b.Prepare(db)
+		assert.ErrorIs(t, err, ErrReadOnlyTransaction)
+		assert.Contains(t, err.Error(), b.Kind().String())
+	}
+
+	assert.NoError(t, tx.Rollback())
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	t.Log("---- Pass ----")
+}
@@ -0,0 +1,74 @@
+package toki
+
+import (
+	"errors"
+	"fmt"
+)
+
+// WhereTupleIn adds a row-value IN condition matching composite keys,
+// (a, b) IN (($1, $2), ($3, $4)), binding every value. Each row must hold
+// one value per column. Dialects without row-value lists get the equivalent
+// (a = $1 AND b = $2) OR (a = $3 AND b = $4). It starts the WHERE clause or
+// is ANDed to it.
+func (b *Builder) WhereTupleIn(columns []string, rows [][]interface{}) (*Builder, error) {
+	if len(columns) == 0 {
+		return nil, errors.New("WhereTupleIn needs at least one column")
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("WhereTupleIn needs at least one row")
+	}
+	for i, row := range rows {
+		if len(row) != len(columns) {
+			return nil, fmt.Errorf("row %d has %d values, want %d", i, len(row), len(columns))
+		}
+	}
+
+	return b.Where(b.tupleIn(columns, rows)), nil
+}
+
+// tupleIn renders columns IN rows, binding the values
+func (b *Builder) tupleIn(columns []string, rows [][]interface{}) string {
+	var buf []byte
+
+	if !b.Dialect().rowValues {
+		buf = append(buf, '(')
+		for i, row := range rows {
+			if i > 0 {
+				buf = append(buf, " OR "...)
+			}
+			buf = append(buf, '(')
+			for j, col := range columns {
+				if j > 0 {
+					buf = append(buf, " AND "...)
+				}
+				buf = append(buf, col+" = "...)
+				buf = b.appendValue(buf, row[j], false)
+			}
+			buf = append(buf, ')')
+		}
+		return string(append(buf, ')'))
+	}
+
+	buf = append(buf, '(')
+	for i, col := range columns {
+		if i > 0 {
+			buf = append(buf, ", "...)
+		}
+		buf = append(buf, col...)
+	}
+	buf = append(buf, ") IN ("...)
+	for i, row := range rows {
+		if i > 0 {
+			buf = append(buf, ", "...)
+		}
+		buf = append(buf, '(')
+		for j, v := range row {
+			if j > 0 {
+				buf = append(buf, ", "...)
+			}
+			buf = b.appendValue(buf, v, false)
+		}
+		buf = append(buf, ')')
+	}
+	return string(append(buf, ')'))
+}
@@ -0,0 +1,38 @@
+package toki
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWhereTupleIn(t *testing.T) {
+	rows := [][]interface{}{{1, 2}, {3, 4}}
+
+	b, err := New().Select("*").From("memberships").Where("active = ?", true).
+		WhereTupleIn([]string{"tenant_id", "user_id"}, rows)
+	assert.NoError(t, err)
+	b.Limit(10)
+	assert.Equal(t, "SELECT * FROM memberships WHERE active = $1 AND (tenant_id, user_id) IN (($2, $3), ($4, $5)) LIMIT $6", b.String())
+	assert.Equal(t, []interface{}{true, 1, 2, 3, 4, 10}, b.args)
+
+	b, err = New().WithDialect(SQLite).Select("*").From("memberships").
+		WhereTupleIn([]string{"tenant_id", "user_id"}, rows)
+	assert.NoError(t, err)
+	b.AndWhere("active = ?", true)
+	assert.Equal(t, "SELECT * FROM memberships WHERE ((tenant_id = $1 AND user_id = $2) OR (tenant_id = $3 AND user_id = $4)) AND active = $5", b.String())
+	assert.Equal(t, []interface{}{1, 2, 3, 4, true}, b.args)
+
+	b, err = New().Select("*").From("orders").WhereTupleIn([]string{"region", "id"}, [][]interface{}{{Raw("'eu'"), 7}})
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM orders WHERE (region, id) IN (('eu', $1))", b.String())
+
+	_, err = New().Select("*").From("memberships").WhereTupleIn([]string{"tenant_id", "user_id"}, [][]interface{}{{1, 2}, {3}})
+	assert.EqualError(t, err, "row 1 has 1 values, want 2")
+
+	_, err = New().Select("*").From("memberships").WhereTupleIn([]string{"tenant_id"}, nil)
+	assert.EqualError(t, err, "WhereTupleIn needs at least one row")
+
+	_, err = New().Select("*").From("memberships").WhereTupleIn(nil, rows)
+	assert.EqualError(t, err, "WhereTupleIn needs at least one column")
+}
@@ -1,11 +1,171 @@
 package toki
 
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+)
+
 // SQLExpression represents a raw SQL expression
 type SQLExpression interface {
 	SQL() string
 }
 
-// Raw creates a raw SQL expression
+// Raw is an SQL expression written out in full.
+//
+// Deprecated: use Expr, which also binds args. Raw reads like the
+// Builder.Raw query at call sites; it is kept so existing code compiles.
 type Raw string
 
 func (r Raw) SQL() string { return string(r) }
+
+// Expression is an SQL fragment with bound args, made by Expr
+type Expression struct {
+	sql  string
+	args []interface{}
+}
+
+// Expr makes an SQL expression from sql with its ? placeholders bound to
+// args, such as Expr("NOW()") or Expr("price * ?", 1.2). It can be used
+// wherever an SQLExpression is accepted, and as a condition arg:
+// Where("created_at > ?", toki.Expr("NOW() - ?::interval", "1 day")).
+// Without args sql is used exactly as written, so a literal ? such as the
+// jsonb operator stays; with args the placeholders must match them, or the
+// builder fails with ErrArgCount. An arg may itself be an expression with
+// args, such as Cast.
+func Expr(sql string, args ...interface{}) *Expression {
+	return &Expression{sql: sql, args: args}
+}
+
+// SQL returns the expression with ? placeholders for its args
+func (e *Expression) SQL() string { return e.sql }
+
+func (e *Expression) bind(d *Dialect) (string, []interface{}, error) {
+	if len(e.args) == 0 {
+		return e.sql, nil, nil
+	}
+	if n := countPlaceholders(e.sql); n != len(e.args) {
+		return e.sql, e.args, fmt.Errorf("expression expects %d arg(s), got %d: %w", n, len(e.args), ErrArgCount)
+	}
+	return bindArgs(d, e.sql, e.args)
+}
+
+var (
+	// Null is an explicit SQL NULL. Values and Set render it as the NULL
+	// literal; bound as a condition arg it binds NULL.
+	Null = nullMarker{}
+
+	// UseDefault makes an INSERT use the column default, rendering the
+	// DEFAULT keyword in Values and InsertMap. Bound anywhere else, as in
+	// Set or Where, it fails the builder; in a raw query it fails when the
+	// statement runs.
+	UseDefault = defaultMarker{}
+)
+
+type nullMarker struct{}
+
+func (nullMarker) SQL() string { return "NULL" }
+
+// Value binds NULL when Null is used as an arg
+func (nullMarker) Value() (driver.Value, error) { return nil, nil }
+
+type defaultMarker struct{}
+
+func (defaultMarker) SQL() string { return "DEFAULT" }
+
+// Value rejects UseDefault bound as an arg, outside INSERT values
+func (defaultMarker) Value() (driver.Value, error) {
+	return nil, errUseDefault
+}
+
+// errUseDefault is reported when UseDefault is bound outside INSERT values
+var errUseDefault = errors.New("toki.UseDefault is only valid in INSERT values")
+
+// boundExpression is an SQLExpression with bound args. Builders render it
+// for their dialect when it is added, with ? placeholders for args, and
+// bind the args in place so they are numbered with the rest of the
+// statement. SQL renders it for PostgreSQL without the args.
+type boundExpression interface {
+	SQLExpression
+	bind(d *Dialect) (string, []interface{}, error)
+}
+
+// expression renders e for the builder, binding the args of a
+// boundExpression. A failure is kept for Prepare to report.
+func (b *Builder) expression(e SQLExpression) string {
+	bound, ok := e.(boundExpression)
+	if !ok {
+		return e.SQL()
+	}
+	query, args, err := bound.bind(b.Dialect())
+	if err != nil {
+		b.fail(err)
+		return query
+	}
+	if len(args) == 0 {
+		// nothing to number, so a literal ? stays as written
+		return query
+	}
+	query = b.convertPlaceholders(query)
+	b.appendArgs(args)
+	return query
+}
+
+// inlineArgs replaces each ? in condition whose arg is a boundExpression
+// with the expression, splicing the expression's args in its place
+func (b *Builder) inlineArgs(condition string, args []interface{}) (string, []interface{}) {
+	condition, args, err := bindArgs(b.Dialect(), condition, args)
+	if err != nil {
+		b.fail(err)
+	}
+	return condition, args
+}
+
+// bindArgs is inlineArgs for dialect d, reporting the first expression that
+// fails to bind
+func bindArgs(d *Dialect, condition string, args []interface{}) (string, []interface{}, error) {
+	inline := false
+	for _, arg := range args {
+		if _, ok := arg.(boundExpression); ok {
+			inline = true
+			break
+		}
+	}
+	if !inline {
+		return condition, args, nil
+	}
+
+	var out strings.Builder
+	var firstErr error
+	flat := make([]interface{}, 0, len(args))
+	n := 0
+	scanSQL(condition, func(from, to int, code bool) {
+		if !code {
+			out.WriteString(condition[from:to])
+			return
+		}
+		for i := from; i < to; i++ {
+			if condition[i] != '?' || n >= len(args) {
+				out.WriteByte(condition[i])
+				continue
+			}
+			arg := args[n]
+			n++
+			bound, ok := arg.(boundExpression)
+			if !ok {
+				out.WriteByte('?')
+				flat = append(flat, arg)
+				continue
+			}
+			query, exprArgs, err := bound.bind(d)
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+			out.WriteString(query)
+			flat = append(flat, exprArgs...)
+		}
+	})
+
+	return out.String(), append(flat, args[n:]...), firstErr
+}
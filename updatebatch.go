@@ -0,0 +1,160 @@
+package toki
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// UpdateBatch initializes one UPDATE setting each row of table matched by
+// keyColumn to its own values. Every row must hold keyColumn and the same
+// other columns. PostgreSQL joins a VALUES list with UPDATE ... FROM; other
+// dialects set each column with CASE keyColumn WHEN ... END and restrict the
+// rows with keyColumn IN (...).
+func (b *Builder) UpdateBatch(table, keyColumn string, rows []map[string]interface{}) (*Builder, error) {
+	if len(rows) == 0 {
+		return nil, errors.New("UpdateBatch needs at least one row")
+	}
+
+	columns := make([]string, 0, len(rows[0]))
+	for col := range rows[0] {
+		if col != keyColumn {
+			columns = append(columns, col)
+		}
+	}
+	sort.Strings(columns)
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("UpdateBatch rows set no columns besides the key %s", keyColumn)
+	}
+
+	for i, row := range rows {
+		if _, ok := row[keyColumn]; !ok {
+			return nil, fmt.Errorf("row %d has no %s key column", i, keyColumn)
+		}
+		same := len(row) == len(columns)+1
+		for _, col := range columns {
+			if _, ok := row[col]; !ok {
+				same = false
+			}
+		}
+		if !same {
+			return nil, fmt.Errorf("row %d sets columns %s, want %s", i, strings.Join(sortedKeys(row), ", "),
+				strings.Join(sortedKeys(rows[0]), ", "))
+		}
+	}
+
+	b.Update(table)
+	if b.Dialect().updateFrom {
+		b.updateFromValues(table, keyColumn, columns, rows)
+	} else {
+		b.updateCase(keyColumn, columns, rows)
+	}
+	return b, nil
+}
+
+// updateFromValues renders
+// SET col = v.col FROM (VALUES ...) AS v (key, cols) WHERE table.key = v.key
+func (b *Builder) updateFromValues(table, keyColumn string, columns []string, rows []map[string]interface{}) {
+	var sb strings.Builder
+	for i, col := range columns {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(col + " = v." + col)
+	}
+	b.changed()
+	b.clauses.set = append(b.clauses.set, sb.String())
+
+	names := append([]string{keyColumn}, columns...)
+	var buf []byte
+	buf = append(buf, "(VALUES "...)
+	for i, row := range rows {
+		if i > 0 {
+			buf = append(buf, ", "...)
+		}
+		buf = append(buf, '(')
+		for j, col := range names {
+			if j > 0 {
+				buf = append(buf, ", "...)
+			}
+			// VALUES parameters are typed as text unless the first row
+			// gives the column a type
+			buf = b.appendValue(buf, row[col], i == 0)
+		}
+		buf = append(buf, ')')
+	}
+	buf = append(buf, ") AS v ("+strings.Join(names, ", ")+")"...)
+
+	b.changed()
+	b.clauses.from = append(b.clauses.from, string(buf))
+	b.Where(table + "." + keyColumn + " = v." + keyColumn)
+}
+
+// updateCase renders
+// SET col = CASE key WHEN ... THEN ... END WHERE key IN (...)
+func (b *Builder) updateCase(keyColumn string, columns []string, rows []map[string]interface{}) {
+	var buf []byte
+	for i, col := range columns {
+		if i > 0 {
+			buf = append(buf, ", "...)
+		}
+		buf = append(buf, col+" = CASE "+keyColumn...)
+		for _, row := range rows {
+			buf = append(buf, " WHEN "...)
+			buf = b.appendValue(buf, row[keyColumn], false)
+			buf = append(buf, " THEN "...)
+			buf = b.appendValue(buf, row[col], false)
+		}
+		buf = append(buf, " END"...)
+	}
+	b.changed()
+	b.clauses.set = append(b.clauses.set, string(buf))
+
+	buf = append(buf[:0], keyColumn+" IN ("...)
+	for i, row := range rows {
+		if i > 0 {
+			buf = append(buf, ", "...)
+		}
+		buf = b.appendValue(buf, row[keyColumn], false)
+	}
+	buf = append(buf, ')')
+	b.Where(string(buf))
+}
+
+// appendValue renders an SQLExpression inline or binds v, casting its
+// placeholder to the type of v when cast is set
+func (b *Builder) appendValue(buf []byte, v interface{}, cast bool) []byte {
+	if expr, ok := v.(SQLExpression); ok {
+		return append(buf, b.expression(expr)...)
+	}
+	v = normalizeArg(v)
+	b.argIndex++
+	buf = appendPlaceholder(buf, b.argIndex)
+	b.addArg(v)
+	if cast {
+		if typ := paramType(v); typ != "" {
+			buf = append(buf, "::"+typ...)
+		}
+	}
+	return buf
+}
+
+// paramType returns the PostgreSQL type of a bound Go value, or "" when it
+// is left to the database
+func paramType(v interface{}) string {
+	switch v.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return "bigint"
+	case float32, float64:
+		return "double precision"
+	case bool:
+		return "boolean"
+	case time.Time:
+		return "timestamptz"
+	case []byte:
+		return "bytea"
+	}
+	return ""
+}
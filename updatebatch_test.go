@@ -0,0 +1,48 @@
+package toki
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateBatch(t *testing.T) {
+	rows := []map[string]interface{}{
+		{"id": 1, "name": "a", "price": 10},
+		{"id": 2, "name": "b", "price": 20},
+		{"id": 3, "name": "c", "price": 30},
+	}
+
+	b, err := New().UpdateBatch("products", "id", rows)
+	assert.NoError(t, err)
+	assert.Equal(t, "UPDATE products SET name = v.name, price = v.price "+
+		"FROM (VALUES ($1::bigint, $2, $3::bigint), ($4, $5, $6), ($7, $8, $9)) AS v (id, name, price) "+
+		"WHERE products.id = v.id", b.String())
+	assert.Equal(t, []interface{}{1, "a", 10, 2, "b", 20, 3, "c", 30}, b.args)
+
+	b, err = New().WithDialect(SQLite).UpdateBatch("products", "id", rows)
+	assert.NoError(t, err)
+	assert.Equal(t, "UPDATE products SET "+
+		"name = CASE id WHEN $1 THEN $2 WHEN $3 THEN $4 WHEN $5 THEN $6 END, "+
+		"price = CASE id WHEN $7 THEN $8 WHEN $9 THEN $10 WHEN $11 THEN $12 END "+
+		"WHERE id IN ($13, $14, $15)", b.String())
+	assert.Equal(t, []interface{}{1, "a", 2, "b", 3, "c", 1, 10, 2, 20, 3, 30, 1, 2, 3}, b.args)
+
+	// conditions added afterwards continue the numbering
+	b, err = New().WithDialect(SQLite).UpdateBatch("products", "id", rows[:1])
+	assert.NoError(t, err)
+	b.AndWhere("deleted_at IS NULL AND shop_id = ?", 7)
+	assert.Equal(t, "UPDATE products SET name = CASE id WHEN $1 THEN $2 END, price = CASE id WHEN $3 THEN $4 END "+
+		"WHERE id IN ($5) AND deleted_at IS NULL AND shop_id = $6", b.String())
+}
+
+func TestUpdateBatchErrors(t *testing.T) {
+	_, err := New().UpdateBatch("products", "id", nil)
+	assert.EqualError(t, err, "UpdateBatch needs at least one row")
+	_, err = New().UpdateBatch("products", "id", []map[string]interface{}{{"id": 1}})
+	assert.EqualError(t, err, "UpdateBatch rows set no columns besides the key id")
+	_, err = New().UpdateBatch("products", "id", []map[string]interface{}{{"id": 1, "name": "a"}, {"name": "b"}})
+	assert.EqualError(t, err, "row 1 has no id key column")
+	_, err = New().UpdateBatch("products", "id", []map[string]interface{}{{"id": 1, "name": "a"}, {"id": 2, "price": 3}})
+	assert.EqualError(t, err, "row 1 sets columns id, price, want id, name")
+}
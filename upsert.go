@@ -0,0 +1,149 @@
+package toki
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// UpsertStruct initializes an INSERT of the mapped fields of v, like
+// InsertStruct, that updates the existing row instead when it conflicts on
+// conflictColumns. updateColumns are set from the proposed row through
+// EXCLUDED; when empty, every inserted column except the conflict columns,
+// the pk-tagged key columns and the created timestamp column is updated.
+// Columns are renamed by the builder's column map, as in InsertStruct.
+// With nothing left to update the conflict is ignored with DO NOTHING, in
+// which case Returning yields no row for an existing one.
+func (b *Builder) UpsertStruct(table string, v interface{}, conflictColumns []string, updateColumns []string) *Builder {
+	val, ok := structValue(v)
+	if !ok {
+		return b.Insert(table).onConflict(conflictColumns, updateColumns)
+	}
+
+	columns, values := structColumns(val)
+	columns, values = b.mapColumns(b.applyInsertTimestamps(val.Type(), columns, values))
+	b.Insert(table, columns...).Values(values...)
+
+	if len(updateColumns) == 0 {
+		updateColumns = b.upsertColumns(val.Type(), columns, conflictColumns)
+	}
+
+	return b.onConflict(conflictColumns, updateColumns)
+}
+
+// UpsertStructs initializes a multi-row INSERT of the mapped fields of each
+// struct in rows, a slice of structs or struct pointers, that updates the
+// existing row instead when it conflicts on conflictColumns. Every inserted
+// column except the conflict columns, the pk-tagged key columns and the
+// created timestamp column is set from EXCLUDED. Columns come in field order
+// and every row must map the same ones, so an omitempty field must be set in
+// all rows or in none.
+//
+// Run it with ExecUpsert, which splits the rows to stay under the bind
+// parameter limit and runs every chunk in one transaction. PostgreSQL
+// rejects a statement that updates a row twice, so the conflict columns
+// must be unique across rows.
+func (b *Builder) UpsertStructs(table string, rows interface{}, conflictColumns []string) *Builder {
+	val := reflect.ValueOf(rows)
+	if val.Kind() != reflect.Slice && val.Kind() != reflect.Array {
+		b.fail(fmt.Errorf("UpsertStructs needs a slice of structs, got %T", rows))
+		return b
+	}
+	if val.Len() == 0 {
+		b.fail(errors.New("UpsertStructs needs at least one row"))
+		return b
+	}
+
+	var (
+		columns []string
+		typ     reflect.Type
+	)
+	for i := 0; i < val.Len(); i++ {
+		row, ok := structValue(val.Index(i).Interface())
+		if !ok {
+			b.fail(fmt.Errorf("UpsertStructs row %d is not a struct or is nil", i))
+			return b
+		}
+
+		rowColumns, values := structColumns(row)
+		rowColumns, values = b.mapColumns(b.applyInsertTimestamps(row.Type(), rowColumns, values))
+		if i == 0 {
+			columns, typ = rowColumns, row.Type()
+			b.Insert(table, columns...)
+		} else if !slices.Equal(rowColumns, columns) {
+			b.fail(fmt.Errorf("UpsertStructs row %d maps columns (%s), unlike row 0 (%s)",
+				i, strings.Join(rowColumns, ", "), strings.Join(columns, ", ")))
+			return b
+		}
+		b.Values(values...)
+	}
+
+	return b.onConflict(conflictColumns, b.upsertColumns(typ, columns, conflictColumns))
+}
+
+// ExecUpsert runs a multi-row upsert built by UpsertStructs and returns the
+// total rows affected. The rows are split into chunks under the bind
+// parameter limit, as by ExecChunked, and the chunks run in one transaction,
+// so a failing chunk leaves no partial changes behind. It runs on the
+// builder's transaction when it has one, otherwise on a new transaction of
+// db.
+func (b *Builder) ExecUpsert(ctx context.Context, db *sql.DB) (int64, error) {
+	if b.kind != KindInsert || b.clauses.conflict == "" {
+		return 0, errors.New("ExecUpsert needs an upsert built by UpsertStructs")
+	}
+	return b.ExecChunked(ctx, db, 0, InTransaction())
+}
+
+// upsertColumns returns the inserted columns an upsert of typ updates by
+// default: all but the conflict columns, the pk-tagged key columns and the
+// created timestamp column
+func (b *Builder) upsertColumns(typ reflect.Type, columns, conflictColumns []string) []string {
+	skip := append([]string(nil), conflictColumns...)
+	for _, key := range keyFields(typ) {
+		if column, ok := mapColumn(b.columnMap, key.column); ok {
+			skip = append(skip, column)
+		}
+	}
+	if opts := b.timestampOptions(); opts != nil {
+		skip = append(skip, opts.createdColumn())
+	}
+
+	var update []string
+	for _, col := range columns {
+		if indexOf(skip, col) < 0 {
+			update = append(update, col)
+		}
+	}
+	return update
+}
+
+// onConflict adds ON CONFLICT (target) DO UPDATE SET for columns, taking
+// each value from EXCLUDED, or DO NOTHING when columns is empty
+func (b *Builder) onConflict(target, columns []string) *Builder {
+	var sb strings.Builder
+	sb.WriteString("ON CONFLICT (")
+	sb.WriteString(strings.Join(target, ", "))
+	if len(columns) == 0 {
+		sb.WriteString(") DO NOTHING")
+		b.changed()
+		b.clauses.conflict = sb.String()
+		return b
+	}
+
+	sb.WriteString(") DO UPDATE SET ")
+	for i, col := range columns {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(col)
+		sb.WriteString(" = EXCLUDED.")
+		sb.WriteString(col)
+	}
+	b.changed()
+	b.clauses.conflict = sb.String()
+	return b
+}
@@ -0,0 +1,169 @@
+package toki
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+type syncedProduct struct {
+	ID        int64     `db:"id,pk,omitempty"`
+	SKU       string    `db:"sku"`
+	Name      string    `db:"name"`
+	Price     int       `db:"price"`
+	Cache     string    `db:"-"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+func TestUpsertStruct(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	p := syncedProduct{SKU: "A-1", Name: "Widget", Price: 250, Cache: "x", CreatedAt: now, UpdatedAt: now}
+
+	b := New().UpsertStruct("products", &p, []string{"sku"}, nil).Returning("id")
+	assert.Equal(t, "INSERT INTO products (sku, name, price, created_at, updated_at) VALUES ($1, $2, $3, $4, $5) "+
+		"ON CONFLICT (sku) DO UPDATE SET name = EXCLUDED.name, price = EXCLUDED.price, created_at = EXCLUDED.created_at, updated_at = EXCLUDED.updated_at RETURNING id",
+		b.String())
+	assert.Equal(t, []interface{}{"A-1", "Widget", 250, now, now}, b.args)
+
+	b = New().UpsertStruct("products", &p, []string{"sku"}, []string{"price"})
+	assert.Equal(t, "INSERT INTO products (sku, name, price, created_at, updated_at) VALUES ($1, $2, $3, $4, $5) "+
+		"ON CONFLICT (sku) DO UPDATE SET price = EXCLUDED.price", b.String())
+
+	// the created timestamp keeps its first value
+	clock := func() time.Time { return now }
+	p.ID = 9
+	b = New().WithTimestamps(TimestampOptions{Now: clock}).UpsertStruct("products", &p, []string{"sku"}, nil)
+	assert.Equal(t, "INSERT INTO products (id, sku, name, price, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6) "+
+		"ON CONFLICT (sku) DO UPDATE SET name = EXCLUDED.name, price = EXCLUDED.price, updated_at = EXCLUDED.updated_at", b.String())
+
+	type link struct {
+		UserID int64 `db:"user_id"`
+		TagID  int64 `db:"tag_id"`
+	}
+	b = New().UpsertStruct("user_tags", link{UserID: 1, TagID: 2}, []string{"user_id", "tag_id"}, nil)
+	assert.Equal(t, "INSERT INTO user_tags (user_id, tag_id) VALUES ($1, $2) ON CONFLICT (user_id, tag_id) DO NOTHING", b.String())
+}
+
+func TestUpsertStructs(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	products := []syncedProduct{
+		{SKU: "A-1", Name: "Widget", Price: 250, CreatedAt: now, UpdatedAt: now},
+		{SKU: "A-2", Name: "Gadget", Price: 300, CreatedAt: now, UpdatedAt: now},
+		{SKU: "A-3", Name: "Gizmo", Price: 120, CreatedAt: now, UpdatedAt: now},
+	}
+	conflict := "ON CONFLICT (sku) DO UPDATE SET name = EXCLUDED.name, price = EXCLUDED.price, updated_at = EXCLUDED.updated_at"
+
+	b := New().WithTimestamps(TimestampOptions{}).UpsertStructs("products", products[:2], []string{"sku"})
+	assert.Equal(t, "INSERT INTO products (sku, name, price, created_at, updated_at) VALUES ($1, $2, $3, $4, $5), ($6, $7, $8, $9, $10) "+conflict, b.String())
+	assert.Equal(t, []interface{}{"A-1", "Widget", 250, now, now, "A-2", "Gadget", 300, now, now}, b.args)
+
+	// pointers and chunked execution in one transaction
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare("INSERT INTO products (sku, name, price, created_at, updated_at) VALUES ($1, $2, $3, $4, $5), ($6, $7, $8, $9, $10) "+conflict).
+		ExpectExec().
+		WithArgs("A-1", "Widget", 250, now, now, "A-2", "Gadget", 300, now, now).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectPrepare("INSERT INTO products (sku, name, price, created_at, updated_at) VALUES ($1, $2, $3, $4, $5) "+conflict).
+		ExpectExec().
+		WithArgs("A-3", "Gizmo", 120, now, now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	rows := []*syncedProduct{&products[0], &products[1], &products[2]}
+	total, err := New().WithTimestamps(TimestampOptions{}).UpsertStructs("products", rows, []string{"sku"}).
+		ExecChunked(context.Background(), db, 2, InTransaction())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpsertStructsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		rows interface{}
+		err  string
+	}{
+		{
+			name: "Not a slice",
+			rows: syncedProduct{SKU: "A-1"},
+			err:  "UpsertStructs needs a slice of structs, got toki.syncedProduct",
+		},
+		{
+			name: "Empty",
+			rows: []syncedProduct{},
+			err:  "UpsertStructs needs at least one row",
+		},
+		{
+			name: "Nil row",
+			rows: []*syncedProduct{{SKU: "A-1"}, nil},
+			err:  "UpsertStructs row 1 is not a struct or is nil",
+		},
+		{
+			name: "Different columns",
+			rows: []syncedProduct{{SKU: "A-1"}, {ID: 7, SKU: "A-2"}},
+			err:  "UpsertStructs row 1 maps columns (id, sku, name, price, created_at, updated_at), unlike row 0 (sku, name, price, created_at, updated_at)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := New().UpsertStructs("products", tt.rows, []string{"sku"}).ToSQL()
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestUpsertKeysAndColumnMap(t *testing.T) {
+	type currency struct {
+		Code string `db:"code,pk"`
+		Name string `db:"name"`
+		Rate int    `db:"rate"`
+	}
+
+	// the pk-tagged key is not updated, whatever its name
+	b := New().UpsertStruct("currencies", currency{"IDR", "Rupiah", 1}, []string{"name"}, nil)
+	assert.Equal(t, "INSERT INTO currencies (code, name, rate) VALUES ($1, $2, $3) ON CONFLICT (name) DO UPDATE SET rate = EXCLUDED.rate", b.String())
+
+	columnMap := map[string]string{"code": "iso_code", "rate": ""}
+	b = New().WithColumnMap(columnMap).UpsertStruct("currencies", currency{"IDR", "Rupiah", 1}, []string{"iso_code"}, nil)
+	assert.Equal(t, "INSERT INTO currencies (iso_code, name) VALUES ($1, $2) ON CONFLICT (iso_code) DO UPDATE SET name = EXCLUDED.name", b.String())
+
+	b = New().WithColumnMap(columnMap).UpsertStructs("currencies", []currency{{"IDR", "Rupiah", 1}, {"USD", "Dollar", 2}}, []string{"name"})
+	assert.Equal(t, "INSERT INTO currencies (iso_code, name) VALUES ($1, $2), ($3, $4) ON CONFLICT (name) DO NOTHING", b.String())
+}
+
+func TestExecUpsert(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	type tag struct {
+		Name  string `db:"name"`
+		Count int    `db:"count"`
+	}
+	mock.ExpectBegin()
+	mock.ExpectPrepare("INSERT INTO tags (name, count) VALUES ($1, $2), ($3, $4) ON CONFLICT (name) DO UPDATE SET count = EXCLUDED.count").
+		ExpectExec().
+		WithArgs("go", 3, "sql", 5).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	total, err := New().UpsertStructs("tags", []tag{{"go", 3}, {"sql", 5}}, []string{"name"}).ExecUpsert(context.Background(), db)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	_, err = New().Insert("tags", "name").Values("go").ExecUpsert(context.Background(), db)
+	assert.EqualError(t, err, "ExecUpsert needs an upsert built by UpsertStructs")
+}
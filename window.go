@@ -0,0 +1,293 @@
+package toki
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// WindowExpr builds a window function call, fn OVER (PARTITION BY ...
+// ORDER BY ... frame). Build validates it and returns the SQL for use as a
+// select column:
+//
+//	avg, err := toki.Window("AVG(amount)").
+//		PartitionBy("account_id").
+//		OrderBy("day").
+//		Rows(toki.Preceding(6), toki.CurrentRow()).
+//		As("moving_avg").
+//		Build()
+type WindowExpr struct {
+	fn        string
+	partition []string
+	order     []string
+	frame     string
+	start     FrameBound
+	end       FrameBound
+	exclude   FrameExclusion
+	alias     string
+}
+
+// FrameBound is the start or end of a window frame, made by Preceding,
+// Following or CurrentRow
+type FrameBound struct {
+	kind   boundKind
+	offset interface{}
+}
+
+type boundKind int
+
+// bound kinds in frame order, a frame cannot start after it ends
+const (
+	boundNone boundKind = iota
+	boundUnboundedPreceding
+	boundPreceding
+	boundCurrentRow
+	boundFollowing
+	boundUnboundedFollowing
+)
+
+// Unbounded is the offset of a frame reaching the first or last row of the
+// partition, as in Preceding(Unbounded)
+var Unbounded = unboundedOffset{}
+
+type unboundedOffset struct{}
+
+// Preceding is a frame bound offset rows, groups or values before the
+// current row. offset is an integer, Unbounded, or an SQLExpression such as
+// Expr("INTERVAL '7 days'") for RANGE frames.
+func Preceding(offset interface{}) FrameBound {
+	if offset == Unbounded {
+		return FrameBound{kind: boundUnboundedPreceding}
+	}
+	return FrameBound{kind: boundPreceding, offset: offset}
+}
+
+// Following is a frame bound offset rows, groups or values after the
+// current row, with the offsets Preceding accepts
+func Following(offset interface{}) FrameBound {
+	if offset == Unbounded {
+		return FrameBound{kind: boundUnboundedFollowing}
+	}
+	return FrameBound{kind: boundFollowing, offset: offset}
+}
+
+// CurrentRow is the frame bound at the current row
+func CurrentRow() FrameBound {
+	return FrameBound{kind: boundCurrentRow}
+}
+
+// FrameExclusion removes rows around the current one from a frame
+type FrameExclusion string
+
+const (
+	// ExcludeCurrentRow removes the current row
+	ExcludeCurrentRow FrameExclusion = "EXCLUDE CURRENT ROW"
+	// ExcludeGroup removes the current row and its ORDER BY peers
+	ExcludeGroup FrameExclusion = "EXCLUDE GROUP"
+	// ExcludeTies removes the peers of the current row but not the row
+	ExcludeTies FrameExclusion = "EXCLUDE TIES"
+	// ExcludeNoOthers removes nothing, the default
+	ExcludeNoOthers FrameExclusion = "EXCLUDE NO OTHERS"
+)
+
+// Window starts a window expression over the function call fn, for example
+// "SUM(amount)" or "ROW_NUMBER()"
+func Window(fn string) *WindowExpr {
+	return &WindowExpr{fn: fn}
+}
+
+// PartitionBy sets the PARTITION BY columns
+func (w *WindowExpr) PartitionBy(columns ...string) *WindowExpr {
+	w.partition = append(w.partition, columns...)
+	return w
+}
+
+// OrderBy sets the ORDER BY columns, optionally followed by ASC or DESC
+func (w *WindowExpr) OrderBy(columns ...string) *WindowExpr {
+	w.order = append(w.order, columns...)
+	return w
+}
+
+// Rows sets a frame counting rows: ROWS BETWEEN start AND end
+func (w *WindowExpr) Rows(start, end FrameBound) *WindowExpr {
+	return w.setFrame("ROWS", start, end)
+}
+
+// Range sets a frame of ORDER BY values: RANGE BETWEEN start AND end.
+// Offsets other than Unbounded need exactly one ORDER BY column.
+func (w *WindowExpr) Range(start, end FrameBound) *WindowExpr {
+	return w.setFrame("RANGE", start, end)
+}
+
+// Groups sets a frame counting groups of ORDER BY peers: GROUPS BETWEEN
+// start AND end. It needs an ORDER BY.
+func (w *WindowExpr) Groups(start, end FrameBound) *WindowExpr {
+	return w.setFrame("GROUPS", start, end)
+}
+
+func (w *WindowExpr) setFrame(mode string, start, end FrameBound) *WindowExpr {
+	w.frame, w.start, w.end = mode, start, end
+	return w
+}
+
+// Exclude sets the frame exclusion, which needs a frame
+func (w *WindowExpr) Exclude(e FrameExclusion) *WindowExpr {
+	w.exclude = e
+	return w
+}
+
+// As names the result column
+func (w *WindowExpr) As(alias string) *WindowExpr {
+	w.alias = alias
+	return w
+}
+
+// Build validates the expression and renders it
+func (w *WindowExpr) Build() (string, error) {
+	if err := w.validate(); err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.WriteString(w.fn)
+	sb.WriteString(" OVER (")
+
+	var clauses []string
+	if len(w.partition) > 0 {
+		clauses = append(clauses, "PARTITION BY "+strings.Join(w.partition, ", "))
+	}
+	if len(w.order) > 0 {
+		clauses = append(clauses, "ORDER BY "+strings.Join(w.order, ", "))
+	}
+	if w.frame != "" {
+		frame := w.frame + " BETWEEN " + w.start.sql() + " AND " + w.end.sql()
+		if w.exclude != "" {
+			frame += " " + string(w.exclude)
+		}
+		clauses = append(clauses, frame)
+	}
+	sb.WriteString(strings.Join(clauses, " "))
+	sb.WriteByte(')')
+
+	if w.alias != "" {
+		sb.WriteString(" AS ")
+		sb.WriteString(w.alias)
+	}
+	return sb.String(), nil
+}
+
+// validate rejects frames the database would refuse
+func (w *WindowExpr) validate() error {
+	if w.fn == "" {
+		return errors.New("window function is empty")
+	}
+	if w.alias != "" {
+		if err := checkIdent("alias", w.alias); err != nil {
+			return err
+		}
+	}
+	if w.frame == "" {
+		if w.exclude != "" {
+			return errors.New("window frame exclusion needs a frame")
+		}
+		return nil
+	}
+
+	if w.start.kind == boundNone || w.end.kind == boundNone {
+		return errors.New("window frame bounds must be made with Preceding, Following or CurrentRow")
+	}
+	if w.start.kind == boundUnboundedFollowing {
+		return errors.New("window frame cannot start at UNBOUNDED FOLLOWING")
+	}
+	if w.end.kind == boundUnboundedPreceding {
+		return errors.New("window frame cannot end at UNBOUNDED PRECEDING")
+	}
+	if w.frame == "GROUPS" && len(w.order) == 0 {
+		return errors.New("GROUPS frame needs an ORDER BY")
+	}
+	if w.start.kind > w.end.kind {
+		return fmt.Errorf("window frame starts at %s, after its end %s", w.start.sql(), w.end.sql())
+	}
+
+	for _, bound := range []FrameBound{w.start, w.end} {
+		if bound.kind != boundPreceding && bound.kind != boundFollowing {
+			continue
+		}
+		if err := w.checkOffset(bound.offset); err != nil {
+			return err
+		}
+	}
+
+	switch w.exclude {
+	case "", ExcludeCurrentRow, ExcludeGroup, ExcludeTies, ExcludeNoOthers:
+	default:
+		return fmt.Errorf("unknown window frame exclusion %q", w.exclude)
+	}
+
+	return nil
+}
+
+// checkOffset validates a PRECEDING or FOLLOWING offset for the frame mode
+func (w *WindowExpr) checkOffset(offset interface{}) error {
+	if w.frame == "RANGE" && len(w.order) != 1 {
+		return fmt.Errorf("RANGE frame with an offset needs exactly one ORDER BY column, got %d", len(w.order))
+	}
+
+	if _, ok := offset.(SQLExpression); ok {
+		return nil
+	}
+	n, ok := frameOffset(offset)
+	if !ok {
+		return fmt.Errorf("window frame offset must be an integer, Unbounded or SQLExpression, got %T", offset)
+	}
+	if n < 0 {
+		return fmt.Errorf("window frame offset must not be negative, got %d", n)
+	}
+	return nil
+}
+
+func (f FrameBound) sql() string {
+	switch f.kind {
+	case boundUnboundedPreceding:
+		return "UNBOUNDED PRECEDING"
+	case boundUnboundedFollowing:
+		return "UNBOUNDED FOLLOWING"
+	case boundCurrentRow:
+		return "CURRENT ROW"
+	}
+
+	offset := ""
+	if expr, ok := f.offset.(SQLExpression); ok {
+		offset = expr.SQL()
+	} else if n, ok := frameOffset(f.offset); ok {
+		offset = strconv.FormatInt(n, 10)
+	}
+	if f.kind == boundPreceding {
+		return offset + " PRECEDING"
+	}
+	return offset + " FOLLOWING"
+}
+
+// frameOffset converts an integer offset
+func frameOffset(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int8:
+		return int64(n), true
+	case int16:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case uint8:
+		return int64(n), true
+	case uint16:
+		return int64(n), true
+	case uint32:
+		return int64(n), true
+	}
+	return 0, false
+}
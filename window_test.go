@@ -0,0 +1,92 @@
+package toki
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWindow(t *testing.T) {
+	tests := []struct {
+		name   string
+		window *WindowExpr
+		want   string
+	}{
+		{
+			name:   "partition and order",
+			window: Window("ROW_NUMBER()").PartitionBy("team_id").OrderBy("score DESC").As("rank"),
+			want:   "ROW_NUMBER() OVER (PARTITION BY team_id ORDER BY score DESC) AS rank",
+		},
+		{
+			name:   "empty over",
+			window: Window("COUNT(*)"),
+			want:   "COUNT(*) OVER ()",
+		},
+		{
+			name:   "moving average",
+			window: Window("AVG(amount)").PartitionBy("account_id").OrderBy("day").Rows(Preceding(6), CurrentRow()),
+			want:   "AVG(amount) OVER (PARTITION BY account_id ORDER BY day ROWS BETWEEN 6 PRECEDING AND CURRENT ROW)",
+		},
+		{
+			name:   "running total",
+			window: Window("SUM(amount)").OrderBy("created_at", "id").Range(Preceding(Unbounded), CurrentRow()).As("running_total"),
+			want:   "SUM(amount) OVER (ORDER BY created_at, id RANGE BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW) AS running_total",
+		},
+		{
+			name:   "range interval",
+			window: Window("COUNT(*)").OrderBy("created_at").Range(Preceding(Raw("INTERVAL '7 days'")), CurrentRow()),
+			want:   "COUNT(*) OVER (ORDER BY created_at RANGE BETWEEN INTERVAL '7 days' PRECEDING AND CURRENT ROW)",
+		},
+		{
+			name:   "groups with exclusion",
+			window: Window("SUM(x)").OrderBy("y").Groups(Preceding(1), Following(1)).Exclude(ExcludeTies),
+			want:   "SUM(x) OVER (ORDER BY y GROUPS BETWEEN 1 PRECEDING AND 1 FOLLOWING EXCLUDE TIES)",
+		},
+		{
+			name:   "whole partition",
+			window: Window("MAX(x)").PartitionBy("g").Rows(Preceding(Unbounded), Following(Unbounded)).Exclude(ExcludeCurrentRow),
+			want:   "MAX(x) OVER (PARTITION BY g ROWS BETWEEN UNBOUNDED PRECEDING AND UNBOUNDED FOLLOWING EXCLUDE CURRENT ROW)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.window.Build()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	avg, err := Window("AVG(amount)").OrderBy("day").Rows(Preceding(2), CurrentRow()).As("avg3").Build()
+	assert.NoError(t, err)
+	b := New().Select("day", avg).From("sales").Where("day >= ?", "2024-01-01")
+	assert.Equal(t, "SELECT day, AVG(amount) OVER (ORDER BY day ROWS BETWEEN 2 PRECEDING AND CURRENT ROW) AS avg3 FROM sales WHERE day >= $1", b.String())
+}
+
+func TestWindowInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		window *WindowExpr
+		want   string
+	}{
+		{"range offset without order", Window("SUM(x)").Range(Preceding(3), CurrentRow()), "RANGE frame with an offset needs exactly one ORDER BY column, got 0"},
+		{"range offset with two order columns", Window("SUM(x)").OrderBy("a", "b").Range(CurrentRow(), Following(1)), "RANGE frame with an offset needs exactly one ORDER BY column, got 2"},
+		{"groups without order", Window("SUM(x)").Groups(Preceding(Unbounded), CurrentRow()), "GROUPS frame needs an ORDER BY"},
+		{"start after end", Window("SUM(x)").OrderBy("a").Rows(CurrentRow(), Preceding(1)), "window frame starts at CURRENT ROW, after its end 1 PRECEDING"},
+		{"unbounded following start", Window("SUM(x)").Rows(Following(Unbounded), Following(Unbounded)), "window frame cannot start at UNBOUNDED FOLLOWING"},
+		{"unbounded preceding end", Window("SUM(x)").Rows(Preceding(Unbounded), Preceding(Unbounded)), "window frame cannot end at UNBOUNDED PRECEDING"},
+		{"negative offset", Window("SUM(x)").Rows(Preceding(-1), CurrentRow()), "window frame offset must not be negative, got -1"},
+		{"string offset", Window("SUM(x)").Rows(Preceding("1; DROP TABLE t"), CurrentRow()), "window frame offset must be an integer, Unbounded or SQLExpression, got string"},
+		{"zero bound", Window("SUM(x)").Rows(FrameBound{}, CurrentRow()), "window frame bounds must be made with Preceding, Following or CurrentRow"},
+		{"exclude without frame", Window("SUM(x)").Exclude(ExcludeGroup), "window frame exclusion needs a frame"},
+		{"bad alias", Window("SUM(x)").As("x y"), `invalid alias name "x y"`},
+		{"empty function", Window(""), "window function is empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.window.Build()
+			assert.EqualError(t, err, tt.want)
+		})
+	}
+}